@@ -30,6 +30,7 @@ var envList = []string{
 	localdata.EnvNameMirrorSyncScript,
 	localdata.EnvNameLogPath,
 	localdata.EnvNameDebug,
+	localdata.EnvNameContainerRuntime,
 }
 
 func newEnvCmd() *cobra.Command {
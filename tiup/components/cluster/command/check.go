@@ -16,8 +16,11 @@ package command
 import (
 	"path"
 
+	perrs "github.com/pingcap/errors"
+
 	"github.com/pingcap/tiup/pkg/cluster/manager"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	tiupmeta "github.com/pingcap/tiup/pkg/environment"
 	"github.com/pingcap/tiup/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -27,15 +30,68 @@ func newCheckCmd() *cobra.Command {
 		Opr:          &operator.CheckOptions{},
 		IdentityFile: path.Join(utils.UserHome(), ".ssh", "id_rsa"),
 	}
+	fOpt := fleetOptions{}
+	var overlays []string
 	cmd := &cobra.Command{
-		Use:   "check <topology.yml | cluster-name>",
+		Use:   "check <topology.yml | cluster-name...>",
 		Short: "Perform preflight checks for the cluster.",
 		Long: `Perform preflight checks for the cluster. By default, it checks deploy servers
 before a cluster is deployed, the input is the topology.yaml for the cluster.
 If '--cluster' is set, it will perform checks for an existing cluster, the input
 is the cluster name. Some checks are ignore in this mode, such as port and dir
-conflict checks with other clusters`,
+conflict checks with other clusters. Multiple clusters can be checked at once
+with '--all', '--cluster-label' or a glob pattern of cluster names in this mode.
+
+Custom checks are loaded from the YAML rule files in '~/.tiup/check.d' (or the
+directory set by '--check-dir'), each file contains a list of rules:
+
+  checks:
+    - name: auditd
+      description: auditd must be running
+      command: systemctl is-active auditd
+      expect: "^active$"
+      fix: systemctl enable --now auditd  # run with '--apply'
+      sudo: true
+    - name: company-audit
+      binary: audit-checker-{arch}  # copied to and run on every host
+      args: ["--strict"]
+      sudo: true                    # run the command and fix as root
+      warn: true
+
+The binaries are copied to the hosts, '{os}' and '{arch}' in the path are
+replaced by the platform of each host, e.g. audit-checker-arm64, they must be
+scripts or executables built for the platform.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opt.PluginDir == "" {
+				opt.PluginDir = tiupmeta.GlobalEnv().Profile().Path(operator.CheckPluginDirName)
+			}
+
+			sel, err := fOpt.selector(args)
+			if err != nil {
+				return err
+			}
+			if sel.IsFleet() {
+				if !opt.ExistCluster {
+					return perrs.New("'--cluster' is required to check multiple clusters")
+				}
+				return runOnClusters(sel, &fOpt, false, func(name string) error {
+					// each cluster loads its own custom checks into the options
+					clusterOpt := opt
+					oprOpt := *opt.Opr
+					clusterOpt.Opr = &oprOpt
+					return cm.CheckCluster(name, clusterOpt, gOpt)
+				})
+			}
+
+			if !opt.ExistCluster {
+				// the topology checked is composed of the files in the
+				// argument and the ones set by '-f'
+				files := topologyFiles(args, overlays)
+				if len(files) == 0 {
+					return cmd.Help()
+				}
+				args, opt.TopoFiles = files[:1], files[1:]
+			}
 			if len(args) != 1 {
 				return cmd.Help()
 			}
@@ -58,7 +114,11 @@ conflict checks with other clusters`,
 	cmd.Flags().BoolVar(&opt.Opr.EnableDisk, "enable-disk", false, "Enable disk IO (fio) check")
 	cmd.Flags().BoolVar(&opt.ApplyFix, "apply", false, "Try to fix failed checks")
 	cmd.Flags().BoolVar(&opt.ExistCluster, "cluster", false, "Check existing cluster, the input is a cluster name.")
+	cmd.Flags().StringArrayVarP(&overlays, "topology", "f", nil, "The topology files overlaying the former ones in order")
+	cmd.Flags().StringVar(&opt.VarsFile, "vars-file", "", "The YAML file of the variables substituted in the topology files")
+	cmd.Flags().StringVar(&opt.PluginDir, "check-dir", "", "The directory to load custom check rules from, default to '~/.tiup/check.d'.")
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "api-timeout", 10, "Timeout in seconds when querying PD APIs.")
+	addFleetFlags(cmd, &fOpt)
 
 	return cmd
 }
@@ -0,0 +1,66 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configs of a cluster",
+	}
+
+	cmd.AddCommand(
+		newConfigDiffCmd(),
+	)
+	return cmd
+}
+
+func newConfigDiffCmd() *cobra.Command {
+	opt := manager.ConfigDiffOptions{}
+	cmd := &cobra.Command{
+		Use:   "diff <cluster-name>",
+		Short: "Show the config drift of the instances",
+		Long: `Show the config drift of the instances. The configs rendered from the topology
+(intended), the config files on the hosts (deployed) and the configs reported by
+the running PD, TiKV and TiDB instances (live) are compared.
+
+With '--reconcile', the intended config files are pushed to the drifted instances,
+the live configs are changed online if all the drifted options support it, and
+the other instances with live drift are restarted one by one, with the leaders
+evicted like reload, to load the config files.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.ConfigDiff(clusterName, opt, gOpt, skipConfirm)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opt.Reconcile, "reconcile", false, "Push the intended configs to the drifted instances")
+	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only diff specified roles")
+	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only diff specified nodes")
+	cmd.Flags().BoolVar(&gOpt.IgnoreConfigCheck, "ignore-config-check", false, "Ignore the config check result")
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "api-timeout", 10, "Timeout in seconds when querying the config APIs.")
+
+	return cmd
+}
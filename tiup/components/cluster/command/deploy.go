@@ -15,7 +15,6 @@ package command
 
 import (
 	"context"
-	"os"
 	"path"
 
 	"github.com/pingcap/tiup/pkg/cluster/manager"
@@ -37,13 +36,20 @@ func newDeploy() *cobra.Command {
 	opt := manager.DeployOptions{
 		IdentityFile: path.Join(utils.UserHome(), ".ssh", "id_rsa"),
 	}
+	var overlays []string
 	cmd := &cobra.Command{
-		Use:          "deploy <cluster-name> <version> <topology.yaml>",
-		Short:        "Deploy a cluster for production",
-		Long:         "Deploy a cluster for production. SSH connection will be used to deploy files, as well as creating system users for running the service.",
+		Use:   "deploy <cluster-name> <version> [topology.yaml]",
+		Short: "Deploy a cluster for production",
+		Long: `Deploy a cluster for production. SSH connection will be used to deploy files, as well as creating system users for running the service.
+
+The topology can be composed of several files, the files set by '-f' overlay the
+former ones in order, e.g. 'deploy prod v5.2.1 -f base.yaml -f prod.yaml'. A topology
+file can include shared fragments with 'include: [monitoring.yaml]', and use
+'${VAR}' or '${VAR:-default}' to substitute the variables from the environment or
+the file set by '--vars-file'.`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			shouldContinue, err := tui.CheckCommandArgsAndMayPrintHelp(cmd, args, 3)
+			shouldContinue, err := tui.CheckCommandArgsAndMayPrintHelp(cmd, args, 2)
 			if err != nil {
 				return err
 			}
@@ -60,12 +66,15 @@ func newDeploy() *cobra.Command {
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 			teleCommand = append(teleCommand, version)
 
-			topoFile := args[2]
-			if data, err := os.ReadFile(topoFile); err == nil {
+			topoFiles := topologyFiles(args[2:], overlays)
+			if len(args) > 3 || len(topoFiles) == 0 {
+				return cmd.Help()
+			}
+			if data, err := spec.ComposeTopology(topoFiles, opt.VarsFile); err == nil {
 				teleTopology = string(data)
 			}
 
-			return cm.Deploy(clusterName, version, topoFile, opt, postDeployHook, skipConfirm, gOpt)
+			return cm.Deploy(clusterName, version, topoFiles, opt, postDeployHook, skipConfirm, gOpt)
 		},
 	}
 
@@ -75,10 +84,20 @@ func newDeploy() *cobra.Command {
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.IgnoreConfigCheck, "ignore-config-check", "", opt.IgnoreConfigCheck, "Ignore the config check result")
 	cmd.Flags().BoolVarP(&opt.NoLabels, "no-labels", "", false, "Don't check TiKV labels")
+	cmd.Flags().StringArrayVarP(&overlays, "topology", "f", nil, "The topology files overlaying the former ones in order")
+	cmd.Flags().StringVar(&opt.VarsFile, "vars-file", "", "The YAML file of the variables substituted in the topology files")
 
 	return cmd
 }
 
+// topologyFiles returns the topology files in the arguments followed by the ones
+// set by '-f', the latter files overlay the former ones
+func topologyFiles(args, overlays []string) []string {
+	files := make([]string, 0, len(args)+len(overlays))
+	files = append(files, args...)
+	return append(files, overlays...)
+}
+
 func postDeployHook(builder *task.Builder, topo spec.Topology, gOpt operator.Options) {
 	nodeInfoTask := task.NewBuilder(builder.Logger).Func("Check status", func(ctx context.Context) error {
 		var err error
@@ -29,11 +29,23 @@ func newDisplayCmd() *cobra.Command {
 		showDashboardOnly bool
 		showVersionOnly   bool
 		showTiKVLabels    bool
+		fOpt              fleetOptions
+		display           func(clusterName string, fleet bool) error
 	)
 	cmd := &cobra.Command{
-		Use:   "display <cluster-name>",
+		Use:   "display <cluster-name>...",
 		Short: "Display information of a TiDB cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			sel, err := fOpt.selector(args)
+			if err != nil {
+				return err
+			}
+			if sel.IsFleet() {
+				return runOnClusters(sel, &fOpt, false, func(name string) error {
+					return display(name, true)
+				})
+			}
+
 			if len(args) != 1 {
 				return cmd.Help()
 			}
@@ -42,38 +54,48 @@ func newDisplayCmd() *cobra.Command {
 			clusterReport.ID = scrubClusterName(clusterName)
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 
-			exist, err := tidbSpec.Exist(clusterName)
-			if err != nil {
-				return err
-			}
+			return display(clusterName, false)
+		},
+	}
 
-			if !exist {
-				return perrs.Errorf("Cluster %s not found", clusterName)
-			}
+	// display shows the information of a cluster, the version is prefixed by
+	// the cluster name when multiple clusters are displayed
+	display = func(clusterName string, fleet bool) error {
+		exist, err := tidbSpec.Exist(clusterName)
+		if err != nil {
+			return err
+		}
 
-			metadata, err := spec.ClusterMetadata(clusterName)
-			if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
-				!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
-				return err
-			}
+		if !exist {
+			return perrs.Errorf("Cluster %s not found", clusterName)
+		}
+
+		metadata, err := spec.ClusterMetadata(clusterName)
+		if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+			!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+			return err
+		}
 
-			if showVersionOnly {
-				fmt.Println(metadata.Version)
+		if showVersionOnly {
+			if fleet {
+				fmt.Printf("%s: %s\n", clusterName, metadata.Version)
 				return nil
 			}
+			fmt.Println(metadata.Version)
+			return nil
+		}
 
-			if showDashboardOnly {
-				tlsCfg, err := metadata.Topology.TLSConfig(tidbSpec.Path(clusterName, spec.TLSCertKeyDir))
-				if err != nil {
-					return err
-				}
-				return cm.DisplayDashboardInfo(clusterName, tlsCfg)
-			}
-			if showTiKVLabels {
-				return cm.DisplayTiKVLabels(clusterName, gOpt)
+		if showDashboardOnly {
+			tlsCfg, err := metadata.Topology.TLSConfig(tidbSpec.Path(clusterName, spec.TLSCertKeyDir))
+			if err != nil {
+				return err
 			}
-			return cm.Display(clusterName, gOpt)
-		},
+			return cm.DisplayDashboardInfo(clusterName, tlsCfg)
+		}
+		if showTiKVLabels {
+			return cm.DisplayTiKVLabels(clusterName, gOpt)
+		}
+		return cm.Display(clusterName, gOpt)
 	}
 
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only display specified roles")
@@ -82,6 +104,7 @@ func newDisplayCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&showDashboardOnly, "dashboard", false, "Only display TiDB Dashboard information")
 	cmd.Flags().BoolVar(&showVersionOnly, "version", false, "Only display TiDB cluster version")
 	cmd.Flags().BoolVar(&showTiKVLabels, "labels", false, "Only display labels of specified TiKV role or nodes")
+	addFleetFlags(cmd, &fOpt)
 
 	return cmd
 }
@@ -0,0 +1,45 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor <cluster-name>",
+		Short: "Diagnose the live state of a running cluster",
+		Long: `Diagnose the live state of a running cluster. It reports down or tombstone
+stores, unbalanced leaders and regions, config drift of PD and TiKV against the
+topology, clock skew between hosts, disks near capacity, failed changefeeds and
+binlog nodes, and instances running a version different from the meta, along
+with suggested commands to fix them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.Doctor(clusterName, gOpt)
+		},
+	}
+
+	cmd.Flags().Uint64Var(&gOpt.APITimeout, "api-timeout", 10, "Timeout in seconds when querying component APIs.")
+
+	return cmd
+}
@@ -37,6 +37,7 @@ func newEditConfigCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&opt.NewTopoFile, "topology-file", "", opt.NewTopoFile, "Use provided topology file to substitute the original one instead of editing it.")
+	cmd.Flags().BoolVarP(&opt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
 
 	return cmd
 }
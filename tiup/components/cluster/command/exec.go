@@ -20,11 +20,22 @@ import (
 
 func newExecCmd() *cobra.Command {
 	opt := manager.ExecOptions{}
+	fOpt := fleetOptions{}
 	cmd := &cobra.Command{
-		Use:    "exec <cluster-name>",
+		Use:    "exec <cluster-name>...",
 		Short:  "Run shell command on host in the tidb cluster",
 		Hidden: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			sel, err := fOpt.selector(args)
+			if err != nil {
+				return err
+			}
+			if sel.IsFleet() {
+				return runOnClusters(sel, &fOpt, false, func(name string) error {
+					return cm.Exec(name, opt, gOpt)
+				})
+			}
+
 			if len(args) != 1 {
 				return cmd.Help()
 			}
@@ -41,6 +52,7 @@ func newExecCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&opt.Sudo, "sudo", false, "use root permissions (default false)")
 	cmd.Flags().StringSliceVarP(&gOpt.Roles, "role", "R", nil, "Only exec on host with specified roles")
 	cmd.Flags().StringSliceVarP(&gOpt.Nodes, "node", "N", nil, "Only exec on host with specified nodes")
+	addFleetFlags(cmd, &fOpt)
 
 	return cmd
 }
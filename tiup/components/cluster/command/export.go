@@ -0,0 +1,62 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a cluster to other platforms",
+	}
+
+	cmd.AddCommand(
+		newExportK8sCmd(),
+	)
+	return cmd
+}
+
+func newExportK8sCmd() *cobra.Command {
+	opt := manager.ExportK8sOptions{}
+	cmd := &cobra.Command{
+		Use:   "k8s <cluster-name>",
+		Short: "Export a cluster to the TidbCluster resource of tidb-operator",
+		Long: `Export a cluster to the TidbCluster resource of tidb-operator. The versions,
+replicas, resource limits, server configs and TLS settings in the topology are
+exported, drainers are exported as ConfigMaps for the tidb-drainer chart.
+
+The fields that can't be represented in Kubernetes are listed as warnings, and
+the TidbCluster is validated against the bundled CRD schema before written out.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			return cm.ExportK8s(clusterName, opt)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opt.Output, "output", "o", "", "Write the resources to the file instead of stdout")
+	cmd.Flags().StringVarP(&opt.Namespace, "namespace", "n", "", "The namespace of the resources")
+	cmd.Flags().StringVar(&opt.StorageClass, "storage-class", "", "The storage class of the persistent volumes")
+
+	return cmd
+}
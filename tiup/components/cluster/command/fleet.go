@@ -0,0 +1,94 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/spf13/cobra"
+)
+
+// fleetOptions are the flags to run a command on a group of clusters
+type fleetOptions struct {
+	all         bool
+	labels      []string
+	concurrency int
+}
+
+func addFleetFlags(cmd *cobra.Command, opt *fleetOptions) {
+	cmd.Flags().BoolVar(&opt.all, "all", false, "Run on all the clusters")
+	cmd.Flags().StringSliceVar(&opt.labels, "cluster-label", nil, "Run on the clusters with the label (key=value), set by `tiup cluster label`")
+	cmd.Flags().IntVar(&opt.concurrency, "cluster-concurrency", 1, "Max number of clusters to run on at the same time")
+}
+
+// selected returns true if the clusters are selected by flags instead of names
+func (opt *fleetOptions) selected() bool {
+	return opt.all || len(opt.labels) > 0
+}
+
+// selector builds the cluster selector from the flags and the name patterns
+func (opt *fleetOptions) selector(patterns []string) (manager.ClusterSelector, error) {
+	labels, err := manager.ParseClusterLabels(opt.labels)
+	if err != nil {
+		return manager.ClusterSelector{}, err
+	}
+	return manager.ClusterSelector{
+		All:      opt.all,
+		Patterns: patterns,
+		Labels:   labels,
+	}, nil
+}
+
+// runOnClusters runs fn on every cluster selected, interactive commands must be
+// confirmed by `--yes` if they are going to run on multiple clusters at the same time
+func runOnClusters(sel manager.ClusterSelector, opt *fleetOptions, interactive bool, fn func(name string) error) error {
+	names, err := cm.SelectClusters(sel)
+	if err != nil {
+		return err
+	}
+	if interactive && opt.concurrency > 1 && len(names) > 1 && !skipConfirm {
+		return perrs.New("'--yes' is required to run on multiple clusters concurrently")
+	}
+	teleCommand = append(teleCommand, "fleet")
+	return cm.ForEachCluster(names, opt.concurrency, fn)
+}
+
+func newLabelCmd() *cobra.Command {
+	var remove []string
+	cmd := &cobra.Command{
+		Use:   "label <cluster-name> [key=value...]",
+		Short: "Set labels of a cluster to group clusters",
+		Long: `Set labels of a cluster, the labels are stored in the meta of the cluster and
+can be used to run commands on a group of clusters with '--cluster-label'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			clusterReport.ID = scrubClusterName(clusterName)
+			teleCommand = append(teleCommand, scrubClusterName(clusterName))
+
+			labels, err := manager.ParseClusterLabels(args[1:])
+			if err != nil {
+				return err
+			}
+			return cm.SetClusterLabels(clusterName, labels, remove)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&remove, "remove", nil, "Remove the labels with the keys")
+
+	return cmd
+}
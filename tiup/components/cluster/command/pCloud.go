@@ -19,10 +19,38 @@ import (
 )
 
 func newCloudCmd() *cobra.Command {
+	fOpt := fleetOptions{}
 	cmd := &cobra.Command{
 		Use:   "cloud <cluster-name> <operation>",
 		Short: "backup data to cloud for PiTR/restore backup data from cloud",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if fOpt.selected() && len(args) == 1 {
+				// the clusters are selected by flags, only the operation is given
+				args = append([]string{""}, args...)
+			}
+			if len(args) == 2 {
+				patterns := []string{args[0]}
+				if args[0] == "" {
+					patterns = nil
+				}
+				sel, err := fOpt.selector(patterns)
+				if err != nil {
+					return err
+				}
+				if sel.IsFleet() {
+					return runOnClusters(sel, &fOpt, true, func(name string) error {
+						switch args[1] {
+						case "snapshot", "checkpoint", "mkcp", "make-checkpoint":
+							return cm.SetCheckpoint(name, skipConfirm)
+						case "backup":
+							return cm.Backup2Cloud(name, gOpt)
+						default:
+							return perrs.Errorf("Cloud cmd %s not support for multiple clusters", args[1])
+						}
+					})
+				}
+			}
+
 			if len(args) != 2 && len(args) != 3 {
 				return cmd.Help()
 			}
@@ -58,5 +86,8 @@ func newCloudCmd() *cobra.Command {
 			}
 		},
 	}
+
+	addFleetFlags(cmd, &fOpt)
+
 	return cmd
 }
@@ -0,0 +1,61 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"path"
+
+	"github.com/pingcap/tiup/pkg/cluster/manager"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newPlanCmd() *cobra.Command {
+	opt := manager.PlanOptions{
+		IdentityFile: path.Join(utils.UserHome(), ".ssh", "id_rsa"),
+	}
+	var overlays []string
+	cmd := &cobra.Command{
+		Use:   "plan <topology.yaml>",
+		Short: "Plan the placement of instances sharing hosts",
+		Long: `Plan the placement of instances sharing hosts. The memory, NUMA nodes and
+disks of the hosts are probed via SSH, then the topology is completed for the
+hosts with multiple instances:
+
+  - the conflicting ports are shifted
+  - the instances are bound to NUMA nodes (numa_node)
+  - the data of TiKV, TiFlash, PD, Pump and Drainer is spread on the disks (data_dir)
+  - the memory of the host is shared by the instances (resource_control.memory_limit)
+
+The values set in the topology are kept. The NUMA nodes and disks of a host are
+limited to the 'numa_nodes' and 'disks' of the hosts inventory if they are set. The planned topology can be deployed directly.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := topologyFiles(args, overlays)
+			if len(files) == 0 {
+				return cmd.Help()
+			}
+			return cm.Plan(files, opt, gOpt)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opt.User, "user", "u", utils.CurrentUser(), "The user name to login via SSH.")
+	cmd.Flags().StringVarP(&opt.IdentityFile, "identity_file", "i", opt.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
+	cmd.Flags().StringVar(&opt.HostsFile, "hosts", "", "The YAML file of the hosts inventory, merged by host into the one in topology")
+	cmd.Flags().StringVarP(&opt.Output, "output", "o", "", "The file to write the planned topology to, default to stdout")
+	cmd.Flags().StringArrayVarP(&overlays, "topology", "f", nil, "The topology files overlaying the former ones in order")
+	cmd.Flags().StringVar(&opt.VarsFile, "vars-file", "", "The YAML file of the variables substituted in the topology files")
+
+	return cmd
+}
@@ -20,18 +20,40 @@ import (
 )
 
 func newReloadCmd() *cobra.Command {
-	var skipRestart bool
+	var (
+		skipRestart bool
+		fOpt        fleetOptions
+	)
 	cmd := &cobra.Command{
-		Use:   "reload <cluster-name>",
+		Use:   "reload <cluster-name>...",
 		Short: "Reload a TiDB cluster's config and restart if needed",
+		Long: `Reload a TiDB cluster's config and restart if needed. The config files of PD,
+TiKV and TiDB instances are compared with the deployed ones before refreshed:
+
+  - the instances without any change are not restarted
+  - the options that can be changed at runtime are applied online via the config
+    APIs of the components, the instances with only such changes are not restarted
+  - the other instances are restarted to load the new config files
+
+The instances of other components are always restarted.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 1 {
-				return cmd.Help()
+			if err := validRoles(gOpt.Roles); err != nil {
+				return err
 			}
 
-			if err := validRoles(gOpt.Roles); err != nil {
+			sel, err := fOpt.selector(args)
+			if err != nil {
 				return err
 			}
+			if sel.IsFleet() {
+				return runOnClusters(sel, &fOpt, true, func(name string) error {
+					return cm.Reload(name, gOpt, skipRestart, skipConfirm)
+				})
+			}
+
+			if len(args) != 1 {
+				return cmd.Help()
+			}
 
 			clusterName := args[0]
 			clusterReport.ID = scrubClusterName(clusterName)
@@ -47,6 +69,7 @@ func newReloadCmd() *cobra.Command {
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
 	cmd.Flags().BoolVar(&skipRestart, "skip-restart", false, "Only refresh configuration to remote and do not restart services")
+	addFleetFlags(cmd, &fOpt)
 
 	return cmd
 }
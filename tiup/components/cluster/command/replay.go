@@ -15,9 +15,12 @@ package command
 
 import (
 	"fmt"
+	"os"
 	"path"
+	"strconv"
 	"strings"
 
+	"github.com/fatih/color"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/checkpoint"
 	"github.com/pingcap/tiup/pkg/cluster/audit"
@@ -27,27 +30,57 @@ import (
 )
 
 func newReplayCmd() *cobra.Command {
+	var (
+		listSteps bool
+		fromStep  int
+		skipSteps []int
+	)
 	cmd := &cobra.Command{
 		Use:   "replay <audit-id>",
 		Short: "Replay previous operation and skip successed steps",
+		Long: `Replay previous operation and skip successed steps.
+
+Every task executed by the operation is recorded as a step in the audit log,
+the steps finished are skipped on replay by default. Use '--list' to show the
+steps, '--from-step' to re-execute the operation from a step and '--skip' to
+skip specific steps even if they failed.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return cmd.Help()
 			}
 
 			file := path.Join(spec.AuditDir(), args[0])
-			if !checkpoint.HasCheckPoint() {
+			steps, err := readReplaySteps(file)
+			if err != nil {
+				return errors.Annotate(err, "read steps from audit log failed")
+			}
+			if listSteps {
+				printReplaySteps(steps, nil, false)
+				return nil
+			}
+
+			skipped, err := selectSkippedSteps(steps, fromStep, skipSteps)
+			if err != nil {
+				return err
+			}
+			checkpoint.SkipSteps(skipped)
+
+			// re-executed steps must not hit the command level checkpoints
+			// if the user chose where the operation starts from
+			if fromStep == 0 && !checkpoint.HasCheckPoint() {
 				if err := checkpoint.SetCheckPoint(file); err != nil {
 					return errors.Annotate(err, "set checkpoint failed")
 				}
 			}
 
-			args, err := audit.CommandArgs(file)
+			args, err = audit.CommandArgs(file)
 			if err != nil {
 				return errors.Annotate(err, "read audit log failed")
 			}
 
 			if !skipConfirm {
+				printReplaySteps(steps, skipped, true)
+				warnTopologyChanged(file, args)
 				if err := tui.PromptForConfirmOrAbortError(
 					fmt.Sprintf("Will replay the command `tiup cluster %s`\nDo you want to continue? [y/N]: ", strings.Join(args[1:], " ")),
 				); err != nil {
@@ -55,10 +88,147 @@ func newReplayCmd() *cobra.Command {
 				}
 			}
 
+			replayedArgs = args
 			rootCmd.SetArgs(args[1:])
 			return rootCmd.Execute()
 		},
 	}
 
+	cmd.Flags().BoolVar(&listSteps, "list", false, "List the steps recorded in the audit log")
+	cmd.Flags().IntVar(&fromStep, "from-step", 0, "Re-execute the operation from the step, all the steps before it are skipped")
+	cmd.Flags().IntSliceVar(&skipSteps, "skip", nil, "Skip the steps even if they failed")
+
 	return cmd
 }
+
+func readReplaySteps(file string) ([]*checkpoint.Step, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	defer f.Close()
+	return checkpoint.ParseSteps(f)
+}
+
+// selectSkippedSteps returns the steps to skip on replay, they are the steps
+// finished by default, or all the steps before fromStep if it's set
+func selectSkippedSteps(steps []*checkpoint.Step, fromStep int, skipSteps []int) ([]*checkpoint.Step, error) {
+	if fromStep < 0 || fromStep > len(steps) {
+		return nil, errors.Errorf("step %d is out of range, there are %d steps in the audit log", fromStep, len(steps))
+	}
+	skip := make(map[int]bool)
+	for _, idx := range skipSteps {
+		if idx < 1 || idx > len(steps) {
+			return nil, errors.Errorf("step %d is out of range, there are %d steps in the audit log", idx, len(steps))
+		}
+		skip[idx] = true
+	}
+
+	var skipped []*checkpoint.Step
+	for _, s := range steps {
+		switch {
+		case skip[s.Index]:
+		case fromStep > 0 && s.Index < fromStep:
+		case fromStep == 0 && s.Done():
+		default:
+			continue
+		}
+		skipped = append(skipped, s)
+	}
+	return skipped, nil
+}
+
+// printReplaySteps prints the steps, and whether they will be re-executed if replay is set
+func printReplaySteps(steps, skipped []*checkpoint.Step, replay bool) {
+	if len(steps) == 0 {
+		log.Warnf("No step is recorded in the audit log, all the tasks will be executed")
+		return
+	}
+
+	skip := make(map[int]bool)
+	for _, s := range skipped {
+		skip[s.Index] = true
+	}
+
+	header := []string{"Step", "Status", "Task"}
+	if replay {
+		header = append(header, "Replay")
+	}
+	stepTable := [][]string{header}
+	for _, s := range steps {
+		status := color.GreenString("Done")
+		if !s.Done() {
+			status = color.HiRedString("Failed")
+		}
+		row := []string{strconv.Itoa(s.Index), status, s.Task}
+		if replay {
+			if skip[s.Index] {
+				row = append(row, "skip")
+			} else {
+				row = append(row, color.YellowString("execute"))
+			}
+		}
+		stepTable = append(stepTable, row)
+	}
+	tui.PrintTable(stepTable, true)
+	fmt.Println("Tasks not recorded in the audit log, such as connecting to the hosts, are always executed.")
+}
+
+// replayedArgs is the command replayed, it's recorded in the audit log of
+// the replay instead of the replay command
+var replayedArgs []string
+
+// operatedCluster returns the existing cluster the command operates on, which
+// is the argument after the subcommand
+func operatedCluster(args []string) string {
+	if len(args) < 3 || tidbSpec == nil {
+		return ""
+	}
+	if exist, _ := tidbSpec.Exist(args[2]); !exist {
+		return ""
+	}
+	return args[2]
+}
+
+// recordMetaHash records the hash of the meta of the cluster operated on in
+// the audit log, so the replay can tell if the meta is changed since
+func recordMetaHash() {
+	args := os.Args
+	if replayedArgs != nil {
+		args = replayedArgs
+	}
+	name := operatedCluster(args)
+	if name == "" {
+		return
+	}
+	if hash, err := tidbSpec.MetaHash(name); err == nil {
+		checkpoint.RecordMetaHash(hash)
+	}
+}
+
+// warnTopologyChanged warns if the meta of the cluster has been changed after
+// the operation in the audit log. The steps are identified by their tasks, so
+// the finished steps of the changed nodes are still skipped, and the tasks of
+// the added nodes are executed.
+func warnTopologyChanged(file string, args []string) {
+	clusterName := operatedCluster(args)
+	if clusterName == "" {
+		return
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	recorded, err := checkpoint.ParseMetaHash(f)
+	if err != nil || recorded == "" {
+		return
+	}
+	if hash, err := tidbSpec.MetaHash(clusterName); err != nil || hash == recorded {
+		return
+	}
+	log.Warnf("The topology of cluster %s has been changed since the operation in the audit log,",
+		color.YellowString(clusterName))
+	log.Warnf("the finished steps are still skipped even if their nodes are changed, use --from-step to execute them again.")
+	log.Warnf("The tasks of the added nodes are executed, and the steps of the removed nodes are ignored.")
+}
@@ -163,7 +163,7 @@ func init() {
 	rootCmd.PersistentFlags().Uint64Var(&gOpt.OptTimeout, "wait-timeout", 120, "Timeout in seconds to wait for an operation to complete, ignored for operations that don't fit.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
 	rootCmd.PersistentFlags().BoolVar(&gOpt.NativeSSH, "native-ssh", gOpt.NativeSSH, "(EXPERIMENTAL) Use the native SSH client installed on local system instead of the build-in one.")
-	rootCmd.PersistentFlags().StringVar((*string)(&gOpt.SSHType), "ssh", "", "(EXPERIMENTAL) The executor type: 'builtin', 'system', 'none'.")
+	rootCmd.PersistentFlags().StringVar((*string)(&gOpt.SSHType), "ssh", "", "(EXPERIMENTAL) The executor type: 'builtin', 'system', 'none', 'container'.")
 	rootCmd.PersistentFlags().IntVarP(&gOpt.Concurrency, "concurrency", "c", 5, "max number of parallel tasks allowed")
 	rootCmd.PersistentFlags().StringVar(&gOpt.DisplayMode, "format", "default", "(EXPERIMENTAL) The format of output, available values are [default, json]")
 	rootCmd.PersistentFlags().StringVar(&gOpt.SSHProxyHost, "ssh-proxy-host", "", "The SSH proxy host used to connect to remote host.")
@@ -182,6 +182,8 @@ func init() {
 
 	rootCmd.AddCommand(
 		newCheckCmd(),
+		newPlanCmd(),
+		newDoctorCmd(),
 		newDeploy(),
 		newStartCmd(),
 		newStopCmd(),
@@ -198,6 +200,9 @@ func init() {
 		newImportCmd(),
 		newEditConfigCmd(),
 		newShowConfigCmd(),
+		newConfigCmd(),
+		newSecretCmd(),
+		newExportCmd(),
 		newReloadCmd(),
 		newPatchCmd(),
 		newRenameCmd(),
@@ -211,6 +216,7 @@ func init() {
 		newReplayCmd(),
 		newTemplateCmd(),
 		newCloudCmd(),
+		newLabelCmd(),
 	)
 }
 
@@ -386,6 +392,7 @@ func Execute() {
 			}
 		}
 	}
+	recordMetaHash()
 	err = logger.OutputAuditLogIfEnabled()
 	if err != nil {
 		zap.L().Warn("Write audit log file failed", zap.Error(err))
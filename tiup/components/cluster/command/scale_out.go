@@ -14,7 +14,6 @@
 package command
 
 import (
-	"os"
 	"path/filepath"
 
 	"github.com/pingcap/tiup/pkg/cluster/manager"
@@ -29,6 +28,7 @@ func newScaleOutCmd() *cobra.Command {
 	opt := manager.DeployOptions{
 		IdentityFile: filepath.Join(utils.UserHome(), ".ssh", "id_rsa"),
 	}
+	var overlays []string
 	cmd := &cobra.Command{
 		Use:          "scale-out <cluster-name> [topology.yaml]",
 		Short:        "Scale out a TiDB cluster",
@@ -36,7 +36,7 @@ func newScaleOutCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				clusterName string
-				topoFile    string
+				topoFiles   []string
 			)
 
 			// tiup cluster scale-out --stage1 --stage2
@@ -50,10 +50,12 @@ func newScaleOutCmd() *cobra.Command {
 			if opt.Stage2 && len(args) == 1 {
 				clusterName = args[0]
 			} else {
-				if len(args) == 2 {
-					clusterName = args[0]
-					topoFile = args[1]
-				} else {
+				if len(args) < 1 || len(args) > 2 {
+					return cmd.Help()
+				}
+				clusterName = args[0]
+				topoFiles = topologyFiles(args[1:], overlays)
+				if len(topoFiles) == 0 {
 					return cmd.Help()
 				}
 			}
@@ -61,14 +63,14 @@ func newScaleOutCmd() *cobra.Command {
 			clusterReport.ID = scrubClusterName(clusterName)
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 
-			// stage2: topoFile is ""
-			if data, err := os.ReadFile(topoFile); err == nil {
+			// stage2: topoFiles is empty
+			if data, err := spec.ComposeTopology(topoFiles, opt.VarsFile); err == nil {
 				teleTopology = string(data)
 			}
 
 			return cm.ScaleOut(
 				clusterName,
-				topoFile,
+				topoFiles,
 				postScaleOutHook,
 				final,
 				opt,
@@ -83,8 +85,11 @@ func newScaleOutCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&opt.IdentityFile, "identity_file", "i", opt.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.UsePassword, "password", "p", false, "Use password of target hosts. If specified, password authentication will be used.")
 	cmd.Flags().BoolVarP(&opt.NoLabels, "no-labels", "", false, "Don't check TiKV labels")
+	cmd.Flags().BoolVarP(&opt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
 	cmd.Flags().BoolVarP(&opt.Stage1, "stage1", "", false, "Don't start the new instance after scale-out, need to manually execute cluster scale-out --stage2")
 	cmd.Flags().BoolVarP(&opt.Stage2, "stage2", "", false, "Start the new instance and init config after scale-out --stage1")
+	cmd.Flags().StringArrayVarP(&overlays, "topology", "f", nil, "The topology files overlaying the former ones in order")
+	cmd.Flags().StringVar(&opt.VarsFile, "vars-file", "", "The YAML file of the variables substituted in the topology files")
 
 	return cmd
 }
@@ -0,0 +1,159 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/secret"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+func newSecretCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Manage the secrets referenced in topology",
+		Long: fmt.Sprintf(`Manage the secrets referenced in topology.
+
+The passwords and access keys in topology can be written as references such as
+'secret://grafana-password', they are kept in the meta as they are, and only
+resolved when the config files are generated. The secrets are stored in a local
+vault encrypted by the passphrase in $%[1]s, or by the key file
+in $%[2]s or created by 'secret init'. The secrets not in the vault are
+looked up by the plugin command in $%[3]s, the name of the secret is
+appended to its arguments and the value is read from its stdout.`,
+			spec.EnvSecretPassphrase, spec.EnvSecretKeyFile, spec.EnvSecretProvider),
+	}
+
+	cmd.AddCommand(
+		newSecretInitCmd(),
+		newSecretSetCmd(),
+		newSecretListCmd(),
+		newSecretDeleteCmd(),
+	)
+	return cmd
+}
+
+func newSecretInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the key file of the secret vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := spec.ProfilePath(spec.SecretKeyFile)
+			if err := secret.GenerateKeyFile(path); err != nil {
+				if os.IsExist(perrs.Cause(err)) {
+					return perrs.Errorf("the key file %s already exists", path)
+				}
+				return err
+			}
+			log.Infof("The key file of the secret vault is created at %s, keep a copy of it in a safe place", path)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newSecretSetCmd() *cobra.Command {
+	var fromFile string
+	cmd := &cobra.Command{
+		Use:   "set <name>",
+		Short: "Add or update a secret in the vault",
+		Long: `Add or update a secret in the vault. The value is asked interactively unless
+'--from-file' is set, it's never read from the arguments to keep it out of the
+shell history and the audit log.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			var value string
+			switch fromFile {
+			case "":
+				value = tui.PromptForPassword("Input the value of secret %s: ", args[0])
+			case "-":
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return perrs.AddStack(err)
+				}
+				value = strings.TrimSuffix(string(data), "\n")
+			default:
+				data, err := os.ReadFile(fromFile)
+				if err != nil {
+					return perrs.AddStack(err)
+				}
+				value = string(data)
+			}
+			if value == "" {
+				return perrs.Errorf("the value of secret %s is empty", args[0])
+			}
+
+			if err := spec.SaveSecret(args[0], value); err != nil {
+				return err
+			}
+			log.Infof("Secret %s is saved, reference it as %s%s in topology", args[0], secret.RefPrefix, args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "Read the value from the file, '-' for stdin")
+	return cmd
+}
+
+func newSecretListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the names of secrets in the vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vault, err := spec.OpenSecretVault()
+			if err != nil {
+				return err
+			}
+			rows := [][]string{{"Name", "Reference"}}
+			for _, name := range vault.Names() {
+				rows = append(rows, []string{name, secret.RefPrefix + name})
+			}
+			tui.PrintTable(rows, true)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newSecretDeleteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a secret from the vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			deleted, err := spec.DeleteSecret(args[0])
+			if err != nil {
+				return err
+			}
+			if !deleted {
+				return perrs.Errorf("secret %s not found", args[0])
+			}
+			log.Infof("Secret %s is deleted", args[0])
+			return nil
+		},
+	}
+	return cmd
+}
@@ -29,6 +29,8 @@ type TemplateOptions struct {
 	Full    bool // print full template
 	MultiDC bool // print template for deploying to multiple data center
 	Local   bool // print and render local template
+	Base    bool // print the base template of composed topologies
+	Overlay bool // print the overlay template of composed topologies
 }
 
 // LocalTemplate contains the variables for print local template.
@@ -68,8 +70,8 @@ func newTemplateCmd() *cobra.Command {
 		Use:   "template",
 		Short: "Print topology template",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if sumBool(opt.Full, opt.MultiDC, opt.Local) > 1 {
-				return errors.New("at most one of 'full', 'multi-dc', or 'local' can be specified")
+			if sumBool(opt.Full, opt.MultiDC, opt.Local, opt.Base, opt.Overlay) > 1 {
+				return errors.New("at most one of 'full', 'multi-dc', 'local', 'base' or 'overlay' can be specified")
 			}
 			name := "minimal.yaml"
 			switch {
@@ -79,6 +81,10 @@ func newTemplateCmd() *cobra.Command {
 				name = "multi-dc.yaml"
 			case opt.Local:
 				name = "local.tpl"
+			case opt.Base:
+				name = "base.yaml"
+			case opt.Overlay:
+				name = "overlay.yaml"
 			}
 
 			fp := path.Join("examples", "cluster", name)
@@ -118,6 +124,8 @@ func newTemplateCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&opt.Full, "full", false, "Print the full topology template for TiDB cluster.")
 	cmd.Flags().BoolVar(&opt.MultiDC, "multi-dc", false, "Print template for deploying to multiple data center.")
 	cmd.Flags().BoolVar(&opt.Local, "local", false, "Print and render template for deploying a simple cluster locally.")
+	cmd.Flags().BoolVar(&opt.Base, "base", false, "Print the base template shared by the topologies of several environments.")
+	cmd.Flags().BoolVar(&opt.Overlay, "overlay", false, "Print the overlay template of an environment, used with the base template by 'deploy -f base.yaml -f overlay.yaml'.")
 
 	// template values for rendering
 	cmd.Flags().StringVar(&localOpt.GlobalUser, "user", "tidb", "The user who runs the tidb cluster.")
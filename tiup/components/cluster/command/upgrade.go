@@ -20,20 +20,38 @@ import (
 
 func newUpgradeCmd() *cobra.Command {
 	offlineMode := false
+	fOpt := fleetOptions{}
 
 	cmd := &cobra.Command{
-		Use:   "upgrade <cluster-name> <version>",
+		Use:   "upgrade <cluster-name>... <version>",
 		Short: "Upgrade a specified TiDB cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 2 {
+			// the clusters are selected either by flags or by a single name
+			if len(args) < 1 || (!fOpt.selected() && len(args) < 2) {
 				return cmd.Help()
 			}
 
-			clusterName := args[0]
-			version, err := utils.FmtVer(args[1])
+			version, err := utils.FmtVer(args[len(args)-1])
 			if err != nil {
 				return err
 			}
+
+			sel, err := fOpt.selector(args[:len(args)-1])
+			if err != nil {
+				return err
+			}
+			if sel.IsFleet() {
+				teleCommand = append(teleCommand, version)
+				return runOnClusters(sel, &fOpt, true, func(name string) error {
+					return cm.Upgrade(name, version, gOpt, skipConfirm, offlineMode)
+				})
+			}
+
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
 			clusterReport.ID = scrubClusterName(clusterName)
 			teleCommand = append(teleCommand, scrubClusterName(clusterName))
 			teleCommand = append(teleCommand, version)
@@ -45,6 +63,7 @@ func newUpgradeCmd() *cobra.Command {
 	cmd.Flags().Uint64Var(&gOpt.APITimeout, "transfer-timeout", 600, "Timeout in seconds when transferring PD and TiKV store leaders")
 	cmd.Flags().BoolVarP(&gOpt.IgnoreConfigCheck, "ignore-config-check", "", false, "Ignore the config check result")
 	cmd.Flags().BoolVarP(&offlineMode, "offline", "", false, "Upgrade a stopped cluster")
+	addFleetFlags(cmd, &fOpt)
 
 	return cmd
 }
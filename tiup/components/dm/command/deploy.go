@@ -57,7 +57,7 @@ func newDeployCmd() *cobra.Command {
 				return err
 			}
 
-			return cm.Deploy(clusterName, version, topoFile, opt, postDeployHook, skipConfirm, gOpt)
+			return cm.Deploy(clusterName, version, []string{topoFile}, opt, postDeployHook, skipConfirm, gOpt)
 		},
 	}
 
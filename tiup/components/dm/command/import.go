@@ -96,7 +96,7 @@ func newImportCmd() *cobra.Command {
 			err = cm.Deploy(
 				clusterName,
 				clusterVersion,
-				f.Name(),
+				[]string{f.Name()},
 				manager.DeployOptions{
 					IdentityFile: cansible.SSHKeyPath(),
 					User:         tiuputils.CurrentUser(),
@@ -40,7 +40,7 @@ func newScaleOutCmd() *cobra.Command {
 			clusterName := args[0]
 			topoFile := args[1]
 
-			return cm.ScaleOut(clusterName, topoFile, postScaleOutHook, nil, opt, skipConfirm, gOpt)
+			return cm.ScaleOut(clusterName, []string{topoFile}, postScaleOutHook, nil, opt, skipConfirm, gOpt)
 		},
 	}
 
@@ -28,11 +28,15 @@ type Metadata struct {
 	User    string `yaml:"user"`       // the user to run and manage cluster on remote
 	Version string `yaml:"dm_version"` // the version of TiDB cluster
 	// EnableFirewall bool   `yaml:"firewall"`
+	Labels map[string]string `yaml:"labels,omitempty"` // labels to group clusters
 
 	Topology *Specification `yaml:"topology"`
 }
 
-var _ cspec.UpgradableMetadata = &Metadata{}
+var (
+	_ cspec.UpgradableMetadata = &Metadata{}
+	_ cspec.LabeledMetadata    = &Metadata{}
+)
 
 // SetVersion implement UpgradableMetadata interface.
 func (m *Metadata) SetVersion(s string) {
@@ -44,6 +48,11 @@ func (m *Metadata) SetUser(s string) {
 	m.User = s
 }
 
+// SetLabels implement LabeledMetadata interface.
+func (m *Metadata) SetLabels(labels map[string]string) {
+	m.Labels = labels
+}
+
 // GetTopology implements Metadata interface.
 func (m *Metadata) GetTopology() cspec.Topology {
 	return m.Topology
@@ -64,6 +73,7 @@ func (m *Metadata) GetBaseMeta() *cspec.BaseMeta {
 	return &cspec.BaseMeta{
 		Version: m.Version,
 		User:    m.User,
+		Labels:  m.Labels,
 	}
 }
 
@@ -0,0 +1,535 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/errors"
+	pgapi "github.com/pingcap/tiup/components/playground/api"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// the number of log lines returned if it's not set
+const defaultLogLines = 100
+
+// the interval to check whether the cluster is ready while waiting
+const readyCheckInterval = time.Second
+
+// router serves the API along with the legacy command endpoint
+func (p *Playground) router() http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/command", p.commandHandler)
+
+	s := r.PathPrefix(pgapi.PathPrefix).Subrouter()
+	s.HandleFunc("/openapi.yaml", p.apiSpec).Methods(http.MethodGet)
+	s.HandleFunc("/instances", p.apiInstances).Methods(http.MethodGet)
+	s.HandleFunc("/instances", p.apiScaleOut).Methods(http.MethodPost)
+	s.HandleFunc("/instances/{instance}", p.apiScaleIn).Methods(http.MethodDelete)
+	s.HandleFunc("/instances/{instance}/restart", p.apiRestart).Methods(http.MethodPost)
+	s.HandleFunc("/instances/{instance}/logs", p.apiLogs).Methods(http.MethodGet)
+	s.HandleFunc("/ready", p.apiReady).Methods(http.MethodGet)
+	s.HandleFunc("/topology", p.apiTopology).Methods(http.MethodGet)
+	s.HandleFunc("/topology/cluster", p.apiClusterTopology).Methods(http.MethodGet)
+	s.HandleFunc("/snapshots", p.apiSnapshotSave).Methods(http.MethodPost)
+	s.HandleFunc("/snapshots/restore", p.apiSnapshotRestore).Methods(http.MethodPost)
+	s.HandleFunc("/faults", p.apiInjectFault).Methods(http.MethodPost)
+	s.HandleFunc("/faults", p.apiClearFault).Methods(http.MethodDelete)
+	s.HandleFunc("/faults/{instance}", p.apiClearFault).Methods(http.MethodDelete)
+	s.HandleFunc("/components/{component}/upgrade", p.apiUpgrade).Methods(http.MethodPost)
+	s.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, errors.Errorf("%s %s not found", r.Method, r.URL.Path))
+	})
+	return r
+}
+
+func (p *Playground) apiSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(pgapi.OpenAPISpec)
+}
+
+func (p *Playground) apiInstances(w http.ResponseWriter, r *http.Request) {
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	resp := pgapi.InstancesResponse{Instances: []pgapi.Instance{}}
+	healths := p.instanceHealths()
+	_ = p.WalkInstances(func(cid string, ins instance.Instance) error {
+		inst := p.apiInstance(cid, ins)
+		inst.Health, inst.HealthDetail = healths[ins].state, healths[ins].detail
+		resp.Instances = append(resp.Instances, inst)
+		return nil
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (p *Playground) apiScaleOut(w http.ResponseWriter, r *http.Request) {
+	req := pgapi.ScaleOutRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Annotate(err, "invalid request"))
+		return
+	}
+	cid := componentID(req.Component)
+	if !isComponent(cid) {
+		writeError(w, http.StatusBadRequest, errors.Errorf("unknown component %s", req.Component))
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 0 {
+		writeError(w, http.StatusBadRequest, errors.Errorf("invalid count %d", req.Count))
+		return
+	}
+
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	fmt.Printf("receive request: scale out %d %s\n", req.Count, cid)
+	resp := pgapi.InstancesResponse{Instances: []pgapi.Instance{}}
+	var insts []instance.Instance
+	for i := 0; i < req.Count; i++ {
+		cfg := instance.Config{
+			Host:       req.Host,
+			Port:       req.Port,
+			StatusPort: req.StatusPort,
+			ConfigPath: req.ConfigPath,
+			BinPath:    req.BinPath,
+			Version:    req.Version,
+			Labels:     req.Labels,
+			// the limits are validated when the instance is added
+			MemoryLimit: req.MemoryLimit,
+			CPUQuota:    req.CPUQuota,
+		}
+		ins, err := p.scaleOutInstance(new(bytes.Buffer), cid, cfg)
+		if err != nil {
+			// the instances started keep running, they're listed in the error
+			writeJSON(w, http.StatusInternalServerError, pgapi.Error{
+				Message:   strings.TrimSpace(err.Error()),
+				Instances: resp.Instances,
+			})
+			return
+		}
+		resp.Instances = append(resp.Instances, p.apiInstance(cid, ins))
+		insts = append(insts, ins)
+	}
+	healths := p.instanceHealths()
+	for i, ins := range insts {
+		resp.Instances[i].Health, resp.Instances[i].HealthDetail = healths[ins].state, healths[ins].detail
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (p *Playground) apiScaleIn(w http.ResponseWriter, r *http.Request) {
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	_, ins, ok := p.findInstance(w, r)
+	if !ok {
+		return
+	}
+	fmt.Printf("receive request: scale in %d\n", ins.Pid())
+	p.writeResult(w, func(buf *bytes.Buffer) error {
+		return p.handleScaleIn(buf, ins.Pid())
+	})
+}
+
+func (p *Playground) apiRestart(w http.ResponseWriter, r *http.Request) {
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	cid, ins, ok := p.findInstance(w, r)
+	if !ok {
+		return
+	}
+	name := p.instanceName(cid, ins)
+	fmt.Printf("receive request: restart %s\n", name)
+
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+	if f, ok := p.faults[ins]; ok {
+		writeError(w, http.StatusConflict, errors.Errorf("%s has the fault %s, clear it first", name, f.Kind))
+		return
+	}
+	if err := p.restartInstance(ins); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pgapi.MessageResponse{
+		Message: fmt.Sprintf("%s restarted, the pid is %d now\n", name, ins.Pid()),
+	})
+}
+
+func (p *Playground) apiLogs(w http.ResponseWriter, r *http.Request) {
+	lines := defaultLogLines
+	if s := r.URL.Query().Get("lines"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, errors.Errorf("invalid lines %s", s))
+			return
+		}
+		lines = n
+	}
+
+	if !p.lockCmd(w) {
+		return
+	}
+	_, ins, ok := p.findInstance(w, r)
+	p.cmdMu.Unlock()
+	if !ok {
+		return
+	}
+
+	logs, err := utils.TailN(ins.LogFile(), lines)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range logs {
+		fmt.Fprintln(w, line)
+	}
+}
+
+func (p *Playground) apiReady(w http.ResponseWriter, r *http.Request) {
+	var timeout time.Duration
+	if s := r.URL.Query().Get("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.Annotate(err, "invalid timeout"))
+			return
+		}
+		timeout = d
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	for {
+		// the instances are added by the boot without cmdMu, so they're not
+		// checked until it's finished
+		var resp pgapi.ReadyResponse
+		if atomic.LoadInt32(&p.bootFinished) == 1 {
+			p.cmdMu.Lock()
+			resp = p.readiness()
+			p.cmdMu.Unlock()
+		} else {
+			resp = p.bootReadiness()
+		}
+
+		if resp.Ready {
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		if resp.Failed {
+			writeJSON(w, http.StatusServiceUnavailable, resp)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			writeJSON(w, http.StatusServiceUnavailable, resp)
+			return
+		case <-time.After(readyCheckInterval):
+		}
+	}
+}
+
+func (p *Playground) apiTopology(w http.ResponseWriter, r *http.Request) {
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	buf := new(bytes.Buffer)
+	if err := p.handleDump(buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (p *Playground) apiClusterTopology(w http.ResponseWriter, r *http.Request) {
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	buf := new(bytes.Buffer)
+	if err := p.handleExportCluster(buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (p *Playground) apiSnapshotSave(w http.ResponseWriter, r *http.Request) {
+	p.apiSnapshot(w, r, p.handleSnapshotSave)
+}
+
+func (p *Playground) apiSnapshotRestore(w http.ResponseWriter, r *http.Request) {
+	p.apiSnapshot(w, r, p.handleSnapshotRestore)
+}
+
+func (p *Playground) apiSnapshot(w http.ResponseWriter, r *http.Request, handle func(w io.Writer, file string) error) {
+	req := pgapi.SnapshotRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Annotate(err, "invalid request"))
+		return
+	}
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, errors.New("the path of the snapshot is not set"))
+		return
+	}
+
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	fmt.Printf("receive request: %s %s\n", r.URL.Path, req.Path)
+	p.writeResult(w, func(buf *bytes.Buffer) error {
+		return handle(buf, req.Path)
+	})
+}
+
+func (p *Playground) apiInjectFault(w http.ResponseWriter, r *http.Request) {
+	req := pgapi.FaultRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Annotate(err, "invalid request"))
+		return
+	}
+	fault, err := parseFault(req)
+	if err == nil {
+		err = fault.validate()
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	cid, ins := p.lookupInstance(req.Instance)
+	if ins == nil {
+		writeError(w, http.StatusNotFound, errors.Errorf("instance %s not found", req.Instance))
+		return
+	}
+	fmt.Printf("receive request: fault %s %s\n", fault.Kind, p.instanceName(cid, ins))
+	p.writeResult(w, func(buf *bytes.Buffer) error {
+		return p.injectFault(buf, cid, ins, fault)
+	})
+}
+
+func (p *Playground) apiClearFault(w http.ResponseWriter, r *http.Request) {
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	if _, ok := mux.Vars(r)["instance"]; !ok {
+		fmt.Println("receive request: clear faults")
+		p.writeResult(w, func(buf *bytes.Buffer) error {
+			return p.handleFault(buf, 0, &Fault{Kind: FaultClear})
+		})
+		return
+	}
+
+	cid, ins, ok := p.findInstance(w, r)
+	if !ok {
+		return
+	}
+	fmt.Printf("receive request: clear the fault of %s\n", p.instanceName(cid, ins))
+	p.writeResult(w, func(buf *bytes.Buffer) error {
+		return p.clearFault(buf, ins)
+	})
+}
+
+func (p *Playground) apiUpgrade(w http.ResponseWriter, r *http.Request) {
+	comp := mux.Vars(r)["component"]
+	cid := componentID(comp)
+	if !isComponent(cid) {
+		writeError(w, http.StatusBadRequest, errors.Errorf("unknown component %s", comp))
+		return
+	}
+	req := pgapi.UpgradeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Annotate(err, "invalid request"))
+		return
+	}
+	if req.Version == "" {
+		writeError(w, http.StatusBadRequest, errors.New("the version to upgrade to is not set"))
+		return
+	}
+
+	if !p.lockCmd(w) {
+		return
+	}
+	defer p.cmdMu.Unlock()
+
+	fmt.Printf("receive request: upgrade %s to %s\n", cid, req.Version)
+	p.writeResult(w, func(buf *bytes.Buffer) error {
+		return p.handleUpgrade(buf, cid, req.Version, req.BinPath)
+	})
+}
+
+// lockCmd locks cmdMu if the cluster is booted, or it responses 503
+func (p *Playground) lockCmd(w http.ResponseWriter) bool {
+	if atomic.LoadInt32(&p.bootFinished) == 0 {
+		writeError(w, http.StatusServiceUnavailable, errors.New("the playground is booting"))
+		return false
+	}
+	p.cmdMu.Lock()
+	return true
+}
+
+// writeResult runs the handler of a command and responses its output as the
+// message, cmdMu should be held
+func (p *Playground) writeResult(w http.ResponseWriter, handle func(buf *bytes.Buffer) error) {
+	buf := new(bytes.Buffer)
+	if err := handle(buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pgapi.MessageResponse{Message: buf.String()})
+}
+
+// findInstance looks up the instance in the path, it responses 404 if it's
+// not found, cmdMu should be held
+func (p *Playground) findInstance(w http.ResponseWriter, r *http.Request) (string, instance.Instance, bool) {
+	key := mux.Vars(r)["instance"]
+	cid, ins := p.lookupInstance(key)
+	if ins == nil {
+		writeError(w, http.StatusNotFound, errors.Errorf("instance %s not found", key))
+		return "", nil, false
+	}
+	return cid, ins, true
+}
+
+// lookupInstance returns the instance by its name or pid
+func (p *Playground) lookupInstance(key string) (cid string, ins instance.Instance) {
+	pid, err := strconv.Atoi(key)
+	_ = p.WalkInstances(func(wcid string, winst instance.Instance) error {
+		if (err == nil && winst.Pid() == pid) || p.instanceName(wcid, winst) == key {
+			cid, ins = wcid, winst
+		}
+		return nil
+	})
+	return
+}
+
+// instanceName returns the name of the instance, which is kept across the
+// restarts while the pid is not
+func (p *Playground) instanceName(cid string, ins instance.Instance) string {
+	return fmt.Sprintf("%s-%d", cid, p.instanceSpecs[ins].ID)
+}
+
+func (p *Playground) apiInstance(cid string, ins instance.Instance) pgapi.Instance {
+	s := p.instanceSpecs[ins]
+	host := s.Config.Host
+	if host == "" {
+		host = p.bootOptions.Host
+	}
+	inst := pgapi.Instance{
+		Name:      p.instanceName(cid, ins),
+		Component: cid,
+		ID:        s.ID,
+		PID:       ins.Pid(),
+		Status:    p.instanceStatus(ins),
+		Host:      instance.AdvertiseHost(host),
+		Ports:     ins.Ports(),
+		Version:   p.instanceVersion(ins).String(),
+		Uptime:    ins.Uptime(),
+		LogFile:   ins.LogFile(),
+		Restarts:  p.restartCount(ins),
+	}
+
+	p.faultMu.Lock()
+	if f, ok := p.faults[ins]; ok {
+		inst.Fault = string(f.Kind)
+	}
+	p.faultMu.Unlock()
+	return inst
+}
+
+func (p *Playground) instanceStatus(ins instance.Instance) string {
+	if atomic.LoadInt32(&p.paused) == 1 {
+		return pgapi.StatusPaused
+	}
+
+	p.faultMu.Lock()
+	f, ok := p.faults[ins]
+	p.faultMu.Unlock()
+	if ok && f.Kind == FaultPause {
+		return pgapi.StatusPaused
+	}
+	if syscall.Kill(ins.Pid(), 0) != nil {
+		return pgapi.StatusExited
+	}
+	return pgapi.StatusRunning
+}
+
+// componentID returns the id of the component, ticdc is an alias of cdc
+func componentID(comp string) string {
+	if comp == "ticdc" {
+		return spec.ComponentCDC
+	}
+	return comp
+}
+
+func isComponent(cid string) bool {
+	switch cid {
+	case spec.ComponentPD, spec.ComponentTiKV, spec.ComponentTiDB, spec.ComponentTiFlash,
+		spec.ComponentCDC, spec.ComponentPump, spec.ComponentDrainer:
+		return true
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logprinter.Warnf("failed to write the response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, pgapi.Error{Message: strings.TrimSpace(err.Error())})
+}
@@ -0,0 +1,240 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is the client of the API of a playground
+type Client struct {
+	addr       string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the playground listening on the addr, like
+// 127.0.0.1:9527. The requests are not timed out except by their contexts,
+// as scaling out or upgrading may take minutes.
+func NewClient(addr string) *Client {
+	return &Client{
+		addr:       addr,
+		httpClient: &http.Client{},
+	}
+}
+
+// Instances lists the instances
+func (c *Client) Instances(ctx context.Context) ([]Instance, error) {
+	resp := InstancesResponse{}
+	if err := c.doJSON(ctx, http.MethodGet, "/instances", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Instances, nil
+}
+
+// ScaleOut scales out a component and returns the new instances
+func (c *Client) ScaleOut(ctx context.Context, req ScaleOutRequest) ([]Instance, error) {
+	resp := InstancesResponse{}
+	if err := c.doJSON(ctx, http.MethodPost, "/instances", nil, req, &resp); err != nil {
+		// the instances started before the failure are returned with the error
+		if e, ok := err.(*Error); ok {
+			return e.Instances, err
+		}
+		return nil, err
+	}
+	return resp.Instances, nil
+}
+
+// ScaleIn scales in an instance by its name or pid
+func (c *Client) ScaleIn(ctx context.Context, instance string) (string, error) {
+	return c.doMessage(ctx, http.MethodDelete, "/instances/"+instance, nil)
+}
+
+// Restart restarts an instance by its name or pid
+func (c *Client) Restart(ctx context.Context, instance string) (string, error) {
+	return c.doMessage(ctx, http.MethodPost, "/instances/"+instance+"/restart", nil)
+}
+
+// Logs returns the last lines of the log of an instance
+func (c *Client) Logs(ctx context.Context, instance string, lines int) (string, error) {
+	query := url.Values{}
+	if lines > 0 {
+		query.Set("lines", strconv.Itoa(lines))
+	}
+	data, err := c.doRaw(ctx, http.MethodGet, "/instances/"+instance+"/logs", query)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Ready checks whether the cluster is ready, it waits in the timeout until
+// it's ready if the timeout is greater than 0. A not ready cluster is not an
+// error, check ReadyResponse.Ready instead.
+func (c *Client) Ready(ctx context.Context, timeout time.Duration) (*ReadyResponse, error) {
+	query := url.Values{}
+	if timeout > 0 {
+		query.Set("timeout", timeout.String())
+	}
+	resp := &ReadyResponse{}
+	err := c.doJSON(ctx, http.MethodGet, "/ready", query, nil, resp)
+	if e, ok := err.(*Error); ok && e.StatusCode == http.StatusServiceUnavailable {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// WaitReady waits until the cluster is ready in the timeout
+func (c *Client) WaitReady(ctx context.Context, timeout time.Duration) error {
+	resp, err := c.Ready(ctx, timeout)
+	if err != nil {
+		return err
+	}
+	if !resp.Ready {
+		return fmt.Errorf("the cluster is not ready: %s", strings.Join(resp.Reasons, "; "))
+	}
+	return nil
+}
+
+// Topology returns the running topology in YAML
+func (c *Client) Topology(ctx context.Context) ([]byte, error) {
+	return c.doRaw(ctx, http.MethodGet, "/topology", nil)
+}
+
+// ClusterTopology returns the running instances as the topology of tiup
+// cluster in YAML
+func (c *Client) ClusterTopology(ctx context.Context) ([]byte, error) {
+	return c.doRaw(ctx, http.MethodGet, "/topology/cluster", nil)
+}
+
+// SaveSnapshot saves a snapshot to the path on the host of the playground
+func (c *Client) SaveSnapshot(ctx context.Context, path string) (string, error) {
+	return c.doMessage(ctx, http.MethodPost, "/snapshots", SnapshotRequest{Path: path})
+}
+
+// RestoreSnapshot restores the snapshot at the path on the host of the playground
+func (c *Client) RestoreSnapshot(ctx context.Context, path string) (string, error) {
+	return c.doMessage(ctx, http.MethodPost, "/snapshots/restore", SnapshotRequest{Path: path})
+}
+
+// InjectFault injects a fault into an instance
+func (c *Client) InjectFault(ctx context.Context, req FaultRequest) (string, error) {
+	return c.doMessage(ctx, http.MethodPost, "/faults", req)
+}
+
+// ClearFault clears the fault of an instance, or all the faults if the
+// instance is empty
+func (c *Client) ClearFault(ctx context.Context, instance string) (string, error) {
+	path := "/faults"
+	if instance != "" {
+		path += "/" + instance
+	}
+	return c.doMessage(ctx, http.MethodDelete, path, nil)
+}
+
+// Upgrade upgrades the instances of a component one by one
+func (c *Client) Upgrade(ctx context.Context, component string, req UpgradeRequest) (string, error) {
+	return c.doMessage(ctx, http.MethodPost, "/components/"+component+"/upgrade", req)
+}
+
+func (c *Client) doMessage(ctx context.Context, method, path string, body interface{}) (string, error) {
+	resp := MessageResponse{}
+	if err := c.doJSON(ctx, method, path, nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
+
+// doJSON sends the body in JSON and decodes the response into out, the
+// response of a failed request is also decoded into out if it's JSON
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	data, err := c.do(ctx, method, path, query, reader)
+	if e, ok := err.(*Error); ok && out != nil {
+		_ = json.Unmarshal(data, out)
+		return e
+	}
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (c *Client) doRaw(ctx context.Context, method, path string, query url.Values) ([]byte, error) {
+	data, err := c.do(ctx, method, path, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// do sends the request and returns the response body, it returns an *Error
+// along with the body if the status code is not 2xx
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) ([]byte, error) {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     c.addr,
+		Path:     PathPrefix + path,
+		RawQuery: query.Encode(),
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return data, nil
+	}
+
+	e := &Error{StatusCode: resp.StatusCode}
+	if json.Unmarshal(data, e) != nil || e.Message == "" {
+		e.Message = strings.TrimSpace(string(data))
+	}
+	return data, e
+}
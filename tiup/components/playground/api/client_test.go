@@ -0,0 +1,102 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	ready := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case PathPrefix + "/instances":
+			req := ScaleOutRequest{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "tidb", req.Component)
+			assert.Equal(t, "v5.1.0", req.Version)
+			if req.Count > 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(Error{Message: "failed to start tidb-2", Instances: []Instance{{Name: "tidb-1", PID: 42}}})
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(InstancesResponse{Instances: []Instance{{Name: "tidb-1", PID: 42}}})
+		case PathPrefix + "/ready":
+			assert.Equal(t, "30s", r.URL.Query().Get("timeout"))
+			code := http.StatusServiceUnavailable
+			if ready {
+				code = http.StatusOK
+			}
+			w.WriteHeader(code)
+			_ = json.NewEncoder(w).Encode(ReadyResponse{Ready: ready, Reasons: []string{"tikv-0 is exited"}})
+		case PathPrefix + "/instances/tikv-0/logs":
+			assert.Equal(t, "2", r.URL.Query().Get("lines"))
+			_, _ = w.Write([]byte("a\nb\n"))
+		case PathPrefix + "/faults/tikv-0":
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(Error{Message: "instance tikv-0 not found"})
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("bad gateway\n"))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(strings.TrimPrefix(srv.URL, "http://"))
+
+	insts, err := c.ScaleOut(ctx, ScaleOutRequest{Component: "tidb", InstanceConfig: InstanceConfig{Version: "v5.1.0"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []Instance{{Name: "tidb-1", PID: 42}}, insts)
+
+	// the instances started before the failure are returned with the error
+	insts, err = c.ScaleOut(ctx, ScaleOutRequest{Component: "tidb", Count: 2, InstanceConfig: InstanceConfig{Version: "v5.1.0"}})
+	assert.Contains(t, err.Error(), "failed to start tidb-2")
+	assert.Equal(t, []Instance{{Name: "tidb-1", PID: 42}}, insts)
+
+	// not ready is not an error
+	resp, err := c.Ready(ctx, 30*time.Second)
+	assert.Nil(t, err)
+	assert.False(t, resp.Ready)
+	err = c.WaitReady(ctx, 30*time.Second)
+	assert.Contains(t, err.Error(), "tikv-0 is exited")
+	ready = true
+	assert.Nil(t, c.WaitReady(ctx, 30*time.Second))
+
+	logs, err := c.Logs(ctx, "tikv-0", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "a\nb\n", logs)
+
+	_, err = c.ClearFault(ctx, "tikv-0")
+	e, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, e.StatusCode)
+	assert.Equal(t, "instance tikv-0 not found", e.Message)
+
+	// the body is the message if it's not JSON
+	_, err = c.Topology(ctx)
+	e, ok = err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadGateway, e.StatusCode)
+	assert.Equal(t, "bad gateway", e.Message)
+}
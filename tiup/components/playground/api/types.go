@@ -0,0 +1,182 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package api is the HTTP control API of playground, it has the types in the
+// requests and responses, and a client of the API.
+package api
+
+import (
+	_ "embed" // for the OpenAPI spec
+	"fmt"
+)
+
+// PathPrefix is the prefix of the paths of the API, the version of the API
+// is changed only if there're incompatible changes
+const PathPrefix = "/api/v1"
+
+// OpenAPISpec is the OpenAPI spec of the API
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
+
+// status of instances
+const (
+	StatusRunning = "running"
+	StatusPaused  = "paused"
+	StatusExited  = "exited"
+)
+
+// health of instances
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+	// HealthStopped is the health of the instances stopped on purpose, like
+	// by a fault or for a snapshot
+	HealthStopped = "stopped"
+	// HealthCrashed is the health of the instances quit unexpectedly
+	HealthCrashed = "crashed"
+)
+
+// Instance is an instance of the playground
+type Instance struct {
+	// Name is the unique name of the instance, like tikv-0, it's kept across
+	// the restarts, while the pid is not
+	Name      string         `json:"name"`
+	Component string         `json:"component"`
+	ID        int            `json:"id"`
+	PID       int            `json:"pid"`
+	Status    string         `json:"status"`
+	Host      string         `json:"host"`
+	Ports     map[string]int `json:"ports"`
+	Version   string         `json:"version"`
+	Uptime    string         `json:"uptime"`
+	LogFile   string         `json:"log_file"`
+	// Health is checked by PD for the stores, and by the status ports for
+	// the others
+	Health string `json:"health"`
+	// HealthDetail is why the instance is not healthy
+	HealthDetail string `json:"health_detail,omitempty"`
+	// Fault is the kind of the fault injected into the instance
+	Fault string `json:"fault,omitempty"`
+	// Restarts is the number of the times it's restarted after crashes by
+	// the playground started with --restart-on-failure
+	Restarts int `json:"restarts"`
+}
+
+// InstancesResponse is the response of listing or scaling out instances
+type InstancesResponse struct {
+	Instances []Instance `json:"instances"`
+}
+
+// InstanceConfig is the config of a new instance, the fields not set are
+// inherited from the component
+type InstanceConfig struct {
+	Host       string            `json:"host,omitempty"`
+	Port       int               `json:"port,omitempty"`
+	StatusPort int               `json:"status_port,omitempty"`
+	ConfigPath string            `json:"config_path,omitempty"`
+	BinPath    string            `json:"bin_path,omitempty"`
+	Version    string            `json:"version,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// MemoryLimit and CPUQuota are in the format of systemd, e.g. 2G and 150%
+	MemoryLimit string `json:"memory_limit,omitempty"`
+	CPUQuota    string `json:"cpu_quota,omitempty"`
+}
+
+// ScaleOutRequest is the request to scale out a component
+type ScaleOutRequest struct {
+	Component string `json:"component"`
+	// Count is the number of instances, it's 1 if not set
+	Count int `json:"count,omitempty"`
+	InstanceConfig
+}
+
+// ReadyResponse is the response of checking whether the cluster is ready
+type ReadyResponse struct {
+	Ready bool `json:"ready"`
+	// Failed is set if the cluster won't be ready, like an instance crashed
+	// or the data failed to be seeded
+	Failed bool `json:"failed,omitempty"`
+	// Reasons are why the cluster is not ready
+	Reasons   []string          `json:"reasons,omitempty"`
+	Crashed   []CrashedInstance `json:"crashed,omitempty"`
+	Endpoints *Endpoints        `json:"endpoints,omitempty"`
+}
+
+// CrashedInstance is an instance quit unexpectedly
+type CrashedInstance struct {
+	Name    string `json:"name"`
+	Error   string `json:"error"`
+	LogFile string `json:"log_file"`
+	// CrashLog is the file the last lines of the log are saved to when it
+	// crashed
+	CrashLog string `json:"crash_log,omitempty"`
+	// Restarting is set if it's going to be restarted, the cluster is not
+	// failed for it
+	Restarting bool `json:"restarting,omitempty"`
+}
+
+// Endpoints are the endpoints of a ready cluster, they're also written to the
+// ready file of the playground
+type Endpoints struct {
+	API string   `json:"api"`
+	PD  []string `json:"pd"`
+	// TiDB are the addresses of the MySQL protocol
+	TiDB       []string `json:"tidb,omitempty"`
+	TiFlash    []string `json:"tiflash,omitempty"`
+	TiCDC      []string `json:"ticdc,omitempty"`
+	Dashboard  string   `json:"dashboard,omitempty"`
+	Prometheus string   `json:"prometheus,omitempty"`
+	Grafana    string   `json:"grafana,omitempty"`
+}
+
+// SnapshotRequest is the request to save or restore a snapshot
+type SnapshotRequest struct {
+	// Path is the path of the snapshot archive on the host of playground
+	Path string `json:"path"`
+}
+
+// FaultRequest is the request to inject a fault into an instance
+type FaultRequest struct {
+	Kind string `json:"kind"`
+	// Instance is the name or pid of the instance
+	Instance string `json:"instance"`
+	// the durations are in the format of Go, like 1m30s
+	Duration string `json:"duration,omitempty"`
+	Delay    string `json:"delay,omitempty"`
+	Offset   string `json:"offset,omitempty"`
+	Lib      string `json:"lib,omitempty"`
+}
+
+// UpgradeRequest is the request to upgrade a component
+type UpgradeRequest struct {
+	Version string `json:"version"`
+	BinPath string `json:"bin_path,omitempty"`
+}
+
+// MessageResponse is the response of the operations without other results
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Error is the response of a failed request
+type Error struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+	// the instances started before a scale-out failed, they keep running
+	Instances []Instance `json:"instances,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (status %d)", e.Message, e.StatusCode)
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pgapi "github.com/pingcap/tiup/components/playground/api"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPI(t *testing.T) {
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = &BootOptions{Version: "v5.1.0"}
+	srv := httptest.NewServer(p.router())
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := pgapi.NewClient(strings.TrimPrefix(srv.URL, "http://"))
+	status := func(err error) int {
+		if e, ok := err.(*pgapi.Error); ok {
+			return e.StatusCode
+		}
+		return 0
+	}
+
+	resp, err := http.Get(srv.URL + pgapi.PathPrefix + "/openapi.yaml")
+	assert.Nil(t, err)
+	spec, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, pgapi.OpenAPISpec, spec)
+
+	// only the readiness is served while booting
+	_, err = c.Instances(ctx)
+	assert.Equal(t, http.StatusServiceUnavailable, status(err))
+	ready, err := c.Ready(ctx, 0)
+	assert.Nil(t, err)
+	assert.False(t, ready.Ready)
+	assert.Equal(t, []string{"the playground is booting"}, ready.Reasons)
+
+	atomic.StoreInt32(&p.bootFinished, 1)
+	insts, err := c.Instances(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, insts, 0)
+	ready, err = c.Ready(ctx, time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ready.Ready)
+	assert.Equal(t, "http://127.0.0.1:0", ready.Endpoints.API)
+
+	topo, err := c.Topology(ctx)
+	assert.Nil(t, err)
+	assert.Contains(t, string(topo), "version: v5.1.0")
+
+	// the invalid requests
+	_, err = c.ScaleOut(ctx, pgapi.ScaleOutRequest{Component: "tispark"})
+	assert.Equal(t, http.StatusBadRequest, status(err))
+	_, err = c.Upgrade(ctx, "tidb", pgapi.UpgradeRequest{})
+	assert.Equal(t, http.StatusBadRequest, status(err))
+	_, err = c.InjectFault(ctx, pgapi.FaultRequest{Kind: "kill", Instance: "tikv-0", Duration: "1 minute"})
+	assert.Equal(t, http.StatusBadRequest, status(err))
+	_, err = c.InjectFault(ctx, pgapi.FaultRequest{Kind: "latency", Instance: "tikv-0"})
+	assert.Equal(t, http.StatusBadRequest, status(err))
+	_, err = c.SaveSnapshot(ctx, "")
+	assert.Equal(t, http.StatusBadRequest, status(err))
+
+	// the instances not found
+	_, err = c.InjectFault(ctx, pgapi.FaultRequest{Kind: "kill", Instance: "tikv-0"})
+	assert.Equal(t, http.StatusNotFound, status(err))
+	_, err = c.Restart(ctx, "tikv-0")
+	assert.Equal(t, http.StatusNotFound, status(err))
+	_, err = c.ScaleIn(ctx, "1234")
+	assert.Equal(t, http.StatusNotFound, status(err))
+	_, err = c.Logs(ctx, "tidb-0", 10)
+	assert.Equal(t, http.StatusNotFound, status(err))
+
+	msg, err := c.ClearFault(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "no fault is injected\n", msg)
+}
+
+func TestParseFault(t *testing.T) {
+	fault, err := parseFault(pgapi.FaultRequest{Kind: "clock-skew", Offset: "-10s", Duration: "1m", Lib: "/lib/faketime.so"})
+	assert.Nil(t, err)
+	assert.Nil(t, fault.validate())
+	assert.Equal(t, FaultClockSkew, fault.Kind)
+	assert.Equal(t, "-10s", fault.Offset.String())
+	assert.Equal(t, "1m0s", fault.Duration.String())
+
+	fault, err = parseFault(pgapi.FaultRequest{Kind: "clock-skew", Offset: "500ms", Lib: "/lib/faketime.so"})
+	assert.Nil(t, err)
+	assert.NotNil(t, fault.validate())
+	fault, err = parseFault(pgapi.FaultRequest{Kind: "clear"})
+	assert.Nil(t, err)
+	assert.NotNil(t, fault.validate())
+}
+
+func TestAPIReadyWhileBooting(t *testing.T) {
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = &BootOptions{Version: "v5.1.0"}
+	srv := httptest.NewServer(p.router())
+	defer srv.Close()
+	c := pgapi.NewClient(strings.TrimPrefix(srv.URL, "http://"))
+
+	// the instances are added by the boot without cmdMu, they're not read
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			_, err := p.addInstance("pd", instance.Config{})
+			assert.Nil(t, err)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		ready, err := c.Ready(context.Background(), 0)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"the playground is booting"}, ready.Reasons)
+	}
+	<-done
+}
+
+func TestAPIHosts(t *testing.T) {
+	assert.Equal(t, []string{"127.0.0.1"}, apiHosts(""))
+	assert.Equal(t, []string{"127.0.0.1"}, apiHosts("localhost"))
+	assert.Equal(t, []string{"127.0.0.1"}, apiHosts("::1"))
+	assert.Equal(t, []string{"0.0.0.0"}, apiHosts("0.0.0.0"))
+	assert.Equal(t, []string{"127.0.0.1", "10.0.1.5"}, apiHosts("10.0.1.5"))
+}
@@ -14,16 +14,17 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"strconv"
 
-	"github.com/pingcap/errors"
+	"github.com/AstroProfundis/tabby"
+	"github.com/fatih/color"
+	"github.com/juju/ansiterm"
+	pgapi "github.com/pingcap/tiup/components/playground/api"
 	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/spf13/cobra"
 )
 
@@ -35,44 +36,25 @@ const (
 	ScaleInCommandType  CommandType = "scale-in"
 	ScaleOutCommandType CommandType = "scale-out"
 	DisplayCommandType  CommandType = "display"
+	DumpCommandType     CommandType = "dump"
+
+	SnapshotSaveCommandType    CommandType = "snapshot-save"
+	SnapshotRestoreCommandType CommandType = "snapshot-restore"
+
+	FaultCommandType   CommandType = "fault"
+	UpgradeCommandType CommandType = "upgrade"
 )
 
 // Command send to Playground.
 type Command struct {
 	CommandType CommandType
-	PID         int // Set when scale-in
+	PID         int    // Set when scale-in or injecting a fault
+	Snapshot    string // Set when saving or restoring a snapshot, the path of the archive
+	Fault       *Fault // Set when injecting or clearing a fault
 	ComponentID string
 	instance.Config
 }
 
-func buildCommands(tp CommandType, opt *BootOptions) (cmds []Command) {
-	commands := []struct {
-		comp string
-		instance.Config
-	}{
-		{"pd", opt.PD},
-		{"tikv", opt.TiKV},
-		{"pump", opt.Pump},
-		{"tiflash", opt.TiFlash},
-		{"tidb", opt.TiDB},
-		{"ticdc", opt.TiCDC},
-		{"drainer", opt.Drainer},
-	}
-
-	for _, cmd := range commands {
-		for i := 0; i < cmd.Num; i++ {
-			c := Command{
-				CommandType: tp,
-				ComponentID: cmd.comp,
-				Config:      cmd.Config,
-			}
-
-			cmds = append(cmds, c)
-		}
-	}
-	return
-}
-
 func newScaleOut() *cobra.Command {
 	var opt BootOptions
 	cmd := &cobra.Command{
@@ -119,6 +101,30 @@ func newScaleOut() *cobra.Command {
 	cmd.Flags().StringVarP(&opt.Pump.BinPath, "pump.binpath", "", opt.Pump.BinPath, "Pump instance binary path")
 	cmd.Flags().StringVarP(&opt.Drainer.BinPath, "drainer.binpath", "", opt.Drainer.BinPath, "Drainer instance binary path")
 
+	cmd.Flags().StringVarP(&opt.TiDB.Version, "db.version", "", opt.TiDB.Version, "TiDB instance version")
+	cmd.Flags().StringVarP(&opt.TiKV.Version, "kv.version", "", opt.TiKV.Version, "TiKV instance version")
+	cmd.Flags().StringVarP(&opt.PD.Version, "pd.version", "", opt.PD.Version, "PD instance version")
+	cmd.Flags().StringVarP(&opt.TiFlash.Version, "tiflash.version", "", opt.TiFlash.Version, "TiFlash instance version")
+	cmd.Flags().StringVarP(&opt.TiCDC.Version, "ticdc.version", "", opt.TiCDC.Version, "TiCDC instance version")
+	cmd.Flags().StringVarP(&opt.Pump.Version, "pump.version", "", opt.Pump.Version, "Pump instance version")
+	cmd.Flags().StringVarP(&opt.Drainer.Version, "drainer.version", "", opt.Drainer.Version, "Drainer instance version")
+
+	cmd.Flags().StringVarP(&opt.TiDB.MemoryLimit, "db.memory-limit", "", opt.TiDB.MemoryLimit, "TiDB instance memory limit, like 2G")
+	cmd.Flags().StringVarP(&opt.TiKV.MemoryLimit, "kv.memory-limit", "", opt.TiKV.MemoryLimit, "TiKV instance memory limit, like 2G")
+	cmd.Flags().StringVarP(&opt.PD.MemoryLimit, "pd.memory-limit", "", opt.PD.MemoryLimit, "PD instance memory limit, like 2G")
+	cmd.Flags().StringVarP(&opt.TiFlash.MemoryLimit, "tiflash.memory-limit", "", opt.TiFlash.MemoryLimit, "TiFlash instance memory limit, like 2G")
+	cmd.Flags().StringVarP(&opt.TiCDC.MemoryLimit, "ticdc.memory-limit", "", opt.TiCDC.MemoryLimit, "TiCDC instance memory limit, like 2G")
+	cmd.Flags().StringVarP(&opt.Pump.MemoryLimit, "pump.memory-limit", "", opt.Pump.MemoryLimit, "Pump instance memory limit, like 2G")
+	cmd.Flags().StringVarP(&opt.Drainer.MemoryLimit, "drainer.memory-limit", "", opt.Drainer.MemoryLimit, "Drainer instance memory limit, like 2G")
+
+	cmd.Flags().StringVarP(&opt.TiDB.CPUQuota, "db.cpu-quota", "", opt.TiDB.CPUQuota, "TiDB instance CPU quota, like 150%")
+	cmd.Flags().StringVarP(&opt.TiKV.CPUQuota, "kv.cpu-quota", "", opt.TiKV.CPUQuota, "TiKV instance CPU quota, like 150%")
+	cmd.Flags().StringVarP(&opt.PD.CPUQuota, "pd.cpu-quota", "", opt.PD.CPUQuota, "PD instance CPU quota, like 150%")
+	cmd.Flags().StringVarP(&opt.TiFlash.CPUQuota, "tiflash.cpu-quota", "", opt.TiFlash.CPUQuota, "TiFlash instance CPU quota, like 150%")
+	cmd.Flags().StringVarP(&opt.TiCDC.CPUQuota, "ticdc.cpu-quota", "", opt.TiCDC.CPUQuota, "TiCDC instance CPU quota, like 150%")
+	cmd.Flags().StringVarP(&opt.Pump.CPUQuota, "pump.cpu-quota", "", opt.Pump.CPUQuota, "Pump instance CPU quota, like 150%")
+	cmd.Flags().StringVarP(&opt.Drainer.CPUQuota, "drainer.cpu-quota", "", opt.Drainer.CPUQuota, "Drainer instance CPU quota, like 150%")
+
 	return cmd
 }
 
@@ -154,73 +160,108 @@ func newDisplay() *cobra.Command {
 	return cmd
 }
 
+func newDump() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "dump",
+		Short:   "Dump the running topology of the playground",
+		Example: "tiup playground dump > playground.yaml # Boot it again by `tiup playground -f playground.yaml`",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			data, err := client.Topology(context.Background())
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
+	}
+	return cmd
+}
+
 func scaleIn(pids []int) error {
-	port, err := targetTag()
+	client, err := newClient()
 	if err != nil {
 		return err
 	}
 
-	var cmds []Command
 	for _, pid := range pids {
-		c := Command{
-			CommandType: ScaleInCommandType,
-			PID:         pid,
+		msg, err := client.ScaleIn(context.Background(), strconv.Itoa(pid))
+		if err != nil {
+			return err
 		}
-		cmds = append(cmds, c)
+		fmt.Print(msg)
 	}
-
-	addr := "127.0.0.1:" + strconv.Itoa(port)
-	return sendCommandsAndPrintResult(cmds, addr)
+	return nil
 }
 
 func scaleOut(args []string, opt *BootOptions) (num int, err error) {
-	port, err := targetTag()
-	if err != nil {
-		return 0, err
+	var reqs []pgapi.ScaleOutRequest
+	for _, c := range opt.componentConfigs() {
+		if c.cfg.Num == 0 {
+			continue
+		}
+		reqs = append(reqs, pgapi.ScaleOutRequest{
+			Component: c.comp,
+			Count:     c.cfg.Num,
+			InstanceConfig: pgapi.InstanceConfig{
+				Host:        c.cfg.Host,
+				ConfigPath:  c.cfg.ConfigPath,
+				BinPath:     c.cfg.BinPath,
+				Version:     c.cfg.Version,
+				MemoryLimit: c.cfg.MemoryLimit,
+				CPUQuota:    c.cfg.CPUQuota,
+			},
+		})
+		num += c.cfg.Num
 	}
-
-	cmds := buildCommands(ScaleOutCommandType, opt)
-	if len(cmds) == 0 {
+	if num == 0 {
 		return 0, nil
 	}
 
-	addr := "127.0.0.1:" + strconv.Itoa(port)
-	return len(cmds), sendCommandsAndPrintResult(cmds, addr)
+	client, err := newClient()
+	if err != nil {
+		return 0, err
+	}
+	for _, req := range reqs {
+		// the instances started before a failure are listed with the error
+		insts, err := client.ScaleOut(context.Background(), req)
+		for _, inst := range insts {
+			fmt.Printf("scale out %s success, the pid is %d\n", inst.Name, inst.PID)
+			if inst.Component == spec.ComponentTiDB {
+				connectMsg := "To connect new added TiDB: mysql --comments --host %s --port %d -u root -p (no password)"
+				fmt.Println(color.GreenString(connectMsg, inst.Host, inst.Ports["port"]))
+			}
+		}
+		if err != nil {
+			return num, err
+		}
+	}
+	return num, nil
 }
 
 func display(args []string) error {
-	port, err := targetTag()
+	client, err := newClient()
 	if err != nil {
 		return err
 	}
-	c := Command{
-		CommandType: DisplayCommandType,
+	insts, err := client.Instances(context.Background())
+	if err != nil {
+		return err
 	}
 
-	addr := "127.0.0.1:" + strconv.Itoa(port)
-	return sendCommandsAndPrintResult([]Command{c}, addr)
-}
-
-func sendCommandsAndPrintResult(cmds []Command, addr string) error {
-	for _, cmd := range cmds {
-		data, err := json.Marshal(&cmd)
-		if err != nil {
-			return errors.AddStack(err)
-		}
-
-		url := fmt.Sprintf("http://%s/command", addr)
-
-		resp, err := http.Post(url, "application/json", bytes.NewReader(data))
-		if err != nil {
-			return errors.AddStack(err)
-		}
-		defer resp.Body.Close()
-
-		_, err = io.Copy(os.Stdout, resp.Body)
-		if err != nil {
-			return errors.AddStack(err)
+	w := ansiterm.NewTabWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	t := tabby.NewCustom(w)
+	t.AddHeader("Pid", "Name", "Role", "Status", "Health", "Restarts", "Version", "Uptime")
+	for _, inst := range insts {
+		status := inst.Status
+		if inst.Fault != "" {
+			status += " (fault " + inst.Fault + ")"
 		}
+		t.AddLine(strconv.Itoa(inst.PID), inst.Name, inst.Component, status, inst.Health, strconv.Itoa(inst.Restarts), inst.Version, inst.Uptime)
 	}
-
+	t.Print()
 	return nil
 }
@@ -16,8 +16,10 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/pingcap/errors"
+	pgapi "github.com/pingcap/tiup/components/playground/api"
 )
 
 // targetTag find the target playground we want to send the command.
@@ -57,3 +59,12 @@ func targetTag() (port int, err error) {
 
 	return
 }
+
+// newClient returns the API client of the target playground
+func newClient() (*pgapi.Client, error) {
+	port, err := targetTag()
+	if err != nil {
+		return nil, err
+	}
+	return pgapi.NewClient("127.0.0.1:" + strconv.Itoa(port)), nil
+}
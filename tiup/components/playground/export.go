@@ -0,0 +1,305 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// the formats of export
+const (
+	exportPlayground      = "playground"
+	exportClusterTopology = "cluster-topology"
+)
+
+// managedConfigKeys are the config items set by tiup cluster itself, like
+// the addresses and the dirs, they're not exported
+var managedConfigKeys = map[string][]string{
+	spec.ComponentPD: {
+		"name", "data-dir", "client-urls", "peer-urls", "advertise-client-urls",
+		"advertise-peer-urls", "initial-cluster", "log.file.filename",
+	},
+	spec.ComponentTiKV: {
+		"server.addr", "server.advertise-addr", "server.status-addr", "pd.endpoints",
+		"storage.data-dir", "log-file", "log.file.filename",
+	},
+	spec.ComponentTiDB: {
+		"host", "port", "status.status-port", "path", "store", "log.file.filename",
+	},
+	spec.ComponentTiFlash: {
+		"path", "tmp_path", "listen_host", "tcp_port", "http_port", "flash.service_addr",
+		"flash.tidb_status_addr", "flash.proxy.config", "flash.flash_cluster.cluster_manager_path",
+		"flash.flash_cluster.log", "logger.log", "logger.errorlog", "raft.pd_addr",
+		"status.metrics_port",
+	},
+	spec.ComponentCDC:     {"addr", "advertise-addr", "pd", "data-dir", "log-file"},
+	spec.ComponentPump:    {"addr", "advertise-addr", "pd-urls", "data-dir", "log-file"},
+	spec.ComponentDrainer: {"addr", "advertise-addr", "pd-urls", "data-dir", "log-file"},
+}
+
+func newExport() *cobra.Command {
+	var format, output string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the running playground to other formats",
+		Long: `Export the running playground to other formats:
+
+  cluster-topology  the topology of 'tiup cluster deploy', with the version, the
+                    ports, the config files and the resource limits of instances
+  playground        the topology file of 'tiup playground -f', like 'dump'
+
+The config items managed by tiup cluster in the config files, like the addresses
+and the dirs, are not exported to the cluster topology.`,
+		Example: `  tiup playground export --format cluster-topology -o topology.yaml
+  tiup cluster check topology.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			var data []byte
+			switch format {
+			case exportClusterTopology:
+				data, err = client.ClusterTopology(context.Background())
+			case exportPlayground:
+				data, err = client.Topology(context.Background())
+			default:
+				return errors.Errorf("unknown format %s, it should be %s or %s", format, exportClusterTopology, exportPlayground)
+			}
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return errors.AddStack(err)
+			}
+			fmt.Printf("The topology is written to %s\n", output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", exportClusterTopology, "The format to export, cluster-topology or playground")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write to the file instead of stdout")
+	return cmd
+}
+
+// handleExportCluster writes the running instances as the topology of tiup
+// cluster, the topology is validated before written
+func (p *Playground) handleExportCluster(w io.Writer) error {
+	topo := &spec.Specification{}
+	var warnings []string
+	version := p.bootOptions.Version
+
+	// the config files shared by all the instances of a component are
+	// exported to the server configs
+	configPaths := make(map[string]map[string]bool)
+	_ = p.WalkInstances(func(cid string, ins instance.Instance) error {
+		if configPaths[cid] == nil {
+			configPaths[cid] = make(map[string]bool)
+		}
+		configPaths[cid][p.instanceSpecs[ins].Config.ConfigPath] = true
+		return nil
+	})
+	serverConfigs := make(map[string]map[string]interface{})
+	for _, c := range p.bootOptions.componentConfigs() {
+		if len(configPaths[c.comp]) != 1 {
+			continue
+		}
+		for path := range configPaths[c.comp] {
+			cfg, removed, err := exportConfig(c.comp, path)
+			if err != nil {
+				return err
+			}
+			serverConfigs[c.comp] = cfg
+			warnings = append(warnings, removed...)
+		}
+	}
+
+	var labelKeys []string
+	err := p.WalkInstances(func(cid string, ins instance.Instance) error {
+		s := p.instanceSpecs[ins]
+		name := p.instanceName(cid, ins)
+		if v := p.instanceVersion(ins).String(); v != version {
+			warnings = append(warnings, fmt.Sprintf("%s is of version %s, while the cluster is deployed in %s", name, v, version))
+		}
+
+		var cfg map[string]interface{}
+		if _, ok := serverConfigs[cid]; !ok {
+			var removed []string
+			var err error
+			if cfg, removed, err = exportConfig(cid, s.Config.ConfigPath); err != nil {
+				return err
+			}
+			warnings = append(warnings, removed...)
+		}
+		res := meta.ResourceControl{MemoryLimit: s.Config.MemoryLimit, CPUQuota: s.Config.CPUQuota}
+		host := instance.AdvertiseHost(s.Config.Host)
+		if s.Config.Host == "" {
+			host = instance.AdvertiseHost(p.bootOptions.Host)
+		}
+		ports := ins.Ports()
+
+		switch cid {
+		case spec.ComponentPD:
+			topo.PDServers = append(topo.PDServers, &spec.PDSpec{
+				Host: host, Name: name, ClientPort: ports["status_port"], PeerPort: ports["port"],
+				Config: cfg, ResourceControl: res,
+			})
+		case spec.ComponentTiKV:
+			if labels := ins.(*instance.TiKVInstance).Labels; len(labels) > 0 {
+				if cfg == nil {
+					cfg = make(map[string]interface{})
+				}
+				cfg["server.labels"] = labels
+				for k := range labels {
+					labelKeys = append(labelKeys, k)
+				}
+			}
+			topo.TiKVServers = append(topo.TiKVServers, &spec.TiKVSpec{
+				Host: host, Port: ports["port"], StatusPort: ports["status_port"],
+				Config: cfg, ResourceControl: res,
+			})
+		case spec.ComponentTiDB:
+			topo.TiDBServers = append(topo.TiDBServers, &spec.TiDBSpec{
+				Host: host, Port: ports["port"], StatusPort: ports["status_port"],
+				Config: cfg, ResourceControl: res,
+			})
+		case spec.ComponentTiFlash:
+			topo.TiFlashServers = append(topo.TiFlashServers, &spec.TiFlashSpec{
+				Host: host, TCPPort: ports["tcp_port"], HTTPPort: ports["port"],
+				FlashServicePort: ports["service_port"], FlashProxyPort: ports["proxy_port"],
+				FlashProxyStatusPort: ports["proxy_status_port"], StatusPort: ports["status_port"],
+				Config: cfg, ResourceControl: res,
+			})
+		case spec.ComponentCDC:
+			topo.CDCServers = append(topo.CDCServers, &spec.CDCSpec{
+				Host: host, Port: ports["port"], Config: cfg, ResourceControl: res,
+			})
+		case spec.ComponentPump:
+			topo.PumpServers = append(topo.PumpServers, &spec.PumpSpec{
+				Host: host, Port: ports["port"], Config: cfg, ResourceControl: res,
+			})
+		case spec.ComponentDrainer:
+			topo.Drainers = append(topo.Drainers, &spec.DrainerSpec{
+				Host: host, Port: ports["port"], Config: cfg, ResourceControl: res,
+			})
+		default:
+			return errors.Errorf("unknown component %s", cid)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the labels are effective only if they're known by PD
+	if len(labelKeys) > 0 {
+		if serverConfigs[spec.ComponentPD] == nil {
+			serverConfigs[spec.ComponentPD] = make(map[string]interface{})
+		}
+		if _, ok := serverConfigs[spec.ComponentPD]["replication.location-labels"]; !ok {
+			serverConfigs[spec.ComponentPD]["replication.location-labels"] = uniqueSorted(labelKeys)
+		}
+	}
+	topo.ServerConfigs = spec.ServerConfigs{
+		PD:      serverConfigs[spec.ComponentPD],
+		TiKV:    serverConfigs[spec.ComponentTiKV],
+		TiDB:    serverConfigs[spec.ComponentTiDB],
+		TiFlash: serverConfigs[spec.ComponentTiFlash],
+		CDC:     serverConfigs[spec.ComponentCDC],
+		Pump:    serverConfigs[spec.ComponentPump],
+		Drainer: serverConfigs[spec.ComponentDrainer],
+	}
+	if m := p.monitor; m != nil {
+		topo.Monitors = append(topo.Monitors, &spec.PrometheusSpec{Host: m.host, Port: m.port})
+	}
+	if g := p.grafana; g != nil {
+		topo.Grafanas = append(topo.Grafanas, &spec.GrafanaSpec{Host: g.host, Port: g.port})
+	}
+
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	// the defaults are filled and the topology is validated when it's parsed
+	if err := yaml.UnmarshalStrict(data, &spec.Specification{}); err != nil {
+		return errors.Annotate(err, "the exported topology is invalid")
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# The topology of tiup cluster exported from the playground, deploy it by:\n")
+	fmt.Fprintf(buf, "#   tiup cluster check topology.yaml\n")
+	fmt.Fprintf(buf, "#   tiup cluster deploy <cluster-name> %s topology.yaml\n", version)
+	for _, warning := range warnings {
+		fmt.Fprintf(buf, "# WARNING: %s\n", warning)
+	}
+	buf.Write(data)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// exportConfig reads the config file of the component in flattened keys,
+// it returns the warnings of the items managed by tiup cluster, which are
+// removed
+func exportConfig(cid, path string) (map[string]interface{}, []string, error) {
+	if path == "" {
+		return nil, nil, nil
+	}
+	cfg := make(map[string]interface{})
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, nil, errors.Annotatef(err, "failed to parse the config of %s", cid)
+	}
+	cfg = spec.FlattenMap(cfg)
+
+	var warnings []string
+	for _, key := range managedConfigKeys[cid] {
+		if _, ok := cfg[key]; ok {
+			delete(cfg, key)
+			warnings = append(warnings, fmt.Sprintf("%s in %s is set by tiup cluster, it's not exported", key, path))
+		}
+	}
+	if len(cfg) == 0 {
+		return nil, warnings, nil
+	}
+	return cfg, warnings, nil
+}
+
+func uniqueSorted(ss []string) []string {
+	sort.Strings(ss)
+	var result []string
+	for i, s := range ss {
+		if i == 0 || s != ss[i-1] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExportCluster(t *testing.T) {
+	file := writeTopology(t, playgroundTopology)
+	config := "[server]\naddr = \"0.0.0.0:20160\"\ngrpc-concurrency = 8\n[raftstore]\nsync-log = false\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(filepath.Dir(file), "tikv.toml"), []byte(config), 0644))
+	opt := &BootOptions{}
+	assert.Nil(t, loadTopology(file, opt))
+	opt.PD.Num = 1
+
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = opt
+	for _, c := range opt.componentConfigs() {
+		for _, cfg := range c.cfg.InstanceConfigs() {
+			_, err := p.addInstance(c.comp, cfg)
+			assert.Nil(t, err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, p.handleExportCluster(buf))
+	assert.Contains(t, buf.String(), "tiup cluster deploy <cluster-name> v5.0.1 topology.yaml")
+	assert.Contains(t, buf.String(), "# WARNING: server.addr in")
+
+	topo := &spec.Specification{}
+	assert.Nil(t, yaml.UnmarshalStrict(buf.Bytes(), topo))
+	assert.Len(t, topo.PDServers, 1)
+	assert.Equal(t, "pd-0", topo.PDServers[0].Name)
+	assert.Equal(t, p.pds[0].StatusPort, topo.PDServers[0].ClientPort)
+	assert.Equal(t, p.pds[0].Port, topo.PDServers[0].PeerPort)
+
+	// the shared config file is exported to the server configs, without the
+	// items managed by tiup cluster
+	assert.Len(t, topo.TiKVServers, 3)
+	assert.Equal(t, map[string]interface{}{
+		"server.grpc-concurrency": 8,
+		"raftstore.sync-log":      false,
+	}, topo.ServerConfigs.TiKV)
+	assert.Equal(t, 20260, topo.TiKVServers[0].Port)
+	assert.Equal(t, 20280, topo.TiKVServers[0].StatusPort)
+	assert.Equal(t, "127.0.0.2", topo.TiKVServers[2].Host)
+	assert.Equal(t, map[interface{}]interface{}{"zone": "z3"}, topo.TiKVServers[2].Config["server.labels"])
+	assert.Equal(t, []interface{}{"zone"}, topo.ServerConfigs.PD["replication.location-labels"])
+	assert.Equal(t, "2G", topo.TiKVServers[0].ResourceControl.MemoryLimit)
+	assert.Equal(t, "1G", topo.TiKVServers[2].ResourceControl.MemoryLimit)
+
+	assert.Len(t, topo.TiDBServers, 2)
+	assert.Equal(t, 4100, topo.TiDBServers[0].Port)
+	assert.Empty(t, topo.ServerConfigs.TiDB)
+}
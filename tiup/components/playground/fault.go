@@ -0,0 +1,592 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/errors"
+	pgapi "github.com/pingcap/tiup/components/playground/api"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/proxy"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// FaultKind is the kind of a fault injected into an instance.
+type FaultKind string
+
+// kinds of Fault
+const (
+	FaultKill      FaultKind = "kill"
+	FaultPause     FaultKind = "pause"
+	FaultLatency   FaultKind = "latency"
+	FaultDrop      FaultKind = "drop"
+	FaultDiskFull  FaultKind = "disk-full"
+	FaultClockSkew FaultKind = "clock-skew"
+	FaultClear     FaultKind = "clear"
+)
+
+// Fault is a fault injected into an instance.
+type Fault struct {
+	Kind FaultKind
+	// the fault is cleared after the duration, 0 means until `fault clear`
+	Duration time.Duration
+	Delay    time.Duration // Set when adding latency
+	Offset   time.Duration // Set when skewing the clock
+	Lib      string        // Set when skewing the clock, the path of libfaketime
+}
+
+// activeFault is a fault injected into an instance and not cleared yet
+type activeFault struct {
+	Fault
+	componentID string
+	timer       *time.Timer
+}
+
+// the ports can be proxied by the fault proxies of each component, the
+// others are not proxied as the processes can't advertise other ports
+var faultProxyPorts = map[string][]string{
+	spec.ComponentPD:      {"port", "status_port"},
+	spec.ComponentTiKV:    {"port"},
+	spec.ComponentTiDB:    {"port"},
+	spec.ComponentCDC:     {"port"},
+	spec.ComponentPump:    {"port"},
+	spec.ComponentDrainer: {"port"},
+}
+
+// the paths libfaketime is installed to by the package managers
+var faketimeLibPaths = []string{
+	"/usr/lib/x86_64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib/aarch64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib64/faketime/libfaketime.so.1",
+	"/usr/lib/faketime/libfaketime.so.1",
+	"/usr/local/lib/faketime/libfaketime.so.1",
+	"/opt/homebrew/lib/faketime/libfaketime.1.dylib",
+	"/usr/local/lib/faketime/libfaketime.1.dylib",
+}
+
+func newFault() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fault",
+		Short: "Inject faults into the instances of the playground",
+		Long: `Inject faults into the instances of the playground, the instances are specified
+by their names (e.g. tikv-0) or pids, which can be got by 'tiup playground display'.
+The name of an instance is kept when it's restarted by a fault while its pid is not.
+
+A fault is cleared after the duration if it's set, or it's kept until cleared
+by 'tiup playground fault clear'. There's at most one fault of an instance.
+
+The network faults are injected by the TCP proxies in front of the instances,
+start the playground with --fault-proxy to use them.`,
+	}
+
+	cmd.AddCommand(
+		newFaultCmd(FaultKill, "Kill an instance and start it again when cleared",
+			"tiup playground fault kill --instance tikv-0 --duration 30s"),
+		newFaultCmd(FaultPause, "Pause an instance by SIGSTOP and resume it by SIGCONT when cleared",
+			"tiup playground fault pause --instance tikv-0 --duration 30s"),
+		newFaultCmd(FaultLatency, "Add latency to the connections to an instance",
+			"tiup playground fault latency --instance pd-0 --delay 200ms"),
+		newFaultCmd(FaultDrop, "Drop the connections to an instance and refuse the new ones",
+			"tiup playground fault drop --instance tidb-0 --duration 1m"),
+		newFaultCmd(FaultDiskFull, "Restart a TiKV with the capacity of its data size, so its disk is full",
+			"tiup playground fault disk-full --instance tikv-0"),
+		newFaultCmd(FaultClockSkew, "Restart a TiKV or TiFlash with its clock skewed by libfaketime",
+			"tiup playground fault clock-skew --instance tikv-0 --offset -10s"),
+		newFaultCmd(FaultClear, "Clear the fault of an instance, or all the faults if no instance is set",
+			"tiup playground fault clear --instance tikv-0"),
+	)
+	return cmd
+}
+
+func newFaultCmd(kind FaultKind, short, example string) *cobra.Command {
+	var pid int
+	var name string
+	var duration, delay, offset time.Duration
+	req := pgapi.FaultRequest{Kind: string(kind)}
+
+	cmd := &cobra.Command{
+		Use:     string(kind),
+		Short:   short,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pid != 0 && name != "" {
+				return errors.New("--instance and --pid can't be set together")
+			}
+			if pid != 0 {
+				name = strconv.Itoa(pid)
+			}
+			if name == "" && kind != FaultClear {
+				return cmd.Help()
+			}
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			var msg string
+			if kind == FaultClear {
+				msg, err = client.ClearFault(context.Background(), name)
+			} else {
+				req.Instance = name
+				req.Duration = formatDuration(duration)
+				req.Delay = formatDuration(delay)
+				req.Offset = formatDuration(offset)
+				msg, err = client.InjectFault(context.Background(), req)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Print(msg)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "instance", "", "name of the instance, e.g. tikv-0")
+	cmd.Flags().IntVar(&pid, "pid", 0, "pid of the instance")
+	if kind == FaultClear {
+		return cmd
+	}
+	cmd.Flags().DurationVar(&duration, "duration", 0, "Clear the fault after the duration, 0 means until cleared by 'fault clear'")
+	switch kind {
+	case FaultLatency:
+		cmd.Flags().DurationVar(&delay, "delay", 0, "The latency added to the data in both directions")
+	case FaultClockSkew:
+		cmd.Flags().DurationVar(&offset, "offset", 0, "The offset of the clock, it's rounded to seconds and negative to set the clock back")
+		cmd.Flags().StringVar(&req.Lib, "lib", "", "The path of libfaketime, it's searched in the common paths if not set")
+	}
+	return cmd
+}
+
+// formatDuration formats the duration for the API, it's empty if not set
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// parseFault parses the fault in the request of the API
+func parseFault(req pgapi.FaultRequest) (Fault, error) {
+	fault := Fault{Kind: FaultKind(req.Kind), Lib: req.Lib}
+	for _, d := range []struct {
+		name  string
+		value string
+		dur   *time.Duration
+	}{
+		{"duration", req.Duration, &fault.Duration},
+		{"delay", req.Delay, &fault.Delay},
+		{"offset", req.Offset, &fault.Offset},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fault, errors.Annotatef(err, "invalid %s", d.name)
+		}
+		*d.dur = v
+	}
+	return fault, nil
+}
+
+// validate checks the fault to be injected, and finds libfaketime if it's
+// needed and not set
+func (f *Fault) validate() error {
+	switch f.Kind {
+	case FaultKill, FaultPause, FaultDrop, FaultDiskFull:
+	case FaultLatency:
+		if f.Delay <= 0 {
+			return errors.New("the delay must be greater than 0")
+		}
+	case FaultClockSkew:
+		if f.Offset/time.Second == 0 {
+			return errors.New("the offset must be at least 1s")
+		}
+		if f.Lib == "" {
+			f.Lib = findFaketimeLib()
+		}
+		if f.Lib == "" {
+			return errors.New("libfaketime is not found, install it or set its path by --lib")
+		}
+	default:
+		return errors.Errorf("unknown fault %s", f.Kind)
+	}
+	if f.Duration < 0 {
+		return errors.New("the duration must not be negative")
+	}
+	return nil
+}
+
+func findFaketimeLib() string {
+	for _, path := range faketimeLibPaths {
+		if utils.IsExist(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+func (p *Playground) handleFault(w io.Writer, pid int, fault *Fault) error {
+	if fault == nil {
+		return errors.New("the fault is not set")
+	}
+
+	if fault.Kind == FaultClear && pid == 0 {
+		p.faultMu.Lock()
+		var insts []instance.Instance
+		for ins := range p.faults {
+			insts = append(insts, ins)
+		}
+		p.faultMu.Unlock()
+
+		if len(insts) == 0 {
+			fmt.Fprintln(w, "no fault is injected")
+		}
+		for _, ins := range insts {
+			if err := p.clearFault(w, ins); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var cid string
+	var ins instance.Instance
+	_ = p.WalkInstances(func(wcid string, winst instance.Instance) error {
+		if winst.Pid() == pid {
+			cid = wcid
+			ins = winst
+		}
+		return nil
+	})
+	if ins == nil {
+		return errors.Errorf("no instance with pid %d", pid)
+	}
+
+	if fault.Kind == FaultClear {
+		return p.clearFault(w, ins)
+	}
+	if err := fault.validate(); err != nil {
+		return err
+	}
+	return p.injectFault(w, cid, ins, *fault)
+}
+
+func (p *Playground) injectFault(w io.Writer, cid string, ins instance.Instance, fault Fault) error {
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+
+	name := p.instanceName(cid, ins)
+	if f, ok := p.faults[ins]; ok {
+		return errors.Errorf("%s has the fault %s already, clear it first", name, f.Kind)
+	}
+	proxies := p.faultProxies[ins]
+	pid := ins.Pid()
+
+	switch fault.Kind {
+	case FaultKill:
+		p.faultStopped[pid] = struct{}{}
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+			return errors.AddStack(err)
+		}
+		_ = ins.Wait()
+	case FaultPause:
+		if err := syscall.Kill(pid, syscall.SIGSTOP); err != nil {
+			return errors.AddStack(err)
+		}
+	case FaultLatency, FaultDrop:
+		if len(proxies) == 0 {
+			if !p.bootOptions.FaultProxy {
+				return errors.Errorf("start the playground with --%s to inject network faults", withFaultProxy)
+			}
+			return errors.Errorf("network faults are not supported by %s", name)
+		}
+		for _, tp := range proxies {
+			if fault.Kind == FaultLatency {
+				tp.SetLatency(fault.Delay)
+			} else {
+				tp.SetDrop(true)
+			}
+		}
+	case FaultDiskFull:
+		kv, ok := ins.(*instance.TiKVInstance)
+		if !ok {
+			return errors.Errorf("the disk quota of %s can't be filled", name)
+		}
+		size, err := dirSize(filepath.Join(kv.Dir, "data"))
+		if err != nil {
+			return err
+		}
+		// leave 1MiB available, the store is full but still can start
+		kv.Capacity = uint64(size>>20) + 1
+		if err := p.restartInstance(ins); err != nil {
+			kv.Capacity = 0
+			return err
+		}
+	case FaultClockSkew:
+		if cid != spec.ComponentTiKV && cid != spec.ComponentTiFlash {
+			return errors.Errorf("the clock of %s can't be skewed, as the go programs don't read it by libc", name)
+		}
+		if utils.IsNotExist(fault.Lib) {
+			return errors.Errorf("libfaketime %s not found", fault.Lib)
+		}
+		setEnvs(ins, faketimeEnvs(fault.Lib, fault.Offset))
+		if err := p.restartInstance(ins); err != nil {
+			setEnvs(ins, nil)
+			return err
+		}
+	default:
+		return errors.Errorf("unknown fault %s", fault.Kind)
+	}
+
+	f := &activeFault{Fault: fault, componentID: cid}
+	if fault.Duration > 0 {
+		f.timer = time.AfterFunc(fault.Duration, func() {
+			p.cmdMu.Lock()
+			defer p.cmdMu.Unlock()
+			logIfErr(p.clearFault(io.Discard, ins))
+		})
+	}
+	p.faults[ins] = f
+
+	// the pid is changed if the instance is restarted by the fault
+	msg := fmt.Sprintf("fault %s injected into %s", fault.Kind, name)
+	if fault.Kind != FaultKill {
+		msg += fmt.Sprintf(" (pid %d)", ins.Pid())
+	}
+	if fault.Duration > 0 {
+		msg += fmt.Sprintf(" for %s", fault.Duration)
+	}
+	fmt.Println(msg)
+	fmt.Fprintln(w, msg)
+	return nil
+}
+
+// clearFault clears the fault of the instance, the instance is restarted if
+// the fault is applied when it's started
+func (p *Playground) clearFault(w io.Writer, ins instance.Instance) error {
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+
+	f, ok := p.faults[ins]
+	if !ok {
+		fmt.Fprintf(w, "%s has no fault\n", p.instanceName(p.instanceSpecs[ins].Component, ins))
+		return nil
+	}
+	delete(p.faults, ins)
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	p.revertFault(ins, f)
+
+	var err error
+	switch f.Kind {
+	case FaultKill:
+		err = p.startAgain(ins)
+	case FaultPause:
+		err = errors.AddStack(syscall.Kill(ins.Pid(), syscall.SIGCONT))
+	case FaultDiskFull, FaultClockSkew:
+		err = p.restartInstance(ins)
+	}
+	if err != nil {
+		return errors.Annotatef(err, "failed to clear the fault %s of %s", f.Kind, p.instanceName(f.componentID, ins))
+	}
+
+	msg := fmt.Sprintf("fault %s of %s (pid %d) cleared", f.Kind, p.instanceName(f.componentID, ins), ins.Pid())
+	fmt.Println(msg)
+	fmt.Fprintln(w, msg)
+	return nil
+}
+
+// revertFault reverts the settings of the instance and its proxies changed by
+// the fault, the process is not touched
+func (p *Playground) revertFault(ins instance.Instance, f *activeFault) {
+	switch f.Kind {
+	case FaultLatency, FaultDrop:
+		for _, tp := range p.faultProxies[ins] {
+			tp.SetLatency(0)
+			tp.SetDrop(false)
+		}
+	case FaultDiskFull:
+		ins.(*instance.TiKVInstance).Capacity = 0
+	case FaultClockSkew:
+		setEnvs(ins, nil)
+	}
+}
+
+// resetFaults forgets all the faults, the paused instances are resumed so
+// they can be stopped, and the killed ones are left stopped.
+func (p *Playground) resetFaults() {
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+
+	for ins, f := range p.faults {
+		if f.timer != nil {
+			f.timer.Stop()
+		}
+		p.revertFault(ins, f)
+		if f.Kind == FaultPause {
+			_ = syscall.Kill(ins.Pid(), syscall.SIGCONT)
+		}
+	}
+	p.faults = make(map[instance.Instance]*activeFault)
+}
+
+// stoppedOnPurpose reports whether the process is stopped by a fault or an
+// upgrade
+func (p *Playground) stoppedOnPurpose(pid int) bool {
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+
+	_, ok := p.faultStopped[pid]
+	delete(p.faultStopped, pid)
+	return ok
+}
+
+// stopOnPurpose marks the process is going to be stopped on purpose, so it's
+// not taken as crashed
+func (p *Playground) stopOnPurpose(pid int) {
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+	p.faultStopped[pid] = struct{}{}
+}
+
+// restartInstance stops the instance and starts it again, the faultMu should
+// be held
+func (p *Playground) restartInstance(ins instance.Instance) error {
+	pid := ins.Pid()
+	p.faultStopped[pid] = struct{}{}
+	killProcess(pid, syscall.SIGTERM, ins.Wait)
+	return p.startAgain(ins)
+}
+
+// startAgain starts the stopped instance, it's not added to the started
+// instances again
+func (p *Playground) startAgain(ins instance.Instance) error {
+	fmt.Printf("Start %s instance again\n", ins.Component())
+	ctx := context.WithValue(context.TODO(), logprinter.ContextKeyLogger, log)
+	if err := ins.Start(ctx, p.instanceVersion(ins)); err != nil {
+		return err
+	}
+	p.clearCrash(ins)
+	p.waitInstance(ins)
+	return nil
+}
+
+// startFaultProxies starts the proxies in front of the ports of the instance,
+// and the instance listens on other ports
+func (p *Playground) startFaultProxies(ins instance.Instance) error {
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+
+	names := faultProxyPorts[p.instanceSpecs[ins].Component]
+	if len(names) == 0 || len(p.faultProxies[ins]) > 0 {
+		return nil
+	}
+
+	host := instance.AdvertiseHost(p.bootOptions.Host)
+	if h := p.instanceSpecs[ins].Config.Host; h != "" {
+		host = instance.AdvertiseHost(h)
+	}
+	ports := ins.Ports()
+	listenPorts := make(map[string]int)
+	var proxies []*proxy.TCPProxy
+	for _, name := range names {
+		listenPorts[name] = utils.MustGetFreePort(host, ports[name]+10000)
+		tp, err := proxy.NewLocalTCPProxy(fmt.Sprintf("%s:%d", host, ports[name]), log)
+		if err != nil {
+			for _, tp := range proxies {
+				_ = tp.Stop()
+			}
+			return errors.Annotate(err, "failed to start the fault proxy")
+		}
+		tp.Run([]string{fmt.Sprintf("%s:%d", host, listenPorts[name])})
+		proxies = append(proxies, tp)
+	}
+
+	ins.SetListenPorts(listenPorts)
+	p.faultProxies[ins] = proxies
+	return nil
+}
+
+// closeFaultProxies closes the proxies of the instances, or all the proxies
+// if no instance is specified
+func (p *Playground) closeFaultProxies(insts ...instance.Instance) {
+	p.faultMu.Lock()
+	defer p.faultMu.Unlock()
+
+	if len(insts) == 0 {
+		for ins := range p.faultProxies {
+			insts = append(insts, ins)
+		}
+	}
+	for _, ins := range insts {
+		for _, tp := range p.faultProxies[ins] {
+			_ = tp.Stop()
+		}
+		delete(p.faultProxies, ins)
+	}
+}
+
+// setEnvs sets the extra environment variables of the instance
+func setEnvs(ins instance.Instance, envs map[string]string) {
+	switch inst := ins.(type) {
+	case *instance.TiKVInstance:
+		inst.Envs = envs
+	case *instance.TiFlashInstance:
+		inst.Envs = envs
+	}
+}
+
+// faketimeEnvs returns the environment variables to skew the clock of a
+// process by libfaketime
+func faketimeEnvs(lib string, offset time.Duration) map[string]string {
+	envs := map[string]string{
+		"FAKETIME": fmt.Sprintf("%+d", int64(offset/time.Second)),
+	}
+	if runtime.GOOS == "darwin" {
+		envs["DYLD_INSERT_LIBRARIES"] = lib
+		envs["DYLD_FORCE_FLAT_NAMESPACE"] = "1"
+	} else {
+		envs["LD_PRELOAD"] = lib
+	}
+	return envs
+}
+
+// dirSize returns the total size of the files in the dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, errors.AddStack(err)
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// sleepInstance is an instance running sleep
+type sleepInstance struct {
+	mu   sync.Mutex
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error
+}
+
+func (s *sleepInstance) Pid() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmd.Process.Pid
+}
+
+func (s *sleepInstance) Start(ctx context.Context, version utils.Version) error {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cmd, s.done = cmd, done
+	return nil
+}
+
+func (s *sleepInstance) Wait() error {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	<-done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *sleepInstance) exited() bool {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *sleepInstance) Component() string                   { return "sleep" }
+func (s *sleepInstance) LogFile() string                     { return "" }
+func (s *sleepInstance) Uptime() string                      { return "" }
+func (s *sleepInstance) StatusAddrs() []string               { return nil }
+func (s *sleepInstance) Ports() map[string]int               { return nil }
+func (s *sleepInstance) SetPorts(ports map[string]int)       {}
+func (s *sleepInstance) SetListenPorts(ports map[string]int) {}
+func (s *sleepInstance) SetBinPath(binPath string)           {}
+func (s *sleepInstance) SetResources(res instance.Resources) {}
+
+func TestInjectFault(t *testing.T) {
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = &BootOptions{}
+	ins := &sleepInstance{}
+	assert.Nil(t, ins.Start(context.TODO(), ""))
+	p.addWaitInstance(ins)
+	w := new(bytes.Buffer)
+
+	// the network faults need the proxies
+	err := p.injectFault(w, "tidb", ins, Fault{Kind: FaultLatency, Delay: time.Second})
+	assert.Contains(t, err.Error(), "--fault-proxy")
+	err = p.injectFault(w, "tidb", ins, Fault{Kind: FaultDiskFull})
+	assert.NotNil(t, err)
+	err = p.injectFault(w, "tidb", ins, Fault{Kind: FaultClockSkew})
+	assert.NotNil(t, err)
+
+	pid := ins.Pid()
+	assert.Nil(t, p.injectFault(w, "tidb", ins, Fault{Kind: FaultPause}))
+	assert.NotNil(t, p.injectFault(w, "tidb", ins, Fault{Kind: FaultKill}))
+	assert.Nil(t, p.clearFault(w, ins))
+	assert.Equal(t, pid, ins.Pid())
+
+	// the killed instance is started again when the fault is cleared
+	assert.Nil(t, p.injectFault(w, "tidb", ins, Fault{Kind: FaultKill, Duration: 100 * time.Millisecond}))
+	assert.True(t, ins.exited())
+	time.Sleep(500 * time.Millisecond)
+	assert.NotEqual(t, pid, ins.Pid())
+	assert.False(t, ins.exited())
+	p.faultMu.Lock()
+	assert.Len(t, p.faults, 0)
+	p.faultMu.Unlock()
+	assert.Contains(t, w.String(), "fault kill injected into tidb-0")
+	assert.Contains(t, w.String(), "fault pause injected into tidb-0 (pid")
+
+	atomic.StoreInt32(&p.curSig, int32(syscall.SIGKILL))
+	p.terminate(syscall.SIGKILL)
+	assert.Nil(t, p.wait())
+}
+
+func TestFaketimeEnvs(t *testing.T) {
+	envs := faketimeEnvs("/usr/lib/faketime/libfaketime.so.1", -90*time.Second)
+	assert.Equal(t, "-90", envs["FAKETIME"])
+	envs = faketimeEnvs("/usr/lib/faketime/libfaketime.so.1", 2*time.Hour)
+	assert.Equal(t, "+7200", envs["FAKETIME"])
+}
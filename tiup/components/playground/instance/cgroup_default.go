@@ -0,0 +1,33 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//go:build !linux
+// +build !linux
+
+package instance
+
+import (
+	"os/exec"
+
+	"github.com/pingcap/errors"
+)
+
+// prepareCgroup fails if any limit is set, as cgroup is only on Linux
+func (inst *instance) prepareCgroup() (string, error) {
+	if inst.Resources.IsEmpty() {
+		return "", nil
+	}
+	return "", errors.New("the resource limits of the instances are only supported on Linux")
+}
+
+func joinCgroupOnExec(cmd *exec.Cmd, dir string) {}
@@ -0,0 +1,156 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//go:build linux
+// +build linux
+
+package instance
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+)
+
+// the paths to find the cgroup of playground, they're changed by tests
+var (
+	cgroupRoot     = "/sys/fs/cgroup"
+	procCgroupFile = "/proc/self/cgroup"
+)
+
+// the period of cpu.max in microseconds
+const cpuPeriod = 100000
+
+// the cgroup of playground, the instances are limited in its child cgroups
+var playgroundCgroup struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+// ownCgroup returns the cgroup v2 dir of playground, and enables the memory
+// and cpu controllers for its children. As the processes can only be in the
+// leaf cgroups, the processes in it are moved to a child cgroup first.
+func ownCgroup() (string, error) {
+	playgroundCgroup.once.Do(func() {
+		playgroundCgroup.dir, playgroundCgroup.err = initOwnCgroup()
+	})
+	return playgroundCgroup.dir, playgroundCgroup.err
+}
+
+func initOwnCgroup() (string, error) {
+	f, err := os.Open(procCgroupFile)
+	if err != nil {
+		return "", errors.AddStack(err)
+	}
+	defer f.Close()
+
+	path := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// the line of cgroup v2 is like 0::/user.slice/playground.scope
+		if p := strings.TrimPrefix(scanner.Text(), "0::"); p != scanner.Text() {
+			path = p
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.AddStack(err)
+	}
+	dir := filepath.Join(cgroupRoot, path)
+	controllers, err := os.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if path == "" || err != nil {
+		return "", errors.New("cgroup v2 is required to limit the resources of the instances")
+	}
+	for _, c := range []string{"memory", "cpu"} {
+		if !strings.Contains(" "+string(controllers)+" ", " "+c+" ") {
+			return "", errors.Errorf("the %s controller isn't delegated to the cgroup %s, start playground by `systemd-run --user --scope -p Delegate=yes tiup playground ...`", c, dir)
+		}
+	}
+
+	leaf := filepath.Join(dir, "playground")
+	if err := os.MkdirAll(leaf, 0755); err != nil {
+		return "", errors.Annotatef(err, "failed to create the cgroup %s", leaf)
+	}
+	procs, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return "", errors.AddStack(err)
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		// the processes may quit in the meantime
+		_ = writeCgroupFile(leaf, "cgroup.procs", pid)
+	}
+	if err := writeCgroupFile(dir, "cgroup.subtree_control", "+memory +cpu"); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+		return errors.Annotatef(err, "failed to write %s to %s of the cgroup %s", value, name, dir)
+	}
+	return nil
+}
+
+// prepareCgroup creates the cgroup with the limits for the instance, it's
+// named after the dir of the instance, like tikv-0
+func (inst *instance) prepareCgroup() (string, error) {
+	if inst.Resources.IsEmpty() {
+		return "", nil
+	}
+	parent, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(parent, filepath.Base(inst.Dir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Annotatef(err, "failed to create the cgroup %s", dir)
+	}
+	memMax, cpuMax := "max", "max"
+	if inst.Resources.MemoryLimit > 0 {
+		memMax = strconv.FormatUint(inst.Resources.MemoryLimit, 10)
+	}
+	if inst.Resources.CPUQuota > 0 {
+		cpuMax = strconv.FormatUint(inst.Resources.CPUQuota*cpuPeriod/100, 10)
+	}
+	if err := writeCgroupFile(dir, "memory.max", memMax); err != nil {
+		return "", err
+	}
+	if err := writeCgroupFile(dir, "cpu.max", cpuMax+" "+strconv.Itoa(cpuPeriod)); err != nil {
+		return "", err
+	}
+	if inst.Resources.MemoryLimit > 0 {
+		// the limit isn't effective if the instance swaps, the file is missing
+		// if swap is not accounted
+		_ = writeCgroupFile(dir, "memory.swap.max", "0")
+	}
+	return dir, nil
+}
+
+// joinCgroupOnExec makes the command join the cgroup before its binary is
+// executed, so none of its memory or threads is out of the limits. It's run by
+// a shell writing its own pid into the cgroup, the pid is kept by exec.
+func joinCgroupOnExec(cmd *exec.Cmd, dir string) {
+	const script = `echo $$ > "$0" && exec "$@"`
+	args := []string{"sh", "-c", script, filepath.Join(dir, "cgroup.procs"), cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+}
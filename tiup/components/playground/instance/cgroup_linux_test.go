@@ -0,0 +1,78 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//go:build linux
+// +build linux
+
+package instance
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareCgroup(t *testing.T) {
+	// fake the cgroup files by regular files
+	root := t.TempDir()
+	procFile := filepath.Join(t.TempDir(), "cgroup")
+	cgroupRoot, procCgroupFile = root, procFile
+	defer func() {
+		cgroupRoot, procCgroupFile = "/sys/fs/cgroup", "/proc/self/cgroup"
+	}()
+	dir := filepath.Join(root, "user.slice", "playground.scope")
+	assert.Nil(t, os.MkdirAll(dir, 0755))
+	assert.Nil(t, os.WriteFile(procFile, []byte("1:name=systemd:/\n0::/user.slice/playground.scope\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpu io memory pids\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte("42\n"), 0644))
+
+	inst := &instance{Dir: "/data/tikv-0", Resources: Resources{MemoryLimit: 1 << 30, CPUQuota: 150}}
+	cg, err := inst.prepareCgroup()
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "tikv-0"), cg)
+
+	read := func(dir, name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		assert.Nil(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "42", read(filepath.Join(dir, "playground"), "cgroup.procs"))
+	assert.Equal(t, "+memory +cpu", read(dir, "cgroup.subtree_control"))
+	assert.Equal(t, "1073741824", read(cg, "memory.max"))
+	assert.Equal(t, "0", read(cg, "memory.swap.max"))
+	assert.Equal(t, "150000 100000", read(cg, "cpu.max"))
+
+	// no cgroup without limits
+	cg, err = (&instance{Dir: "/data/pd-0"}).prepareCgroup()
+	assert.Nil(t, err)
+	assert.Empty(t, cg)
+}
+
+func TestJoinCgroupOnExec(t *testing.T) {
+	dir := t.TempDir()
+	cmd := exec.Command("echo", "hello", "playground")
+	joinCgroupOnExec(cmd, dir)
+	out, err := cmd.Output()
+	assert.Nil(t, err)
+	assert.Equal(t, "hello playground\n", string(out))
+
+	// the process joins the cgroup by itself before the binary is executed
+	procs, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	assert.Nil(t, err)
+	assert.Equal(t, strconv.Itoa(cmd.Process.Pid), strings.TrimSpace(string(procs)))
+}
@@ -74,7 +74,7 @@ func (d *Drainer) Start(ctx context.Context, version utils.Version) error {
 
 	args := []string{
 		fmt.Sprintf("--node-id=%s", d.NodeID()),
-		fmt.Sprintf("--addr=%s:%d", d.Host, d.Port),
+		fmt.Sprintf("--addr=%s:%d", d.Host, d.listenPort("port", d.Port)),
 		fmt.Sprintf("--advertise-addr=%s:%d", AdvertiseHost(d.Host), d.Port),
 		fmt.Sprintf("--pd-urls=%s", strings.Join(endpoints, ",")),
 		fmt.Sprintf("--log-file=%s", d.LogFile()),
@@ -84,7 +84,11 @@ func (d *Drainer) Start(ctx context.Context, version utils.Version) error {
 	}
 
 	var err error
-	if d.Process, err = NewComponentProcess(ctx, d.Dir, d.BinPath, "drainer", version, args...); err != nil {
+	envs := d.memoryEnvs("drainer", d.Envs)
+	if d.Process, err = NewComponentProcessWithEnvs(ctx, d.Dir, d.BinPath, "drainer", version, envs, args...); err != nil {
+		return err
+	}
+	if err = d.limit(d.Process); err != nil {
 		return err
 	}
 	logIfErr(d.Process.SetOutputFile(d.LogFile()))
@@ -23,12 +23,64 @@ import (
 
 // Config of the instance.
 type Config struct {
-	ConfigPath string `yaml:"config_path"`
-	BinPath    string `yaml:"bin_path"`
-	Num        int    `yaml:"num"`
-	Host       string `yaml:"host"`
-	Port       int    `yaml:"port"`
-	UpTimeout  int    `yaml:"up_timeout"`
+	ConfigPath string            `yaml:"config_path,omitempty"`
+	BinPath    string            `yaml:"bin_path,omitempty"`
+	Version    string            `yaml:"version,omitempty"`
+	Num        int               `yaml:"num"`
+	Host       string            `yaml:"host,omitempty"`
+	Port       int               `yaml:"port,omitempty"`
+	StatusPort int               `yaml:"status_port,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+	UpTimeout  int               `yaml:"up_timeout,omitempty"`
+	// MemoryLimit and CPUQuota limit the resources of each instance in the
+	// format of systemd, e.g. 2G and 150%
+	MemoryLimit string `yaml:"memory_limit,omitempty"`
+	CPUQuota    string `yaml:"cpu_quota,omitempty"`
+	// Instances overrides the config of each instance by its index
+	Instances []Config `yaml:"instances,omitempty"`
+}
+
+// InstanceConfigs returns the config of each instance, the fields not set in
+// the overrides are inherited from the component.
+func (c Config) InstanceConfigs() []Config {
+	var cfgs []Config
+	for i := 0; i < c.Num; i++ {
+		cfg := c
+		cfg.Num = 1
+		cfg.Instances = nil
+		if i < len(c.Instances) {
+			o := c.Instances[i]
+			if o.ConfigPath != "" {
+				cfg.ConfigPath = o.ConfigPath
+			}
+			if o.BinPath != "" {
+				cfg.BinPath = o.BinPath
+			}
+			if o.Version != "" {
+				cfg.Version = o.Version
+			}
+			if o.Host != "" {
+				cfg.Host = o.Host
+			}
+			if o.Port != 0 {
+				cfg.Port = o.Port
+			}
+			if o.StatusPort != 0 {
+				cfg.StatusPort = o.StatusPort
+			}
+			if len(o.Labels) > 0 {
+				cfg.Labels = o.Labels
+			}
+			if o.MemoryLimit != "" {
+				cfg.MemoryLimit = o.MemoryLimit
+			}
+			if o.CPUQuota != "" {
+				cfg.CPUQuota = o.CPUQuota
+			}
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs
 }
 
 type instance struct {
@@ -39,6 +91,14 @@ type instance struct {
 	StatusPort int // client port for PD
 	ConfigPath string
 	BinPath    string
+	// Envs are the extra environment variables of the process
+	Envs map[string]string
+	// Resources limit the process, the memory settings of the component are
+	// sized to match the memory limit
+	Resources Resources
+	// the ports listened by the process instead of the advertised ones, which
+	// are listened by the fault proxies in front of it
+	listenPorts map[string]int
 }
 
 // Instance represent running component
@@ -58,6 +118,18 @@ type Instance interface {
 	// Wait Should only call this if the instance is started successfully.
 	// The implementation should be safe to call Wait multi times.
 	Wait() error
+	// Ports return the ports of the instance by their names.
+	Ports() map[string]int
+	// SetPorts changes the ports, it should be called before Start.
+	SetPorts(ports map[string]int)
+	// SetListenPorts makes the process listen on other ports than the
+	// advertised ones by their names, it should be called before Start.
+	SetListenPorts(ports map[string]int)
+	// SetBinPath changes the binary, it's used by the next Start, and the
+	// binary of the version is used if it's empty.
+	SetBinPath(binPath string)
+	// SetResources changes the resource limits, it's used by the next Start.
+	SetResources(res Resources)
 }
 
 func (inst *instance) StatusAddrs() (addrs []string) {
@@ -67,6 +139,55 @@ func (inst *instance) StatusAddrs() (addrs []string) {
 	return
 }
 
+func (inst *instance) Ports() map[string]int {
+	return map[string]int{
+		"port":        inst.Port,
+		"status_port": inst.StatusPort,
+	}
+}
+
+func (inst *instance) SetPorts(ports map[string]int) {
+	setPort(&inst.Port, ports, "port")
+	setPort(&inst.StatusPort, ports, "status_port")
+}
+
+func (inst *instance) SetListenPorts(ports map[string]int) {
+	inst.listenPorts = ports
+}
+
+func (inst *instance) SetBinPath(binPath string) {
+	inst.BinPath = binPath
+}
+
+func (inst *instance) SetResources(res Resources) {
+	inst.Resources = res
+}
+
+// limit makes the process run in a cgroup with the resource limits
+func (inst *instance) limit(p Process) error {
+	dir, err := inst.prepareCgroup()
+	if err != nil {
+		return err
+	}
+	p.SetCgroup(dir)
+	return nil
+}
+
+// listenPort returns the port listened by the process for the named port
+func (inst *instance) listenPort(name string, port int) int {
+	if p, ok := inst.listenPorts[name]; ok && p > 0 {
+		return p
+	}
+	return port
+}
+
+// setPort sets the port if it's in the ports
+func setPort(port *int, ports map[string]int, name string) {
+	if p, ok := ports[name]; ok && p > 0 {
+		*port = p
+	}
+}
+
 // CompVersion return the format to run specified version of a component.
 func CompVersion(comp string, version utils.Version) string {
 	if version.IsEmpty() {
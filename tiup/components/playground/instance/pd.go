@@ -69,9 +69,9 @@ func (inst *PDInstance) Start(ctx context.Context, version utils.Version) error
 	args := []string{
 		"--name=" + uid,
 		fmt.Sprintf("--data-dir=%s", filepath.Join(inst.Dir, "data")),
-		fmt.Sprintf("--peer-urls=http://%s:%d", inst.Host, inst.Port),
+		fmt.Sprintf("--peer-urls=http://%s:%d", inst.Host, inst.listenPort("port", inst.Port)),
 		fmt.Sprintf("--advertise-peer-urls=http://%s:%d", AdvertiseHost(inst.Host), inst.Port),
-		fmt.Sprintf("--client-urls=http://%s:%d", inst.Host, inst.StatusPort),
+		fmt.Sprintf("--client-urls=http://%s:%d", inst.Host, inst.listenPort("status_port", inst.StatusPort)),
 		fmt.Sprintf("--advertise-client-urls=http://%s:%d", AdvertiseHost(inst.Host), inst.StatusPort),
 		fmt.Sprintf("--log-file=%s", inst.LogFile()),
 	}
@@ -98,7 +98,11 @@ func (inst *PDInstance) Start(ctx context.Context, version utils.Version) error
 	}
 
 	var err error
-	if inst.Process, err = NewComponentProcess(ctx, inst.Dir, inst.BinPath, "pd", version, args...); err != nil {
+	envs := inst.memoryEnvs("pd", inst.Envs)
+	if inst.Process, err = NewComponentProcessWithEnvs(ctx, inst.Dir, inst.BinPath, "pd", version, envs, args...); err != nil {
+		return err
+	}
+	if err = inst.limit(inst.Process); err != nil {
 		return err
 	}
 	logIfErr(inst.Process.SetOutputFile(inst.LogFile()))
@@ -22,6 +22,8 @@ type Process interface {
 	Pid() int
 	Uptime() string
 	SetOutputFile(fname string) error
+	// SetCgroup makes the process run in the cgroup when it's started
+	SetCgroup(dir string)
 	Cmd() *exec.Cmd
 }
 
@@ -29,6 +31,7 @@ type Process interface {
 type process struct {
 	cmd       *exec.Cmd
 	startTime time.Time
+	cgroup    string
 
 	waitOnce sync.Once
 	waitErr  error
@@ -38,6 +41,9 @@ type process struct {
 func (p *process) Start() error {
 	// fmt.Printf("Starting `%s`: %s", filepath.Base(p.cmd.Path), strings.Join(p.cmd.Args, " "))
 	p.startTime = time.Now()
+	if p.cgroup != "" {
+		joinCgroupOnExec(p.cmd, p.cgroup)
+	}
 	return p.cmd.Start()
 }
 
@@ -45,6 +51,10 @@ func (p *process) Start() error {
 func (p *process) Wait() error {
 	p.waitOnce.Do(func() {
 		p.waitErr = p.cmd.Wait()
+		if p.cgroup != "" {
+			// it's created again when the instance is restarted
+			_ = os.Remove(p.cgroup)
+		}
 	})
 
 	return p.waitErr
@@ -81,6 +91,10 @@ func (p *process) setOutput(w io.Writer) {
 	p.cmd.Stderr = w
 }
 
+func (p *process) SetCgroup(dir string) {
+	p.cgroup = dir
+}
+
 func (p *process) Cmd() *exec.Cmd {
 	return p.cmd
 }
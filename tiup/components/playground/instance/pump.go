@@ -93,7 +93,7 @@ func (p *Pump) Start(ctx context.Context, version utils.Version) error {
 
 	args := []string{
 		fmt.Sprintf("--node-id=%s", p.NodeID()),
-		fmt.Sprintf("--addr=%s:%d", p.Host, p.Port),
+		fmt.Sprintf("--addr=%s:%d", p.Host, p.listenPort("port", p.Port)),
 		fmt.Sprintf("--advertise-addr=%s:%d", AdvertiseHost(p.Host), p.Port),
 		fmt.Sprintf("--pd-urls=%s", strings.Join(endpoints, ",")),
 		fmt.Sprintf("--log-file=%s", p.LogFile()),
@@ -103,7 +103,11 @@ func (p *Pump) Start(ctx context.Context, version utils.Version) error {
 	}
 
 	var err error
-	if p.Process, err = NewComponentProcess(ctx, p.Dir, p.BinPath, "pump", version, args...); err != nil {
+	envs := p.memoryEnvs("pump", p.Envs)
+	if p.Process, err = NewComponentProcessWithEnvs(ctx, p.Dir, p.BinPath, "pump", version, envs, args...); err != nil {
+		return err
+	}
+	if err = p.limit(p.Process); err != nil {
 		return err
 	}
 	logIfErr(p.Process.SetOutputFile(p.LogFile()))
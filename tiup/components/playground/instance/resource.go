@@ -0,0 +1,189 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/mod/semver"
+)
+
+// Resources are the resource limits of an instance, they're applied by cgroup
+// v2 on Linux
+type Resources struct {
+	// MemoryLimit is in bytes, 0 means no limit
+	MemoryLimit uint64
+	// CPUQuota is in the percentage of a CPU, e.g. 200 for 2 CPUs, 0 means
+	// no limit
+	CPUQuota uint64
+}
+
+// IsEmpty returns whether there's no limit
+func (r Resources) IsEmpty() bool {
+	return r.MemoryLimit == 0 && r.CPUQuota == 0
+}
+
+// ParseResources parses the memory_limit and cpu_quota in the format of
+// systemd, e.g. 2G and 150%, the empty ones are not limited
+func ParseResources(memoryLimit, cpuQuota string) (Resources, error) {
+	var res Resources
+	if memoryLimit = strings.TrimSpace(memoryLimit); memoryLimit != "" {
+		num, shift := memoryLimit, uint(0)
+		if i := strings.IndexAny(memoryLimit, "KMGT"); i == len(memoryLimit)-1 {
+			num, shift = memoryLimit[:i], 10*uint(strings.Index("KMGT", memoryLimit[i:])+1)
+		}
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil || n == 0 {
+			return res, errors.Errorf("invalid memory_limit %s, it should be like 2G", memoryLimit)
+		}
+		res.MemoryLimit = n << shift
+	}
+	if cpuQuota = strings.TrimSpace(cpuQuota); cpuQuota != "" {
+		pct, err := strconv.ParseUint(strings.TrimSuffix(cpuQuota, "%"), 10, 64)
+		if err != nil || !strings.HasSuffix(cpuQuota, "%") || pct == 0 {
+			return res, errors.Errorf("invalid cpu_quota %s, it should be like 150%%", cpuQuota)
+		}
+		res.CPUQuota = pct
+	}
+	return res, nil
+}
+
+// memoryItem is a config item sized by the memory limit
+type memoryItem struct {
+	key string
+	// ratio is the ratio of the memory limit the item is set to
+	ratio  float64
+	format func(bytes uint64) interface{}
+	// the item is deprecated since the version, empty if it's not
+	deprecated string
+}
+
+// memoryItems are the config items of the components sized by their memory
+// limits, they're set unless they're set to non-zero values in the config
+var memoryItems = map[string][]memoryItem{
+	// the ratio of TiKV is the same as the default one of the system memory
+	"tikv": {{"storage.block-cache.capacity", 0.45, func(b uint64) interface{} {
+		return fmt.Sprintf("%dMB", b>>20)
+	}, ""}},
+	"tidb": {{"performance.server-memory-quota", 0.8, func(b uint64) interface{} {
+		return int64(b)
+	}, tidbMemoryLimitVersion}},
+	"tiflash": {{"profiles.default.max_memory_usage", 0.8, func(b uint64) interface{} {
+		return int64(b)
+	}, ""}},
+}
+
+// tidbMemoryLimitVersion is the first version of TiDB with the system variable
+// tidb_server_memory_limit, which takes the place of server-memory-quota
+const tidbMemoryLimitVersion = "v6.4.0"
+
+// tidbMemoryLimitRatio is the ratio of the memory TiDB is limited to by
+// tidb_server_memory_limit, TiDB reads its memory from the cgroup
+const tidbMemoryLimitRatio = "80%"
+
+// goComponents are the components written in Go, their GC is tuned by
+// GOMEMLIMIT to keep the heap in the memory limit
+var goComponents = map[string]bool{
+	"pd":      true,
+	"tidb":    true,
+	"cdc":     true,
+	"pump":    true,
+	"drainer": true,
+}
+
+// the ratio of the memory limit GOMEMLIMIT is set to
+const goMemoryLimitRatio = 0.9
+
+// memoryEnvs returns the envs with GOMEMLIMIT set by the memory limit for the
+// Go components
+func (inst *instance) memoryEnvs(component string, envs map[string]string) map[string]string {
+	limit := inst.Resources.MemoryLimit
+	if limit == 0 || !goComponents[component] {
+		return envs
+	}
+	if _, ok := envs["GOMEMLIMIT"]; ok {
+		return envs
+	}
+	sized := map[string]string{"GOMEMLIMIT": strconv.FormatUint(uint64(float64(limit)*goMemoryLimitRatio), 10)}
+	for k, v := range envs {
+		sized[k] = v
+	}
+	return sized
+}
+
+// sizedConfig sizes the memory settings of the component to match the memory
+// limit, it returns the config file to start the instance with, which is
+// written in the dir of the instance if any item is sized
+func (inst *instance) sizedConfig(component, configPath string, version utils.Version) (string, error) {
+	items := memoryItems[component]
+	limit := inst.Resources.MemoryLimit
+	if limit == 0 || len(items) == 0 {
+		return configPath, nil
+	}
+
+	cfg := make(map[string]interface{})
+	if configPath != "" {
+		if _, err := toml.DecodeFile(configPath, &cfg); err != nil {
+			return "", errors.Annotatef(err, "failed to parse the config %s", configPath)
+		}
+	}
+	overwrite := make(map[string]interface{})
+	for _, item := range items {
+		if item.deprecated != "" && (semver.Compare(version.String(), item.deprecated) >= 0 || version.IsNightly()) {
+			continue
+		}
+		if v := spec.GetValueFromPath(cfg, item.key); v != nil && !isZeroConfig(v) {
+			continue
+		}
+		overwrite[item.key] = item.format(uint64(float64(limit) * item.ratio))
+	}
+	if len(overwrite) == 0 {
+		return configPath, nil
+	}
+
+	if err := os.MkdirAll(inst.Dir, 0755); err != nil {
+		return "", errors.AddStack(err)
+	}
+	sizedPath := filepath.Join(inst.Dir, component+"-sized.toml")
+	f, err := os.Create(sizedPath)
+	if err != nil {
+		return "", errors.AddStack(err)
+	}
+	defer f.Close()
+	if err := toml.NewEncoder(f).Encode(spec.MergeConfig(cfg, overwrite)); err != nil {
+		return "", errors.AddStack(err)
+	}
+	return sizedPath, nil
+}
+
+// isZeroConfig returns whether the config value means no limit
+func isZeroConfig(v interface{}) bool {
+	switch v := v.(type) {
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case string:
+		return v == "" || v == "0"
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResources(t *testing.T) {
+	res, err := ParseResources("2G", "150%")
+	assert.Nil(t, err)
+	assert.Equal(t, Resources{MemoryLimit: 2 << 30, CPUQuota: 150}, res)
+	res, err = ParseResources("1048576", "")
+	assert.Nil(t, err)
+	assert.Equal(t, Resources{MemoryLimit: 1 << 20}, res)
+	res, err = ParseResources("", "")
+	assert.Nil(t, err)
+	assert.True(t, res.IsEmpty())
+
+	for _, limit := range []string{"2GB", "G", "0", "-1M", "1.5G"} {
+		_, err = ParseResources(limit, "")
+		assert.NotNil(t, err, limit)
+	}
+	for _, quota := range []string{"150", "0%", "1.5%"} {
+		_, err = ParseResources("", quota)
+		assert.NotNil(t, err, quota)
+	}
+}
+
+func TestSizedConfig(t *testing.T) {
+	dir := t.TempDir()
+	inst := &instance{Dir: dir, Resources: Resources{MemoryLimit: 4 << 30}}
+
+	// the items set in the config are kept
+	configPath := filepath.Join(dir, "tidb.toml")
+	assert.Nil(t, os.WriteFile(configPath, []byte("[performance]\nserver-memory-quota = 1024\n"), 0644))
+	path, err := inst.sizedConfig("tidb", configPath, "v6.1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, configPath, path)
+
+	// the items not set or set to 0 are sized
+	assert.Nil(t, os.WriteFile(configPath, []byte("[performance]\nserver-memory-quota = 0\nmax-procs = 2\n"), 0644))
+	path, err = inst.sizedConfig("tidb", configPath, "v6.1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "tidb-sized.toml"), path)
+	cfg := make(map[string]interface{})
+	_, err = toml.DecodeFile(path, &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"server-memory-quota": int64(4 << 30 * 8 / 10),
+		"max-procs":           int64(2),
+	}, cfg["performance"])
+
+	// TiDB is limited by tidb_server_memory_limit since v6.4.0
+	path, err = inst.sizedConfig("tidb", "", "v6.5.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "", path)
+	db := &TiDBInstance{instance: *inst}
+	assert.Equal(t, "", db.MemoryLimitSQL("v6.1.0"))
+	assert.Equal(t, "SET GLOBAL tidb_server_memory_limit = '80%'", db.MemoryLimitSQL("v6.5.0"))
+	assert.Equal(t, "SET GLOBAL tidb_server_memory_limit = '80%'", db.MemoryLimitSQL("nightly"))
+	assert.Equal(t, "", (&TiDBInstance{}).MemoryLimitSQL("v6.5.0"))
+
+	path, err = inst.sizedConfig("tikv", "", "v6.1.0")
+	assert.Nil(t, err)
+	cfg = make(map[string]interface{})
+	_, err = toml.DecodeFile(path, &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"capacity": "1843MB"}, cfg["storage"].(map[string]interface{})["block-cache"])
+
+	// the Go components are limited by GOMEMLIMIT
+	assert.Equal(t, map[string]string{"GOMEMLIMIT": "3865470566", "A": "B"}, inst.memoryEnvs("pd", map[string]string{"A": "B"}))
+	assert.Equal(t, map[string]string{"GOMEMLIMIT": "1G"}, inst.memoryEnvs("pd", map[string]string{"GOMEMLIMIT": "1G"}))
+	assert.Nil(t, inst.memoryEnvs("tikv", nil))
+}
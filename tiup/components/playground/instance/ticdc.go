@@ -49,13 +49,18 @@ func NewTiCDC(binPath string, dir, host, configPath string, id int, pds []*PDIns
 	return ticdc
 }
 
+// Addr return the address of TiCDC.
+func (c *TiCDC) Addr() string {
+	return fmt.Sprintf("%s:%d", AdvertiseHost(c.Host), c.Port)
+}
+
 // Start implements Instance interface.
 func (c *TiCDC) Start(ctx context.Context, version utils.Version) error {
 	endpoints := pdEndpoints(c.pds, true)
 
 	args := []string{
 		"server",
-		fmt.Sprintf("--addr=%s:%d", c.Host, c.Port),
+		fmt.Sprintf("--addr=%s:%d", c.Host, c.listenPort("port", c.Port)),
 		fmt.Sprintf("--advertise-addr=%s:%d", AdvertiseHost(c.Host), c.Port),
 		fmt.Sprintf("--pd=%s", strings.Join(endpoints, ",")),
 		fmt.Sprintf("--log-file=%s", c.LogFile()),
@@ -71,7 +76,11 @@ func (c *TiCDC) Start(ctx context.Context, version utils.Version) error {
 	}
 
 	var err error
-	if c.Process, err = NewComponentProcess(ctx, c.Dir, c.BinPath, "cdc", version, args...); err != nil {
+	envs := c.memoryEnvs("cdc", c.Envs)
+	if c.Process, err = NewComponentProcessWithEnvs(ctx, c.Dir, c.BinPath, "cdc", version, envs, args...); err != nil {
+		return err
+	}
+	if err = c.limit(c.Process); err != nil {
 		return err
 	}
 	logIfErr(c.Process.SetOutputFile(c.LogFile()))
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/mod/semver"
 )
 
 // TiDBInstance represent a running tidb-server
@@ -54,24 +55,31 @@ func NewTiDBInstance(binPath string, dir, host, configPath string, id, port int,
 // Start calls set inst.cmd and Start
 func (inst *TiDBInstance) Start(ctx context.Context, version utils.Version) error {
 	endpoints := pdEndpoints(inst.pds, false)
+	configPath, err := inst.sizedConfig("tidb", inst.ConfigPath, version)
+	if err != nil {
+		return err
+	}
 
 	args := []string{
-		"-P", strconv.Itoa(inst.Port),
+		"-P", strconv.Itoa(inst.listenPort("port", inst.Port)),
 		"--store=tikv",
 		fmt.Sprintf("--host=%s", inst.Host),
 		fmt.Sprintf("--status=%d", inst.StatusPort),
 		fmt.Sprintf("--path=%s", strings.Join(endpoints, ",")),
 		fmt.Sprintf("--log-file=%s", filepath.Join(inst.Dir, "tidb.log")),
 	}
-	if inst.ConfigPath != "" {
-		args = append(args, fmt.Sprintf("--config=%s", inst.ConfigPath))
+	if configPath != "" {
+		args = append(args, fmt.Sprintf("--config=%s", configPath))
 	}
 	if inst.enableBinlog {
 		args = append(args, "--enable-binlog=true")
 	}
 
-	var err error
-	if inst.Process, err = NewComponentProcess(ctx, inst.Dir, inst.BinPath, "tidb", version, args...); err != nil {
+	envs := inst.memoryEnvs("tidb", inst.Envs)
+	if inst.Process, err = NewComponentProcessWithEnvs(ctx, inst.Dir, inst.BinPath, "tidb", version, envs, args...); err != nil {
+		return err
+	}
+	if err = inst.limit(inst.Process); err != nil {
 		return err
 	}
 	logIfErr(inst.Process.SetOutputFile(inst.LogFile()))
@@ -93,3 +101,14 @@ func (inst *TiDBInstance) LogFile() string {
 func (inst *TiDBInstance) Addr() string {
 	return fmt.Sprintf("%s:%d", AdvertiseHost(inst.Host), inst.Port)
 }
+
+// MemoryLimitSQL returns the statement to limit the memory of TiDB by the
+// system variable tidb_server_memory_limit, it's empty if the memory isn't
+// limited or the version has no such variable
+func (inst *TiDBInstance) MemoryLimitSQL(version utils.Version) string {
+	if inst.Resources.MemoryLimit == 0 ||
+		(semver.Compare(version.String(), tidbMemoryLimitVersion) < 0 && !version.IsNightly()) {
+		return ""
+	}
+	return fmt.Sprintf("SET GLOBAL tidb_server_memory_limit = '%s'", tidbMemoryLimitRatio)
+}
@@ -67,6 +67,25 @@ func NewTiFlashInstance(binPath, dir, host, configPath string, id int, pds []*PD
 	}
 }
 
+// Ports return the ports of TiFlash and its proxy.
+func (inst *TiFlashInstance) Ports() map[string]int {
+	ports := inst.instance.Ports()
+	ports["tcp_port"] = inst.TCPPort
+	ports["service_port"] = inst.ServicePort
+	ports["proxy_port"] = inst.ProxyPort
+	ports["proxy_status_port"] = inst.ProxyStatusPort
+	return ports
+}
+
+// SetPorts changes the ports of TiFlash and its proxy.
+func (inst *TiFlashInstance) SetPorts(ports map[string]int) {
+	inst.instance.SetPorts(ports)
+	setPort(&inst.TCPPort, ports, "tcp_port")
+	setPort(&inst.ServicePort, ports, "service_port")
+	setPort(&inst.ProxyPort, ports, "proxy_port")
+	setPort(&inst.ProxyStatusPort, ports, "proxy_status_port")
+}
+
 func getFlashClusterPath(dir string) string {
 	return fmt.Sprintf("%s/flash_cluster_manager", dir)
 }
@@ -171,12 +190,19 @@ func (inst *TiFlashInstance) Start(ctx context.Context, version utils.Version) e
 		return err
 	}
 
+	configPath, err := inst.sizedConfig("tiflash", inst.ConfigPath, version)
+	if err != nil {
+		return err
+	}
 	args := []string{
 		"server",
-		fmt.Sprintf("--config-file=%s", inst.ConfigPath),
+		fmt.Sprintf("--config-file=%s", configPath),
 	}
 
-	if inst.Process, err = NewComponentProcess(ctx, inst.Dir, inst.BinPath, "tiflash", version, args...); err != nil {
+	if inst.Process, err = NewComponentProcessWithEnvs(ctx, inst.Dir, inst.BinPath, "tiflash", version, inst.Envs, args...); err != nil {
+		return err
+	}
+	if err = inst.limit(inst.Process); err != nil {
 		return err
 	}
 	logIfErr(inst.Process.SetOutputFile(inst.LogFile()))
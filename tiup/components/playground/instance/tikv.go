@@ -19,6 +19,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pingcap/errors"
@@ -28,7 +29,10 @@ import (
 // TiKVInstance represent a running tikv-server
 type TiKVInstance struct {
 	instance
-	pds []*PDInstance
+	Labels map[string]string
+	// Capacity is the store capacity in MiB, 0 means the disk capacity
+	Capacity uint64
+	pds      []*PDInstance
 	Process
 }
 
@@ -58,24 +62,44 @@ func (inst *TiKVInstance) Start(ctx context.Context, version utils.Version) erro
 	if err := inst.checkConfig(); err != nil {
 		return err
 	}
+	configPath, err := inst.sizedConfig("tikv", inst.ConfigPath, version)
+	if err != nil {
+		return err
+	}
 
 	endpoints := pdEndpoints(inst.pds, true)
 	args := []string{
-		fmt.Sprintf("--addr=%s:%d", inst.Host, inst.Port),
+		fmt.Sprintf("--addr=%s:%d", inst.Host, inst.listenPort("port", inst.Port)),
 		fmt.Sprintf("--advertise-addr=%s:%d", AdvertiseHost(inst.Host), inst.Port),
 		fmt.Sprintf("--status-addr=%s:%d", inst.Host, inst.StatusPort),
 		fmt.Sprintf("--pd=%s", strings.Join(endpoints, ",")),
-		fmt.Sprintf("--config=%s", inst.ConfigPath),
+		fmt.Sprintf("--config=%s", configPath),
 		fmt.Sprintf("--data-dir=%s", filepath.Join(inst.Dir, "data")),
 		fmt.Sprintf("--log-file=%s", inst.LogFile()),
 	}
+	if len(inst.Labels) > 0 {
+		var labels []string
+		for k, v := range inst.Labels {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(labels)
+		args = append(args, fmt.Sprintf("--labels=%s", strings.Join(labels, ",")))
+	}
+	if inst.Capacity > 0 {
+		args = append(args, fmt.Sprintf("--capacity=%dMB", inst.Capacity))
+	}
 
-	var err error
 	envs := make(map[string]string)
 	envs["MALLOC_CONF"] = "prof:true,prof_active:false"
+	for k, v := range inst.Envs {
+		envs[k] = v
+	}
 	if inst.Process, err = NewComponentProcessWithEnvs(ctx, inst.Dir, inst.BinPath, "tikv", version, envs, args...); err != nil {
 		return err
 	}
+	if err = inst.limit(inst.Process); err != nil {
+		return err
+	}
 	logIfErr(inst.Process.SetOutputFile(inst.LogFile()))
 
 	return inst.Process.Start()
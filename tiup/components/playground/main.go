@@ -64,6 +64,15 @@ type BootOptions struct {
 	Drainer instance.Config `yaml:"drainer"`
 	Host    string          `yaml:"host"`
 	Monitor bool            `yaml:"monitor"`
+	// FaultProxy puts TCP proxies in front of the instances to inject network faults
+	FaultProxy bool `yaml:"fault_proxy,omitempty"`
+	// RestartOnFailure restarts the crashed instances with backoff
+	RestartOnFailure bool `yaml:"restart_on_failure,omitempty"`
+	// InitSQL is the SQL file run once the cluster is ready
+	InitSQL string `yaml:"init_sql,omitempty"`
+	// Import is the dir exported by Dumpling, of CSV files or of a BR backup,
+	// it's imported once the cluster is ready
+	Import string `yaml:"import,omitempty"`
 }
 
 var (
@@ -72,6 +81,11 @@ var (
 	playgroundReport *telemetry.PlaygroundReport
 	options          = &BootOptions{}
 	tag              string
+	topologyFile     string
+	readyFile        string
+	snapshotName     string
+	apiHost          string
+	tiupHome         string
 	tiupDataDir      string
 	dataDir          string
 	log              = logprinter.NewLogger("")
@@ -81,6 +95,10 @@ const (
 	mode           = "mode"
 	withMonitor    = "monitor"
 	withoutMonitor = "without-monitor"
+	withFaultProxy = "fault-proxy"
+	restartOnFail  = "restart-on-failure"
+	initSQL        = "init-sql"
+	importDir      = "import"
 
 	// instance numbers
 	db      = "db"
@@ -118,6 +136,33 @@ const (
 	ticdcBinpath   = "ticdc.binpath"
 	pumpBinpath    = "pump.binpath"
 	drainerBinpath = "drainer.binpath"
+
+	// versions
+	dbVersion      = "db.version"
+	kvVersion      = "kv.version"
+	pdVersion      = "pd.version"
+	tiflashVersion = "tiflash.version"
+	ticdcVersion   = "ticdc.version"
+	pumpVersion    = "pump.version"
+	drainerVersion = "drainer.version"
+
+	// memory limits
+	dbMemoryLimit      = "db.memory-limit"
+	kvMemoryLimit      = "kv.memory-limit"
+	pdMemoryLimit      = "pd.memory-limit"
+	tiflashMemoryLimit = "tiflash.memory-limit"
+	ticdcMemoryLimit   = "ticdc.memory-limit"
+	pumpMemoryLimit    = "pump.memory-limit"
+	drainerMemoryLimit = "drainer.memory-limit"
+
+	// cpu quotas
+	dbCPUQuota      = "db.cpu-quota"
+	kvCPUQuota      = "kv.cpu-quota"
+	pdCPUQuota      = "pd.cpu-quota"
+	tiflashCPUQuota = "tiflash.cpu-quota"
+	ticdcCPUQuota   = "ticdc.cpu-quota"
+	pumpCPUQuota    = "pump.cpu-quota"
+	drainerCPUQuota = "drainer.cpu-quota"
 )
 
 func installIfMissing(component, version string) error {
@@ -151,7 +196,32 @@ Examples:
   $ tiup playground --pd.config ~/config/pd.toml    # Start a local cluster with specified configuration file
   $ tiup playground --db.binpath /xx/tidb-server    # Start a local cluster with component binary path
   $ tiup playground --mode tikv-slim                # Start a local tikv only cluster (No TiDB or TiFlash Available)
-  $ tiup playground --mode tikv-slim --kv 3 --pd 3  # Start a local tikv only cluster with 6 nodes`,
+  $ tiup playground --mode tikv-slim --kv 3 --pd 3  # Start a local tikv only cluster with 6 nodes
+  $ tiup playground -f playground.yaml              # Start a local cluster with the topology file
+  $ tiup playground v5.0.1 --db.version v5.1.0      # Start a local cluster with TiDB of another version
+  $ tiup playground --snapshot fixture              # Boot the cluster saved in the snapshot fixture
+  $ tiup playground --ready-file ready.json         # Write the endpoints to ready.json when the cluster is ready
+  $ tiup playground --restart-on-failure            # Restart the crashed instances automatically
+  $ tiup playground --kv.memory-limit 2G            # Limit the memory of each TiKV by cgroup v2
+  $ tiup playground --init-sql init.sql --import ./dump  # Seed the data once the cluster is ready
+
+The topology file has the same fields as 'tiup playground dump' prints, the
+config of each instance can be overridden by its index in 'instances':
+
+  version: v5.0.1
+  tikv:
+    num: 3
+    config_path: tikv.toml
+    instances:
+      - labels: { zone: z1 }
+        port: 20160          # the port and status_port are used as is
+      - labels: { zone: z2 }
+        bin_path: /path/to/tikv-server
+      - labels: { zone: z3 }
+        host: 127.0.0.2
+
+For PD, 'port' is the peer port and 'status_port' is the client port. The
+flags set along with the file take precedence over it.`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		Version:       version.NewTiUPVersion().String(),
@@ -160,7 +230,7 @@ Examples:
 		},
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			tiupDataDir = os.Getenv(localdata.EnvNameInstanceDataDir)
-			tiupHome := os.Getenv(localdata.EnvNameHome)
+			tiupHome = os.Getenv(localdata.EnvNameHome)
 			if tiupHome == "" {
 				tiupHome, _ = getAbsolutePath(filepath.Join("~", localdata.ProfileDirName))
 			}
@@ -200,12 +270,25 @@ Examples:
 				teleReport.Version = telemetry.TiUPMeta()
 			}
 
+			if err := populateOpt(cmd.Flags()); err != nil {
+				return err
+			}
+
 			if len(args) > 0 {
 				options.Version = args[0]
 			}
-
-			if err := populateOpt(cmd.Flags()); err != nil {
-				return err
+			var snapshotFile string
+			if snapshotName != "" {
+				if len(args) > 0 {
+					return errors.New("the version can't be set while booting from a snapshot")
+				}
+				var err error
+				if snapshotFile, err = snapshotPath(snapshotName); err != nil {
+					return err
+				}
+				if options, err = bootSnapshotOptions(snapshotFile, options); err != nil {
+					return err
+				}
 			}
 
 			port, err := utils.GetFreePort("0.0.0.0", 9527)
@@ -217,6 +300,18 @@ Examples:
 			if err != nil {
 				return err
 			}
+			p.bootSnapshot = snapshotFile
+			if p.apiHost = apiHost; apiHost != "" && apiHost != "127.0.0.1" {
+				fmt.Println(color.YellowString("Warning: the HTTP API is served on %s without authentication, it can upgrade the instances to any binary and read or write any file", apiHost))
+			}
+			if readyFile != "" {
+				if p.readyFile, err = getAbsolutePath(readyFile); err != nil {
+					return err
+				}
+				// a stale file from the last run is not taken as ready
+				_ = os.Remove(p.readyFile)
+				defer os.Remove(p.readyFile)
+			}
 
 			env, err := environment.InitEnv(repository.Options{})
 			if err != nil {
@@ -267,8 +362,6 @@ Examples:
 
 			// expand version string
 			if !semver.IsValid(options.Version) {
-				var version utils.Version
-				var err error
 				// If any of the binpath arguments is set (which indicates the user is
 				// using a self build binary) and version number is not set, we assume
 				// it is a developer and use the latest release version by default.
@@ -280,14 +373,14 @@ Examples:
 				// all components used.
 				// If none of the binpath arguments is set, use the platform of the
 				// playground binary itself.
+				platform := ""
 				if (options.TiDB.BinPath != "" || options.TiKV.BinPath != "" ||
 					options.PD.BinPath != "" || options.TiFlash.BinPath != "" ||
 					options.TiCDC.BinPath != "" || options.Pump.BinPath != "" ||
 					options.Drainer.BinPath != "") && options.Version == "" {
-					version, err = env.V1Repository().ResolveComponentVersionWithPlatform(spec.ComponentTiDB, options.Version, "linux/amd64")
-				} else {
-					version, err = env.V1Repository().ResolveComponentVersion(spec.ComponentTiDB, options.Version)
+					platform = selfBuiltPlatform
 				}
+				version, err := resolveComponentVersion(spec.ComponentTiDB, options.Version, platform)
 				if err != nil {
 					return errors.Annotate(err, fmt.Sprintf("can not expand version %s to a valid semver string", options.Version))
 				}
@@ -301,6 +394,9 @@ If you'd like to use a TiDB version other than %s, cancel and retry with the fol
 
 				options.Version = version.String()
 			}
+			if err := options.resolvePinnedVersions(); err != nil {
+				return err
+			}
 
 			bootErr := p.bootCluster(ctx, env, options)
 			if bootErr != nil {
@@ -327,9 +423,17 @@ If you'd like to use a TiDB version other than %s, cancel and retry with the fol
 
 	rootCmd.Flags().String(mode, defaultMode, "TiUP playground mode: 'tidb', 'tikv-slim'")
 	rootCmd.Flags().StringVarP(&tag, "tag", "T", "", "Specify a tag for playground")
+	rootCmd.Flags().StringVarP(&topologyFile, "file", "f", "", "Start the playground with the topology file")
+	rootCmd.Flags().StringVar(&snapshotName, "snapshot", "", "Boot the cluster saved in the snapshot with its version, instances and data, see also 'tiup playground snapshot'")
+	rootCmd.Flags().StringVar(&apiHost, "api-host", "127.0.0.1", "The host to serve the HTTP API on besides 127.0.0.1, only set it in a trusted network as the API has no authentication")
+	rootCmd.Flags().StringVar(&readyFile, "ready-file", "", "Write the endpoints in JSON to the file when the cluster is ready, see also 'tiup playground wait'")
 	rootCmd.Flags().Bool(withoutMonitor, false, "Don't start prometheus and grafana component")
 	rootCmd.Flags().Bool(withMonitor, true, "Start prometheus and grafana component")
 	_ = rootCmd.Flags().MarkDeprecated(withMonitor, "Please use --without-monitor to control whether to disable monitor.")
+	rootCmd.Flags().Bool(withFaultProxy, defaultOptions.FaultProxy, "Put TCP proxies in front of the instances to inject network faults by 'tiup playground fault'")
+	rootCmd.Flags().Bool(restartOnFail, defaultOptions.RestartOnFailure, fmt.Sprintf("Restart the crashed instances with backoff, an instance is not restarted if it crashes more than %d times in %s", crashLoopLimit, crashLoopWindow))
+	rootCmd.Flags().String(initSQL, defaultOptions.InitSQL, "Run the SQL file once the cluster is ready")
+	rootCmd.Flags().String(importDir, defaultOptions.Import, "Import the dir exported by Dumpling, of CSV files or of a local BR backup once the cluster is ready")
 
 	rootCmd.Flags().Int(db, defaultOptions.TiDB.Num, "TiDB instance number")
 	rootCmd.Flags().Int(kv, defaultOptions.TiKV.Num, "TiKV instance number")
@@ -363,9 +467,39 @@ If you'd like to use a TiDB version other than %s, cancel and retry with the fol
 	rootCmd.Flags().String(pumpBinpath, defaultOptions.Pump.BinPath, "Pump instance binary path")
 	rootCmd.Flags().String(drainerBinpath, defaultOptions.Drainer.BinPath, "Drainer instance binary path")
 
+	rootCmd.Flags().String(dbVersion, defaultOptions.TiDB.Version, "TiDB instance version. If not provided, TiDB will use the version of playground")
+	rootCmd.Flags().String(kvVersion, defaultOptions.TiKV.Version, "TiKV instance version. If not provided, TiKV will use the version of playground")
+	rootCmd.Flags().String(pdVersion, defaultOptions.PD.Version, "PD instance version. If not provided, PD will use the version of playground")
+	rootCmd.Flags().String(tiflashVersion, defaultOptions.TiFlash.Version, "TiFlash instance version. If not provided, TiFlash will use the version of playground")
+	rootCmd.Flags().String(ticdcVersion, defaultOptions.TiCDC.Version, "TiCDC instance version. If not provided, TiCDC will use the version of playground")
+	rootCmd.Flags().String(pumpVersion, defaultOptions.Pump.Version, "Pump instance version. If not provided, Pump will use the version of playground")
+	rootCmd.Flags().String(drainerVersion, defaultOptions.Drainer.Version, "Drainer instance version. If not provided, Drainer will use the version of playground")
+
+	rootCmd.Flags().String(dbMemoryLimit, defaultOptions.TiDB.MemoryLimit, "TiDB instance memory limit, like 2G, applied by cgroup v2 on Linux, the memory settings of TiDB are sized to match")
+	rootCmd.Flags().String(kvMemoryLimit, defaultOptions.TiKV.MemoryLimit, "TiKV instance memory limit, like 2G, applied by cgroup v2 on Linux, the memory settings of TiKV are sized to match")
+	rootCmd.Flags().String(pdMemoryLimit, defaultOptions.PD.MemoryLimit, "PD instance memory limit, like 2G, applied by cgroup v2 on Linux, the memory settings of PD are sized to match")
+	rootCmd.Flags().String(tiflashMemoryLimit, defaultOptions.TiFlash.MemoryLimit, "TiFlash instance memory limit, like 2G, applied by cgroup v2 on Linux, the memory settings of TiFlash are sized to match")
+	rootCmd.Flags().String(ticdcMemoryLimit, defaultOptions.TiCDC.MemoryLimit, "TiCDC instance memory limit, like 2G, applied by cgroup v2 on Linux, the memory settings of TiCDC are sized to match")
+	rootCmd.Flags().String(pumpMemoryLimit, defaultOptions.Pump.MemoryLimit, "Pump instance memory limit, like 2G, applied by cgroup v2 on Linux, the memory settings of Pump are sized to match")
+	rootCmd.Flags().String(drainerMemoryLimit, defaultOptions.Drainer.MemoryLimit, "Drainer instance memory limit, like 2G, applied by cgroup v2 on Linux, the memory settings of Drainer are sized to match")
+
+	rootCmd.Flags().String(dbCPUQuota, defaultOptions.TiDB.CPUQuota, "TiDB instance CPU quota, like 150% for 1.5 CPUs, applied by cgroup v2 on Linux")
+	rootCmd.Flags().String(kvCPUQuota, defaultOptions.TiKV.CPUQuota, "TiKV instance CPU quota, like 150% for 1.5 CPUs, applied by cgroup v2 on Linux")
+	rootCmd.Flags().String(pdCPUQuota, defaultOptions.PD.CPUQuota, "PD instance CPU quota, like 150% for 1.5 CPUs, applied by cgroup v2 on Linux")
+	rootCmd.Flags().String(tiflashCPUQuota, defaultOptions.TiFlash.CPUQuota, "TiFlash instance CPU quota, like 150% for 1.5 CPUs, applied by cgroup v2 on Linux")
+	rootCmd.Flags().String(ticdcCPUQuota, defaultOptions.TiCDC.CPUQuota, "TiCDC instance CPU quota, like 150% for 1.5 CPUs, applied by cgroup v2 on Linux")
+	rootCmd.Flags().String(pumpCPUQuota, defaultOptions.Pump.CPUQuota, "Pump instance CPU quota, like 150% for 1.5 CPUs, applied by cgroup v2 on Linux")
+	rootCmd.Flags().String(drainerCPUQuota, defaultOptions.Drainer.CPUQuota, "Drainer instance CPU quota, like 150% for 1.5 CPUs, applied by cgroup v2 on Linux")
+
 	rootCmd.AddCommand(newDisplay())
 	rootCmd.AddCommand(newScaleOut())
 	rootCmd.AddCommand(newScaleIn())
+	rootCmd.AddCommand(newSnapshot())
+	rootCmd.AddCommand(newDump())
+	rootCmd.AddCommand(newFault())
+	rootCmd.AddCommand(newUpgrade())
+	rootCmd.AddCommand(newWait())
+	rootCmd.AddCommand(newExport())
 
 	return rootCmd.Execute()
 }
@@ -396,6 +530,12 @@ func populateOpt(flagSet *pflag.FlagSet) (err error) {
 		return
 	}
 
+	if topologyFile != "" {
+		if err = loadTopology(topologyFile, options); err != nil {
+			return
+		}
+	}
+
 	flagSet.Visit(func(flag *pflag.Flag) {
 		switch flag.Name {
 		case withMonitor:
@@ -409,6 +549,20 @@ func populateOpt(flagSet *pflag.FlagSet) (err error) {
 				return
 			}
 			options.Monitor = !options.Monitor
+		case withFaultProxy:
+			options.FaultProxy, err = strconv.ParseBool(flag.Value.String())
+			if err != nil {
+				return
+			}
+		case restartOnFail:
+			options.RestartOnFailure, err = strconv.ParseBool(flag.Value.String())
+			if err != nil {
+				return
+			}
+		case initSQL:
+			options.InitSQL = flag.Value.String()
+		case importDir:
+			options.Import = flag.Value.String()
 		case db:
 			options.TiDB.Num, err = strconv.Atoi(flag.Value.String())
 			if err != nil {
@@ -475,6 +629,51 @@ func populateOpt(flagSet *pflag.FlagSet) (err error) {
 		case drainerBinpath:
 			options.Drainer.BinPath = flag.Value.String()
 
+		case dbVersion:
+			options.TiDB.Version = flag.Value.String()
+		case kvVersion:
+			options.TiKV.Version = flag.Value.String()
+		case pdVersion:
+			options.PD.Version = flag.Value.String()
+		case tiflashVersion:
+			options.TiFlash.Version = flag.Value.String()
+		case ticdcVersion:
+			options.TiCDC.Version = flag.Value.String()
+		case pumpVersion:
+			options.Pump.Version = flag.Value.String()
+		case drainerVersion:
+			options.Drainer.Version = flag.Value.String()
+
+		case dbMemoryLimit:
+			options.TiDB.MemoryLimit = flag.Value.String()
+		case kvMemoryLimit:
+			options.TiKV.MemoryLimit = flag.Value.String()
+		case pdMemoryLimit:
+			options.PD.MemoryLimit = flag.Value.String()
+		case tiflashMemoryLimit:
+			options.TiFlash.MemoryLimit = flag.Value.String()
+		case ticdcMemoryLimit:
+			options.TiCDC.MemoryLimit = flag.Value.String()
+		case pumpMemoryLimit:
+			options.Pump.MemoryLimit = flag.Value.String()
+		case drainerMemoryLimit:
+			options.Drainer.MemoryLimit = flag.Value.String()
+
+		case dbCPUQuota:
+			options.TiDB.CPUQuota = flag.Value.String()
+		case kvCPUQuota:
+			options.TiKV.CPUQuota = flag.Value.String()
+		case pdCPUQuota:
+			options.PD.CPUQuota = flag.Value.String()
+		case tiflashCPUQuota:
+			options.TiFlash.CPUQuota = flag.Value.String()
+		case ticdcCPUQuota:
+			options.TiCDC.CPUQuota = flag.Value.String()
+		case pumpCPUQuota:
+			options.Pump.CPUQuota = flag.Value.String()
+		case drainerCPUQuota:
+			options.Drainer.CPUQuota = flag.Value.String()
+
 		case dbTimeout:
 			options.TiDB.UpTimeout, err = strconv.Atoi(flag.Value.String())
 			if err != nil {
@@ -519,6 +718,32 @@ func tryConnect(dsn string) error {
 	return nil
 }
 
+// selfBuiltPlatform is the platform to resolve the versions of self built
+// binaries, every released version is available on it
+const selfBuiltPlatform = "linux/amd64"
+
+// resolveComponentVersion expands the version constraint of the component to
+// a valid semver string, it's resolved for the platform if it's set, or for
+// the platform of playground itself
+var resolveComponentVersion = func(component, constraint, platform string) (utils.Version, error) {
+	repo := environment.GlobalEnv().V1Repository()
+	if platform != "" {
+		return repo.ResolveComponentVersionWithPlatform(component, constraint, platform)
+	}
+	return repo.ResolveComponentVersion(component, constraint)
+}
+
+// execSQL runs the statement by root in the TiDB of the addr
+func execSQL(dbAddr, stmt string) error {
+	db, err := sql.Open("mysql", fmt.Sprintf("root:@tcp(%s)/", dbAddr))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(stmt)
+	return errors.AddStack(err)
+}
+
 // checkDB check if the addr is connectable by getting a connection from sql.DB. timeout <=0 means no timeout
 func checkDB(dbAddr string, timeout int) bool {
 	dsn := fmt.Sprintf("root:@tcp(%s)/", dbAddr)
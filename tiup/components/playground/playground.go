@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -39,6 +40,7 @@ import (
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/environment"
 	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/proxy"
 	"github.com/pingcap/tiup/pkg/tui/progress"
 	"github.com/pingcap/tiup/pkg/utils"
 	"golang.org/x/mod/semver"
@@ -52,6 +54,14 @@ const forceKillAfterDuration = time.Second * 10
 type Playground struct {
 	dataDir string
 	booted  bool
+	// set when the cluster is booted, the commands are accepted since then
+	bootFinished int32
+	// the file to write the endpoints to when the cluster is ready
+	readyFile string
+	// the host to serve the HTTP API on besides 127.0.0.1
+	apiHost string
+	// the snapshot the cluster is booted from
+	bootSnapshot string
 	// the latest receive signal
 	curSig      int32
 	bootOptions *BootOptions
@@ -66,8 +76,38 @@ type Playground struct {
 	drainers         []*instance.Drainer
 	startedInstances []instance.Instance
 
+	// serializes the commands received by HTTP
+	cmdMu sync.Mutex
+
 	idAlloc        map[string]int
 	instanceWaiter errgroup.Group
+	// the id and config of instances, they're kept in snapshots
+	instanceSpecs map[instance.Instance]instanceSpec
+
+	// set while the instances are stopped to save or restore a snapshot
+	paused  int32
+	resumed chan struct{}
+
+	// the faults injected into the instances and the proxies to inject the
+	// network faults, they're guarded by faultMu
+	faultMu      sync.Mutex
+	faults       map[instance.Instance]*activeFault
+	faultProxies map[instance.Instance][]*proxy.TCPProxy
+	// the pids of the processes stopped on purpose by faults, upgrades or
+	// scaling in
+	faultStopped map[int]struct{}
+
+	// the instances quit unexpectedly and how they're restarted by the
+	// supervisor, guarded by crashMu
+	crashMu  sync.Mutex
+	crashes  map[instance.Instance]crash
+	restarts map[instance.Instance]*restartHistory
+
+	// set while the data is seeded by --init-sql and --import, and the error
+	// if it fails, guarded by seedMu
+	seedMu  sync.Mutex
+	seeding bool
+	seedErr error
 
 	// not nil iff we start the exec.Cmd successfully.
 	// we should and can safely call wait() to make sure the process quit
@@ -87,9 +127,15 @@ type MonitorInfo struct {
 // NewPlayground create a Playground instance.
 func NewPlayground(dataDir string, port int) *Playground {
 	return &Playground{
-		dataDir: dataDir,
-		port:    port,
-		idAlloc: make(map[string]int),
+		dataDir:       dataDir,
+		port:          port,
+		idAlloc:       make(map[string]int),
+		instanceSpecs: make(map[instance.Instance]instanceSpec),
+		faults:        make(map[instance.Instance]*activeFault),
+		faultProxies:  make(map[instance.Instance][]*proxy.TCPProxy),
+		faultStopped:  make(map[int]struct{}),
+		crashes:       make(map[instance.Instance]crash),
+		restarts:      make(map[instance.Instance]*restartHistory),
 	}
 }
 
@@ -268,6 +314,8 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 		fmt.Fprintf(w, "no instance with id: %d\n", pid)
 		return nil
 	}
+	p.stopOnPurpose(pid)
+	p.clearCrash(inst)
 
 	switch cid {
 	case spec.ComponentPD:
@@ -368,6 +416,7 @@ func (p *Playground) handleScaleIn(w io.Writer, pid int) error {
 	if err != nil {
 		return errors.AddStack(err)
 	}
+	p.closeFaultProxies(inst)
 
 	logIfErr(p.renderSDFile())
 
@@ -386,6 +435,18 @@ func (p *Playground) sanitizeConfig(boot instance.Config, cfg *instance.Config)
 	if cfg.Host == "" {
 		cfg.Host = boot.Host
 	}
+	if cfg.Version == "" {
+		cfg.Version = boot.Version
+	}
+	if cfg.UpTimeout == 0 {
+		cfg.UpTimeout = boot.UpTimeout
+	}
+	if cfg.MemoryLimit == "" {
+		cfg.MemoryLimit = boot.MemoryLimit
+	}
+	if cfg.CPUQuota == "" {
+		cfg.CPUQuota = boot.CPUQuota
+	}
 
 	path, err := getAbsolutePath(cfg.ConfigPath)
 	if err != nil {
@@ -418,18 +479,49 @@ func (p *Playground) sanitizeComponentConfig(cid string, cfg *instance.Config) e
 
 func (p *Playground) startInstance(ctx context.Context, inst instance.Instance) error {
 	fmt.Printf("Start %s instance\n", inst.Component())
-	err := inst.Start(ctx, utils.Version(p.bootOptions.Version))
+	if p.bootOptions.FaultProxy {
+		if err := p.startFaultProxies(inst); err != nil {
+			return err
+		}
+	}
+	err := inst.Start(ctx, p.instanceVersion(inst))
 	if err != nil {
 		return err
 	}
+	p.clearCrash(inst)
 	p.addWaitInstance(inst)
 	return nil
 }
 
+// instanceVersion returns the version of the instance, it's the version
+// pinned for the instance or the version of the playground.
+func (p *Playground) instanceVersion(inst instance.Instance) utils.Version {
+	if v := p.instanceSpecs[inst].Config.Version; v != "" {
+		return utils.Version(v)
+	}
+	return utils.Version(p.bootOptions.Version)
+}
+
 func (p *Playground) addWaitInstance(inst instance.Instance) {
 	p.startedInstances = append(p.startedInstances, inst)
+	p.waitInstance(inst)
+}
+
+// waitInstance waits for the instance to quit in the background.
+func (p *Playground) waitInstance(inst instance.Instance) {
+	pid := inst.Pid()
+	name := p.instanceName(p.instanceSpecs[inst].Component, inst)
 	p.instanceWaiter.Go(func() error {
 		err := inst.Wait()
+		if atomic.LoadInt32(&p.paused) == 1 || p.stoppedOnPurpose(pid) {
+			fmt.Printf("%s stopped\n", inst.Component())
+			return nil
+		}
+		var backoff time.Duration
+		restart := false
+		if atomic.LoadInt32(&p.curSig) == 0 {
+			backoff, restart = p.recordCrash(inst, name, err)
+		}
 		if err != nil && atomic.LoadInt32(&p.curSig) == 0 {
 			fmt.Print(color.RedString("%s quit: %s\n", inst.Component(), err.Error()))
 			if lines, _ := utils.TailN(inst.LogFile(), 10); len(lines) > 0 {
@@ -441,19 +533,29 @@ func (p *Playground) addWaitInstance(inst instance.Instance) {
 		} else {
 			fmt.Printf("%s quit\n", inst.Component())
 		}
+		if restart {
+			return p.restartCrashed(inst, name, backoff)
+		}
 		return err
 	})
 }
 
 func (p *Playground) handleScaleOut(w io.Writer, cmd *Command) error {
 	// Ignore Config.Num, always one command as scale out one instance.
-	err := p.sanitizeComponentConfig(cmd.ComponentID, &cmd.Config)
+	_, err := p.scaleOutInstance(w, cmd.ComponentID, cmd.Config)
+	return err
+}
+
+// scaleOutInstance adds and starts an instance, it waits for a TiDB to be
+// connectable before returning
+func (p *Playground) scaleOutInstance(w io.Writer, cid string, cfg instance.Config) (instance.Instance, error) {
+	err := p.sanitizeComponentConfig(cid, &cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	inst, err := p.addInstance(cmd.ComponentID, cmd.Config)
+	inst, err := p.addInstance(cid, cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = p.startInstance(
@@ -461,12 +563,13 @@ func (p *Playground) handleScaleOut(w io.Writer, cmd *Command) error {
 		inst,
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if cmd.ComponentID == "tidb" {
+	if cid == spec.ComponentTiDB {
 		addr := p.tidbs[len(p.tidbs)-1].Addr()
-		if checkDB(addr, cmd.UpTimeout) {
+		if checkDB(addr, cfg.UpTimeout) {
+			limitTiDBMemory(p.tidbs[len(p.tidbs)-1], p.instanceVersion(inst))
 			ss := strings.Split(addr, ":")
 			connectMsg := "To connect new added TiDB: mysql --comments --host %s --port %s -u root -p (no password)"
 			fmt.Println(color.GreenString(connectMsg, ss[0], ss[1]))
@@ -476,7 +579,7 @@ func (p *Playground) handleScaleOut(w io.Writer, cmd *Command) error {
 
 	logIfErr(p.renderSDFile())
 
-	return nil
+	return inst, nil
 }
 
 func (p *Playground) handleCommand(cmd *Command, w io.Writer) error {
@@ -488,14 +591,47 @@ func (p *Playground) handleCommand(cmd *Command, w io.Writer) error {
 		return p.handleScaleIn(w, cmd.PID)
 	case ScaleOutCommandType:
 		return p.handleScaleOut(w, cmd)
+	case SnapshotSaveCommandType:
+		return p.handleSnapshotSave(w, cmd.Snapshot)
+	case SnapshotRestoreCommandType:
+		return p.handleSnapshotRestore(w, cmd.Snapshot)
+	case DumpCommandType:
+		return p.handleDump(w)
+	case FaultCommandType:
+		return p.handleFault(w, cmd.PID, cmd.Fault)
+	case UpgradeCommandType:
+		return p.handleUpgrade(w, cmd.ComponentID, cmd.Version, cmd.BinPath)
 	}
 
 	return nil
 }
 
+// listenAndServeHTTP serves the HTTP API on 127.0.0.1, which the commands of
+// playground connect to, and on the API host if it's set
 func (p *Playground) listenAndServeHTTP() error {
-	http.HandleFunc("/command", p.commandHandler)
-	return http.ListenAndServe(":"+strconv.Itoa(p.port), nil)
+	hosts := apiHosts(p.apiHost)
+	handler := p.router()
+	errCh := make(chan error, len(hosts))
+	for _, host := range hosts {
+		server := &http.Server{Addr: net.JoinHostPort(host, strconv.Itoa(p.port)), Handler: handler}
+		go func() {
+			errCh <- server.ListenAndServe()
+		}()
+	}
+	return <-errCh
+}
+
+// apiHosts returns the hosts to serve the HTTP API on
+func apiHosts(host string) []string {
+	ip := net.ParseIP(host)
+	switch {
+	case ip != nil && ip.IsUnspecified():
+		// all the interfaces include the loopback one
+		return []string{host}
+	case host == "" || host == "localhost" || ip != nil && ip.IsLoopback():
+		return []string{"127.0.0.1"}
+	}
+	return []string{"127.0.0.1", host}
 }
 
 func (p *Playground) commandHandler(w http.ResponseWriter, r *http.Request) {
@@ -518,7 +654,14 @@ func (p *Playground) commandHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, err)
 		return
 	}
+	if atomic.LoadInt32(&p.bootFinished) == 0 {
+		w.WriteHeader(403)
+		fmt.Fprintln(w, "the playground is booting")
+		return
+	}
 
+	p.cmdMu.Lock()
+	defer p.cmdMu.Unlock()
 	err = p.handleCommand(cmd, w)
 	if err != nil {
 		w.WriteHeader(403)
@@ -617,10 +760,11 @@ func (p *Playground) addInstance(componentID string, cfg instance.Config) (ins i
 		}
 	}
 
-	dataDir := p.dataDir
+	return p.addInstanceWithID(componentID, p.allocID(componentID), cfg)
+}
 
-	id := p.allocID(componentID)
-	dir := filepath.Join(dataDir, fmt.Sprintf("%s-%d", componentID, id))
+func (p *Playground) addInstanceWithID(componentID string, id int, cfg instance.Config) (ins instance.Instance, err error) {
+	dir := filepath.Join(p.dataDir, fmt.Sprintf("%s-%d", componentID, id))
 	// look more like listen ip?
 	host := p.bootOptions.Host
 	if cfg.Host != "" {
@@ -630,6 +774,11 @@ func (p *Playground) addInstance(componentID string, cfg instance.Config) (ins i
 	// use the advertised host instead of 0.0.0.0
 	host = instance.AdvertiseHost(host)
 
+	res, err := instance.ParseResources(cfg.MemoryLimit, cfg.CPUQuota)
+	if err != nil {
+		return nil, errors.Annotatef(err, "invalid config of %s", componentID)
+	}
+
 	switch componentID {
 	case spec.ComponentPD:
 		inst := instance.NewPDInstance(cfg.BinPath, dir, host, cfg.ConfigPath, id)
@@ -649,6 +798,7 @@ func (p *Playground) addInstance(componentID string, cfg instance.Config) (ins i
 		p.tidbs = append(p.tidbs, inst)
 	case spec.ComponentTiKV:
 		inst := instance.NewTiKVInstance(cfg.BinPath, dir, host, cfg.ConfigPath, id, p.pds)
+		inst.Labels = cfg.Labels
 		ins = inst
 		p.tikvs = append(p.tikvs, inst)
 	case spec.ComponentTiFlash:
@@ -671,6 +821,16 @@ func (p *Playground) addInstance(componentID string, cfg instance.Config) (ins i
 		return nil, errors.Errorf("unknown component: %s", componentID)
 	}
 
+	// the ports set in the topology are used as is, except the port of TiDB,
+	// which is the preferred one
+	ports := map[string]int{"status_port": cfg.StatusPort}
+	if componentID != spec.ComponentTiDB {
+		ports["port"] = cfg.Port
+	}
+	ins.SetPorts(ports)
+	ins.SetResources(res)
+
+	p.instanceSpecs[ins] = instanceSpec{Component: componentID, ID: id, Config: cfg}
 	return
 }
 
@@ -684,9 +844,11 @@ func (p *Playground) waitAllTidbUp() []string {
 			wg.Add(1)
 			prefix := color.YellowString(db.Addr())
 			bar := bars.AddBar(prefix)
+			version := p.instanceVersion(db)
 			go func(dbInst *instance.TiDBInstance) {
 				defer wg.Done()
 				if s := checkDB(dbInst.Addr(), options.TiDB.UpTimeout); s {
+					limitTiDBMemory(dbInst, version)
 					{
 						appendMutex.Lock()
 						succ = append(succ, dbInst.Addr())
@@ -711,6 +873,18 @@ func (p *Playground) waitAllTidbUp() []string {
 	return succ
 }
 
+// limitTiDBMemory limits the memory of TiDB by tidb_server_memory_limit once
+// it's up, for the versions without performance.server-memory-quota sized
+func limitTiDBMemory(db *instance.TiDBInstance, version utils.Version) {
+	stmt := db.MemoryLimitSQL(version)
+	if stmt == "" {
+		return
+	}
+	if err := execSQL(db.Addr(), stmt); err != nil {
+		fmt.Println(color.YellowString("Failed to limit the memory of TiDB %s: %s", db.Addr(), err))
+	}
+}
+
 func (p *Playground) waitAllTiFlashUp() {
 	if len(p.tiflashs) > 0 {
 		var endpoints []string
@@ -771,6 +945,17 @@ func (p *Playground) bootCluster(ctx context.Context, env *environment.Environme
 	}
 
 	p.bootOptions = options
+	if err := checkSeedOptions(options); err != nil {
+		return err
+	}
+
+	// the API is served while booting, so the readiness can be checked
+	go func() {
+		err := p.listenAndServeHTTP()
+		if err != nil {
+			fmt.Printf("listenAndServeHTTP quit: %s\n", err)
+		}
+	}()
 
 	if options.PD.Num < 1 || options.TiKV.Num < 1 {
 		return fmt.Errorf("all components count must be great than 0 (tikv=%v, pd=%v)", options.TiKV.Num, options.PD.Num)
@@ -787,56 +972,24 @@ func (p *Playground) bootCluster(ctx context.Context, env *environment.Environme
 		}
 	}
 
-	instances := []struct {
-		comp string
-		instance.Config
-	}{
-		{spec.ComponentPD, options.PD},
-		{spec.ComponentTiKV, options.TiKV},
-		{spec.ComponentPump, options.Pump},
-		{spec.ComponentTiDB, options.TiDB},
-		{spec.ComponentCDC, options.TiCDC},
-		{spec.ComponentDrainer, options.Drainer},
-		{spec.ComponentTiFlash, options.TiFlash},
-	}
-
-	for _, inst := range instances {
-		for i := 0; i < inst.Num; i++ {
-			_, err := p.addInstance(inst.comp, inst.Config)
-			if err != nil {
-				return err
+	if p.bootSnapshot != "" {
+		if err := p.bootSnapshotInstances(p.bootSnapshot); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range options.componentConfigs() {
+			for _, cfg := range c.cfg.InstanceConfigs() {
+				_, err := p.addInstance(c.comp, cfg)
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
 
 	fmt.Println("Playground Bootstrapping...")
 
-	anyPumpReady := false
-	// Start all instance except tiflash.
-	err := p.WalkInstances(func(cid string, ins instance.Instance) error {
-		if cid == spec.ComponentTiFlash {
-			return nil
-		}
-
-		err := p.startInstance(ctx, ins)
-		if err != nil {
-			return err
-		}
-
-		// if no any pump, tidb will quit right away.
-		if cid == spec.ComponentPump && !anyPumpReady {
-			ctx, cancel := context.WithTimeout(context.TODO(), time.Second*120)
-			err = ins.(*instance.Pump).Ready(ctx)
-			cancel()
-			if err != nil {
-				return err
-			}
-			anyPumpReady = true
-		}
-
-		return nil
-	})
-	if err != nil {
+	if err := p.startInstances(ctx); err != nil {
 		return err
 	}
 
@@ -866,16 +1019,7 @@ func (p *Playground) bootCluster(ctx context.Context, env *environment.Environme
 
 	if len(succ) > 0 {
 		// start TiFlash after at least one TiDB is up.
-		var started []*instance.TiFlashInstance
-		for _, flash := range p.tiflashs {
-			if err := p.startInstance(ctx, flash); err != nil {
-				fmt.Println(color.RedString("TiFlash %s failed to start: %s", flash.Addr(), err))
-			} else {
-				started = append(started, flash)
-			}
-		}
-		p.tiflashs = started
-		p.waitAllTiFlashUp()
+		p.startTiFlash(ctx)
 
 		fmt.Println(color.GreenString("CLUSTER START SUCCESSFULLY, Enjoy it ^-^"))
 		for _, dbAddr := range succ {
@@ -901,23 +1045,72 @@ func (p *Playground) bootCluster(ctx context.Context, env *environment.Environme
 
 	dumpDSN(filepath.Join(p.dataDir, "dsn"), p.tidbs)
 
-	go func() {
-		// fmt.Printf("serve at :%d\n", p.port)
-		err := p.listenAndServeHTTP()
-		if err != nil {
-			fmt.Printf("listenAndServeHTTP quit: %s\n", err)
-		}
-	}()
-
 	logIfErr(p.renderSDFile())
 
 	if g := p.grafana; g != nil {
 		p.updateMonitorTopology(spec.ComponentGrafana, MonitorInfo{g.host, g.port, g.cmd.Path})
 	}
 
+	// the cluster is not ready until the data is seeded
+	if options.InitSQL != "" || options.Import != "" {
+		p.seedMu.Lock()
+		p.seeding = true
+		p.seedMu.Unlock()
+		go p.seedData(ctx, options)
+	}
+
+	atomic.StoreInt32(&p.bootFinished, 1)
+	if p.readyFile != "" {
+		go p.writeReadyFile(p.readyFile)
+	}
+
 	return nil
 }
 
+// startInstances starts all the instances except TiFlash, which should be
+// started after at least one TiDB is up.
+func (p *Playground) startInstances(ctx context.Context) error {
+	anyPumpReady := false
+	return p.WalkInstances(func(cid string, ins instance.Instance) error {
+		if cid == spec.ComponentTiFlash {
+			return nil
+		}
+
+		err := p.startInstance(ctx, ins)
+		if err != nil {
+			return err
+		}
+
+		// if no any pump, tidb will quit right away.
+		if cid == spec.ComponentPump && !anyPumpReady {
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second*120)
+			err = ins.(*instance.Pump).Ready(ctx)
+			cancel()
+			if err != nil {
+				return err
+			}
+			anyPumpReady = true
+		}
+
+		return nil
+	})
+}
+
+// startTiFlash starts the TiFlash instances and waits for them up, the ones
+// failed to start are removed.
+func (p *Playground) startTiFlash(ctx context.Context) {
+	var started []*instance.TiFlashInstance
+	for _, flash := range p.tiflashs {
+		if err := p.startInstance(ctx, flash); err != nil {
+			fmt.Println(color.RedString("TiFlash %s failed to start: %s", flash.Addr(), err))
+		} else {
+			started = append(started, flash)
+		}
+	}
+	p.tiflashs = started
+	p.waitAllTiFlashUp()
+}
+
 func (p *Playground) updateMonitorTopology(componentID string, info MonitorInfo) {
 	info.IP = instance.AdvertiseHost(info.IP)
 	fmt.Print(color.GreenString("To view the %s: http://%s:%d\n", strings.Title(componentID), info.IP, info.Port))
@@ -948,19 +1141,25 @@ func (p *Playground) wait() error {
 }
 
 func (p *Playground) terminate(sig syscall.Signal) {
-	kill := func(pid int, wait func() error) {
-		if sig != syscall.SIGINT {
-			_ = syscall.Kill(pid, sig)
-		}
+	p.resetFaults()
+	p.terminateInstances(sig)
+	p.closeFaultProxies()
 
-		timer := time.AfterFunc(forceKillAfterDuration, func() {
-			_ = syscall.Kill(pid, syscall.SIGKILL)
-		})
+	if p.monitor != nil {
+		killProcess(p.monitor.cmd.Process.Pid, sig, p.monitor.wait)
+	}
+
+	if p.ngmonitoring != nil {
+		killProcess(p.ngmonitoring.cmd.Process.Pid, sig, p.ngmonitoring.wait)
+	}
 
-		_ = wait()
-		timer.Stop()
+	if p.grafana != nil {
+		killProcess(p.grafana.cmd.Process.Pid, sig, p.grafana.wait)
 	}
+}
 
+// terminateInstances stops the started instances in the reverse order.
+func (p *Playground) terminateInstances(sig syscall.Signal) {
 	for i := len(p.startedInstances); i > 0; i-- {
 		inst := p.startedInstances[i-1]
 		if sig == syscall.SIGKILL {
@@ -969,20 +1168,23 @@ func (p *Playground) terminate(sig syscall.Signal) {
 			fmt.Printf("Wait %s(%d) to quit...\n", inst.Component(), inst.Pid())
 		}
 
-		kill(inst.Pid(), inst.Wait)
+		killProcess(inst.Pid(), sig, inst.Wait)
 	}
+}
 
-	if p.monitor != nil {
-		kill(p.monitor.cmd.Process.Pid, p.monitor.wait)
+// killProcess sends the signal to the process and waits for it to quit, it's
+// killed if not quit in time.
+func killProcess(pid int, sig syscall.Signal, wait func() error) {
+	if sig != syscall.SIGINT {
+		_ = syscall.Kill(pid, sig)
 	}
 
-	if p.ngmonitoring != nil {
-		kill(p.ngmonitoring.cmd.Process.Pid, p.ngmonitoring.wait)
-	}
+	timer := time.AfterFunc(forceKillAfterDuration, func() {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+	})
 
-	if p.grafana != nil {
-		kill(p.grafana.cmd.Process.Pid, p.grafana.wait)
-	}
+	_ = wait()
+	timer.Stop()
 }
 
 func (p *Playground) renderSDFile() error {
@@ -0,0 +1,438 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	pgapi "github.com/pingcap/tiup/components/playground/api"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// the timeout of each health check
+const healthCheckTimeout = 2 * time.Second
+
+// the number of log lines of a crashed instance printed by wait
+const crashLogLines = 20
+
+// crash is an instance quit unexpectedly
+type crash struct {
+	name string
+	err  error
+	// crashLog is the file saved the last lines of the log when it crashed
+	crashLog string
+	// restarting is set if it's going to be restarted by the supervisor
+	restarting bool
+}
+
+// health is the health of an instance
+type health struct {
+	state  string
+	detail string
+}
+
+func newWait() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait until the cluster of the playground is ready",
+		Long: `Wait until the cluster of the playground is ready, that is it's booted, all
+the instances are healthy and the data of --init-sql and --import is seeded. The
+endpoints of the cluster are printed in JSON when it's ready.
+
+It exits with a non-zero code if the cluster is not ready in the timeout, or
+any instance crashes, and the last lines of the logs of the crashed instances
+are printed. The playground can be started in the background just before it.`,
+		Example: `  tiup playground --tag ci &
+  tiup playground wait --timeout 5m`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return waitReady(timeout)
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "The max time to wait")
+	return cmd
+}
+
+// waitReady waits until the cluster of the target playground is ready, the
+// playground may not be started yet
+func waitReady(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	reachable := false
+	lastErr := errors.New("no playground running")
+	for {
+		client, err := newClient()
+		if err == nil {
+			var resp *pgapi.ReadyResponse
+			// leave the time to receive the response
+			resp, err = client.Ready(ctx, time.Until(deadline)-time.Second)
+			switch {
+			case err == nil && resp.Ready:
+				data, err := json.MarshalIndent(resp.Endpoints, "", "  ")
+				if err != nil {
+					return errors.AddStack(err)
+				}
+				fmt.Println(string(data))
+				return nil
+			case err == nil && resp.Failed:
+				printCrashes(resp.Crashed)
+				return errors.Errorf("the cluster failed to be ready: %s", strings.Join(resp.Reasons, "; "))
+			case err == nil:
+				reachable = true
+				err = errors.New(strings.Join(resp.Reasons, "; "))
+			case reachable && stderrors.Is(err, syscall.ECONNREFUSED):
+				return errors.New("the playground quit before the cluster is ready")
+			}
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("the cluster is not ready in %s: %s", timeout, lastErr)
+		case <-time.After(readyCheckInterval):
+		}
+	}
+}
+
+func printCrashes(crashes []pgapi.CrashedInstance) {
+	for _, c := range crashes {
+		fmt.Println(color.RedString("%s crashed: %s", c.Name, c.Error))
+		if lines, _ := utils.TailN(c.LogFile, crashLogLines); len(lines) > 0 {
+			for _, line := range lines {
+				fmt.Println(line)
+			}
+			fmt.Println(color.YellowString("...\ncheck detail log from: %s", c.LogFile))
+		}
+		if c.CrashLog != "" {
+			fmt.Println(color.YellowString("the crash log: %s", c.CrashLog))
+		}
+	}
+}
+
+// recordCrash records the instance quit unexpectedly and saves the last lines
+// of its log, it returns whether the instance should be restarted and the
+// backoff before restarting it
+func (p *Playground) recordCrash(ins instance.Instance, name string, err error) (time.Duration, bool) {
+	if err == nil {
+		err = errors.New("quit unexpectedly")
+	}
+	crashLog, logErr := writeCrashLog(ins, name, err)
+	if logErr != nil {
+		fmt.Print(color.YellowString("Failed to write the crash log of %s: %s\n", name, logErr))
+	}
+
+	p.crashMu.Lock()
+	defer p.crashMu.Unlock()
+	c := crash{name: name, err: err, crashLog: crashLog}
+	var backoff time.Duration
+	if p.bootOptions != nil && p.bootOptions.RestartOnFailure {
+		if backoff, c.restarting = p.superviseCrash(ins, time.Now()); !c.restarting {
+			c.err = errors.Errorf("%s, it's not restarted since it crashed more than %d times in %s",
+				err, crashLoopLimit, crashLoopWindow)
+		}
+	}
+	p.crashes[ins] = c
+	return backoff, c.restarting
+}
+
+// clearCrash forgets the crash of the instance when it's started again
+func (p *Playground) clearCrash(ins instance.Instance) {
+	p.crashMu.Lock()
+	defer p.crashMu.Unlock()
+	delete(p.crashes, ins)
+}
+
+// crashedInstances returns the instances quit unexpectedly, it's safe to call
+// while booting
+func (p *Playground) crashedInstances() (crashed []pgapi.CrashedInstance) {
+	p.crashMu.Lock()
+	defer p.crashMu.Unlock()
+	for ins, c := range p.crashes {
+		crashed = append(crashed, pgapi.CrashedInstance{
+			Name:       c.name,
+			Error:      c.err.Error(),
+			LogFile:    ins.LogFile(),
+			CrashLog:   c.crashLog,
+			Restarting: c.restarting,
+		})
+	}
+	return
+}
+
+// readiness checks whether the cluster is ready and the data is seeded, cmdMu
+// should be held after the cluster is booted
+func (p *Playground) readiness() pgapi.ReadyResponse {
+	resp := p.clusterReadiness()
+	seeding, err := p.seedState()
+	switch {
+	case err != nil:
+		resp.Ready, resp.Failed, resp.Endpoints = false, true, nil
+		resp.Reasons = append(resp.Reasons, fmt.Sprintf("failed to seed the data: %s", err))
+	case seeding && resp.Ready:
+		resp.Ready, resp.Endpoints = false, nil
+		resp.Reasons = append(resp.Reasons, "the data is being seeded")
+	}
+	return resp
+}
+
+// bootReadiness is the readiness while the cluster is booting, only the
+// crashes are checked, so it's safe without cmdMu while the instances are
+// being added
+func (p *Playground) bootReadiness() pgapi.ReadyResponse {
+	resp := p.crashReadiness()
+	resp.Reasons = append(resp.Reasons, "the playground is booting")
+	return resp
+}
+
+// crashReadiness reports the crashed instances, it's safe to call while
+// booting
+func (p *Playground) crashReadiness() pgapi.ReadyResponse {
+	resp := pgapi.ReadyResponse{Crashed: p.crashedInstances()}
+	for _, c := range resp.Crashed {
+		if c.Restarting {
+			resp.Reasons = append(resp.Reasons, fmt.Sprintf("%s crashed: %s, it's restarting", c.Name, c.Error))
+			continue
+		}
+		resp.Failed = true
+		resp.Reasons = append(resp.Reasons, fmt.Sprintf("%s crashed: %s", c.Name, c.Error))
+	}
+	return resp
+}
+
+// clusterReadiness checks whether the instances are ready, cmdMu should be
+// held after the cluster is booted
+func (p *Playground) clusterReadiness() pgapi.ReadyResponse {
+	resp := p.crashReadiness()
+	if atomic.LoadInt32(&p.bootFinished) == 0 {
+		resp.Reasons = append(resp.Reasons, "the playground is booting")
+		return resp
+	}
+	if atomic.LoadInt32(&p.paused) == 1 {
+		resp.Reasons = append(resp.Reasons, "the instances are stopped for a snapshot")
+		return resp
+	}
+
+	healths := p.instanceHealths()
+	_ = p.WalkInstances(func(cid string, ins instance.Instance) error {
+		h := healths[ins]
+		if h.state != pgapi.HealthHealthy && h.state != pgapi.HealthCrashed {
+			resp.Reasons = append(resp.Reasons, fmt.Sprintf("%s is %s: %s", p.instanceName(cid, ins), h.state, h.detail))
+		}
+		return nil
+	})
+	if len(resp.Reasons) == 0 {
+		resp.Ready = true
+		resp.Endpoints = p.endpoints()
+	}
+	return resp
+}
+
+// instanceHealths checks the health of all the instances, cmdMu should be held
+func (p *Playground) instanceHealths() map[instance.Instance]health {
+	client := utils.NewHTTPClient(healthCheckTimeout, nil)
+	ctx := context.Background()
+
+	// the states of the stores by their addresses, they're got from any PD
+	var stores map[string]string
+	var storesErr error
+	storeState := func(addr string) (string, error) {
+		if stores == nil && storesErr == nil {
+			stores, storesErr = p.storeStates(ctx, client)
+		}
+		if storesErr != nil {
+			return "", storesErr
+		}
+		if state, ok := stores[addr]; ok {
+			return state, nil
+		}
+		return "", errors.New("the store is not registered in PD")
+	}
+	checkStore := func(addr string) error {
+		state, err := storeState(addr)
+		if err == nil && state != "Up" {
+			err = errors.Errorf("the store is %s in PD", state)
+		}
+		return err
+	}
+	checkURL := func(url string) error {
+		_, err := client.Get(ctx, url)
+		return err
+	}
+
+	crashed := make(map[instance.Instance]string)
+	p.crashMu.Lock()
+	for ins, c := range p.crashes {
+		crashed[ins] = c.err.Error()
+		if c.restarting {
+			crashed[ins] += ", it's restarting"
+		}
+	}
+	p.crashMu.Unlock()
+
+	healths := make(map[instance.Instance]health)
+	_ = p.WalkInstances(func(cid string, ins instance.Instance) error {
+		if detail, ok := crashed[ins]; ok {
+			healths[ins] = health{pgapi.HealthCrashed, detail}
+			return nil
+		}
+		if status := p.instanceStatus(ins); status != pgapi.StatusRunning {
+			healths[ins] = health{pgapi.HealthStopped, status}
+			return nil
+		}
+
+		var err error
+		switch inst := ins.(type) {
+		case *instance.PDInstance:
+			err = checkURL(fmt.Sprintf("http://%s/pd/ping", inst.Addr()))
+		case *instance.TiKVInstance:
+			err = checkStore(inst.StoreAddr())
+		case *instance.TiFlashInstance:
+			err = checkStore(inst.Addr())
+		case *instance.TiDBInstance:
+			err = checkURL(fmt.Sprintf("http://%s/status", inst.StatusAddrs()[0]))
+		case *instance.TiCDC:
+			err = checkURL(fmt.Sprintf("http://%s/status", inst.Addr()))
+		case interface{ Addr() string }:
+			var conn net.Conn
+			if conn, err = net.DialTimeout("tcp", inst.Addr(), healthCheckTimeout); err == nil {
+				conn.Close()
+			}
+		}
+		if err != nil {
+			healths[ins] = health{pgapi.HealthUnhealthy, err.Error()}
+		} else {
+			healths[ins] = health{state: pgapi.HealthHealthy}
+		}
+		return nil
+	})
+	return healths
+}
+
+// storeStates returns the states of the stores by their addresses
+func (p *Playground) storeStates(ctx context.Context, client *utils.HTTPClient) (map[string]string, error) {
+	err := errors.New("no PD")
+	for _, pd := range p.pds {
+		var data []byte
+		data, err = client.Get(ctx, fmt.Sprintf("http://%s/pd/api/v1/stores", pd.Addr()))
+		if err != nil {
+			continue
+		}
+		stores := api.StoresInfo{}
+		if err = json.Unmarshal(data, &stores); err != nil {
+			return nil, errors.AddStack(err)
+		}
+		states := make(map[string]string)
+		for _, s := range stores.Stores {
+			// a tombstone store may have the same address as a new one
+			if _, ok := states[s.Store.Address]; !ok || s.Store.StateName != "Tombstone" {
+				states[s.Store.Address] = s.Store.StateName
+			}
+		}
+		return states, nil
+	}
+	return nil, errors.Annotate(err, "failed to get the stores from PD")
+}
+
+// endpoints returns the endpoints of the cluster, cmdMu should be held
+func (p *Playground) endpoints() *pgapi.Endpoints {
+	e := &pgapi.Endpoints{API: fmt.Sprintf("http://127.0.0.1:%d", p.port)}
+	for _, pd := range p.pds {
+		e.PD = append(e.PD, pd.Addr())
+	}
+	for _, db := range p.tidbs {
+		e.TiDB = append(e.TiDB, db.Addr())
+	}
+	for _, flash := range p.tiflashs {
+		e.TiFlash = append(e.TiFlash, flash.Addr())
+	}
+	for _, cdc := range p.ticdcs {
+		e.TiCDC = append(e.TiCDC, cdc.Addr())
+	}
+	if len(p.pds) > 0 && len(p.tidbs) > 0 && hasDashboard(p.pds[0].Addr()) {
+		e.Dashboard = fmt.Sprintf("http://%s/dashboard", p.pds[0].Addr())
+	}
+	if m := p.monitor; m != nil {
+		e.Prometheus = fmt.Sprintf("http://%s:%d", m.host, m.port)
+	}
+	if g := p.grafana; g != nil {
+		e.Grafana = fmt.Sprintf("http://%s:%d", g.host, g.port)
+	}
+	return e
+}
+
+// writeReadyFile writes the endpoints to the file once the cluster is ready,
+// it gives up if the cluster fails or the playground quits
+func (p *Playground) writeReadyFile(file string) {
+	for atomic.LoadInt32(&p.curSig) == 0 {
+		p.cmdMu.Lock()
+		resp := p.readiness()
+		p.cmdMu.Unlock()
+
+		if resp.Failed {
+			fmt.Println(color.RedString("The cluster failed to be ready, %s is not written: %s",
+				file, strings.Join(resp.Reasons, "; ")))
+			return
+		}
+		if resp.Ready {
+			if err := writeFileAtomically(file, resp.Endpoints); err != nil {
+				fmt.Println(color.RedString("Failed to write the ready file: %s", err))
+				return
+			}
+			fmt.Println(color.GreenString("The cluster is ready, the endpoints are written to %s", file))
+			return
+		}
+		time.Sleep(readyCheckInterval)
+	}
+}
+
+// writeFileAtomically writes v in JSON to a temporary file and renames it to
+// the file, so the file is complete once it exists
+func writeFileAtomically(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.AddStack(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(os.Rename(tmp.Name(), file))
+}
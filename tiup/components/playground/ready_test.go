@@ -0,0 +1,72 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	pgapi "github.com/pingcap/tiup/components/playground/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = &BootOptions{}
+
+	stopped := &sleepInstance{}
+	crashed := &sleepInstance{}
+	for id, ins := range []*sleepInstance{stopped, crashed} {
+		assert.Nil(t, ins.Start(context.TODO(), ""))
+		p.instanceSpecs[ins] = instanceSpec{Component: "sleep", ID: id}
+		p.addWaitInstance(ins)
+	}
+
+	// the instances stopped on purpose are not crashed
+	p.stopOnPurpose(stopped.Pid())
+	assert.Nil(t, syscall.Kill(stopped.Pid(), syscall.SIGKILL))
+	assert.Nil(t, syscall.Kill(crashed.Pid(), syscall.SIGKILL))
+	assert.NotNil(t, p.wait())
+
+	// the crashes are reported while booting
+	resp := p.readiness()
+	assert.False(t, resp.Ready)
+	assert.True(t, resp.Failed)
+	assert.Equal(t, []string{"sleep-1 crashed: signal: killed", "the playground is booting"}, resp.Reasons)
+	assert.Equal(t, []pgapi.CrashedInstance{{Name: "sleep-1", Error: "signal: killed"}}, resp.Crashed)
+
+	// the crash is forgotten when it's started again
+	assert.Nil(t, p.startAgain(crashed))
+	resp = p.readiness()
+	assert.False(t, resp.Failed)
+	assert.Equal(t, []string{"the playground is booting"}, resp.Reasons)
+	assert.Nil(t, syscall.Kill(crashed.Pid(), syscall.SIGTERM))
+	_ = crashed.Wait()
+}
+
+func TestWriteFileAtomically(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ready.json")
+	assert.Nil(t, writeFileAtomically(file, &pgapi.Endpoints{API: "http://127.0.0.1:9527"}))
+
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"api": "http://127.0.0.1:9527", "pd": null}`, string(data))
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
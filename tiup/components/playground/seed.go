@@ -0,0 +1,268 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/environment"
+	tiupexec "github.com/pingcap/tiup/pkg/exec"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// the components to import the data
+const (
+	componentLightning = "tidb-lightning"
+	componentBR        = "br"
+)
+
+// brBackupMeta is the file in the dir of a BR backup
+const brBackupMeta = "backupmeta"
+
+// seedTarget is the addresses of the cluster the data is seeded into
+type seedTarget struct {
+	tidbAddr       string
+	tidbStatusAddr string
+	pdAddrs        []string
+}
+
+// checkSeedOptions checks the files of --init-sql and --import and makes
+// their paths absolute
+func checkSeedOptions(options *BootOptions) error {
+	if options.InitSQL == "" && options.Import == "" {
+		return nil
+	}
+	if options.TiDB.Num < 1 {
+		return errors.New("the data can't be seeded by --init-sql or --import without TiDB")
+	}
+	for _, path := range []*string{&options.InitSQL, &options.Import} {
+		if *path == "" {
+			continue
+		}
+		abs, err := getAbsolutePath(*path)
+		if err != nil {
+			return errors.Annotatef(err, "cannot eval absolute path: %s", *path)
+		}
+		if utils.IsNotExist(abs) {
+			return errors.Errorf("%s doesn't exist", abs)
+		}
+		*path = abs
+	}
+	return nil
+}
+
+// seedState returns whether the data is being seeded, and the error if it
+// failed to be seeded
+func (p *Playground) seedState() (bool, error) {
+	p.seedMu.Lock()
+	defer p.seedMu.Unlock()
+	return p.seeding, p.seedErr
+}
+
+// seedData runs the SQL file of --init-sql and imports the data of --import
+// once the instances are ready, the cluster is ready after they're done
+func (p *Playground) seedData(ctx context.Context, options *BootOptions) {
+	target, err := p.waitSeedTarget()
+	if err == nil && options.InitSQL != "" {
+		fmt.Printf("Running %s\n", options.InitSQL)
+		err = runInitSQL(ctx, target.tidbAddr, options.InitSQL)
+	}
+	if err == nil && options.Import != "" {
+		fmt.Printf("Importing %s\n", options.Import)
+		err = p.importData(ctx, options.Version, options.Import, target)
+	}
+
+	if err != nil {
+		fmt.Println(color.RedString("Failed to seed the data: %s", err))
+	} else {
+		fmt.Println(color.GreenString("The data is seeded"))
+	}
+	p.seedMu.Lock()
+	defer p.seedMu.Unlock()
+	p.seeding, p.seedErr = false, err
+}
+
+// waitSeedTarget waits until the instances are ready, and returns the
+// addresses to seed the data into
+func (p *Playground) waitSeedTarget() (*seedTarget, error) {
+	for atomic.LoadInt32(&p.curSig) == 0 {
+		p.cmdMu.Lock()
+		resp := p.clusterReadiness()
+		var target *seedTarget
+		if resp.Ready && len(p.tidbs) > 0 {
+			target = &seedTarget{
+				tidbAddr:       p.tidbs[0].Addr(),
+				tidbStatusAddr: p.tidbs[0].StatusAddrs()[0],
+				pdAddrs:        resp.Endpoints.PD,
+			}
+		}
+		p.cmdMu.Unlock()
+
+		if resp.Failed {
+			return nil, errors.Errorf("the cluster failed to be ready: %s", strings.Join(resp.Reasons, "; "))
+		}
+		if target != nil {
+			return target, nil
+		}
+		time.Sleep(readyCheckInterval)
+	}
+	return nil, errors.New("the playground is quitting")
+}
+
+// runInitSQL runs the statements in the SQL file by root
+func runInitSQL(ctx context.Context, tidbAddr, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	db, err := sql.Open("mysql", fmt.Sprintf("root:@tcp(%s)/?multiStatements=true", tidbAddr))
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, string(data)); err != nil {
+		return errors.Annotatef(err, "failed to run %s", file)
+	}
+	return nil
+}
+
+// importData restores the dir by BR if it's a BR backup, or imports it by
+// TiDB Lightning if it's exported by Dumpling or has CSV files
+func (p *Playground) importData(ctx context.Context, version, dir string, target *seedTarget) error {
+	seedDir := filepath.Join(p.dataDir, "seed")
+	if err := os.MkdirAll(seedDir, 0755); err != nil {
+		return errors.AddStack(err)
+	}
+
+	if utils.IsExist(filepath.Join(dir, brBackupMeta)) {
+		logFile := filepath.Join(seedDir, "br.log")
+		args := []string{
+			"restore", "full",
+			"--pd", strings.Join(target.pdAddrs, ","),
+			"--storage", "local://" + dir,
+			"--log-file", logFile,
+		}
+		return runSeedComponent(ctx, componentBR, version, seedDir, logFile, args)
+	}
+
+	logFile := filepath.Join(seedDir, "tidb-lightning.log")
+	configPath := filepath.Join(seedDir, "tidb-lightning.toml")
+	cfg, err := lightningConfig(dir, logFile, target)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(configPath)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer f.Close()
+	if err := toml.NewEncoder(f).Encode(cfg); err != nil {
+		return errors.AddStack(err)
+	}
+	return runSeedComponent(ctx, componentLightning, version, seedDir, logFile, []string{"--config", configPath})
+}
+
+// lightningConfig returns the config of TiDB Lightning to import the dir,
+// the data is written by SQL since it's small in a playground
+func lightningConfig(dir, logFile string, target *seedTarget) (map[string]interface{}, error) {
+	host, port, err := splitHostPort(target.tidbAddr)
+	if err != nil {
+		return nil, err
+	}
+	_, statusPort, err := splitHostPort(target.tidbStatusAddr)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"lightning": map[string]interface{}{
+			"level":              "info",
+			"file":               logFile,
+			"check-requirements": false,
+		},
+		"checkpoint": map[string]interface{}{
+			"enable": false,
+		},
+		"tikv-importer": map[string]interface{}{
+			"backend": "tidb",
+		},
+		"mydumper": map[string]interface{}{
+			"data-source-dir": dir,
+		},
+		"tidb": map[string]interface{}{
+			"host":        host,
+			"port":        port,
+			"user":        "root",
+			"status-port": statusPort,
+			"pd-addr":     target.pdAddrs[0],
+		},
+	}, nil
+}
+
+func splitHostPort(addr string) (string, int, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, errors.AddStack(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, errors.Annotatef(err, "invalid port of %s", addr)
+	}
+	return host, p, nil
+}
+
+// runSeedComponent runs the component to seed the data, which is downloaded
+// if it's missing, its output is appended to the log file
+func runSeedComponent(ctx context.Context, component, version, dir, logFile string, args []string) error {
+	params := &tiupexec.PrepareCommandParams{
+		Ctx:         ctx,
+		Component:   component,
+		Version:     utils.Version(version),
+		InstanceDir: dir,
+		WD:          dir,
+		Args:        args,
+		SysProcAttr: instance.SysProcAttr,
+		Env:         environment.GlobalEnv(),
+	}
+	cmd, err := tiupexec.PrepareCommand(params)
+	if err != nil {
+		return err
+	}
+
+	log, err := os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer log.Close()
+	cmd.Stdout = log
+	cmd.Stderr = log
+
+	if err := cmd.Run(); err != nil {
+		return errors.Annotatef(err, "%s failed, see %s", component, logFile)
+	}
+	return nil
+}
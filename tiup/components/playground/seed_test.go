@@ -0,0 +1,81 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSeedOptions(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "init.sql")
+	assert.Nil(t, os.WriteFile(file, []byte("CREATE DATABASE test;"), 0644))
+
+	assert.Nil(t, checkSeedOptions(&BootOptions{}))
+	assert.NotNil(t, checkSeedOptions(&BootOptions{InitSQL: file}))
+	opt := &BootOptions{TiDB: instance.Config{Num: 1}, InitSQL: file, Import: dir}
+	assert.Nil(t, checkSeedOptions(opt))
+	opt.Import = filepath.Join(dir, "dump")
+	assert.Contains(t, checkSeedOptions(opt).Error(), "doesn't exist")
+}
+
+func TestSeedReadiness(t *testing.T) {
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = &BootOptions{}
+	atomic.StoreInt32(&p.bootFinished, 1)
+	assert.True(t, p.readiness().Ready)
+
+	// the cluster is not ready until the data is seeded
+	p.seeding = true
+	resp := p.readiness()
+	assert.False(t, resp.Ready)
+	assert.False(t, resp.Failed)
+	assert.Nil(t, resp.Endpoints)
+	assert.Equal(t, []string{"the data is being seeded"}, resp.Reasons)
+	assert.True(t, p.clusterReadiness().Ready)
+
+	p.seeding, p.seedErr = false, errors.New("table not found")
+	resp = p.readiness()
+	assert.False(t, resp.Ready)
+	assert.True(t, resp.Failed)
+	assert.Equal(t, []string{"failed to seed the data: table not found"}, resp.Reasons)
+}
+
+func TestLightningConfig(t *testing.T) {
+	cfg, err := lightningConfig("/data/dump", "/tmp/tidb-lightning.log", &seedTarget{
+		tidbAddr:       "127.0.0.1:4000",
+		tidbStatusAddr: "127.0.0.1:10080",
+		pdAddrs:        []string{"127.0.0.1:2379"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"host":        "127.0.0.1",
+		"port":        4000,
+		"user":        "root",
+		"status-port": 10080,
+		"pd-addr":     "127.0.0.1:2379",
+	}, cfg["tidb"])
+	assert.Equal(t, "tidb", cfg["tikv-importer"].(map[string]interface{})["backend"])
+	assert.Equal(t, "/data/dump", cfg["mydumper"].(map[string]interface{})["data-source-dir"])
+
+	_, err = lightningConfig("/data/dump", "", &seedTarget{tidbAddr: "127.0.0.1"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,442 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/localdata"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// the file of the snapshot manifest, it's the first entry of the archive
+	snapshotManifest = "snapshot.yaml"
+	snapshotFormat   = 1
+)
+
+var snapshotNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// instanceSpec is the id and config used to create an instance
+type instanceSpec struct {
+	Component string          `yaml:"component"`
+	ID        int             `yaml:"id"`
+	Config    instance.Config `yaml:"config"`
+	Ports     map[string]int  `yaml:"ports"`
+}
+
+// snapshot is the manifest of a snapshot, the data dirs of the instances are
+// archived along with it
+type snapshot struct {
+	Format    int            `yaml:"format"`
+	Options   *BootOptions   `yaml:"options"`
+	Instances []instanceSpec `yaml:"instances"`
+}
+
+func newSnapshot() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save or restore the state of the playground",
+		Long: `Save or restore the state of the playground. The instances are stopped while
+the data is archived or restored, and they're started again after that.
+
+A name is saved to the snapshot directory of TiUP, a path can be used instead.
+
+'restore' replaces the cluster of a running playground of the same version. To
+boot the identical cluster of a snapshot, with its version, instances, ports
+and data, start a new playground by 'tiup playground --snapshot <name>'.`,
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:     "save <name>",
+			Short:   "Save the data and topology of the playground",
+			Example: "tiup playground snapshot save fixture --tag test",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if len(args) != 1 {
+					return cmd.Help()
+				}
+				file, err := snapshotPath(args[0])
+				if err != nil {
+					return err
+				}
+				if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+					return errors.AddStack(err)
+				}
+				return sendSnapshotCommand(SnapshotSaveCommandType, file)
+			},
+		},
+		&cobra.Command{
+			Use:     "restore <name>",
+			Short:   "Restore the data and topology of the playground from a snapshot",
+			Example: "tiup playground snapshot restore fixture --tag test",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if len(args) != 1 {
+					return cmd.Help()
+				}
+				file, err := snapshotPath(args[0])
+				if err != nil {
+					return err
+				}
+				if utils.IsNotExist(file) {
+					return errors.Errorf("snapshot %s not found", file)
+				}
+				return sendSnapshotCommand(SnapshotRestoreCommandType, file)
+			},
+		},
+	)
+	return cmd
+}
+
+// snapshotPath returns the archive of a snapshot, the path is used directly
+// if it's not a plain name
+func snapshotPath(name string) (string, error) {
+	if filepath.Base(name) != name {
+		return getAbsolutePath(name)
+	}
+	if !snapshotNameRegexp.MatchString(name) {
+		return "", errors.Errorf("invalid snapshot name %s, only letters, digits, '.', '_' and '-' are allowed", name)
+	}
+	return filepath.Join(tiupHome, localdata.StorageParentDir, "playground", "snapshots", name+".tar.gz"), nil
+}
+
+func sendSnapshotCommand(tp CommandType, file string) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	var msg string
+	if tp == SnapshotSaveCommandType {
+		msg, err = client.SaveSnapshot(context.Background(), file)
+	} else {
+		msg, err = client.RestoreSnapshot(context.Background(), file)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Print(msg)
+	return nil
+}
+
+func (p *Playground) handleSnapshotSave(w io.Writer, file string) error {
+	snap := &snapshot{
+		Format:  snapshotFormat,
+		Options: p.bootOptions,
+	}
+	var logFiles []string
+	_ = p.WalkInstances(func(cid string, ins instance.Instance) error {
+		s := p.instanceSpecs[ins]
+		s.Ports = ins.Ports()
+		snap.Instances = append(snap.Instances, s)
+		logFiles = append(logFiles, ins.LogFile())
+		return nil
+	})
+
+	p.pause()
+	err := writeSnapshot(file, p.dataDir, snap, logFiles)
+	if rerr := p.resume(); err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "snapshot saved to %s\n", file)
+	return nil
+}
+
+func (p *Playground) handleSnapshotRestore(w io.Writer, file string) error {
+	snap, err := readSnapshot(file)
+	if err != nil {
+		return err
+	}
+	if snap.Options.Version != p.bootOptions.Version {
+		return errors.Errorf("the snapshot is saved from a playground of %s, while this one is %s, boot it by 'tiup playground --snapshot %s' instead",
+			snap.Options.Version, p.bootOptions.Version, file)
+	}
+
+	p.pause()
+	err = p.restoreSnapshot(file, snap)
+	if rerr := p.resume(); err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if p.monitor != nil {
+		p.updateMonitorTopology(spec.ComponentPrometheus,
+			MonitorInfo{p.monitor.host, p.monitor.port, filepath.Join(p.dataDir, "prometheus")})
+	}
+	if g := p.grafana; g != nil {
+		p.updateMonitorTopology(spec.ComponentGrafana, MonitorInfo{g.host, g.port, g.cmd.Path})
+	}
+
+	fmt.Fprintf(w, "snapshot %s restored\n", file)
+	return nil
+}
+
+// restoreSnapshot replaces the data and instances with the ones in snapshot,
+// the instances should be stopped
+func (p *Playground) restoreSnapshot(file string, snap *snapshot) error {
+	if err := p.extractSnapshot(file, snap); err != nil {
+		return err
+	}
+
+	p.closeFaultProxies()
+	p.pds, p.tikvs, p.tidbs, p.tiflashs = nil, nil, nil, nil
+	p.ticdcs, p.pumps, p.drainers = nil, nil, nil
+	p.idAlloc = make(map[string]int)
+	p.instanceSpecs = make(map[instance.Instance]instanceSpec)
+	snap.Options.Monitor = p.bootOptions.Monitor
+	p.bootOptions = snap.Options
+	p.booted = false
+	defer func() { p.booted = true }()
+	return p.addSnapshotInstances(snap)
+}
+
+// extractSnapshot replaces the data dirs of the current instances and the
+// ones in the snapshot with the data in the snapshot
+func (p *Playground) extractSnapshot(file string, snap *snapshot) error {
+	specs := append([]instanceSpec{}, snap.Instances...)
+	for _, s := range p.instanceSpecs {
+		specs = append(specs, s)
+	}
+	for _, s := range specs {
+		if err := os.RemoveAll(filepath.Join(p.dataDir, fmt.Sprintf("%s-%d", s.Component, s.ID))); err != nil {
+			return errors.AddStack(err)
+		}
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer f.Close()
+	if err := utils.Untar(f, p.dataDir); err != nil {
+		return errors.Annotatef(err, "failed to extract snapshot %s", file)
+	}
+	_ = os.Remove(filepath.Join(p.dataDir, snapshotManifest))
+	return nil
+}
+
+// addSnapshotInstances creates the instances in the snapshot with the same
+// ids and ports, as the addresses are stored in the data, the proxies are
+// started along with them
+func (p *Playground) addSnapshotInstances(snap *snapshot) error {
+	for _, s := range snap.Instances {
+		ins, err := p.addInstanceWithID(s.Component, s.ID, s.Config)
+		if err != nil {
+			return err
+		}
+		ins.SetPorts(s.Ports)
+		if s.ID >= p.idAlloc[s.Component] {
+			p.idAlloc[s.Component] = s.ID + 1
+		}
+	}
+	return nil
+}
+
+// pause stops all the instances, the playground keeps running until resumed
+func (p *Playground) pause() {
+	atomic.StoreInt32(&p.paused, 1)
+	resumed := make(chan struct{})
+	p.resumed = resumed
+	p.instanceWaiter.Go(func() error {
+		<-resumed
+		return nil
+	})
+
+	p.resetFaults()
+	p.terminateInstances(syscall.SIGTERM)
+	p.startedInstances = nil
+}
+
+// resume starts the instances stopped by pause
+func (p *Playground) resume() error {
+	defer func() {
+		atomic.StoreInt32(&p.paused, 0)
+		close(p.resumed)
+	}()
+
+	ctx := context.WithValue(context.TODO(), logprinter.ContextKeyLogger, log)
+	if err := p.startInstances(ctx); err != nil {
+		return err
+	}
+	if succ := p.waitAllTidbUp(); len(succ) > 0 {
+		p.startTiFlash(ctx)
+	}
+
+	dumpDSN(filepath.Join(p.dataDir, "dsn"), p.tidbs)
+	logIfErr(p.renderSDFile())
+	fmt.Println(color.GreenString("Playground resumed"))
+	return nil
+}
+
+// writeSnapshot archives the manifest and the data dirs of instances, the log
+// files are skipped
+func writeSnapshot(file, dataDir string, snap *snapshot, skipped []string) error {
+	manifest, err := yaml.Marshal(snap)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	skip := make(map[string]struct{})
+	for _, f := range skipped {
+		skip[f] = struct{}{}
+	}
+
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{
+		Name: snapshotManifest,
+		Mode: 0644,
+		Size: int64(len(manifest)),
+	}); err != nil {
+		return errors.AddStack(err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return errors.AddStack(err)
+	}
+
+	for _, s := range snap.Instances {
+		root := filepath.Join(dataDir, fmt.Sprintf("%s-%d", s.Component, s.ID))
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if _, ok := skip[path]; ok || !(info.IsDir() || info.Mode().IsRegular()) {
+				return nil
+			}
+			name, err := filepath.Rel(dataDir, path)
+			if err != nil {
+				return err
+			}
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(name)
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			src, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			_, err = io.CopyN(tw, src, info.Size())
+			return err
+		})
+		if err != nil {
+			return errors.Annotatef(err, "failed to archive %s", root)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := gw.Close(); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(os.Rename(tmp, file))
+}
+
+// bootSnapshotOptions returns the options to boot the cluster saved in the
+// snapshot, only whether to start the monitor is kept from the current ones,
+// and the data is not seeded again
+func bootSnapshotOptions(file string, opt *BootOptions) (*BootOptions, error) {
+	snap, err := readSnapshot(file)
+	if err != nil {
+		return nil, err
+	}
+	snap.Options.Monitor = opt.Monitor
+	snap.Options.InitSQL, snap.Options.Import = "", ""
+	return snap.Options, nil
+}
+
+// bootSnapshotInstances extracts the data and creates the instances of the
+// snapshot to boot the playground
+func (p *Playground) bootSnapshotInstances(file string) error {
+	snap, err := readSnapshot(file)
+	if err != nil {
+		return err
+	}
+	if err := p.extractSnapshot(file, snap); err != nil {
+		return err
+	}
+	return p.addSnapshotInstances(snap)
+}
+
+// readSnapshot reads the manifest of a snapshot
+func readSnapshot(file string) (*snapshot, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Annotatef(err, "invalid snapshot %s", file)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != snapshotManifest {
+		return nil, errors.Errorf("invalid snapshot %s, the manifest is not found", file)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	snap := &snapshot{}
+	if err := yaml.Unmarshal(data, snap); err != nil {
+		return nil, errors.Annotatef(err, "invalid snapshot %s", file)
+	}
+	if snap.Format != snapshotFormat || snap.Options == nil {
+		return nil, errors.Errorf("unsupported snapshot %s of format %d", file, snap.Format)
+	}
+	return snap, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotArchive(t *testing.T) {
+	dataDir := t.TempDir()
+	for file, content := range map[string]string{
+		"pd-0/data/member/wal/0.wal": "wal",
+		"pd-0/pd.log":                "log",
+		"tikv-1/data/db/CURRENT":     "MANIFEST-000001",
+		"tidb-0/tidb.log":            "log",
+		"port":                       "9527",
+	} {
+		path := filepath.Join(dataDir, file)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	snap := &snapshot{
+		Format:  snapshotFormat,
+		Options: &BootOptions{Version: "v5.0.0", Host: "127.0.0.1"},
+		Instances: []instanceSpec{
+			{Component: "pd", ID: 0, Ports: map[string]int{"port": 2380, "status_port": 2379}},
+			{Component: "tikv", ID: 1, Ports: map[string]int{"port": 20161, "status_port": 20181}},
+		},
+	}
+	file := filepath.Join(t.TempDir(), "fixture.tar.gz")
+	assert.Nil(t, writeSnapshot(file, dataDir, snap, []string{filepath.Join(dataDir, "pd-0", "pd.log")}))
+
+	loaded, err := readSnapshot(file)
+	assert.Nil(t, err)
+	assert.Equal(t, snap, loaded)
+
+	restoreDir := t.TempDir()
+	f, err := os.Open(file)
+	assert.Nil(t, err)
+	defer f.Close()
+	assert.Nil(t, utils.Untar(f, restoreDir))
+	data, err := os.ReadFile(filepath.Join(restoreDir, "tikv-1", "data", "db", "CURRENT"))
+	assert.Nil(t, err)
+	assert.Equal(t, "MANIFEST-000001", string(data))
+	assert.True(t, utils.IsExist(filepath.Join(restoreDir, "pd-0", "data", "member", "wal", "0.wal")))
+	// the log files and the dirs of other instances are not archived
+	assert.True(t, utils.IsNotExist(filepath.Join(restoreDir, "pd-0", "pd.log")))
+	assert.True(t, utils.IsNotExist(filepath.Join(restoreDir, "tidb-0")))
+	assert.True(t, utils.IsNotExist(filepath.Join(restoreDir, "port")))
+
+	_, err = readSnapshot(filepath.Join(dataDir, "port"))
+	assert.NotNil(t, err)
+}
+
+func TestBootSnapshot(t *testing.T) {
+	dataDir := t.TempDir()
+	path := filepath.Join(dataDir, "tikv-1", "data", "db", "CURRENT")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, os.WriteFile(path, []byte("MANIFEST-000001"), 0644))
+	assert.Nil(t, os.MkdirAll(filepath.Join(dataDir, "pd-0", "data"), 0755))
+	snap := &snapshot{
+		Format:  snapshotFormat,
+		Options: &BootOptions{Version: "v5.0.0", Host: "127.0.0.1", Monitor: true, InitSQL: "/tmp/init.sql"},
+		Instances: []instanceSpec{
+			{Component: "pd", ID: 0, Ports: map[string]int{"port": 2380, "status_port": 2379}},
+			{Component: "tikv", ID: 1, Ports: map[string]int{"port": 20161, "status_port": 20181}},
+		},
+	}
+	file := filepath.Join(t.TempDir(), "fixture.tar.gz")
+	assert.Nil(t, writeSnapshot(file, dataDir, snap, nil))
+
+	// the cluster is booted as it's saved, while the data is not seeded again
+	opts, err := bootSnapshotOptions(file, &BootOptions{Version: "v5.1.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, "v5.0.0", opts.Version)
+	assert.False(t, opts.Monitor)
+	assert.Empty(t, opts.InitSQL)
+
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = opts
+	assert.Nil(t, p.bootSnapshotInstances(file))
+	assert.Len(t, p.pds, 1)
+	assert.Len(t, p.tikvs, 1)
+	assert.Equal(t, 20161, p.tikvs[0].Port)
+	assert.Equal(t, 1, p.instanceSpecs[p.tikvs[0]].ID)
+	data, err := os.ReadFile(filepath.Join(p.dataDir, "tikv-1", "data", "db", "CURRENT"))
+	assert.Nil(t, err)
+	assert.Equal(t, "MANIFEST-000001", string(data))
+
+	// the new instances don't reuse the ids
+	ins, err := p.addInstance("tikv", instance.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, p.instanceSpecs[ins].ID)
+}
+
+func TestSnapshotPath(t *testing.T) {
+	tiupHome = "/home/tidb/.tiup"
+	path, err := snapshotPath("fixture")
+	assert.Nil(t, err)
+	assert.Equal(t, "/home/tidb/.tiup/storage/playground/snapshots/fixture.tar.gz", path)
+
+	path, err = snapshotPath("/tmp/fixture.tar.gz")
+	assert.Nil(t, err)
+	assert.Equal(t, "/tmp/fixture.tar.gz", path)
+
+	_, err = snapshotPath("-fixture")
+	assert.NotNil(t, err)
+}
+
+func TestInstancePorts(t *testing.T) {
+	flash := instance.NewTiFlashInstance("", t.TempDir(), "127.0.0.1", "", 0, nil, nil)
+	flash.SetPorts(map[string]int{"port": 18123, "tcp_port": 19000, "proxy_status_port": 0})
+	ports := flash.Ports()
+	assert.Equal(t, 18123, ports["port"])
+	assert.Equal(t, 19000, ports["tcp_port"])
+	assert.Equal(t, flash.ProxyStatusPort, ports["proxy_status_port"])
+	assert.NotZero(t, ports["proxy_status_port"])
+}
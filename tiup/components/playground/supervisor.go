@@ -0,0 +1,195 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+const (
+	// the backoff before restarting a crashed instance, it's doubled after
+	// each crash in the crash loop window
+	restartBackoffBase = time.Second
+	restartBackoffMax  = 30 * time.Second
+	// an instance is not restarted anymore if it crashes more than
+	// crashLoopLimit times in crashLoopWindow
+	crashLoopLimit  = 5
+	crashLoopWindow = 5 * time.Minute
+	// the number of the last log lines saved in the crash log
+	crashLogTailLines = 100
+	crashLogName      = "crash.log"
+)
+
+// restartHistory is how a crashed instance is restarted by the supervisor
+type restartHistory struct {
+	// restarts is the number of the times it's restarted
+	restarts int
+	// crashes are the times it crashed in the crash loop window
+	crashes []time.Time
+}
+
+// restartBackoff returns the backoff before the nth restart in the crash
+// loop window
+func restartBackoff(n int) time.Duration {
+	backoff := restartBackoffBase
+	for i := 1; i < n && backoff < restartBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > restartBackoffMax {
+		backoff = restartBackoffMax
+	}
+	return backoff
+}
+
+// superviseCrash decides whether the crashed instance is restarted, it's
+// restarted after the returned backoff unless it's in a crash loop. crashMu
+// should be held.
+func (p *Playground) superviseCrash(ins instance.Instance, now time.Time) (time.Duration, bool) {
+	h := p.restarts[ins]
+	if h == nil {
+		h = &restartHistory{}
+		p.restarts[ins] = h
+	}
+	crashes := h.crashes[:0]
+	for _, t := range h.crashes {
+		if now.Sub(t) < crashLoopWindow {
+			crashes = append(crashes, t)
+		}
+	}
+	h.crashes = append(crashes, now)
+	if len(h.crashes) > crashLoopLimit {
+		return 0, false
+	}
+	return restartBackoff(len(h.crashes)), true
+}
+
+// restartCount returns the number of the times the instance is restarted by
+// the supervisor
+func (p *Playground) restartCount(ins instance.Instance) int {
+	p.crashMu.Lock()
+	defer p.crashMu.Unlock()
+	if h := p.restarts[ins]; h != nil {
+		return h.restarts
+	}
+	return 0
+}
+
+// restartCrashed restarts the crashed instance after the backoff, it's
+// retried with longer backoffs if the instance fails to start until it's in
+// a crash loop. It gives up if the playground is quitting or the instance is
+// scaled in.
+func (p *Playground) restartCrashed(ins instance.Instance, name string, backoff time.Duration) error {
+	for {
+		fmt.Print(color.YellowString("Restart %s in %s\n", name, backoff))
+		if !p.sleepUntilBooted(backoff) {
+			return nil
+		}
+		err := p.restartCrashedOnce(ins)
+		if err == nil {
+			return nil
+		}
+
+		fmt.Print(color.RedString("Failed to restart %s: %s\n", name, err))
+		var restart bool
+		if backoff, restart = p.recordCrash(ins, name, err); !restart {
+			return err
+		}
+	}
+}
+
+func (p *Playground) restartCrashedOnce(ins instance.Instance) error {
+	p.cmdMu.Lock()
+	defer p.cmdMu.Unlock()
+
+	// the instances are started again by resume if they're paused
+	if atomic.LoadInt32(&p.curSig) != 0 || atomic.LoadInt32(&p.paused) == 1 {
+		return nil
+	}
+	found := false
+	_ = p.WalkInstances(func(_ string, inst instance.Instance) error {
+		found = found || inst == ins
+		return nil
+	})
+	if !found {
+		return nil
+	}
+
+	if err := p.startAgain(ins); err != nil {
+		return err
+	}
+	p.crashMu.Lock()
+	p.restarts[ins].restarts++
+	p.crashMu.Unlock()
+	return nil
+}
+
+// sleepUntilBooted sleeps for the duration and until the cluster is booted,
+// it returns false if the playground is quitting
+func (p *Playground) sleepUntilBooted(d time.Duration) bool {
+	const step = 100 * time.Millisecond
+	deadline := time.Now().Add(d)
+	for {
+		if atomic.LoadInt32(&p.curSig) != 0 {
+			return false
+		}
+		if !time.Now().Before(deadline) && atomic.LoadInt32(&p.bootFinished) == 1 {
+			return true
+		}
+		time.Sleep(step)
+	}
+}
+
+// writeCrashLog appends the crash and the last lines of the log of the
+// instance to the crash log in the directory of the instance, it returns the
+// path of the crash log
+func writeCrashLog(ins instance.Instance, name string, crashErr error) (string, error) {
+	logFile := ins.LogFile()
+	if logFile == "" {
+		return "", nil
+	}
+	lines, err := utils.TailN(logFile, crashLogTailLines)
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return "", err
+	}
+
+	file := filepath.Join(filepath.Dir(logFile), crashLogName)
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", errors.AddStack(err)
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s crashed: %s\n", time.Now().Format(time.RFC3339), name, crashErr)
+	fmt.Fprintf(&b, "the last %d lines of %s:\n", len(lines), logFile)
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	if _, err := f.WriteString(b.String()); err != nil {
+		return "", errors.AddStack(err)
+	}
+	return file, nil
+}
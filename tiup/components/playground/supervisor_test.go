@@ -0,0 +1,108 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, restartBackoff(1))
+	assert.Equal(t, 4*time.Second, restartBackoff(3))
+	assert.Equal(t, restartBackoffMax, restartBackoff(10))
+
+	p := NewPlayground(t.TempDir(), 0)
+	ins := &sleepInstance{}
+	now := time.Now()
+	for i := 1; i <= crashLoopLimit; i++ {
+		backoff, restart := p.superviseCrash(ins, now)
+		assert.True(t, restart)
+		assert.Equal(t, restartBackoff(i), backoff)
+	}
+	_, restart := p.superviseCrash(ins, now)
+	assert.False(t, restart)
+
+	// the crashes out of the window are forgotten
+	backoff, restart := p.superviseCrash(ins, now.Add(crashLoopWindow))
+	assert.True(t, restart)
+	assert.Equal(t, restartBackoffBase, backoff)
+}
+
+func TestRestartOnFailure(t *testing.T) {
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = &BootOptions{RestartOnFailure: true}
+	atomic.StoreInt32(&p.bootFinished, 1)
+
+	crashed := &sleepInstance{}
+	assert.Nil(t, crashed.Start(context.TODO(), ""))
+	p.instanceSpecs[crashed] = instanceSpec{Component: "sleep"}
+	p.addWaitInstance(crashed)
+	assert.Nil(t, syscall.Kill(crashed.Pid(), syscall.SIGKILL))
+
+	// the cluster is not failed while the instance is restarting
+	assert.Eventually(t, func() bool {
+		return len(p.crashedInstances()) > 0
+	}, 10*time.Second, 10*time.Millisecond)
+	resp := p.readiness()
+	assert.False(t, resp.Failed)
+	assert.Equal(t, []string{"sleep-0 crashed: signal: killed, it's restarting"}, resp.Reasons)
+	assert.True(t, resp.Crashed[0].Restarting)
+
+	// it's not restarted since it's not in the cluster anymore, and the crash
+	// doesn't fail the playground
+	assert.Nil(t, p.wait())
+	assert.Equal(t, 0, p.restartCount(crashed))
+}
+
+func TestWriteCrashLog(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "tikv.log")
+	var lines []string
+	for i := 0; i < crashLogTailLines+10; i++ {
+		lines = append(lines, "line")
+	}
+	lines = append(lines, "panic")
+	assert.Nil(t, os.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+	ins := &logInstance{sleepInstance{}, logFile}
+	for i := 0; i < 2; i++ {
+		file, err := writeCrashLog(ins, "tikv-0", errors.New("signal: killed"))
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(dir, crashLogName), file)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, crashLogName))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "tikv-0 crashed: signal: killed\n"))
+	assert.Equal(t, 2, strings.Count(string(data), "panic\n"))
+	assert.Equal(t, 2*crashLogTailLines, strings.Count(string(data), "line\n")+2)
+}
+
+// logInstance is a sleepInstance with a log file
+type logInstance struct {
+	sleepInstance
+	logFile string
+}
+
+func (l *logInstance) LogFile() string { return l.logFile }
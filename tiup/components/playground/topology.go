@@ -0,0 +1,221 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// componentConfig is the config of a component in BootOptions
+type componentConfig struct {
+	comp string
+	cfg  *instance.Config
+}
+
+// componentConfigs returns the configs of all components in BootOptions
+func (o *BootOptions) componentConfigs() []componentConfig {
+	return []componentConfig{
+		{spec.ComponentPD, &o.PD},
+		{spec.ComponentTiKV, &o.TiKV},
+		{spec.ComponentPump, &o.Pump},
+		{spec.ComponentTiDB, &o.TiDB},
+		{spec.ComponentCDC, &o.TiCDC},
+		{spec.ComponentDrainer, &o.Drainer},
+		{spec.ComponentTiFlash, &o.TiFlash},
+	}
+}
+
+// resolvePinnedVersions expands the versions pinned for the components and
+// instances the same way as the version of playground, the versions of self
+// built binaries are resolved for the platform every version is available on
+func (o *BootOptions) resolvePinnedVersions() error {
+	resolve := func(comp string, cfg *instance.Config, selfBuilt bool) error {
+		if cfg.Version == "" || semver.IsValid(cfg.Version) {
+			return nil
+		}
+		platform := ""
+		if selfBuilt {
+			platform = selfBuiltPlatform
+		}
+		version, err := resolveComponentVersion(comp, cfg.Version, platform)
+		if err != nil {
+			return errors.Annotatef(err, "can not expand version %s of %s to a valid semver string", cfg.Version, comp)
+		}
+		fmt.Println(color.YellowString("Using the version %s of %s for version constraint \"%s\".", version, comp, cfg.Version))
+		cfg.Version = version.String()
+		return nil
+	}
+
+	for _, c := range o.componentConfigs() {
+		if err := resolve(c.comp, c.cfg, c.cfg.BinPath != ""); err != nil {
+			return err
+		}
+		for i := range c.cfg.Instances {
+			inst := &c.cfg.Instances[i]
+			if err := resolve(c.comp, inst, c.cfg.BinPath != "" || inst.BinPath != ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadTopology reads the boot options from a topology file, the options not
+// set in the file are kept, and the relative paths in it are relative to the
+// directory of the file
+func loadTopology(file string, opt *BootOptions) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return errors.Annotatef(err, "failed to read topology file %s", file)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(opt); err != nil && err != io.EOF {
+		return errors.Annotatef(err, "failed to parse topology file %s", file)
+	}
+
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	dir := filepath.Dir(absFile)
+	resolve := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) && !strings.HasPrefix(*path, "~/") {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+
+	resolve(&opt.InitSQL)
+	resolve(&opt.Import)
+	for _, c := range opt.componentConfigs() {
+		cfg := c.cfg
+		resolve(&cfg.ConfigPath)
+		resolve(&cfg.BinPath)
+		for i := range cfg.Instances {
+			resolve(&cfg.Instances[i].ConfigPath)
+			resolve(&cfg.Instances[i].BinPath)
+		}
+		if len(cfg.Instances) > cfg.Num {
+			cfg.Num = len(cfg.Instances)
+		}
+		if err := validateComponentConfig(c.comp, cfg); err != nil {
+			return errors.Annotatef(err, "invalid topology file %s", file)
+		}
+	}
+	return nil
+}
+
+func validateComponentConfig(comp string, cfg *instance.Config) error {
+	if cfg.Num > 1 && cfg.Port != 0 && comp != spec.ComponentTiDB {
+		return errors.Errorf("the port of %s can't be shared by %d instances, set it in instances", comp, cfg.Num)
+	}
+	if cfg.Num > 1 && cfg.StatusPort != 0 {
+		return errors.Errorf("the status_port of %s can't be shared by %d instances, set it in instances", comp, cfg.Num)
+	}
+
+	if _, err := instance.ParseResources(cfg.MemoryLimit, cfg.CPUQuota); err != nil {
+		return errors.Annotatef(err, "invalid config of %s", comp)
+	}
+
+	hasLabels := len(cfg.Labels) > 0
+	for _, inst := range cfg.Instances {
+		if inst.Num != 0 || inst.UpTimeout != 0 || len(inst.Instances) > 0 {
+			return errors.Errorf("num, up_timeout and instances of %s can't be set for an instance", comp)
+		}
+		if _, err := instance.ParseResources(inst.MemoryLimit, inst.CPUQuota); err != nil {
+			return errors.Annotatef(err, "invalid config of %s", comp)
+		}
+		hasLabels = hasLabels || len(inst.Labels) > 0
+	}
+	if hasLabels && comp != spec.ComponentTiKV {
+		return errors.Errorf("labels are only supported by tikv, but they're set for %s", comp)
+	}
+	return nil
+}
+
+// handleDump writes the running topology in the format of the topology file
+func (p *Playground) handleDump(w io.Writer) error {
+	opts := *p.bootOptions
+	configs := make(map[string]*instance.Config)
+	for _, c := range opts.componentConfigs() {
+		c.cfg.Num = 0
+		c.cfg.Port = 0
+		c.cfg.StatusPort = 0
+		c.cfg.Instances = nil
+		configs[c.comp] = c.cfg
+	}
+
+	err := p.WalkInstances(func(cid string, ins instance.Instance) error {
+		cfg, ok := configs[cid]
+		if !ok {
+			return errors.Errorf("unknown component %s", cid)
+		}
+
+		// only the fields different from the component are kept
+		inst := p.instanceSpecs[ins].Config
+		ports := ins.Ports()
+		inst.Num = 0
+		inst.UpTimeout = 0
+		inst.Port = ports["port"]
+		inst.StatusPort = ports["status_port"]
+		if inst.ConfigPath == cfg.ConfigPath {
+			inst.ConfigPath = ""
+		}
+		if inst.BinPath == cfg.BinPath {
+			inst.BinPath = ""
+		}
+		if inst.Version == cfg.Version {
+			inst.Version = ""
+		}
+		if inst.Host == cfg.Host {
+			inst.Host = ""
+		}
+		if reflect.DeepEqual(inst.Labels, cfg.Labels) {
+			inst.Labels = nil
+		}
+		if inst.MemoryLimit == cfg.MemoryLimit {
+			inst.MemoryLimit = ""
+		}
+		if inst.CPUQuota == cfg.CPUQuota {
+			inst.CPUQuota = ""
+		}
+
+		cfg.Num++
+		cfg.Instances = append(cfg.Instances, inst)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&opts)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	_, err = w.Write(data)
+	return err
+}
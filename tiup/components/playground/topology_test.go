@@ -0,0 +1,135 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/stretchr/testify/assert"
+)
+
+const playgroundTopology = `
+version: v5.0.1
+host: 127.0.0.1
+init_sql: init.sql
+tikv:
+  config_path: tikv.toml
+  memory_limit: 2G
+  instances:
+    - labels: { zone: z1 }
+      port: 20260
+      status_port: 20280
+    - labels: { zone: z2 }
+      bin_path: bin/tikv-server
+    - labels: { zone: z3 }
+      host: 127.0.0.2
+      memory_limit: 1G
+tidb:
+  num: 2
+  port: 4100
+`
+
+func writeTopology(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "playground.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestLoadTopology(t *testing.T) {
+	file := writeTopology(t, playgroundTopology)
+	dir := filepath.Dir(file)
+
+	// the options not set in the file are kept
+	opt := &BootOptions{Monitor: true, PD: instance.Config{Num: 1}, TiKV: instance.Config{Num: 1}}
+	assert.Nil(t, loadTopology(file, opt))
+	assert.Equal(t, "v5.0.1", opt.Version)
+	assert.True(t, opt.Monitor)
+	assert.Equal(t, 1, opt.PD.Num)
+	assert.Equal(t, 3, opt.TiKV.Num)
+	assert.Equal(t, filepath.Join(dir, "tikv.toml"), opt.TiKV.ConfigPath)
+	assert.Equal(t, filepath.Join(dir, "init.sql"), opt.InitSQL)
+
+	cfgs := opt.TiKV.InstanceConfigs()
+	assert.Len(t, cfgs, 3)
+	assert.Equal(t, map[string]string{"zone": "z1"}, cfgs[0].Labels)
+	assert.Equal(t, 20260, cfgs[0].Port)
+	assert.Equal(t, 20280, cfgs[0].StatusPort)
+	assert.Equal(t, filepath.Join(dir, "tikv.toml"), cfgs[1].ConfigPath)
+	assert.Equal(t, filepath.Join(dir, "bin", "tikv-server"), cfgs[1].BinPath)
+	assert.Equal(t, "127.0.0.2", cfgs[2].Host)
+	assert.Equal(t, 0, cfgs[2].Port)
+	assert.Equal(t, "2G", cfgs[0].MemoryLimit)
+	assert.Equal(t, "1G", cfgs[2].MemoryLimit)
+
+	// the port of TiDB is the preferred one of all instances
+	cfgs = opt.TiDB.InstanceConfigs()
+	assert.Len(t, cfgs, 2)
+	assert.Equal(t, 4100, cfgs[1].Port)
+
+	for _, content := range []string{
+		"tikv:\n  num: 2\n  port: 20160\n",
+		"pd:\n  instances:\n    - {}\n    - {}\n  status_port: 2379\n",
+		"tidb:\n  labels: { zone: z1 }\n",
+		"tikv:\n  instances:\n    - num: 2\n",
+		"tikv:\n  memory_limit: 2GB\n",
+		"tidb:\n  instances:\n    - cpu_quota: 2\n",
+		"unknown: 1\n",
+	} {
+		assert.NotNil(t, loadTopology(writeTopology(t, content), &BootOptions{}), content)
+	}
+}
+
+func TestDumpTopology(t *testing.T) {
+	opt := &BootOptions{}
+	assert.Nil(t, loadTopology(writeTopology(t, playgroundTopology), opt))
+	opt.PD.Num = 1
+
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = opt
+	for _, c := range opt.componentConfigs() {
+		for _, cfg := range c.cfg.InstanceConfigs() {
+			_, err := p.addInstance(c.comp, cfg)
+			assert.Nil(t, err)
+		}
+	}
+	assert.Equal(t, map[string]string{"zone": "z2"}, p.tikvs[1].Labels)
+	assert.Equal(t, 20260, p.tikvs[0].Port)
+	assert.Equal(t, instance.Resources{MemoryLimit: 1 << 30}, p.tikvs[2].Resources)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, p.handleDump(buf))
+
+	// the dumped topology boots the same instances
+	dumped := &BootOptions{}
+	assert.Nil(t, loadTopology(writeTopology(t, buf.String()), dumped))
+	assert.Equal(t, "v5.0.1", dumped.Version)
+	assert.Equal(t, 1, dumped.PD.Num)
+	assert.Equal(t, 3, dumped.TiKV.Num)
+	assert.Equal(t, opt.TiKV.ConfigPath, dumped.TiKV.ConfigPath)
+	assert.Equal(t, "", dumped.TiKV.Instances[0].ConfigPath)
+	assert.Equal(t, 20260, dumped.TiKV.Instances[0].Port)
+	assert.Equal(t, p.tikvs[1].Port, dumped.TiKV.Instances[1].Port)
+	assert.Equal(t, opt.TiKV.Instances[1].BinPath, dumped.TiKV.Instances[1].BinPath)
+	assert.Equal(t, "127.0.0.2", dumped.TiKV.Instances[2].Host)
+	assert.Equal(t, map[string]string{"zone": "z3"}, dumped.TiKV.Instances[2].Labels)
+	assert.Equal(t, "2G", dumped.TiKV.MemoryLimit)
+	assert.Equal(t, "", dumped.TiKV.Instances[0].MemoryLimit)
+	assert.Equal(t, "1G", dumped.TiKV.Instances[2].MemoryLimit)
+	assert.Equal(t, 2, dumped.TiDB.Num)
+	assert.Equal(t, 0, dumped.TiFlash.Num)
+}
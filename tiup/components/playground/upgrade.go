@@ -0,0 +1,231 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	pgapi "github.com/pingcap/tiup/components/playground/api"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+// the time to wait for an upgraded instance up, except the ones with their
+// own up timeouts
+const upgradeUpTimeout = 120
+
+func newUpgrade() *cobra.Command {
+	var binPath string
+	cmd := &cobra.Command{
+		Use:   "upgrade <component> <version>",
+		Short: "Upgrade the instances of a component one by one",
+		Long: `Upgrade the instances of a component one by one, each instance is restarted
+with the binary of the version and its data is kept. The next instance is
+restarted after the previous one is up.
+
+The leaders of a TiKV are evicted before it's restarted, and so is the leader
+of PD. The component is one of pd, tikv, tidb, tiflash, ticdc, pump and drainer.`,
+		Example: `  tiup playground upgrade tidb v5.1.0
+  tiup playground upgrade tikv ^5
+  tiup playground upgrade tikv nightly --binpath /path/to/tikv-server`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+			comp := args[0]
+			if comp == "ticdc" {
+				comp = spec.ComponentCDC
+			}
+			if binPath != "" {
+				path, err := getAbsolutePath(binPath)
+				if err != nil {
+					return err
+				}
+				binPath = path
+			}
+
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			msg, err := client.Upgrade(context.Background(), comp, pgapi.UpgradeRequest{
+				Version: args[1],
+				BinPath: binPath,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Print(msg)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&binPath, "binpath", "", "The binary to upgrade to, it's used instead of the binary of the version")
+	return cmd
+}
+
+func (p *Playground) handleUpgrade(w io.Writer, cid, version, binPath string) error {
+	var cfg *instance.Config
+	for _, c := range p.bootOptions.componentConfigs() {
+		if c.comp == cid {
+			cfg = c.cfg
+		}
+	}
+	if cfg == nil {
+		return errors.Errorf("unknown component %s", cid)
+	}
+	if version == "" {
+		return errors.New("the version to upgrade to is not set")
+	}
+
+	var insts []instance.Instance
+	_ = p.WalkInstances(func(wcid string, ins instance.Instance) error {
+		if wcid == cid {
+			insts = append(insts, ins)
+		}
+		return nil
+	})
+	if len(insts) == 0 {
+		return errors.Errorf("no instance of %s", cid)
+	}
+
+	p.faultMu.Lock()
+	for _, ins := range insts {
+		if f, ok := p.faults[ins]; ok {
+			p.faultMu.Unlock()
+			return errors.Errorf("%s(%d) has the fault %s, clear it first", cid, ins.Pid(), f.Kind)
+		}
+	}
+	p.faultMu.Unlock()
+
+	// the version is expanded the same way as the one of playground
+	if !semver.IsValid(version) {
+		platform := ""
+		if binPath != "" {
+			platform = selfBuiltPlatform
+		}
+		v, err := resolveComponentVersion(cid, version, platform)
+		if err != nil {
+			return errors.Annotatef(err, "can not expand version %s of %s to a valid semver string", version, cid)
+		}
+		version = v.String()
+	}
+
+	// download the binary before stopping any instance
+	if binPath == "" {
+		env := environment.GlobalEnv()
+		if _, err := env.DownloadComponentIfMissing(cid, utils.Version(version)); err != nil {
+			return err
+		}
+	}
+
+	for i, ins := range insts {
+		msg := fmt.Sprintf("Upgrading %s(%d) to %s (%d/%d)", cid, ins.Pid(), version, i+1, len(insts))
+		fmt.Println(msg)
+		fmt.Fprintln(w, msg)
+
+		if err := p.upgradeInstance(cid, ins, version, binPath); err != nil {
+			return errors.Annotatef(err, "failed to upgrade %s(%d)", cid, ins.Pid())
+		}
+	}
+
+	// the new instances are scaled out with the version
+	cfg.Version = version
+	cfg.BinPath = binPath
+	logIfErr(p.renderSDFile())
+
+	msg := color.GreenString("%d instances of %s upgraded to %s", len(insts), cid, version)
+	fmt.Println(msg)
+	fmt.Fprintln(w, msg)
+	return nil
+}
+
+// upgradeInstance restarts the instance with the binary of the version, and
+// waits for it up
+func (p *Playground) upgradeInstance(cid string, ins instance.Instance, version, binPath string) error {
+	pdClient := p.pdClient()
+
+	switch inst := ins.(type) {
+	case *instance.PDInstance:
+		if leader, err := pdClient.GetLeader(); err == nil && leader.Name == inst.Name() {
+			logIfErr(pdClient.EvictPDLeader(timeoutOpt))
+		}
+	case *instance.TiKVInstance:
+		evictOpt := &utils.RetryOption{Delay: time.Second, Timeout: time.Minute}
+		countLeader := func(string) (int, error) {
+			store, err := pdClient.GetCurrentStore(inst.StoreAddr())
+			if err != nil || store.Status == nil {
+				return 0, err
+			}
+			return store.Status.LeaderCount, nil
+		}
+		logIfErr(pdClient.EvictStoreLeader(inst.StoreAddr(), evictOpt, countLeader))
+		defer func() {
+			logIfErr(pdClient.RemoveStoreEvict(inst.StoreAddr()))
+		}()
+	}
+
+	p.faultMu.Lock()
+	s := p.instanceSpecs[ins]
+	s.Config.Version = version
+	s.Config.BinPath = binPath
+	p.instanceSpecs[ins] = s
+	ins.SetBinPath(binPath)
+	err := p.restartInstance(ins)
+	p.faultMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	up := false
+	switch inst := ins.(type) {
+	case *instance.TiDBInstance:
+		if up = checkDB(inst.Addr(), p.bootOptions.TiDB.UpTimeout); up {
+			limitTiDBMemory(inst, p.instanceVersion(inst))
+		}
+	case *instance.TiKVInstance:
+		up = checkStoreStatus(pdClient, inst.StoreAddr(), upgradeUpTimeout)
+	case *instance.TiFlashInstance:
+		up = checkStoreStatus(pdClient, inst.Addr(), p.bootOptions.TiFlash.UpTimeout)
+	case interface{ Addr() string }:
+		up = checkPort(inst.Addr(), upgradeUpTimeout)
+	}
+	if !up {
+		return errors.Errorf("%s is not up after restarted, check detail log from: %s", cid, ins.LogFile())
+	}
+	return nil
+}
+
+// checkPort checks if the addr accepts connections in the timeout seconds
+func checkPort(addr string, timeout int) bool {
+	for i := 0; i < timeout; i++ {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		time.Sleep(time.Second)
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/components/playground/instance"
+	"github.com/pingcap/tiup/pkg/environment"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeResolver resolves the version constraints by the table, and records
+// the platforms they are resolved for
+func fakeResolver(t *testing.T, versions map[string]string) map[string]string {
+	platforms := make(map[string]string)
+	resolve := resolveComponentVersion
+	resolveComponentVersion = func(component, constraint, platform string) (utils.Version, error) {
+		v, ok := versions[component+"@"+constraint]
+		if !ok {
+			return "", errors.Errorf("no version of %s matches %s", component, constraint)
+		}
+		platforms[component+"@"+constraint] = platform
+		return utils.Version(v), nil
+	}
+	t.Cleanup(func() { resolveComponentVersion = resolve })
+	return platforms
+}
+
+func TestResolvePinnedVersions(t *testing.T) {
+	platforms := fakeResolver(t, map[string]string{
+		"tidb@^5":      "v5.4.3",
+		"tikv@nightly": "v7.0.0-alpha-nightly-20230101",
+		"tikv@~6.1":    "v6.1.7",
+		"tiflash@^5.1": "v5.1.5",
+	})
+
+	opt := &BootOptions{}
+	assert.Nil(t, loadTopology(writeTopology(t, `
+version: v5.0.1
+tidb:
+  num: 1
+  version: ^5
+tikv:
+  version: nightly
+  instances:
+    - {}
+    - version: ~6.1
+    - version: v6.1.0
+tiflash:
+  num: 1
+  version: ^5.1
+  bin_path: /path/to/tiflash
+`), opt))
+	assert.Nil(t, opt.resolvePinnedVersions())
+	assert.Equal(t, "v5.4.3", opt.TiDB.Version)
+	assert.Equal(t, "v7.0.0-alpha-nightly-20230101", opt.TiKV.Version)
+	assert.Equal(t, "", opt.TiKV.Instances[0].Version)
+	assert.Equal(t, "v6.1.7", opt.TiKV.Instances[1].Version)
+	assert.Equal(t, "v6.1.0", opt.TiKV.Instances[2].Version)
+	assert.Equal(t, "v5.1.5", opt.TiFlash.Version)
+	assert.Equal(t, "", opt.PD.Version)
+
+	// the self built binaries are resolved for the platform every version is on
+	assert.Equal(t, "", platforms["tidb@^5"])
+	assert.Equal(t, selfBuiltPlatform, platforms["tiflash@^5.1"])
+	_, resolved := platforms["tikv@v6.1.0"]
+	assert.False(t, resolved)
+
+	opt.PD.Version = "^9"
+	err := opt.resolvePinnedVersions()
+	assert.Contains(t, err.Error(), "can not expand version ^9 of pd")
+}
+
+func TestUpgradeMixedVersions(t *testing.T) {
+	fakeResolver(t, map[string]string{"pump@^5": "v5.4.3"})
+
+	// the binaries are started with the profile of a fake environment
+	dir := t.TempDir()
+	env := &environment.Environment{}
+	env.SetProfile(localdata.NewProfile(filepath.Join(dir, "home"), &localdata.TiUPConfig{}))
+	environment.SetGlobalEnv(env)
+	defer environment.SetGlobalEnv(nil)
+
+	binPath := filepath.Join(dir, "pump-server")
+	assert.Nil(t, os.WriteFile(binPath, []byte("#!/bin/sh\nexec sleep 60\n"), 0755))
+
+	opt := &BootOptions{}
+	assert.Nil(t, loadTopology(writeTopology(t, `
+version: v5.0.1
+tidb:
+  version: v5.1.0
+pump:
+  num: 2
+  bin_path: `+binPath+`
+  instances:
+    - {}
+    - version: v4.0.16
+`), opt))
+
+	p := NewPlayground(filepath.Join(dir, "data"), 0)
+	p.bootOptions = opt
+	for _, cfg := range opt.Pump.InstanceConfigs() {
+		ins, err := p.addInstance("pump", cfg)
+		assert.Nil(t, err)
+		assert.Nil(t, ins.Start(context.TODO(), p.instanceVersion(ins)))
+		p.addWaitInstance(ins)
+
+		// the fake binary doesn't listen, the port is taken by the test
+		l, err := net.Listen("tcp", ins.(*instance.Pump).Addr())
+		assert.Nil(t, err)
+		defer l.Close()
+	}
+	defer func() {
+		atomic.StoreInt32(&p.curSig, int32(syscall.SIGKILL))
+		p.terminate(syscall.SIGKILL)
+		_ = p.wait()
+	}()
+	assert.Equal(t, utils.Version("v5.0.1"), p.instanceVersion(p.pumps[0]))
+	assert.Equal(t, utils.Version("v4.0.16"), p.instanceVersion(p.pumps[1]))
+	pids := []int{p.pumps[0].Pid(), p.pumps[1].Pid()}
+	// the waiters wait on the processes before the instances are restarted,
+	// as an instance is never upgraded right after it's started
+	time.Sleep(200 * time.Millisecond)
+
+	w := new(bytes.Buffer)
+	assert.Nil(t, p.handleUpgrade(w, "pump", "^5", binPath))
+	assert.Contains(t, w.String(), "2 instances of pump upgraded to v5.4.3")
+	for i, ins := range p.pumps {
+		assert.NotEqual(t, pids[i], ins.Pid())
+		assert.Equal(t, utils.Version("v5.4.3"), p.instanceVersion(ins))
+	}
+
+	// the other components keep their versions, and the new instances are
+	// scaled out with the version upgraded to
+	assert.Equal(t, "v5.1.0", opt.TiDB.Version)
+	assert.Equal(t, "", opt.PD.Version)
+	assert.Equal(t, "v5.4.3", opt.Pump.Version)
+	cfg := instance.Config{}
+	assert.Nil(t, p.sanitizeComponentConfig("pump", &cfg))
+	assert.Equal(t, "v5.4.3", cfg.Version)
+
+	err := p.handleUpgrade(w, "pump", "^9", binPath)
+	assert.Contains(t, err.Error(), "can not expand version ^9 of pump")
+}
+
+func TestInstanceVersion(t *testing.T) {
+	opt := &BootOptions{}
+	assert.Nil(t, loadTopology(writeTopology(t, `
+version: v5.0.1
+tidb:
+  num: 1
+  version: v5.1.0
+tikv:
+  instances:
+    - {}
+    - version: nightly
+`), opt))
+	opt.PD.Num = 1
+
+	p := NewPlayground(t.TempDir(), 0)
+	p.bootOptions = opt
+	for _, c := range opt.componentConfigs() {
+		for _, cfg := range c.cfg.InstanceConfigs() {
+			_, err := p.addInstance(c.comp, cfg)
+			assert.Nil(t, err)
+		}
+	}
+	assert.Equal(t, utils.Version("v5.0.1"), p.instanceVersion(p.pds[0]))
+	assert.Equal(t, utils.Version("v5.1.0"), p.instanceVersion(p.tidbs[0]))
+	assert.Equal(t, utils.Version("v5.0.1"), p.instanceVersion(p.tikvs[0]))
+	assert.Equal(t, utils.Version("nightly"), p.instanceVersion(p.tikvs[1]))
+
+	// the instances scaled out use the version of the component
+	cfg := instance.Config{}
+	assert.Nil(t, p.sanitizeComponentConfig("tidb", &cfg))
+	assert.Equal(t, "v5.1.0", cfg.Version)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, p.handleDump(buf))
+	dumped := &BootOptions{}
+	assert.Nil(t, loadTopology(writeTopology(t, buf.String()), dumped))
+	assert.Equal(t, "v5.1.0", dumped.TiDB.Version)
+	assert.Equal(t, "", dumped.TiKV.Instances[0].Version)
+	assert.Equal(t, "nightly", dumped.TiKV.Instances[1].Version)
+
+	w := new(bytes.Buffer)
+	assert.NotNil(t, p.handleUpgrade(w, "tidb", "", ""))
+	assert.NotNil(t, p.handleUpgrade(w, "tispark", "v5.1.0", ""))
+	err := p.handleUpgrade(w, "tiflash", "v5.1.0", "")
+	assert.Contains(t, err.Error(), "no instance of tiflash")
+}
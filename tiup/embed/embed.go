@@ -2,6 +2,9 @@ package embed
 
 import (
 	goembed "embed"
+	"errors"
+	"io/fs"
+	"path"
 )
 
 //go:embed templates
@@ -19,3 +22,38 @@ var embedExamples goembed.FS
 func ReadExample(path string) ([]byte, error) {
 	return embedExamples.ReadFile(path)
 }
+
+//go:embed schemas
+var embedSchemas goembed.FS
+
+// ReadSchema read the config schema file embed
+func ReadSchema(path string) ([]byte, error) {
+	return embedSchemas.ReadFile(path)
+}
+
+// ListSchemas returns the file names of the config schemas of the component,
+// nil if there is no schema for the component
+func ListSchemas(component string) ([]string, error) {
+	entries, err := embedSchemas.ReadDir(path.Join("schemas", component))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+//go:embed k8s
+var embedK8s goembed.FS
+
+// ReadK8sManifest read the bundled Kubernetes manifest, e.g. the CRD schemas
+func ReadK8sManifest(path string) ([]byte, error) {
+	return embedK8s.ReadFile(path)
+}
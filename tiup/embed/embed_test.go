@@ -72,3 +72,45 @@ func (s *embedSuite) TestCanReadExamples(c *check.C) {
 		c.Assert(embedData, check.BytesEquals, data)
 	}
 }
+
+// Test can read all file in /schemas
+func (s *embedSuite) TestCanReadSchemas(c *check.C) {
+	paths, err := getAllFilePaths("schemas")
+	c.Assert(err, check.IsNil)
+	c.Assert(len(paths), check.Greater, 0)
+
+	for _, path := range paths {
+		c.Log("check file: ", path)
+
+		data, err := os.ReadFile(path)
+		c.Assert(err, check.IsNil)
+
+		embedData, err := ReadSchema(path)
+		c.Assert(err, check.IsNil)
+
+		c.Assert(embedData, check.BytesEquals, data)
+
+		names, err := ListSchemas(filepath.Base(filepath.Dir(path)))
+		c.Assert(err, check.IsNil)
+		c.Assert(names, check.Not(check.HasLen), 0)
+	}
+}
+
+// Test can read all file in /k8s
+func (s *embedSuite) TestCanReadK8sManifests(c *check.C) {
+	paths, err := getAllFilePaths("k8s")
+	c.Assert(err, check.IsNil)
+	c.Assert(len(paths), check.Greater, 0)
+
+	for _, path := range paths {
+		c.Log("check file: ", path)
+
+		data, err := os.ReadFile(path)
+		c.Assert(err, check.IsNil)
+
+		embedData, err := ReadK8sManifest(path)
+		c.Assert(err, check.IsNil)
+
+		c.Assert(embedData, check.BytesEquals, data)
+	}
+}
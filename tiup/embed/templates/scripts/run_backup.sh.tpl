@@ -0,0 +1,38 @@
+#!/bin/bash
+set -e
+
+# WARNING: This file was auto-generated. Do not edit!
+#          All your edit might be overwritten!
+DEPLOY_DIR={{.DeployDir}}
+cd "${DEPLOY_DIR}" || exit 1
+
+exec >> "{{.LogDir}}/backup.log"
+exec 2>&1
+
+{{- if not .PruneOnly}}
+
+NAME=$(date +%Y%m%d-%H%M%S)
+echo "[$(date)] start backup ${NAME}"
+
+bin/br backup full \
+    --pd "{{.PD}}" \
+{{- if .TLSEnabled}}
+    --ca tls/ca.crt \
+    --cert tls/backup.crt \
+    --key tls/backup.pem \
+{{- end}}
+{{- range .Filters}}
+    --filter '{{.}}' \
+{{- end}}
+    --storage "{{.Storage}}" \
+    --log-file "{{.LogDir}}/br-${NAME}.log"
+
+echo "[$(date)] finish backup ${NAME}"
+{{- end}}
+{{- if and .Retention .LocalPath}}
+
+# keep the latest {{.Retention}} backups, the files of a backup in local
+# storage are written on every TiKV host, so they're pruned on each of them
+echo "[$(date)] prune backups in {{.LocalPath}}"
+ls -1d "{{.LocalPath}}"/*/ | sort | head -n -{{.Retention}} | xargs -r rm -rf
+{{- end}}
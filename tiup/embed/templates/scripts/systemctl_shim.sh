@@ -0,0 +1,226 @@
+#!/bin/sh
+# tiup-systemctl-shim v1
+#
+# WARNING: This file was auto-generated by tiup for the containers running
+#          without systemd. Do not edit!
+#
+# It supervises the services defined by the unit files in /etc/systemd/system,
+# only the subset of systemctl used by tiup is supported:
+#   daemon-reload, start, stop, restart, enable, disable, status, is-active,
+#   is-enabled and list-unit-files
+
+UNIT_DIR=/etc/systemd/system
+WANTS_DIR="$UNIT_DIR/multi-user.target.wants"
+STATE_DIR=/var/run/tiup-systemd
+STOP_TIMEOUT=90
+
+mkdir -p "$STATE_DIR"
+
+unit_name() {
+    case "$1" in
+        *.service|*.timer|*.target) echo "$1" ;;
+        *) echo "$1.service" ;;
+    esac
+}
+
+# unit_prop <unit file> <key> prints the value of the key in the unit file
+unit_prop() {
+    sed -n "s/^$2=//p" "$1" | head -n 1
+}
+
+# main_pid <unit> prints the pid of the supervisor if the unit is running
+main_pid() {
+    pidfile="$STATE_DIR/$1.pid"
+    [ -f "$pidfile" ] || return 1
+    pid=$(cat "$pidfile")
+    [ -n "$pid" ] && kill -0 "$pid" 2>/dev/null && echo "$pid"
+}
+
+is_enabled() {
+    [ -e "$WANTS_DIR/$1" ]
+}
+
+start_unit() {
+    unit=$1
+    file="$UNIT_DIR/$unit"
+    if [ ! -f "$file" ]; then
+        echo "Failed to start $unit: Unit $unit not found." >&2
+        return 5
+    fi
+    case "$unit" in
+        *.timer)
+            echo "Timers are not supported without systemd, $unit is not scheduled." >&2
+            return 0
+            ;;
+    esac
+    main_pid "$unit" >/dev/null && return 0
+
+    exec_start=$(unit_prop "$file" ExecStart)
+    user=$(unit_prop "$file" User)
+    restart=$(unit_prop "$file" Restart)
+    restart_sec=$(unit_prop "$file" RestartSec | sed 's/s$//')
+    [ -n "$user" ] || user=root
+    [ -n "$restart_sec" ] || restart_sec=1
+    pidfile="$STATE_DIR/$unit.pid"
+    rm -f "$pidfile"
+
+    # the supervisor runs in its own session, so that it's kept after the
+    # exec session ends, and is stopped along with the service by killing
+    # the process group
+    setsid sh -c '
+        echo $$ > "$1"
+        ulimit -n 1000000 2>/dev/null
+        while :; do
+            su -s /bin/sh "$3" -c "exec $2"
+            code=$?
+            case "$4" in
+                always) ;;
+                on-failure|on-abnormal) [ $code -ne 0 ] || break ;;
+                *) break ;;
+            esac
+            sleep "$5"
+        done
+        rm -f "$1"
+    ' "$pidfile" "$exec_start" "$user" "$restart" "$restart_sec" \
+        </dev/null >>"$STATE_DIR/$unit.log" 2>&1 &
+
+    i=0
+    while [ ! -s "$pidfile" ]; do
+        i=$((i + 1))
+        if [ $i -gt 50 ]; then
+            echo "Failed to start $unit: the supervisor is not started." >&2
+            return 1
+        fi
+        sleep 0.1
+    done
+    date '+%a %Y-%m-%d %H:%M:%S %Z' > "$STATE_DIR/$unit.since"
+}
+
+stop_unit() {
+    unit=$1
+    if [ ! -f "$UNIT_DIR/$unit" ] && [ ! -f "$STATE_DIR/$unit.pid" ]; then
+        echo "Failed to stop $unit: Unit $unit not loaded." >&2
+        return 5
+    fi
+    if pid=$(main_pid "$unit"); then
+        kill -TERM -"$pid" 2>/dev/null
+        i=0
+        while kill -0 -"$pid" 2>/dev/null; do
+            i=$((i + 1))
+            if [ $i -gt $STOP_TIMEOUT ]; then
+                if [ "$(unit_prop "$UNIT_DIR/$unit" SendSIGKILL)" != "no" ]; then
+                    kill -KILL -"$pid" 2>/dev/null
+                fi
+                break
+            fi
+            sleep 1
+        done
+    fi
+    rm -f "$STATE_DIR/$unit.pid" "$STATE_DIR/$unit.since"
+}
+
+status_unit() {
+    unit=$1
+    file="$UNIT_DIR/$unit"
+    if [ ! -f "$file" ]; then
+        echo "Unit $unit could not be found." >&2
+        return 4
+    fi
+    enabled=disabled
+    is_enabled "$unit" && enabled=enabled
+    echo "● $unit - $(unit_prop "$file" Description)"
+    echo "   Loaded: loaded ($file; $enabled; vendor preset: disabled)"
+    if pid=$(main_pid "$unit"); then
+        echo "   Active: active (running) since $(cat "$STATE_DIR/$unit.since"); supervised by tiup"
+        echo " Main PID: $pid"
+        return 0
+    fi
+    echo "   Active: inactive (dead)"
+    return 3
+}
+
+# skip the options, the signal and scope options are ignored
+while [ $# -gt 0 ]; do
+    case "$1" in
+        --signal|-s) shift 2 ;;
+        -*) shift ;;
+        *) break ;;
+    esac
+done
+
+action=$1
+[ $# -gt 0 ] && shift
+
+code=0
+case "$action" in
+    daemon-reload)
+        ;;
+    start|stop|restart|status)
+        for u in "$@"; do
+            unit=$(unit_name "$u")
+            case "$action" in
+                start) start_unit "$unit" ;;
+                stop) stop_unit "$unit" ;;
+                restart) stop_unit "$unit" 2>/dev/null; start_unit "$unit" ;;
+                status) status_unit "$unit" ;;
+            esac
+            rc=$?
+            [ $rc -eq 0 ] || code=$rc
+        done
+        ;;
+    is-active)
+        for u in "$@"; do
+            if main_pid "$(unit_name "$u")" >/dev/null; then
+                echo active
+            else
+                echo inactive
+                code=3
+            fi
+        done
+        ;;
+    enable|disable)
+        mkdir -p "$WANTS_DIR"
+        for u in "$@"; do
+            unit=$(unit_name "$u")
+            if [ "$action" = enable ]; then
+                if [ ! -f "$UNIT_DIR/$unit" ]; then
+                    echo "Failed to enable unit: Unit file $unit does not exist." >&2
+                    code=1
+                    continue
+                fi
+                is_enabled "$unit" || echo "Created symlink $WANTS_DIR/$unit → $UNIT_DIR/$unit." >&2
+                ln -sf "$UNIT_DIR/$unit" "$WANTS_DIR/$unit"
+            elif is_enabled "$unit"; then
+                rm -f "$WANTS_DIR/$unit"
+                echo "Removed symlink $WANTS_DIR/$unit." >&2
+            fi
+        done
+        ;;
+    is-enabled)
+        for u in "$@"; do
+            if is_enabled "$(unit_name "$u")"; then
+                echo enabled
+            else
+                echo disabled
+                code=1
+            fi
+        done
+        ;;
+    list-unit-files)
+        echo "UNIT FILE STATE"
+        for file in "$UNIT_DIR"/*.service "$UNIT_DIR"/*.timer; do
+            [ -f "$file" ] || continue
+            unit=$(basename "$file")
+            if is_enabled "$unit"; then
+                echo "$unit enabled"
+            else
+                echo "$unit disabled"
+            fi
+        done
+        ;;
+    *)
+        echo "Unknown operation '$action', it's not supported in containers without systemd." >&2
+        code=1
+        ;;
+esac
+exit $code
@@ -0,0 +1,8 @@
+[Unit]
+Description={{.ServiceName}} service
+After=syslog.target network.target remote-fs.target nss-lookup.target
+
+[Service]
+Type=oneshot
+User={{.User}}
+ExecStart={{.DeployDir}}/scripts/run_backup.sh
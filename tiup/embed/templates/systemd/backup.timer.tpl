@@ -0,0 +1,9 @@
+[Unit]
+Description={{.ServiceName}} timer
+
+[Timer]
+OnCalendar={{.Schedule}}
+Unit={{.ServiceName}}.service
+
+[Install]
+WantedBy=timers.target
@@ -0,0 +1,149 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	taskPointMsg = "TaskCheckPoint"
+	taskKey      = "task"
+	errorKey     = "error"
+
+	metaPointMsg = "MetaCheckPoint"
+	metaHashKey  = "meta_hash"
+)
+
+// Step is a task recorded in the audit log
+type Step struct {
+	Index int    // the 1-based position of the step in the audit log
+	Task  string // identity of the task
+	Err   string // the error of the task, empty if it finished
+}
+
+// Done returns if the step finished successfully
+func (s *Step) Done() bool {
+	return s.Err == ""
+}
+
+// ParseSteps returns the tasks recorded in the audit log in the order of execution
+func ParseSteps(r io.Reader) ([]*Step, error) {
+	steps := make([]*Step, 0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxTokenSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		ss := strings.Fields(line)
+		pos := strings.Index(line, "{")
+		if len(ss) < 4 || ss[2] != taskPointMsg || pos == -1 {
+			continue
+		}
+
+		m := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line[pos:]), &m); err != nil {
+			return nil, errors.AddStack(err)
+		}
+		step := &Step{Index: len(steps) + 1}
+		step.Task, _ = m[taskKey].(string)
+		step.Err, _ = m[errorKey].(string)
+		steps = append(steps, step)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Annotate(err, "failed to parse steps in audit file")
+	}
+	return steps, nil
+}
+
+// ParseMetaHash returns the hash of the cluster meta recorded in the audit log
+// when the operation ended, it's empty if the hash is not recorded
+func ParseMetaHash(r io.Reader) (string, error) {
+	hash := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxTokenSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		ss := strings.Fields(line)
+		pos := strings.Index(line, "{")
+		if len(ss) < 4 || ss[2] != metaPointMsg || pos == -1 {
+			continue
+		}
+
+		m := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line[pos:]), &m); err != nil {
+			return "", errors.AddStack(err)
+		}
+		hash, _ = m[metaHashKey].(string)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Annotate(err, "failed to parse meta hash in audit file")
+	}
+	return hash, nil
+}
+
+// RecordMetaHash writes the hash of the cluster meta into the audit log, so
+// the replay of the operation can tell if the meta is changed after it
+func RecordMetaHash(hash string) {
+	zap.L().Info(metaPointMsg, zap.String(metaHashKey, hash))
+}
+
+var (
+	taskMu sync.Mutex
+	// the number of times each task can still be skipped
+	skipTasks map[string]int
+)
+
+// SkipSteps sets the steps to skip when the tasks are executed again, a task
+// executed more than once is skipped as many times as it's in the steps
+func SkipSteps(steps []*Step) {
+	taskMu.Lock()
+	defer taskMu.Unlock()
+
+	skipTasks = make(map[string]int)
+	for _, s := range steps {
+		skipTasks[s.Task]++
+	}
+}
+
+// AcquireTask returns true if the task should be skipped since it's finished
+// in the replayed audit log
+func AcquireTask(task string) bool {
+	taskMu.Lock()
+	defer taskMu.Unlock()
+
+	if skipTasks[task] > 0 {
+		skipTasks[task]--
+		return true
+	}
+	return false
+}
+
+// ReleaseTask writes the result of the task into the audit log, skipped tasks
+// are recorded as finished so the audit log of a replay can be replayed again
+func ReleaseTask(task string, skipped bool, err error) {
+	if err != nil {
+		zap.L().Error(taskPointMsg, zap.String(taskKey, task), zap.String(errorKey, err.Error()))
+		return
+	}
+	zap.L().Info(taskPointMsg, zap.String(taskKey, task), zap.Bool("skipped", skipped))
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSteps(t *testing.T) {
+	assert := require.New(t)
+	r := strings.NewReader(`/usr/bin/tiup-cluster deploy test v5.2.1 topo.yaml
+2021-01-14T12:16:54.579+0800    INFO    CheckPoint      {"host": "172.16.5.140", "port": "22", "sudo": false, "user": "tidb", "cmd": "test  cmd", "stdout": "success", "stderr": "", "__func__": "test", "__hash__": "Unknown"}
+2021-01-14T12:16:55.579+0800    INFO    TaskCheckPoint  {"task": "Mkdir: host=172.16.5.140, directories='/data'", "skipped": false}
+2021-01-14T12:16:56.579+0800    INFO    TaskCheckPoint  {"task": "Mkdir: host=172.16.5.140, directories='/data'", "skipped": true}
+2021-01-14T12:16:57.579+0800    ERROR   TaskCheckPoint  {"task": "CopyFile: host=172.16.5.140", "error": "permission denied"}
+`)
+
+	steps, err := ParseSteps(r)
+	assert.Nil(err)
+	assert.Len(steps, 3)
+	assert.Equal(1, steps[0].Index)
+	assert.Equal("Mkdir: host=172.16.5.140, directories='/data'", steps[0].Task)
+	assert.True(steps[1].Done())
+	assert.Equal(3, steps[2].Index)
+	assert.False(steps[2].Done())
+	assert.Equal("permission denied", steps[2].Err)
+}
+
+func TestSkipSteps(t *testing.T) {
+	assert := require.New(t)
+	defer SkipSteps(nil)
+
+	SkipSteps([]*Step{{Task: "a"}, {Task: "a"}, {Task: "b"}})
+	assert.True(AcquireTask("a"))
+	assert.True(AcquireTask("b"))
+	assert.True(AcquireTask("a"))
+	// each step is only skipped once
+	assert.False(AcquireTask("a"))
+	assert.False(AcquireTask("b"))
+	assert.False(AcquireTask("c"))
+}
+
+func TestParseMetaHash(t *testing.T) {
+	assert := require.New(t)
+	r := strings.NewReader(`/usr/bin/tiup-cluster scale-out test topo.yaml
+2021-01-14T12:16:55.579+0800    INFO    TaskCheckPoint  {"task": "Mkdir: host=172.16.5.140, directories='/data'", "skipped": false}
+2021-01-14T12:16:58.579+0800    INFO    MetaCheckPoint  {"meta_hash": "abc123"}
+`)
+	hash, err := ParseMetaHash(r)
+	assert.Nil(err)
+	assert.Equal("abc123", hash)
+
+	hash, err = ParseMetaHash(strings.NewReader("/usr/bin/tiup-cluster display test\n"))
+	assert.Nil(err)
+	assert.Equal("", hash)
+}
@@ -116,13 +116,13 @@ func (c *BinlogClient) isTombstone(ctx context.Context, ty string, nodeID string
 	return false, errors.Errorf("node not exist: %s", nodeID)
 }
 
-// nolint (unused)
-func (c *BinlogClient) pumpNodeStatus(ctx context.Context) (status []*NodeStatus, err error) {
+// PumpNodeStatus returns the status of all pump nodes saved in etcd.
+func (c *BinlogClient) PumpNodeStatus(ctx context.Context) (status []*NodeStatus, err error) {
 	return c.nodeStatus(ctx, "pumps")
 }
 
-// nolint (unused)
-func (c *BinlogClient) drainerNodeStatus(ctx context.Context) (status []*NodeStatus, err error) {
+// DrainerNodeStatus returns the status of all drainer nodes saved in etcd.
+func (c *BinlogClient) DrainerNodeStatus(ctx context.Context) (status []*NodeStatus, err error) {
 	return c.nodeStatus(ctx, "drainers")
 }
 
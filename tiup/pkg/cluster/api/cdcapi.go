@@ -0,0 +1,92 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// CDCOpenAPIClient is an HTTP client of the TiCDC open API
+type CDCOpenAPIClient struct {
+	addrs      []string
+	tlsEnabled bool
+	client     *utils.HTTPClient
+	ctx        context.Context
+}
+
+// NewCDCOpenAPIClient return a `CDCOpenAPIClient`
+func NewCDCOpenAPIClient(ctx context.Context, addresses []string, timeout time.Duration, tlsConfig *tls.Config) *CDCOpenAPIClient {
+	return &CDCOpenAPIClient{
+		addrs:      addresses,
+		tlsEnabled: tlsConfig != nil,
+		client:     utils.NewHTTPClient(timeout, tlsConfig),
+		ctx:        ctx,
+	}
+}
+
+var (
+	cdcChangefeedsURI = "api/v1/changefeeds"
+)
+
+// CDCRunningError is the last error of a changefeed
+type CDCRunningError struct {
+	Addr    string `json:"addr"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// CDCChangefeed is the brief info of a changefeed
+type CDCChangefeed struct {
+	ID             string           `json:"id"`
+	State          string           `json:"state"`
+	CheckpointTSO  uint64           `json:"checkpoint_tso"`
+	CheckpointTime string           `json:"checkpoint_time"`
+	Error          *CDCRunningError `json:"error,omitempty"`
+}
+
+func (c *CDCOpenAPIClient) getEndpoints(uri string) (endpoints []string) {
+	scheme := "http"
+	if c.tlsEnabled {
+		scheme = "https"
+	}
+	for _, addr := range c.addrs {
+		endpoints = append(endpoints, fmt.Sprintf("%s://%s/%s", scheme, addr, uri))
+	}
+	return endpoints
+}
+
+// GetChangefeeds returns all the changefeeds of the cluster
+func (c *CDCOpenAPIClient) GetChangefeeds() ([]*CDCChangefeed, error) {
+	endpoints := c.getEndpoints(cdcChangefeedsURI)
+
+	var changefeeds []*CDCChangefeed
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, err := c.client.Get(c.ctx, endpoint)
+		if err != nil {
+			return body, err
+		}
+
+		return body, json.Unmarshal(body, &changefeeds)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changefeeds, nil
+}
@@ -732,6 +732,15 @@ func (pc *PDClient) updateConfig(url string, body io.Reader) error {
 	return err
 }
 
+// UpdateConfig changes the running config of PD, the keys are flattened
+func (pc *PDClient) UpdateConfig(config map[string]interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return pc.updateConfig(pdConfigURI, bytes.NewReader(data))
+}
+
 // UpdateReplicateConfig updates the PD replication config
 func (pc *PDClient) UpdateReplicateConfig(body io.Reader) error {
 	return pc.updateConfig(pdConfigReplicate, body)
@@ -745,16 +754,25 @@ func (pc *PDClient) GetReplicateConfig() ([]byte, error) {
 	})
 }
 
-// GetLocationLabels gets the replication.location-labels config from pd server
-func (pc *PDClient) GetLocationLabels() ([]string, bool, error) {
+// GetReplicationConfig gets the parsed replication config from pd server
+func (pc *PDClient) GetReplicationConfig() (*PDReplicationConfig, error) {
 	config, err := pc.GetReplicateConfig()
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
 	rc := PDReplicationConfig{}
 	if err := json.Unmarshal(config, &rc); err != nil {
-		return nil, false, perrs.Annotatef(err, "unmarshal replication config: %s", string(config))
+		return nil, perrs.Annotatef(err, "unmarshal replication config: %s", string(config))
+	}
+	return &rc, nil
+}
+
+// GetLocationLabels gets the replication.location-labels config from pd server
+func (pc *PDClient) GetLocationLabels() ([]string, bool, error) {
+	rc, err := pc.GetReplicationConfig()
+	if err != nil {
+		return nil, false, err
 	}
 
 	return rc.LocationLabels, rc.EnablePlacementRules, nil
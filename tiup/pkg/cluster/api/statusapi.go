@@ -0,0 +1,107 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jeremywohl/flatten"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// StatusClient is an HTTP client of the status port of TiDB and TiKV
+type StatusClient struct {
+	addrs      []string
+	tlsEnabled bool
+	client     *utils.HTTPClient
+	ctx        context.Context
+}
+
+// NewStatusClient return a `StatusClient`
+func NewStatusClient(ctx context.Context, addresses []string, timeout time.Duration, tlsConfig *tls.Config) *StatusClient {
+	return &StatusClient{
+		addrs:      addresses,
+		tlsEnabled: tlsConfig != nil,
+		client:     utils.NewHTTPClient(timeout, tlsConfig),
+		ctx:        ctx,
+	}
+}
+
+var (
+	statusConfigURI   = "config"
+	statusSettingsURI = "settings"
+)
+
+func (c *StatusClient) getEndpoints(uri string) (endpoints []string) {
+	scheme := "http"
+	if c.tlsEnabled {
+		scheme = "https"
+	}
+	for _, addr := range c.addrs {
+		endpoints = append(endpoints, fmt.Sprintf("%s://%s/%s", scheme, addr, uri))
+	}
+	return endpoints
+}
+
+// GetConfig returns the running config of the instance, the keys are flattened
+func (c *StatusClient) GetConfig() (map[string]interface{}, error) {
+	endpoints := c.getEndpoints(statusConfigURI)
+
+	config := map[string]interface{}{}
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		body, err := c.client.Get(c.ctx, endpoint)
+		if err != nil {
+			return body, err
+		}
+
+		return body, json.Unmarshal(body, &config)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return flatten.Flatten(config, "", flatten.DotStyle)
+}
+
+// UpdateConfig changes the running config of the instance, only TiKV supports it
+func (c *StatusClient) UpdateConfig(config map[string]interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	endpoints := c.getEndpoints(statusConfigURI)
+	_, err = tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		return c.client.Post(c.ctx, endpoint, bytes.NewReader(data))
+	})
+	return err
+}
+
+// UpdateSettings changes the settings of the running TiDB instance, such as log_level
+func (c *StatusClient) UpdateSettings(settings map[string]string) error {
+	query := url.Values{}
+	for k, v := range settings {
+		query.Set(k, v)
+	}
+	endpoints := c.getEndpoints(statusSettingsURI + "?" + query.Encode())
+	_, err := tryURLs(endpoints, func(endpoint string) ([]byte, error) {
+		return c.client.Post(c.ctx, endpoint, nil)
+	})
+	return err
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/base52"
+	"github.com/pingcap/tiup/pkg/cluster/secret"
 	"github.com/pingcap/tiup/pkg/crypto/rand"
 	"github.com/pingcap/tiup/pkg/tui"
 	tiuputils "github.com/pingcap/tiup/pkg/utils"
@@ -156,11 +157,16 @@ func OutputAuditLog(dir string, data []byte) error {
 	}
 	defer f.Close()
 
-	args := encodeCommandArgs(os.Args)
+	// the secrets resolved by the command must never be written to the log
+	args := make([]string, 0, len(os.Args))
+	for _, arg := range os.Args {
+		args = append(args, secret.Redact(arg))
+	}
+	args = encodeCommandArgs(args)
 	if _, err := f.Write([]byte(strings.Join(args, " ") + "\n")); err != nil {
 		return errors.Annotate(err, "write audit log")
 	}
-	if _, err := f.Write(data); err != nil {
+	if _, err := f.Write([]byte(secret.Redact(string(data)))); err != nil {
 		return errors.Annotate(err, "write audit log")
 	}
 	return nil
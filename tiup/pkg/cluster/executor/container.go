@@ -0,0 +1,278 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/embed"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/pingcap/tiup/pkg/tui"
+	"go.uber.org/zap"
+)
+
+const (
+	// the directory of the systemctl shim in containers, it's put before the
+	// other directories in PATH so that it takes precedence
+	containerShimDir = "/usr/local/lib/tiup/bin"
+	// the line marking the version of the shim, it's reinstalled if the version is changed
+	containerShimMark = "# tiup-systemctl-shim v1"
+)
+
+var (
+	// the resolved container names of hosts, runtime/host -> container
+	containerNames sync.Map
+	// the containers with the systemctl shim checked, runtime/container -> struct{}
+	containerShims sync.Map
+)
+
+// ContainerExecutor executes the commands in a container by the CLI of the
+// container runtime, it's used to deploy clusters into containers running
+// without sshd, the services are supervised by a systemctl shim if systemd
+// is not running in the container
+type ContainerExecutor struct {
+	Config    *SSHConfig
+	Runtime   string // the CLI of container runtime, docker or podman
+	Container string // the name or ID of the container
+	Sudo      bool   // all commands run with this executor will be using sudo
+	Locale    string // the locale used when executing the command
+}
+
+var _ ctxt.Executor = &ContainerExecutor{}
+
+// newContainerExecutor finds the container of the host and prepares it to
+// run the services
+func newContainerExecutor(sudo bool, c SSHConfig) (*ContainerExecutor, error) {
+	runtime, err := containerRuntime()
+	if err != nil {
+		return nil, err
+	}
+	container, err := resolveContainer(runtime, c.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &ContainerExecutor{
+		Config:    &c,
+		Runtime:   runtime,
+		Container: container,
+		Sudo:      sudo,
+		Locale:    "C",
+	}
+	if err := e.installSystemctlShim(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// containerRuntime returns the CLI of the container runtime set by the env,
+// or the first one of docker and podman found in PATH
+func containerRuntime() (string, error) {
+	if runtime := os.Getenv(localdata.EnvNameContainerRuntime); runtime != "" {
+		return runtime, nil
+	}
+	for _, runtime := range []string{"docker", "podman"} {
+		if _, err := exec.LookPath(runtime); err == nil {
+			return runtime, nil
+		}
+	}
+	return "", errors.Errorf("neither docker nor podman is found in PATH, set %s to the CLI of container runtime",
+		localdata.EnvNameContainerRuntime)
+}
+
+// resolveContainer returns the container of the host, the host is either
+// the name or ID of a running container, or an IP address of it
+func resolveContainer(runtime, host string) (string, error) {
+	key := runtime + "/" + host
+	if name, ok := containerNames.Load(key); ok {
+		return name.(string), nil
+	}
+
+	container := ""
+	if _, _, err := runContainerCLI(context.Background(), runtime, nil,
+		"inspect", "--type", "container", "--format", "{{.Id}}", host); err == nil {
+		container = host
+	} else {
+		ids, _, err := runContainerCLI(context.Background(), runtime, nil, "ps", "-q")
+		if err != nil {
+			return "", err
+		}
+		args := append([]string{"inspect", "--format",
+			"{{.Name}}{{range .NetworkSettings.Networks}} {{.IPAddress}}{{end}}"}, strings.Fields(string(ids))...)
+		if len(args) > 3 {
+			stdout, _, err := runContainerCLI(context.Background(), runtime, nil, args...)
+			if err != nil {
+				return "", err
+			}
+			container = containerByIP(string(stdout), host)
+		}
+	}
+	if container == "" {
+		return "", errors.Errorf("no running container is named %s or has the IP address", host)
+	}
+
+	containerNames.Store(key, container)
+	return container, nil
+}
+
+// containerByIP returns the name of the container with the IP address from
+// the output of inspect, each line is the name followed by the IP addresses
+func containerByIP(inspect, ip string) string {
+	for _, line := range strings.Split(inspect, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, addr := range fields[1:] {
+			if addr == ip {
+				return strings.TrimPrefix(fields[0], "/")
+			}
+		}
+	}
+	return ""
+}
+
+// installSystemctlShim installs the systemctl shim to the container if
+// systemd is not running in it
+func (e *ContainerExecutor) installSystemctlShim() error {
+	key := e.Runtime + "/" + e.Container
+	if _, ok := containerShims.Load(key); ok {
+		return nil
+	}
+
+	shim, err := embed.ReadTemplate(path.Join("templates", "scripts", "systemctl_shim.sh"))
+	if err != nil {
+		return err
+	}
+	dst := path.Join(containerShimDir, "systemctl")
+	// the shim is read from stdin, and it's discarded if systemd is running
+	// or the same version of shim is installed
+	script := fmt.Sprintf(`if [ -d /run/systemd/system ] || grep -qxF '%[1]s' %[2]s 2>/dev/null; then
+	cat >/dev/null
+else
+	mkdir -p %[3]s && cat > %[2]s && chmod 755 %[2]s
+fi`, containerShimMark, dst, containerShimDir)
+	if _, stderr, err := runContainerCLI(context.Background(), e.Runtime, shim,
+		"exec", "-i", "-u", "root", e.Container, "/bin/sh", "-c", script); err != nil {
+		return errors.Annotatef(err, "failed to install systemctl shim to container %s, stderr: %s", e.Container, stderr)
+	}
+
+	containerShims.Store(key, struct{}{})
+	return nil
+}
+
+// Execute implements Executor interface.
+func (e *ContainerExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	user := e.Config.User
+	if e.Sudo || sudo || user == "" {
+		user = "root"
+	}
+
+	// set a basic PATH in case it's empty, the systemctl shim takes precedence
+	cmd = fmt.Sprintf("PATH=%s:$PATH:/bin:/sbin:/usr/bin:/usr/sbin; export PATH; cd; %s", containerShimDir, cmd)
+	if e.Locale != "" {
+		cmd = fmt.Sprintf("export LANG=%s; %s", e.Locale, cmd)
+	}
+
+	if len(timeout) == 0 {
+		timeout = append(timeout, executeDefaultTimeout)
+	}
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithTimeout(ctx, timeout[0])
+	defer cancel()
+
+	// bash is preferred as the commands are written for it, but it may not be
+	// installed in the minimal images
+	stdout, stderr, err := runContainerCLI(ctx, e.Runtime, nil, "exec", "-u", user, e.Container,
+		"/bin/sh", "-c", `command -v bash >/dev/null 2>&1 && exec bash -c "$1" || exec sh -c "$1"`, "sh", cmd)
+
+	zap.L().Info("ContainerCommand",
+		zap.String("container", e.Container),
+		zap.String("cmd", cmd),
+		zap.Error(err),
+		zap.String("stdout", string(stdout)),
+		zap.String("stderr", string(stderr)))
+
+	if err != nil {
+		return stdout, stderr, containerExecuteError(err, "Failed to execute command in container", cmd, stdout, stderr)
+	}
+	return stdout, stderr, nil
+}
+
+// Transfer implements Executer interface, the bandwidth limit and compression
+// are not supported as the files are copied locally
+func (e *ContainerExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	var args []string
+	if download {
+		args = []string{"cp", e.Container + ":" + src, dst}
+	} else {
+		args = []string{"cp", src, e.Container + ":" + dst}
+	}
+	stdout, stderr, err := runContainerCLI(ctx, e.Runtime, nil, args...)
+
+	zap.L().Info("ContainerCopy",
+		zap.String("container", e.Container),
+		zap.Strings("args", args),
+		zap.Error(err))
+
+	if err != nil {
+		return containerExecuteError(err, "Failed to transfer file to container", strings.Join(args, " "), stdout, stderr)
+	}
+	if download || e.Sudo || e.Config.User == "" || e.Config.User == "root" {
+		return nil
+	}
+
+	// the files copied are owned by root, while they are expected to be owned by the user
+	chown := fmt.Sprintf("chown %[1]s:$(id -g -n %[1]s) %[2]s", e.Config.User, dst)
+	_, _, err = e.Execute(ctx, chown, true)
+	return err
+}
+
+func runContainerCLI(ctx context.Context, runtime string, stdin []byte, args ...string) ([]byte, []byte, error) {
+	command := exec.CommandContext(ctx, runtime, args...)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	command.Stdout = stdout
+	command.Stderr = stderr
+	if stdin != nil {
+		command.Stdin = bytes.NewReader(stdin)
+	}
+	err := command.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func containerExecuteError(err error, msg, cmd string, stdout, stderr []byte) error {
+	baseErr := ErrSSHExecuteFailed.
+		Wrap(err, msg).
+		WithProperty(ErrPropSSHCommand, cmd).
+		WithProperty(ErrPropSSHStdout, stdout).
+		WithProperty(ErrPropSSHStderr, stderr)
+	if len(stdout) > 0 || len(stderr) > 0 {
+		output := strings.TrimSpace(strings.Join([]string{string(stdout), string(stderr)}, "\n"))
+		baseErr = baseErr.
+			WithProperty(tui.SuggestionFromFormat("Command output:\n%s\n", color.YellowString(output)))
+	}
+	return baseErr
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/localdata"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRuntime emulates the CLI of container runtime with a container named
+// tikv-1, the commands are executed locally
+const fakeRuntime = `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/calls.log"
+case "$1" in
+    inspect)
+        if [ "$2" = "--type" ]; then
+            [ "$6" = "tikv-1" ]
+            exit $?
+        fi
+        echo "/tikv-1 172.18.0.2"
+        echo "/pd-1 172.18.0.3 10.0.0.3"
+        ;;
+    ps)
+        echo "0123456789ab"
+        echo "ba9876543210"
+        ;;
+    exec)
+        while [ "$1" != "/bin/sh" ]; do shift; done
+        exec "$@"
+        ;;
+    cp)
+        exec cp "$(echo "$2" | sed 's/^[a-z0-9-]*://')" "$(echo "$3" | sed 's/^[a-z0-9-]*://')"
+        ;;
+esac
+`
+
+func TestContainerByIP(t *testing.T) {
+	assert := require.New(t)
+	inspect := "/tikv-1 172.18.0.2\n/pd-1 172.18.0.3 10.0.0.3\n/stopped\n"
+	assert.Equal("tikv-1", containerByIP(inspect, "172.18.0.2"))
+	assert.Equal("pd-1", containerByIP(inspect, "10.0.0.3"))
+	assert.Equal("", containerByIP(inspect, "172.18.0.9"))
+}
+
+func TestContainerExecutor(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+	runtime := filepath.Join(dir, "fake-docker")
+	assert.Nil(os.WriteFile(runtime, []byte(fakeRuntime), 0755))
+	t.Setenv(localdata.EnvNameContainerRuntime, runtime)
+
+	// the shim is not installed to the local host
+	containerShims.Store(runtime+"/tikv-1", struct{}{})
+	containerShims.Store(runtime+"/pd-1", struct{}{})
+
+	e, err := New(SSHTypeContainer, false, SSHConfig{Host: "tikv-1", User: "root"})
+	assert.Nil(err)
+	e2, err := New(SSHTypeContainer, false, SSHConfig{Host: "172.18.0.3", User: "root"})
+	assert.Nil(err)
+	assert.Equal("pd-1", e2.(*CheckPointExecutor).Executor.(*ContainerExecutor).Container)
+	_, err = New(SSHTypeContainer, false, SSHConfig{Host: "172.18.0.9", User: "root"})
+	assert.NotNil(err)
+
+	stdout, _, err := e.Execute(context.Background(), "echo $PATH; echo hello", false)
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	assert.True(strings.HasPrefix(lines[0], containerShimDir+":"))
+	assert.Equal("hello", lines[1])
+	_, _, err = e.Execute(context.Background(), "exit 2", false)
+	assert.NotNil(err)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	assert.Nil(os.WriteFile(src, []byte("data"), 0644))
+	assert.Nil(e.Transfer(context.Background(), src, dst, false, 0, false))
+	data, err := os.ReadFile(dst)
+	assert.Nil(err)
+	assert.Equal("data", string(data))
+
+	calls, err := os.ReadFile(filepath.Join(dir, "calls.log"))
+	assert.Nil(err)
+	assert.Contains(string(calls), "exec -u root tikv-1 /bin/sh -c")
+	assert.Contains(string(calls), "cp "+src+" tikv-1:"+dst)
+}
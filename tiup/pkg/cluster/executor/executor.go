@@ -43,6 +43,10 @@ var (
 	// SSHTypeNone is the type of local executor (no ssh will be used)
 	SSHTypeNone SSHType = "none"
 
+	// SSHTypeContainer is the type of container executor, the commands are
+	// executed in the containers by docker or podman (no ssh will be used)
+	SSHTypeContainer SSHType = "container"
+
 	executeDefaultTimeout = time.Second * 60
 
 	// This command will be execute once the NativeSSHExecutor is created.
@@ -111,6 +115,12 @@ func New(etype SSHType, sudo bool, c SSHConfig) (ctxt.Executor, error) {
 			Locale: "C",
 		}
 		executor = e
+	case SSHTypeContainer:
+		e, err := newContainerExecutor(sudo, c)
+		if err != nil {
+			return nil, err
+		}
+		executor = e
 	default:
 		return nil, errors.Errorf("unregistered executor: %s", etype)
 	}
@@ -118,6 +128,11 @@ func New(etype SSHType, sudo bool, c SSHConfig) (ctxt.Executor, error) {
 	return &CheckPointExecutor{executor, &c}, nil
 }
 
+// UseSSH returns true if the executor connects to the hosts over SSH
+func (t SSHType) UseSSH() bool {
+	return t != SSHTypeNone && t != SSHTypeContainer
+}
+
 func checkLocalIP(ip string) error {
 	ifaces, err := net.Interfaces()
 	if err != nil {
@@ -0,0 +1,460 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/secret"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/meta"
+	"gopkg.in/yaml.v2"
+)
+
+// the default storage requested by the components, the topology has no sizes of disks
+var defaultStorage = map[string]string{
+	spec.ComponentPD:      "10Gi",
+	spec.ComponentTiKV:    "100Gi",
+	spec.ComponentTiFlash: "100Gi",
+	spec.ComponentPump:    "20Gi",
+}
+
+// the ports used by the pods, the custom ports in topology are not kept
+var defaultPorts = map[string]int{
+	spec.ComponentPD:      2379,
+	spec.ComponentTiKV:    20160,
+	spec.ComponentTiDB:    4000,
+	spec.ComponentTiFlash: 9000,
+	spec.ComponentCDC:     8300,
+	spec.ComponentPump:    8250,
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Options controls the resources exported
+type Options struct {
+	Namespace    string // the namespace of the resources
+	StorageClass string // the storage class of the persistent volumes
+}
+
+// Export is the Kubernetes resources exported from a cluster
+type Export struct {
+	TidbCluster *TidbCluster
+	ConfigMaps  []*ConfigMap
+	// the fields of topology that can't be represented in the resources
+	Warnings []string
+}
+
+// component is an exported component with its instances
+type component struct {
+	name      string
+	ports     []int
+	configs   []map[string]interface{}
+	resources []meta.ResourceControl
+	numaNodes []string
+}
+
+// Convert exports the topology of a cluster to the TidbCluster resource, the
+// components not managed by TidbCluster are exported as ConfigMaps
+func Convert(name, version string, topo *spec.Specification, opt Options) (*Export, error) {
+	tcName := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if tcName == "" {
+		return nil, errors.Errorf("cluster name %s can't be used as a Kubernetes resource name", name)
+	}
+
+	e := &Export{}
+	if tcName != name {
+		e.warnf("cluster name %s is not a valid Kubernetes resource name, %s is used", name, tcName)
+	}
+	tc := &TidbCluster{
+		APIVersion: TidbClusterAPIVersion,
+		Kind:       TidbClusterKind,
+		Metadata: ObjectMeta{
+			Name:      tcName,
+			Namespace: opt.Namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "tiup-cluster-export"},
+		},
+		Spec: TidbClusterSpec{
+			Version:              version,
+			PVReclaimPolicy:      "Retain",
+			ConfigUpdateStrategy: "RollingUpdate",
+		},
+	}
+	e.TidbCluster = tc
+	global := topo.GlobalOptions.ResourceControl
+
+	if len(topo.PDServers) > 0 {
+		comp := &component{name: spec.ComponentPD}
+		for _, s := range topo.PDServers {
+			comp.add(s.ClientPort, s.Config, spec.MergeResourceControl(global, s.ResourceControl), s.NumaNode)
+		}
+		server, err := e.serverSpec(comp, topo.ServerConfigs.PD, opt)
+		if err != nil {
+			return nil, err
+		}
+		tc.Spec.PD = server
+	}
+
+	if len(topo.TiKVServers) > 0 {
+		comp := &component{name: spec.ComponentTiKV}
+		for _, s := range topo.TiKVServers {
+			comp.add(s.Port, s.Config, spec.MergeResourceControl(global, s.ResourceControl), s.NumaNode)
+			if lookupConfig(spec.MergeConfig(s.Config), "server.labels") != nil {
+				e.warnf("the labels of tikv instances are not exported, the labels of TiKV stores are set from the Kubernetes nodes by tidb-operator")
+			}
+		}
+		server, err := e.serverSpec(comp, topo.ServerConfigs.TiKV, opt)
+		if err != nil {
+			return nil, err
+		}
+		tc.Spec.TiKV = server
+	}
+
+	if len(topo.TiDBServers) > 0 {
+		comp := &component{name: spec.ComponentTiDB}
+		for _, s := range topo.TiDBServers {
+			comp.add(s.Port, s.Config, spec.MergeResourceControl(global, s.ResourceControl), s.NumaNode)
+		}
+		server, err := e.serverSpec(comp, topo.ServerConfigs.TiDB, opt)
+		if err != nil {
+			return nil, err
+		}
+		tidb := &TiDBSpec{ComponentSpec: server.ComponentSpec, Config: server.Config}
+		if cert := lookupConfig(spec.MergeConfig(topo.ServerConfigs.TiDB), "security.ssl-cert"); cert != nil && cert != "" {
+			tidb.TLSClient = &TLSConfig{Enabled: true}
+			e.warnf("the MySQL client TLS certificate of tidb is not exported, create the Secret %s-tidb-server-secret with it", tcName)
+		}
+		tc.Spec.TiDB = tidb
+	}
+
+	if len(topo.TiFlashServers) > 0 {
+		comp := &component{name: spec.ComponentTiFlash}
+		var learnerConfigs []map[string]interface{}
+		for _, s := range topo.TiFlashServers {
+			comp.add(s.TCPPort, s.Config, spec.MergeResourceControl(global, s.ResourceControl), s.NumaNode)
+			learnerConfigs = append(learnerConfigs, s.LearnerConfig)
+		}
+		server, err := e.serverSpec(comp, topo.ServerConfigs.TiFlash, opt)
+		if err != nil {
+			return nil, err
+		}
+		proxy, err := e.config("tiflash-learner", topo.ServerConfigs.TiFlashLearner, learnerConfigs)
+		if err != nil {
+			return nil, err
+		}
+		tiflash := &TiFlashSpec{ComponentSpec: server.ComponentSpec}
+		// the storage of TiFlash is requested by the claims
+		tiflash.StorageClaims = []StorageClaim{{
+			Resources:        StorageResources{Requests: ResourceList{"storage": tiflash.Requests["storage"]}},
+			StorageClassName: tiflash.StorageClassName,
+		}}
+		tiflash.Requests = nil
+		tiflash.StorageClassName = ""
+		if server.Config != "" || proxy != "" {
+			tiflash.Config = &TiFlashConfig{Config: server.Config, Proxy: proxy}
+		}
+		tc.Spec.TiFlash = tiflash
+	}
+
+	if len(topo.CDCServers) > 0 {
+		comp := &component{name: spec.ComponentCDC}
+		for _, s := range topo.CDCServers {
+			comp.add(s.Port, s.Config, spec.MergeResourceControl(global, s.ResourceControl), s.NumaNode)
+		}
+		server, err := e.serverSpec(comp, topo.ServerConfigs.CDC, opt)
+		if err != nil {
+			return nil, err
+		}
+		tc.Spec.TiCDC = server
+	}
+
+	if len(topo.PumpServers) > 0 {
+		comp := &component{name: spec.ComponentPump}
+		for _, s := range topo.PumpServers {
+			comp.add(s.Port, s.Config, spec.MergeResourceControl(global, s.ResourceControl), s.NumaNode)
+		}
+		server, err := e.serverSpec(comp, topo.ServerConfigs.Pump, opt)
+		if err != nil {
+			return nil, err
+		}
+		tc.Spec.Pump = server
+	}
+
+	// drainer is deployed by the tidb-drainer chart, which reads the config
+	// from a ConfigMap
+	for i, s := range topo.Drainers {
+		cfg, err := e.config(spec.ComponentDrainer, topo.ServerConfigs.Drainer, []map[string]interface{}{s.Config})
+		if err != nil {
+			return nil, err
+		}
+		cm := &ConfigMap{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Metadata: ObjectMeta{
+				Name:      fmt.Sprintf("%s-drainer-%d", tcName, i),
+				Namespace: opt.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "tiup-cluster-export",
+					"app.kubernetes.io/instance":   tcName,
+					"app.kubernetes.io/component":  spec.ComponentDrainer,
+				},
+			},
+			Data: map[string]string{"config-file": cfg},
+		}
+		e.ConfigMaps = append(e.ConfigMaps, cm)
+		e.warnf("drainer %s:%d is not managed by TidbCluster, deploy it with the tidb-drainer chart using the ConfigMap %s",
+			s.Host, s.Port, cm.Metadata.Name)
+	}
+
+	if topo.GlobalOptions.TLSEnabled {
+		tc.Spec.TLSCluster = &TLSConfig{Enabled: true}
+		e.warnf("the TLS certificates of the cluster are not exported, issue the certificates of each component as Secrets %s-<component>-cluster-secret before creating the TidbCluster", tcName)
+	}
+	if len(topo.Monitors)+len(topo.Grafanas)+len(topo.Alertmanagers) > 0 {
+		e.warnf("prometheus, grafana and alertmanager are not exported, monitor the cluster with a TidbMonitor resource")
+	}
+	if len(topo.TiSparkMasters)+len(topo.TiSparkWorkers) > 0 {
+		e.warnf("tispark is not supported by tidb-operator and it's not exported")
+	}
+	if topo.Backup.Enabled() {
+		e.warnf("the scheduled backup is not exported, schedule it with a BackupSchedule resource")
+	}
+	e.warnf("host, ssh_port, deploy_dir, data_dir and log_dir of instances are not exported, the pods are scheduled by Kubernetes and store the data in persistent volumes")
+	return e, nil
+}
+
+func (c *component) add(port int, config map[string]interface{}, rc meta.ResourceControl, numaNode string) {
+	c.ports = append(c.ports, port)
+	c.configs = append(c.configs, config)
+	c.resources = append(c.resources, rc)
+	c.numaNodes = append(c.numaNodes, numaNode)
+}
+
+func (e *Export) warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	for _, w := range e.Warnings {
+		if w == msg {
+			return
+		}
+	}
+	e.Warnings = append(e.Warnings, msg)
+}
+
+// serverSpec returns the spec shared by the components
+func (e *Export) serverSpec(comp *component, global map[string]interface{}, opt Options) (*ServerSpec, error) {
+	cfg, err := e.config(comp.name, global, comp.configs)
+	if err != nil {
+		return nil, err
+	}
+	s := &ServerSpec{
+		ComponentSpec: ComponentSpec{
+			BaseImage: baseImage(comp.name),
+			Replicas:  len(comp.ports),
+			Limits:    e.limits(comp),
+		},
+		Config: cfg,
+	}
+	if storage, ok := defaultStorage[comp.name]; ok {
+		s.Requests = ResourceList{"storage": storage}
+		s.StorageClassName = opt.StorageClass
+		e.warnf("the size of disks is unknown in topology, %s requests %s of storage, adjust it to the data size", comp.name, storage)
+	}
+
+	for _, port := range comp.ports {
+		if port != defaultPorts[comp.name] {
+			e.warnf("the custom ports of %s are not exported, the default ports are used in Kubernetes", comp.name)
+		}
+	}
+	for _, numaNode := range comp.numaNodes {
+		if numaNode != "" {
+			e.warnf("numa_node of %s is not exported, set the CPU manager policy of kubelet to bind CPUs", comp.name)
+		}
+	}
+	return s, nil
+}
+
+// config returns the TOML config of a component, the config of instances is
+// kept only if all of them are the same
+func (e *Export) config(comp string, global map[string]interface{}, instances []map[string]interface{}) (string, error) {
+	merged := spec.MergeConfig(global)
+	for i, cfg := range instances {
+		if !reflect.DeepEqual(cfg, instances[0]) {
+			e.warnf("the instances of %s have different config, only server_configs.%s is exported", comp, comp)
+			break
+		}
+		if i == len(instances)-1 && len(cfg) > 0 {
+			merged = spec.MergeConfig(global, cfg)
+		}
+	}
+	if len(merged) == 0 {
+		return "", nil
+	}
+
+	// the secrets are not resolved, the plaintext should not be written out
+	buf := new(bytes.Buffer)
+	enc := toml.NewEncoder(buf)
+	enc.Indent = ""
+	if err := enc.Encode(merged); err != nil {
+		return "", errors.Annotatef(err, "failed to encode config of %s", comp)
+	}
+	if secret.HasRef(buf.String()) {
+		e.warnf("the config of %s refers to secrets, replace the %s references with the values or mount them from Kubernetes Secrets", comp, secret.RefPrefix)
+	}
+	return buf.String(), nil
+}
+
+// limits converts the resource control of systemd to the resource limits,
+// the limits of the first instance are used if they are different
+func (e *Export) limits(comp *component) ResourceList {
+	rc := comp.resources[0]
+	for _, r := range comp.resources {
+		if r != rc {
+			e.warnf("the instances of %s have different resource_control, the one of the first instance is exported", comp.name)
+			break
+		}
+	}
+
+	limits := ResourceList{}
+	if rc.MemoryLimit != "" {
+		if mem, ok := memoryQuantity(rc.MemoryLimit); ok {
+			limits["memory"] = mem
+		} else {
+			e.warnf("memory_limit %s of %s can't be represented as a Kubernetes quantity", rc.MemoryLimit, comp.name)
+		}
+	}
+	if rc.CPUQuota != "" {
+		if cpu, ok := cpuQuantity(rc.CPUQuota); ok {
+			limits["cpu"] = cpu
+		} else {
+			e.warnf("cpu_quota %s of %s can't be represented as a Kubernetes quantity", rc.CPUQuota, comp.name)
+		}
+	}
+	if rc.IOReadBandwidthMax != "" || rc.IOWriteBandwidthMax != "" {
+		e.warnf("the IO bandwidth limits of %s are not supported by Kubernetes", comp.name)
+	}
+	if rc.LimitCORE != "" {
+		e.warnf("limit_core of %s is not supported by Kubernetes", comp.name)
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+	return limits
+}
+
+// memoryQuantity converts MemoryLimit of systemd, e.g. 2G, to the quantity
+// of Kubernetes, e.g. 2Gi, as both of the suffixes are 1024-based
+func memoryQuantity(limit string) (string, bool) {
+	limit = strings.TrimSpace(limit)
+	if limit == "" {
+		return "", false
+	}
+	suffix := limit[len(limit)-1:]
+	num := limit
+	if strings.Contains("KMGT", suffix) {
+		num = limit[:len(limit)-1]
+	} else {
+		suffix = ""
+	}
+	if _, err := strconv.ParseUint(num, 10, 64); err != nil {
+		return "", false
+	}
+	if suffix == "" {
+		return num, true
+	}
+	return num + suffix + "i", true
+}
+
+// cpuQuantity converts CPUQuota of systemd, e.g. 150%, to the quantity of
+// Kubernetes, e.g. 1500m
+func cpuQuantity(quota string) (string, bool) {
+	pct, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(quota), "%"), 10, 64)
+	if err != nil || !strings.HasSuffix(quota, "%") || pct == 0 {
+		return "", false
+	}
+	if pct%100 == 0 {
+		return strconv.FormatUint(pct/100, 10), true
+	}
+	return strconv.FormatUint(pct*10, 10) + "m", true
+}
+
+func baseImage(comp string) string {
+	switch comp {
+	case spec.ComponentCDC:
+		return "pingcap/ticdc"
+	case spec.ComponentPump:
+		return "pingcap/tidb-binlog"
+	}
+	return "pingcap/" + comp
+}
+
+// lookupConfig returns the value of a dotted key in the folded config
+func lookupConfig(cfg map[string]interface{}, key string) interface{} {
+	var v interface{} = cfg
+	for _, k := range strings.Split(key, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+// Validate marshals the TidbCluster and validates it against the bundled CRD,
+// so the exported resource is accepted by tidb-operator
+func (e *Export) Validate() error {
+	schema, err := LoadCRDSchema("tidbcluster.crd.yaml", "v1alpha1")
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(e.TidbCluster)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var obj interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return errors.Trace(err)
+	}
+	if errs := schema.Validate(obj); len(errs) > 0 {
+		return errors.Errorf("the exported TidbCluster is invalid:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// YAML returns the resources as a multi-document YAML
+func (e *Export) YAML() ([]byte, error) {
+	objs := []interface{}{e.TidbCluster}
+	for _, cm := range e.ConfigMaps {
+		objs = append(objs, cm)
+	}
+	buf := new(bytes.Buffer)
+	for i, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
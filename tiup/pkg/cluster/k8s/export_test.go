@@ -0,0 +1,204 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const exportTopology = `
+global:
+  enable_tls: true
+  resource_control:
+    memory_limit: 8G
+    cpu_quota: 150%
+server_configs:
+  tikv:
+    raftstore.apply-pool-size: 4
+  tidb:
+    security.ssl-cert: /path/to/cert.pem
+    security.ssl-key: /path/to/key.pem
+  tiflash-learner:
+    log-level: info
+  drainer:
+    syncer.db-type: file
+pd_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.2
+  - host: 172.16.5.3
+tikv_servers:
+  - host: 172.16.5.1
+    config:
+      server.labels: { zone: z1 }
+    resource_control:
+      io_read_bandwidth_max: /dev/sda 100M
+  - host: 172.16.5.2
+    config:
+      server.labels: { zone: z2 }
+tidb_servers:
+  - host: 172.16.5.1
+    port: 3306
+    config:
+      log.level: warn
+  - host: 172.16.5.2
+    port: 3306
+    config:
+      log.level: warn
+tiflash_servers:
+  - host: 172.16.5.4
+    numa_node: "0"
+drainer_servers:
+  - host: 172.16.5.5
+    config:
+      syncer.ignore-schemas: mysql
+monitoring_servers:
+  - host: 172.16.5.6
+`
+
+func TestConvert(t *testing.T) {
+	assert := require.New(t)
+
+	topo := &spec.Specification{}
+	assert.Nil(yaml.UnmarshalStrict([]byte(exportTopology), topo))
+	export, err := Convert("prod_cluster", "v5.0.0", topo, Options{Namespace: "tidb", StorageClass: "ssd"})
+	assert.Nil(err)
+	assert.Nil(export.Validate())
+
+	tc := export.TidbCluster
+	assert.Equal("prod-cluster", tc.Metadata.Name)
+	assert.Equal("tidb", tc.Metadata.Namespace)
+	assert.Equal("v5.0.0", tc.Spec.Version)
+	assert.True(tc.Spec.TLSCluster.Enabled)
+
+	assert.Equal(3, tc.Spec.PD.Replicas)
+	assert.Equal("pingcap/pd", tc.Spec.PD.BaseImage)
+	assert.Equal(ResourceList{"memory": "8Gi", "cpu": "1500m"}, tc.Spec.PD.Limits)
+	assert.Equal(ResourceList{"storage": "10Gi"}, tc.Spec.PD.Requests)
+	assert.Equal("ssd", tc.Spec.PD.StorageClassName)
+
+	// the config of instances is different, only server_configs is exported
+	assert.Equal(2, tc.Spec.TiKV.Replicas)
+	assert.Equal("[raftstore]\napply-pool-size = 4\n", tc.Spec.TiKV.Config)
+
+	// the config of instances is the same, it's merged
+	assert.Contains(tc.Spec.TiDB.Config, "[log]\nlevel = \"warn\"")
+	assert.Contains(tc.Spec.TiDB.Config, "ssl-cert = \"/path/to/cert.pem\"")
+	assert.True(tc.Spec.TiDB.TLSClient.Enabled)
+	assert.Nil(tc.Spec.TiDB.Requests)
+
+	assert.Equal(1, tc.Spec.TiFlash.Replicas)
+	assert.Equal("100Gi", tc.Spec.TiFlash.StorageClaims[0].Resources.Requests["storage"])
+	assert.Equal("ssd", tc.Spec.TiFlash.StorageClaims[0].StorageClassName)
+	assert.Equal("log-level = \"info\"\n", tc.Spec.TiFlash.Config.Proxy)
+	assert.Nil(tc.Spec.TiCDC)
+
+	assert.Len(export.ConfigMaps, 1)
+	assert.Equal("prod-cluster-drainer-0", export.ConfigMaps[0].Metadata.Name)
+	assert.Contains(export.ConfigMaps[0].Data["config-file"], "db-type = \"file\"")
+	assert.Contains(export.ConfigMaps[0].Data["config-file"], "ignore-schemas = \"mysql\"")
+
+	warnings := strings.Join(export.Warnings, "\n")
+	for _, w := range []string{
+		"cluster name prod_cluster",
+		"the instances of tikv have different config",
+		"the labels of tikv instances",
+		"the instances of tikv have different resource_control",
+		"the IO bandwidth limits of tikv",
+		"the custom ports of tidb",
+		"numa_node of tiflash",
+		"drainer 172.16.5.5:8249",
+		"TidbMonitor",
+		"TLS certificates",
+	} {
+		assert.Contains(warnings, w)
+	}
+
+	data, err := export.YAML()
+	assert.Nil(err)
+	docs := strings.Split(string(data), "---\n")
+	assert.Len(docs, 2)
+	assert.Contains(docs[0], "kind: TidbCluster")
+	assert.Contains(docs[1], "kind: ConfigMap")
+}
+
+func TestQuantity(t *testing.T) {
+	assert := require.New(t)
+
+	for limit, expected := range map[string]string{"2G": "2Gi", "512M": "512Mi", "1073741824": "1073741824"} {
+		q, ok := memoryQuantity(limit)
+		assert.True(ok)
+		assert.Equal(expected, q)
+	}
+	for _, limit := range []string{"80%", "infinity", "G"} {
+		_, ok := memoryQuantity(limit)
+		assert.False(ok)
+	}
+
+	for quota, expected := range map[string]string{"200%": "2", "50%": "500m"} {
+		q, ok := cpuQuantity(quota)
+		assert.True(ok)
+		assert.Equal(expected, q)
+	}
+	for _, quota := range []string{"2", "0%", "x%"} {
+		_, ok := cpuQuantity(quota)
+		assert.False(ok)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := require.New(t)
+	schema, err := LoadCRDSchema("tidbcluster.crd.yaml", "v1alpha1")
+	assert.Nil(err)
+
+	var obj interface{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+apiVersion: pingcap.com/v1alpha1
+kind: TidbCluster
+metadata:
+  name: Basic
+spec:
+  version: v5.0.0
+  pvReclaimPolicy: Keep
+  pd:
+    replicas: -1
+    limits:
+      cpu: 2 cores
+    unknown: 1
+  tikv:
+    baseImage: pingcap/tikv
+    config:
+      anything: goes
+  tiflash:
+    replicas: 1
+`), &obj))
+
+	errs := schema.Validate(obj)
+	assert.ElementsMatch([]string{
+		`metadata.name: "Basic" does not match ^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`,
+		"spec.pvReclaimPolicy: should be one of [Retain Delete Recycle], got Keep",
+		"spec.pd.limits.cpu: \"2 cores\" does not match " + schema.Properties["spec"].Properties["pd"].Properties["limits"].Properties["cpu"].Pattern,
+		"spec.pd.replicas: should not be less than 0, got -1",
+		"spec.pd.unknown: unknown field",
+		"spec.tikv.replicas: is required",
+		"spec.tiflash.storageClaims: is required",
+	}, errs)
+
+	_, err = LoadCRDSchema("tidbcluster.crd.yaml", "v1")
+	assert.NotNil(err)
+}
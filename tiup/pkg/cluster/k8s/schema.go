@@ -0,0 +1,208 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/embed"
+	"gopkg.in/yaml.v2"
+)
+
+// Schema is the OpenAPI v3 schema of a custom resource, only the keywords
+// used by the bundled CRDs are supported
+type Schema struct {
+	Type                  string             `yaml:"type,omitempty"`
+	Properties            map[string]*Schema `yaml:"properties,omitempty"`
+	AdditionalProperties  *Schema            `yaml:"additionalProperties,omitempty"`
+	Items                 *Schema            `yaml:"items,omitempty"`
+	Required              []string           `yaml:"required,omitempty"`
+	Enum                  []string           `yaml:"enum,omitempty"`
+	Pattern               string             `yaml:"pattern,omitempty"`
+	Minimum               *float64           `yaml:"minimum,omitempty"`
+	IntOrString           bool               `yaml:"x-kubernetes-int-or-string,omitempty"`
+	PreserveUnknownFields bool               `yaml:"x-kubernetes-preserve-unknown-fields,omitempty"`
+}
+
+// crdFile is the part of a CustomResourceDefinition holding the schemas
+type crdFile struct {
+	Spec struct {
+		Versions []struct {
+			Name   string `yaml:"name"`
+			Schema struct {
+				OpenAPIV3Schema *Schema `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// LoadCRDSchema returns the schema of the version in the bundled CRD
+func LoadCRDSchema(file, version string) (*Schema, error) {
+	data, err := embed.ReadK8sManifest(path.Join("k8s", file))
+	if err != nil {
+		return nil, errors.Annotatef(err, "CRD %s not found", file)
+	}
+	crd := crdFile{}
+	if err := yaml.Unmarshal(data, &crd); err != nil {
+		return nil, errors.Annotatef(err, "invalid CRD %s", file)
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version && v.Schema.OpenAPIV3Schema != nil {
+			return v.Schema.OpenAPIV3Schema, nil
+		}
+	}
+	return nil, errors.Errorf("version %s not found in CRD %s", version, file)
+}
+
+// Validate checks the value against the schema, the fields not defined in the
+// schema are rejected unless x-kubernetes-preserve-unknown-fields is set
+func (s *Schema) Validate(v interface{}) []string {
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(field string, v interface{}) []string {
+	if field == "" {
+		field = "."
+	}
+	if s.IntOrString {
+		switch val := v.(type) {
+		case int, int64, uint64:
+			return nil
+		case string:
+			if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(val) {
+				return []string{fmt.Sprintf("%s: %q does not match %s", field, val, s.Pattern)}
+			}
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: should be an integer or a string, got %v", field, v)}
+	}
+
+	switch s.Type {
+	case "":
+		if !s.PreserveUnknownFields {
+			return []string{fmt.Sprintf("%s: the schema has no type", field)}
+		}
+		return nil
+	case "object":
+		return s.validateObject(field, v)
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: should be an array, got %v", field, v)}
+		}
+		var errs []string
+		for i, item := range items {
+			if s.Items != nil {
+				errs = append(errs, s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item)...)
+			}
+		}
+		return errs
+	case "string":
+		val, ok := v.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: should be a string, got %v", field, v)}
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, val) {
+			return []string{fmt.Sprintf("%s: should be one of %v, got %s", field, s.Enum, val)}
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(val) {
+			return []string{fmt.Sprintf("%s: %q does not match %s", field, val, s.Pattern)}
+		}
+	case "integer", "number":
+		var num float64
+		switch val := v.(type) {
+		case int:
+			num = float64(val)
+		case int64:
+			num = float64(val)
+		case uint64:
+			num = float64(val)
+		case float64:
+			if s.Type == "integer" {
+				return []string{fmt.Sprintf("%s: should be an integer, got %v", field, v)}
+			}
+			num = val
+		default:
+			return []string{fmt.Sprintf("%s: should be a %s, got %v", field, s.Type, v)}
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			return []string{fmt.Sprintf("%s: should not be less than %v, got %v", field, *s.Minimum, v)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: should be true or false, got %v", field, v)}
+		}
+	default:
+		return []string{fmt.Sprintf("%s: unsupported type %s in schema", field, s.Type)}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(field string, v interface{}) []string {
+	obj := make(map[string]interface{})
+	switch val := v.(type) {
+	case map[string]interface{}:
+		obj = val
+	case map[interface{}]interface{}:
+		for k, item := range val {
+			obj[fmt.Sprint(k)] = item
+		}
+	default:
+		return []string{fmt.Sprintf("%s: should be an object, got %v", field, v)}
+	}
+	prefix := field + "."
+	if field == "." {
+		prefix = ""
+	}
+
+	var errs []string
+	for _, k := range s.Required {
+		if _, ok := obj[k]; !ok {
+			errs = append(errs, fmt.Sprintf("%s%s: is required", prefix, k))
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if prop, ok := s.Properties[k]; ok {
+			errs = append(errs, prop.validate(prefix+k, obj[k])...)
+			continue
+		}
+		if s.AdditionalProperties != nil {
+			errs = append(errs, s.AdditionalProperties.validate(prefix+k, obj[k])...)
+			continue
+		}
+		if !s.PreserveUnknownFields {
+			errs = append(errs, fmt.Sprintf("%s%s: unknown field", prefix, k))
+		}
+	}
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
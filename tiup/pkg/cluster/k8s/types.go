@@ -0,0 +1,112 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+// The resources of tidb-operator, only the fields set by the export are defined
+const (
+	TidbClusterAPIVersion = "pingcap.com/v1alpha1"
+	TidbClusterKind       = "TidbCluster"
+)
+
+// ObjectMeta is the metadata of Kubernetes resources
+type ObjectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// ResourceList is the resource quantities of a component, e.g. cpu: "2"
+type ResourceList map[string]string
+
+// TidbCluster is the custom resource of tidb-operator
+type TidbCluster struct {
+	APIVersion string          `yaml:"apiVersion"`
+	Kind       string          `yaml:"kind"`
+	Metadata   ObjectMeta      `yaml:"metadata"`
+	Spec       TidbClusterSpec `yaml:"spec"`
+}
+
+// TidbClusterSpec is the spec of TidbCluster
+type TidbClusterSpec struct {
+	Version              string       `yaml:"version"`
+	PVReclaimPolicy      string       `yaml:"pvReclaimPolicy,omitempty"`
+	ConfigUpdateStrategy string       `yaml:"configUpdateStrategy,omitempty"`
+	TLSCluster           *TLSConfig   `yaml:"tlsCluster,omitempty"`
+	PD                   *ServerSpec  `yaml:"pd,omitempty"`
+	TiKV                 *ServerSpec  `yaml:"tikv,omitempty"`
+	TiDB                 *TiDBSpec    `yaml:"tidb,omitempty"`
+	TiFlash              *TiFlashSpec `yaml:"tiflash,omitempty"`
+	TiCDC                *ServerSpec  `yaml:"ticdc,omitempty"`
+	Pump                 *ServerSpec  `yaml:"pump,omitempty"`
+}
+
+// TLSConfig enables TLS of the cluster or the clients
+type TLSConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// ComponentSpec is the spec shared by the components
+type ComponentSpec struct {
+	BaseImage        string       `yaml:"baseImage"`
+	Replicas         int          `yaml:"replicas"`
+	Requests         ResourceList `yaml:"requests,omitempty"`
+	Limits           ResourceList `yaml:"limits,omitempty"`
+	StorageClassName string       `yaml:"storageClassName,omitempty"`
+}
+
+// ServerSpec is the spec of the components configured by a TOML file
+type ServerSpec struct {
+	ComponentSpec `yaml:",inline"`
+	Config        string `yaml:"config,omitempty"`
+}
+
+// TiDBSpec is the spec of TiDB
+type TiDBSpec struct {
+	ComponentSpec `yaml:",inline"`
+	TLSClient     *TLSConfig `yaml:"tlsClient,omitempty"`
+	Config        string     `yaml:"config,omitempty"`
+}
+
+// TiFlashSpec is the spec of TiFlash
+type TiFlashSpec struct {
+	ComponentSpec `yaml:",inline"`
+	StorageClaims []StorageClaim `yaml:"storageClaims"`
+	Config        *TiFlashConfig `yaml:"config,omitempty"`
+}
+
+// StorageClaim is a persistent volume claim of TiFlash
+type StorageClaim struct {
+	Resources        StorageResources `yaml:"resources"`
+	StorageClassName string           `yaml:"storageClassName,omitempty"`
+}
+
+// StorageResources is the requested resources of a StorageClaim
+type StorageResources struct {
+	Requests ResourceList `yaml:"requests"`
+}
+
+// TiFlashConfig is the config of TiFlash and its proxy
+type TiFlashConfig struct {
+	Config string `yaml:"config,omitempty"`
+	Proxy  string `yaml:"proxy,omitempty"`
+}
+
+// ConfigMap is the ConfigMap of Kubernetes
+type ConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
@@ -19,10 +19,10 @@ import (
 	"path/filepath"
 	"strings"
 
+	perrs "github.com/pingcap/errors"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
-	"github.com/pingcap/tiup/pkg/crypto"
 	"github.com/pingcap/tiup/pkg/environment"
 	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/meta"
@@ -125,7 +125,7 @@ func buildScaleOutTask(
 			RootSSH(
 				instance.GetHost(),
 				instance.GetSSHPort(),
-				opt.User,
+				sshUser(newPart, instance.GetHost(), opt.User),
 				s.Password,
 				s.IdentityFile,
 				s.IdentityFilePassphrase,
@@ -230,11 +230,7 @@ func buildScaleOutTask(
 		}
 		// generate and transfer tls cert for instance
 		if topo.BaseTopo().GlobalOptions.TLSEnabled {
-			ca, err := crypto.ReadCA(
-				name,
-				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCACert),
-				m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCAKey),
-			)
+			ca, err := m.readClusterCA(name)
 			if err != nil {
 				iterErr = err
 				return
@@ -338,6 +334,14 @@ func buildScaleOutTask(
 	downloadCompTasks = append(downloadCompTasks, convertStepDisplaysToTasks(dlTasks)...)
 	deployCompTasks = append(deployCompTasks, convertStepDisplaysToTasks(dpTasks)...)
 
+	// the backup agent is refreshed to prune the local backups on the new hosts
+	dlTasks, dpTasks, err = buildBackupAgentTasks(m, name, mergedTopo, base.Version, gOpt, p)
+	if err != nil {
+		return nil, err
+	}
+	downloadCompTasks = append(downloadCompTasks, convertStepDisplaysToTasks(dlTasks)...)
+	deployCompTasks = append(deployCompTasks, convertStepDisplaysToTasks(dpTasks)...)
+
 	builder, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
 	if err != nil {
 		return nil, err
@@ -357,6 +361,7 @@ func buildScaleOutTask(
 
 	builder.Func("Save meta", func(_ context.Context) error {
 		metadata.SetTopology(mergedTopo)
+		recordBackupAgent(metadata)
 		return m.specManager.SaveMeta(name, metadata)
 	})
 
@@ -505,11 +510,7 @@ func buildMonitoredDeployTask(
 				)
 
 			if globalOptions.TLSEnabled && comp == spec.ComponentBlackboxExporter {
-				ca, innerr := crypto.ReadCA(
-					name,
-					m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCACert),
-					m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCAKey),
-				)
+				ca, innerr := m.readClusterCA(name)
 				if innerr != nil {
 					err = innerr
 					return
@@ -532,6 +533,163 @@ func buildMonitoredDeployTask(
 	return
 }
 
+// buildBackupAgentTasks builds the tasks to install and configure the backup agent
+// on the host set in the backup section of the topology
+func buildBackupAgentTasks(
+	m *Manager,
+	name string,
+	topo spec.Topology,
+	version string,
+	gOpt operator.Options,
+	p *tui.SSHConnectionProps,
+) (downloadCompTasks []*task.StepDisplay, deployCompTasks []*task.StepDisplay, err error) {
+	cluster, ok := topo.(*spec.Specification)
+	if !ok || !cluster.Backup.Enabled() {
+		return
+	}
+	backup := &cluster.Backup
+	globalOptions := cluster.GlobalOptions
+
+	// the backup agent runs on one of the hosts of the instances
+	hosts := make(map[string]hostInfo)
+	cluster.IterInstance(func(inst spec.Instance) {
+		if _, found := hosts[inst.GetHost()]; !found {
+			hosts[inst.GetHost()] = hostInfo{ssh: inst.GetSSHPort(), os: inst.OS(), arch: inst.Arch()}
+		}
+	})
+	info, found := hosts[backup.Host]
+	if !found {
+		err = perrs.Errorf("backup host %s is not a host of the cluster", backup.Host)
+		return
+	}
+
+	version = m.bindVersion(spec.ComponentBR, version)
+	downloadCompTasks = append(downloadCompTasks, task.NewBuilder(m.logger).
+		Download(spec.ComponentBR, info.os, info.arch, version).
+		BuildAsStep(fmt.Sprintf("  - Download %s:%s (%s/%s)", spec.ComponentBR, version, info.os, info.arch)))
+
+	deployDir := spec.Abs(globalOptions.User, backup.DeployDir)
+	logDir := spec.Abs(globalOptions.User, backup.LogDir)
+	paths := meta.DirPaths{
+		Deploy: deployDir,
+		Log:    logDir,
+		Cache:  m.specManager.Path(name, spec.TempConfigPath),
+	}
+
+	// the agent on the other hosts only prunes the backups in local storage
+	for _, host := range cluster.BackupHosts() {
+		info := hosts[host]
+		agent := host == backup.Host
+		deployDirs := []string{
+			deployDir,
+			logDir,
+			filepath.Join(deployDir, "scripts"),
+		}
+		if agent {
+			deployDirs = append(deployDirs, filepath.Join(deployDir, "bin"))
+			if globalOptions.TLSEnabled {
+				deployDirs = append(deployDirs, filepath.Join(deployDir, "tls"))
+			}
+		}
+
+		tb := task.NewBuilder(m.logger).
+			UserSSH(
+				host,
+				info.ssh,
+				globalOptions.User,
+				gOpt.SSHTimeout,
+				gOpt.OptTimeout,
+				gOpt.SSHProxyHost,
+				gOpt.SSHProxyPort,
+				gOpt.SSHProxyUser,
+				p.Password,
+				p.IdentityFile,
+				p.IdentityFilePassphrase,
+				gOpt.SSHProxyTimeout,
+				gOpt.SSHType,
+				globalOptions.SSHType,
+			).
+			Mkdir(globalOptions.User, host, deployDirs...)
+
+		if agent {
+			tb = tb.CopyComponent(spec.ComponentBR, info.os, info.arch, version, "", host, deployDir)
+			if globalOptions.TLSEnabled {
+				ca, innerr := m.readClusterCA(name)
+				if innerr != nil {
+					err = innerr
+					return
+				}
+				tb = tb.TLSCert(host, spec.ComponentBR, spec.RoleBackup, 0, ca, paths)
+			}
+		}
+
+		tb = tb.BackupAgentConfig(name, host, backup, cluster.GetPDList(), globalOptions.User, globalOptions.TLSEnabled, paths)
+		deployCompTasks = append(deployCompTasks, tb.BuildAsStep(fmt.Sprintf("  - Deploy backup agent -> %s", host)))
+	}
+	return
+}
+
+// buildBackupAgentTeardownTasks builds the tasks to remove the backup agent
+// from the hosts, a host without any instance of the topology left is logged
+// in with the SSH port of the global options
+func buildBackupAgentTeardownTasks(
+	m *Manager,
+	name string,
+	topo *spec.Specification,
+	agent *spec.BackupAgentMeta,
+	hosts []string,
+	gOpt operator.Options,
+	p *tui.SSHConnectionProps,
+) []*task.StepDisplay {
+	globalOptions := topo.BaseTopo().GlobalOptions
+	sshPorts := make(map[string]int)
+	topo.IterInstance(func(inst spec.Instance) {
+		if _, found := sshPorts[inst.GetHost()]; !found {
+			sshPorts[inst.GetHost()] = inst.GetSSHPort()
+		}
+	})
+
+	tasks := []*task.StepDisplay{}
+	for _, host := range hosts {
+		host := host
+		port, found := sshPorts[host]
+		if !found {
+			port = globalOptions.SSHPort
+		}
+		t := task.NewBuilder(m.logger).
+			UserSSH(
+				host,
+				port,
+				globalOptions.User,
+				gOpt.SSHTimeout,
+				gOpt.OptTimeout,
+				gOpt.SSHProxyHost,
+				gOpt.SSHProxyPort,
+				gOpt.SSHProxyUser,
+				p.Password,
+				p.IdentityFile,
+				p.IdentityFilePassphrase,
+				gOpt.SSHProxyTimeout,
+				gOpt.SSHType,
+				globalOptions.SSHType,
+			).
+			Func("DestroyBackupAgent", func(ctx context.Context) error {
+				return operator.DestroyBackupAgent(ctx, name, globalOptions.User, agent, []string{host}, gOpt)
+			}).
+			BuildAsStep(fmt.Sprintf("  - Remove backup agent -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// recordBackupAgent records the backup agent of the topology in the meta to
+// remove it from the hosts it's no longer on later
+func recordBackupAgent(metadata spec.Metadata) {
+	if cm, ok := metadata.(*spec.ClusterMeta); ok {
+		cm.BackupAgent = cm.Topology.BackupAgent()
+	}
+}
+
 func buildRefreshMonitoredConfigTasks(
 	specManager *spec.SpecManager,
 	name string,
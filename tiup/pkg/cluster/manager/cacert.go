@@ -17,9 +17,12 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"os"
 	"path/filepath"
 
+	"github.com/google/uuid"
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/secret"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/crypto"
 	"github.com/pingcap/tiup/pkg/utils"
@@ -32,7 +35,7 @@ func genAndSaveClusterCA(name, tlsPath string) (*crypto.CertificateAuthority, er
 	}
 
 	// save CA private key
-	if err := utils.SaveFileWithBackup(filepath.Join(tlsPath, spec.TLSCAKey), ca.Key.Pem(), ""); err != nil {
+	if err := savePrivateKey(filepath.Join(tlsPath, spec.TLSCAKey), "tls/ca", ca.Key.Pem()); err != nil {
 		return nil, perrs.Annotatef(err, "cannot save CA private key for %s", name)
 	}
 
@@ -56,7 +59,8 @@ func genAndSaveClientCert(ca *crypto.CertificateAuthority, name, tlsPath string)
 	}
 
 	// save client private key
-	if err := utils.SaveFileWithBackup(filepath.Join(tlsPath, spec.TLSClientKey), privKey.Pem(), ""); err != nil {
+	keyPath := filepath.Join(tlsPath, spec.TLSClientKey)
+	if err := savePrivateKey(keyPath, "tls/client", privKey.Pem()); err != nil {
 		return perrs.Annotatef(err, "cannot save client private key for %s", name)
 	}
 
@@ -83,7 +87,11 @@ func genAndSaveClientCert(ca *crypto.CertificateAuthority, name, tlsPath string)
 		return perrs.Annotatef(err, "cannot save client PEM certificate for %s", name)
 	}
 
-	// save pfx format certificate
+	// save pfx format certificate, it's skipped if the private key is kept
+	// in the secret vault, as the pfx file is encrypted by a known password
+	if spec.SecretFileRef(keyPath) != "" {
+		return nil
+	}
 	clientCert, err := x509.ParseCertificate(cert)
 	if err != nil {
 		return perrs.Annotatef(err, "cannot decode signed client certificate for %s", name)
@@ -101,3 +109,46 @@ func genAndSaveClientCert(ca *crypto.CertificateAuthority, name, tlsPath string)
 
 	return nil
 }
+
+// savePrivateKey saves the private key to the path, it's kept in the secret
+// vault if the key of vault is set, and only the reference is saved to the path
+func savePrivateKey(path, secretPrefix string, keyData []byte) error {
+	if !spec.SecretKey().Empty() {
+		secretName := fmt.Sprintf("%s-%s", secretPrefix, uuid.New().String())
+		if err := spec.SaveSecret(secretName, string(keyData)); err != nil {
+			return err
+		}
+		keyData = []byte(secret.RefPrefix + secretName + "\n")
+	}
+	return utils.SaveFileWithBackup(path, keyData, "")
+}
+
+// readClusterCA reads the CA of the cluster, the private key is looked up
+// from the secret vault if the key file only contains a reference
+func (m *Manager) readClusterCA(name string) (*crypto.CertificateAuthority, error) {
+	rawKey, err := spec.ReadSecretFile(m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCAKey))
+	if err != nil {
+		return nil, perrs.Annotatef(err, "error reading CA private key for %s", name)
+	}
+
+	rawCert, err := os.ReadFile(m.specManager.Path(name, spec.TLSCertKeyDir, spec.TLSCACert))
+	if err != nil {
+		return nil, perrs.Annotatef(err, "error reading CA certificate for %s", name)
+	}
+	return crypto.LoadCA(name, rawCert, rawKey)
+}
+
+// deleteClusterTLSSecrets removes the private keys of CA and client from the
+// secret vault if they are kept there
+func (m *Manager) deleteClusterTLSSecrets(name string) error {
+	for _, file := range []string{spec.TLSCAKey, spec.TLSClientKey} {
+		ref := spec.SecretFileRef(m.specManager.Path(name, spec.TLSCertKeyDir, file))
+		if ref == "" {
+			continue
+		}
+		if _, err := spec.DeleteSecret(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
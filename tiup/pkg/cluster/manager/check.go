@@ -25,7 +25,6 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
@@ -40,8 +39,11 @@ type CheckOptions struct {
 	IdentityFile string // path to the private key file
 	UsePassword  bool   // use password instead of identity file for ssh connection
 	Opr          *operator.CheckOptions
-	ApplyFix     bool // try to apply fixes of failed checks
-	ExistCluster bool // check an exist cluster
+	ApplyFix     bool     // try to apply fixes of failed checks
+	ExistCluster bool     // check an exist cluster
+	PluginDir    string   // directory to load custom check rules from
+	TopoFiles    []string // the topology files overlaying the checked one
+	VarsFile     string   // the file of variables substituted in the topology files
 }
 
 // CheckCluster check cluster before deploying or upgrading
@@ -75,9 +77,9 @@ func (m *Manager) CheckCluster(clusterOrTopoName string, opt CheckOptions, gOpt
 		topo = *metadata.Topology
 		topo.AdjustByVersion(metadata.Version)
 	} else { // check before cluster is deployed
-		topoFileName := clusterOrTopoName
+		topoFiles := append([]string{clusterOrTopoName}, opt.TopoFiles...)
 
-		if err := spec.ParseTopologyYaml(topoFileName, &topo); err != nil {
+		if err := spec.ParseTopologyFiles(topoFiles, opt.VarsFile, &topo); err != nil {
 			return err
 		}
 		spec.ExpandRelativeDir(&topo)
@@ -99,7 +101,7 @@ func (m *Manager) CheckCluster(clusterOrTopoName string, opt CheckOptions, gOpt
 		sshConnProps  *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 	)
-	if gOpt.SSHType != executor.SSHTypeNone {
+	if gOpt.SSHType.UseSSH() {
 		var err error
 		if sshConnProps, err = tui.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword); err != nil {
 			return err
@@ -115,6 +117,14 @@ func (m *Manager) CheckCluster(clusterOrTopoName string, opt CheckOptions, gOpt
 		return err
 	}
 
+	if opt.PluginDir != "" {
+		plugins, err := operator.LoadCheckPlugins(opt.PluginDir)
+		if err != nil {
+			return err
+		}
+		opt.Opr.Plugins = plugins
+	}
+
 	if err := checkSystemInfo(ctx, sshConnProps, sshProxyProps, &topo, &gOpt, &opt); err != nil {
 		return err
 	}
@@ -146,9 +156,22 @@ func checkSystemInfo(
 	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
 	insightVer := spec.TiDBComponentVersion(spec.ComponentCheckCollector, "")
 
+	// the hosts of an existing cluster are logged in by the deploy user
+	loginUser := func(host string) string {
+		if opt.ExistCluster {
+			return opt.User
+		}
+		return topo.Hosts.SSHUser(host, opt.User)
+	}
+
 	uniqueHosts := map[string]int{}             // host -> ssh-port
 	uniqueArchList := make(map[string]struct{}) // map["os-arch"]{}
 
+	pluginBinaries, err := checkPluginBinaries(topo, opt.Opr.Plugins)
+	if err != nil {
+		return err
+	}
+
 	roleFilter := set.NewStringSet(gOpt.Roles...)
 	nodeFilter := set.NewStringSet(gOpt.Nodes...)
 	components := topo.ComponentsByUpdateOrder()
@@ -218,7 +241,7 @@ func checkSystemInfo(
 					RootSSH(
 						inst.GetHost(),
 						inst.GetSSHPort(),
-						opt.User,
+						loginUser(inst.GetHost()),
 						s.Password,
 						s.IdentityFile,
 						s.IdentityFilePassphrase,
@@ -243,15 +266,25 @@ func checkSystemInfo(
 						"", // use default srcPath
 						inst.GetHost(),
 						task.CheckToolsPathDir,
-					).
+					)
+				// copy binaries of custom checks, this must be done before running
+				// insight as the output of the last shell command is checked
+				for _, b := range pluginBinaries[inst.GetHost()] {
+					t2 = t2.
+						CopyFile(b.src, b.dst, inst.GetHost(), false, 0, false).
+						Shell(inst.GetHost(), fmt.Sprintf("chmod +x %s", b.dst), "", false)
+				}
+				t2 = t2.
 					Shell(
 						inst.GetHost(),
 						filepath.Join(task.CheckToolsPathDir, "bin", "insight"),
 						"",
 						false,
-					).
-					BuildAsStep(fmt.Sprintf("  - Getting system info of %s:%d", inst.GetHost(), inst.GetSSHPort()))
-				collectTasks = append(collectTasks, t2)
+					)
+				collectTasks = append(
+					collectTasks,
+					t2.BuildAsStep(fmt.Sprintf("  - Getting system info of %s:%d", inst.GetHost(), inst.GetSSHPort())),
+				)
 
 				// build checking tasks
 				t1 = t1.
@@ -327,6 +360,14 @@ func checkSystemInfo(
 						task.CheckTypePackage,
 						topo,
 						opt.Opr,
+					).
+					// run custom checks
+					CheckSys(
+						inst.GetHost(),
+						"",
+						task.CheckTypePlugin,
+						topo,
+						opt.Opr,
 					)
 			}
 
@@ -339,7 +380,7 @@ func checkSystemInfo(
 				RootSSH(
 					inst.GetHost(),
 					inst.GetSSHPort(),
-					opt.User,
+					loginUser(inst.GetHost()),
 					s.Password,
 					s.IdentityFile,
 					s.IdentityFilePassphrase,
@@ -387,7 +428,7 @@ func checkSystemInfo(
 			RootSSH(
 				host,
 				uniqueHosts[host],
-				opt.User,
+				loginUser(host),
 				s.Password,
 				s.IdentityFile,
 				s.IdentityFilePassphrase,
@@ -489,6 +530,44 @@ func handleCheckResults(ctx context.Context, host string, opt *CheckOptions, t *
 	return items, nil
 }
 
+// pluginBinary is a binary of custom check to copy to a host
+type pluginBinary struct {
+	src string
+	dst string
+}
+
+// checkPluginBinaries returns the binaries of custom checks to copy to each
+// host, the binaries are looked up once for each host, and it fails if any
+// host has no binary built for its platform
+func checkPluginBinaries(topo *spec.Specification, plugins []*operator.CheckPlugin) (map[string][]pluginBinary, error) {
+	binaries := make(map[string][]pluginBinary)
+	var err error
+	topo.IterInstance(func(inst spec.Instance) {
+		if _, found := binaries[inst.GetHost()]; found || err != nil {
+			return
+		}
+		list := []pluginBinary{}
+		for _, plugin := range plugins {
+			if plugin.Binary == "" {
+				continue
+			}
+			var src string
+			if src, err = plugin.LocalBinary(inst.OS(), inst.Arch()); err != nil {
+				return
+			}
+			list = append(list, pluginBinary{
+				src: src,
+				dst: plugin.RemoteBinary(filepath.Join(task.CheckToolsPathDir, "bin")),
+			})
+		}
+		binaries[inst.GetHost()] = list
+	})
+	if err != nil {
+		return nil, err
+	}
+	return binaries, nil
+}
+
 func formatHostCheckResults(results []HostCheckResult) [][]string {
 	lines := make([][]string, 0)
 	for _, r := range results {
@@ -510,6 +589,10 @@ func formatHostCheckResults(results []HostCheckResult) [][]string {
 // fixFailedChecks tries to automatically apply changes to fix failed checks
 func fixFailedChecks(host string, res *operator.CheckResult, t *task.Builder) (string, error) {
 	msg := ""
+	if res.Fix != "" {
+		t.Shell(host, res.Fix, "", res.FixSudo)
+		return fmt.Sprintf("will try to '%s'", color.HiBlueString(res.Fix)), nil
+	}
 	switch res.Name {
 	case operator.CheckNameSysService:
 		if strings.Contains(res.Msg, "not found") {
@@ -0,0 +1,70 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCheckPluginBinaries(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	topo := &spec.Specification{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.1
+    port: 20160
+    os: linux
+    arch: amd64
+  - host: 172.16.5.1
+    port: 20161
+    status_port: 20181
+    data_dir: /data/tikv-1
+    deploy_dir: /deploy/tikv-1
+    os: linux
+    arch: amd64
+  - host: 172.16.5.2
+    os: linux
+    arch: arm64
+`), topo))
+
+	for _, arch := range []string{"amd64", "arm64"} {
+		assert.Nil(os.WriteFile(filepath.Join(dir, "audit-"+arch), []byte("#!/bin/sh\n"), 0755))
+	}
+	plugins := []*operator.CheckPlugin{
+		{Name: "kernel", Command: "uname -r"},
+		{Name: "audit", Binary: filepath.Join(dir, "audit-{arch}")},
+	}
+
+	binaries, err := checkPluginBinaries(topo, plugins)
+	assert.Nil(err)
+	// the binaries are copied once for each host
+	assert.Len(binaries, 2)
+	assert.Len(binaries["172.16.5.1"], 1)
+	assert.Equal(filepath.Join(dir, "audit-amd64"), binaries["172.16.5.1"][0].src)
+	assert.Equal(filepath.Join(dir, "audit-arm64"), binaries["172.16.5.2"][0].src)
+	assert.Equal(binaries["172.16.5.1"][0].dst, binaries["172.16.5.2"][0].dst)
+
+	assert.Nil(os.Remove(filepath.Join(dir, "audit-arm64")))
+	_, err = checkPluginBinaries(topo, plugins)
+	assert.NotNil(err)
+}
@@ -0,0 +1,369 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/checkpoint"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/secret"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// ConfigDiffOptions contains the options for diffing the configs of a cluster
+type ConfigDiffOptions struct {
+	Reconcile bool // push the intended configs to the drifted instances
+}
+
+// instanceDrift is the config drifts of an instance
+type instanceDrift struct {
+	inst   spec.Instance
+	drifts []spec.ConfigDrift
+}
+
+// ConfigDiff compares the configs rendered from the topology, the config files
+// deployed on the hosts and the configs of the running instances
+func (m *Manager) ConfigDiff(name string, opt ConfigDiffOptions, gOpt operator.Options, skipConfirm bool) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+	if err := m.checkTopologySecrets(topo); err != nil {
+		return err
+	}
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	roleFilter := set.NewStringSet(gOpt.Roles...)
+	nodeFilter := set.NewStringSet(gOpt.Nodes...)
+	var instances []spec.Instance
+	for _, comp := range operator.FilterComponent(topo.ComponentsByStartOrder(), roleFilter) {
+		instances = append(instances, operator.FilterInstance(comp.Instances(), nodeFilter)...)
+	}
+
+	// render the intended config files locally
+	intended := make(map[string]map[string]interface{})
+	var rendered []spec.Instance
+	for _, inst := range instances {
+		config, err := m.renderInstanceConfig(name, base, inst)
+		if err != nil {
+			return err
+		}
+		if config != nil {
+			intended[inst.ID()] = config
+			rendered = append(rendered, inst)
+		}
+	}
+	if len(rendered) == 0 {
+		m.logger.Infof("No config file to compare.")
+		return nil
+	}
+
+	// fetch the deployed config files
+	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
+	if err != nil {
+		return err
+	}
+	var fetchTasks []*task.StepDisplay
+	for _, inst := range rendered {
+		t := task.NewBuilder(m.logger).
+			Shell(
+				inst.GetHost(),
+				fmt.Sprintf("cat %s 2>/dev/null || true", instanceConfigPath(base.User, inst)),
+				"config-"+inst.ID(),
+				false,
+			).
+			BuildAsStep(fmt.Sprintf("  - Fetching config of %s -> %s", inst.ComponentName(), inst.ID()))
+		fetchTasks = append(fetchTasks, t)
+	}
+	ctx := ctxt.New(
+		context.Background(),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	t := b.ParallelStep("+ Fetch deployed configs", false, fetchTasks...).Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	var drifted []instanceDrift
+	for _, inst := range rendered {
+		stdout, _, _ := ctxt.GetInner(ctx).GetOutputs("config-" + inst.ID())
+		deployed, err := spec.ParseTomlConfig(stdout)
+		if err != nil {
+			return perrs.Annotatef(err, "failed to parse the config file of %s", inst.ID())
+		}
+		live, err := m.liveInstanceConfig(inst, tlsCfg, gOpt)
+		if err != nil {
+			m.logger.Warnf("Failed to get the live config of %s: %s", inst.ID(), err)
+		}
+		if drifts := spec.DiffConfig(intended[inst.ID()], deployed, live); len(drifts) > 0 {
+			drifted = append(drifted, instanceDrift{inst: inst, drifts: drifts})
+		}
+	}
+
+	if len(drifted) == 0 {
+		m.logger.Infof("No config drift found.")
+		return nil
+	}
+	rows := [][]string{{"Instance", "Role", "Key", "Intended", "Deployed", "Live"}}
+	for _, d := range drifted {
+		for _, drift := range d.drifts {
+			rows = append(rows, []string{
+				d.inst.ID(),
+				d.inst.Role(),
+				drift.Key,
+				secret.Redact(spec.FormatConfigValue(drift.Intended)),
+				secret.Redact(spec.FormatConfigValue(drift.Deployed)),
+				secret.Redact(spec.FormatConfigValue(drift.Live)),
+			})
+		}
+	}
+	tui.PrintTable(rows, true)
+
+	if !opt.Reconcile {
+		m.logger.Infof("Use `%s config diff %s --reconcile` to push the intended configs to the instances.", tui.OsArgs0(), name)
+		return nil
+	}
+	if !skipConfirm {
+		if err := tui.PromptForConfirmOrAbortError(
+			color.HiYellowString("The intended configs will be pushed to %d instances, do you want to continue? [y/N]:", len(drifted)),
+		); err != nil {
+			return err
+		}
+	}
+	return m.reconcileConfig(name, topo, base, drifted, tlsCfg, gOpt)
+}
+
+// reconcileConfig pushes the intended config files to the drifted instances,
+// the live drift is changed online if all the options support it, the other
+// instances with live drift are restarted one by one to load the config files
+func (m *Manager) reconcileConfig(
+	name string,
+	topo spec.Topology,
+	base *spec.BaseMeta,
+	drifted []instanceDrift,
+	tlsCfg *tls.Config,
+	gOpt operator.Options,
+) error {
+	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
+	if err != nil {
+		return err
+	}
+	var regenTasks []*task.StepDisplay
+	for _, d := range drifted {
+		t := task.NewBuilder(m.logger).
+			InitConfig(
+				name,
+				base.Version,
+				m.specManager,
+				d.inst,
+				base.User,
+				gOpt.IgnoreConfigCheck,
+				instanceDirPaths(base.User, d.inst, m.specManager.Path(name, spec.TempConfigPath)),
+			).
+			BuildAsStep(fmt.Sprintf("  - Regenerate config %s -> %s", d.inst.ComponentName(), d.inst.ID()))
+		regenTasks = append(regenTasks, t)
+	}
+	ctx := ctxt.New(
+		context.Background(),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	t := b.ParallelStep("+ Push intended configs", false, regenTasks...).Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return perrs.Trace(err)
+	}
+
+	var restartNodes []string
+	for _, d := range drifted {
+		changes := make(map[string]interface{})
+		online := true
+		for _, drift := range d.drifts {
+			if !drift.LiveDrifted() {
+				continue
+			}
+			if drift.Intended == nil {
+				// an option can't be unset online
+				online = false
+				break
+			}
+			changes[drift.Key] = drift.Intended
+		}
+		if len(changes) == 0 && online {
+			continue
+		}
+		if online {
+			applied, err := m.applyOnlineConfig(d.inst, base.Version, changes, tlsCfg, gOpt)
+			if err != nil {
+				m.logger.Warnf("Failed to change the live config of %s online: %s", d.inst.ID(), err)
+			}
+			if applied {
+				continue
+			}
+		}
+		restartNodes = append(restartNodes, d.inst.ID())
+	}
+	if len(restartNodes) == 0 {
+		m.logger.Infof("Reconciled successfully.")
+		return nil
+	}
+
+	// restart the instances one by one with the leaders evicted, like reload
+	m.logger.Infof("Restarting %v to load the config files", restartNodes)
+	restartOpt := gOpt
+	restartOpt.Roles = nil
+	restartOpt.Nodes = restartNodes
+	if err := operator.Upgrade(ctx, topo, restartOpt, tlsCfg); err != nil {
+		return perrs.Annotate(err, "failed to restart the drifted instances")
+	}
+	m.logger.Infof("Reconciled successfully.")
+	return nil
+}
+
+// configRenderExecutor renders the config files locally, the files transferred
+// to the host are recorded instead, and the commands are not executed
+type configRenderExecutor struct {
+	files map[string][]byte
+}
+
+// Execute implements the Executor interface
+func (e *configRenderExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return nil, nil, nil
+}
+
+// Transfer implements the Executor interface
+func (e *configRenderExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	if download {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	e.files[dst] = data
+	return nil
+}
+
+// renderInstanceFiles renders the files of the instance by the logic of init
+// config, the files are indexed by the paths on the host
+func (m *Manager) renderInstanceFiles(name string, base *spec.BaseMeta, inst spec.Instance) (map[string][]byte, error) {
+	cache, err := os.MkdirTemp("", "tiup-config-render-*")
+	if err != nil {
+		return nil, perrs.AddStack(err)
+	}
+	defer os.RemoveAll(cache)
+
+	e := &configRenderExecutor{files: make(map[string][]byte)}
+	ctx := checkpoint.NewContext(context.WithValue(context.Background(), logprinter.ContextKeyLogger, m.logger))
+	err = inst.InitConfig(ctx, e, name, base.Version, base.User, instanceDirPaths(base.User, inst, cache))
+	// the config check can't run without the host, the files are rendered before it
+	if err != nil && perrs.Cause(err) != spec.ErrorCheckConfig {
+		return nil, perrs.Annotatef(err, "failed to render the config of %s", inst.ID())
+	}
+
+	files := make(map[string][]byte)
+	for dst, data := range e.files {
+		// the service file is transferred to a temporary path before moved to systemd
+		if strings.HasPrefix(dst, "/tmp/") && strings.HasSuffix(dst, ".service") {
+			dst = filepath.Join("/etc/systemd/system", inst.ServiceName())
+		}
+		files[dst] = data
+	}
+	return files, nil
+}
+
+// renderInstanceConfig renders the config file of the instance by the logic
+// of init config, nil if the instance has no such config file
+func (m *Manager) renderInstanceConfig(name string, base *spec.BaseMeta, inst spec.Instance) (map[string]interface{}, error) {
+	files, err := m.renderInstanceFiles(name, base, inst)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := files[instanceConfigPath(base.User, inst)]
+	if !ok {
+		return nil, nil
+	}
+	return spec.ParseTomlConfig(data)
+}
+
+// liveInstanceConfig returns the config of the running instance, nil if the
+// component doesn't report its config
+func (m *Manager) liveInstanceConfig(inst spec.Instance, tlsCfg *tls.Config, gOpt operator.Options) (map[string]interface{}, error) {
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, m.logger)
+	timeout := time.Second * time.Duration(gOpt.APITimeout)
+	switch i := inst.(type) {
+	case *spec.PDInstance:
+		return api.NewPDClient(ctx, []string{inst.ID()}, timeout, tlsCfg).GetConfig()
+	case *spec.TiKVInstance:
+		addr := fmt.Sprintf("%s:%d", inst.GetHost(), i.InstanceSpec.(*spec.TiKVSpec).StatusPort)
+		return api.NewStatusClient(ctx, []string{addr}, timeout, tlsCfg).GetConfig()
+	case *spec.TiDBInstance:
+		addr := fmt.Sprintf("%s:%d", inst.GetHost(), i.InstanceSpec.(*spec.TiDBSpec).StatusPort)
+		return api.NewStatusClient(ctx, []string{addr}, timeout, tlsCfg).GetConfig()
+	}
+	return nil, nil
+}
+
+// instanceConfigPath returns the path of the config file of the instance
+func instanceConfigPath(user string, inst spec.Instance) string {
+	return filepath.Join(spec.Abs(user, inst.DeployDir()), "conf", inst.ComponentName()+".toml")
+}
+
+// instanceDirPaths returns the dirs of the instance to init config
+func instanceDirPaths(user string, inst spec.Instance, cache string) meta.DirPaths {
+	return meta.DirPaths{
+		Deploy: spec.Abs(user, inst.DeployDir()),
+		// data dir would be empty for components which don't need it
+		Data: spec.MultiDirAbs(user, inst.DataDir()),
+		// log dir will always be with values, but might not used by the component
+		Log:   spec.Abs(user, inst.LogDir()),
+		Cache: cache,
+	}
+}
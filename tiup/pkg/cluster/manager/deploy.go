@@ -26,7 +26,6 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
@@ -50,6 +49,7 @@ type DeployOptions struct {
 	NoLabels          bool   // don't check labels for TiKV instance
 	Stage1            bool   // don't start the new instance, just deploy
 	Stage2            bool   // start instances and init Config after stage1
+	VarsFile          string // the file of variables substituted in the topology files
 }
 
 // DeployerInstance is a instance can deploy to a target deploy directory.
@@ -61,7 +61,7 @@ type DeployerInstance interface {
 func (m *Manager) Deploy(
 	name string,
 	clusterVersion string,
-	topoFile string,
+	topoFiles []string,
 	opt DeployOptions,
 	afterDeploy func(b *task.Builder, newPart spec.Topology, gOpt operator.Options),
 	skipConfirm bool,
@@ -86,7 +86,7 @@ func (m *Manager) Deploy(
 	metadata := m.specManager.NewMetadata()
 	topo := metadata.GetTopology()
 
-	if err := spec.ParseTopologyYaml(topoFile, topo); err != nil {
+	if err := spec.ParseTopologyFiles(topoFiles, opt.VarsFile, topo); err != nil {
 		return err
 	}
 	if clusterSpec, ok := topo.(*spec.Specification); ok {
@@ -116,6 +116,13 @@ func (m *Manager) Deploy(
 
 	spec.ExpandRelativeDir(topo)
 
+	if err := m.checkConfigSchema(topo, clusterVersion, opt.IgnoreConfigCheck); err != nil {
+		return err
+	}
+	if err := m.checkTopologySecrets(topo); err != nil {
+		return err
+	}
+
 	base := topo.BaseTopo()
 	if sshType := gOpt.SSHType; sshType != "" {
 		base.GlobalOptions.SSHType = sshType
@@ -150,7 +157,7 @@ func (m *Manager) Deploy(
 		sshConnProps  *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 	)
-	if gOpt.SSHType != executor.SSHTypeNone {
+	if gOpt.SSHType.UseSSH() {
 		var err error
 		if sshConnProps, err = tui.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword); err != nil {
 			return err
@@ -247,7 +254,7 @@ func (m *Manager) Deploy(
 				RootSSH(
 					inst.GetHost(),
 					inst.GetSSHPort(),
-					opt.User,
+					sshUser(topo, inst.GetHost(), opt.User),
 					sshConnProps.Password,
 					sshConnProps.IdentityFile,
 					sshConnProps.IdentityFilePassphrase,
@@ -401,6 +408,14 @@ func (m *Manager) Deploy(
 	downloadCompTasks = append(downloadCompTasks, dlTasks...)
 	deployCompTasks = append(deployCompTasks, dpTasks...)
 
+	// Deploy the backup agent if scheduled backup is set
+	dlTasks, dpTasks, err = buildBackupAgentTasks(m, name, topo, clusterVersion, gOpt, sshProxyProps)
+	if err != nil {
+		return err
+	}
+	downloadCompTasks = append(downloadCompTasks, dlTasks...)
+	deployCompTasks = append(deployCompTasks, dpTasks...)
+
 	builder := task.NewBuilder(m.logger).
 		Step("+ Generate SSH keys",
 			task.NewBuilder(m.logger).
@@ -432,6 +447,7 @@ func (m *Manager) Deploy(
 
 	metadata.SetUser(globalOptions.User)
 	metadata.SetVersion(clusterVersion)
+	recordBackupAgent(metadata)
 	err = m.specManager.SaveMeta(name, metadata)
 
 	if err != nil {
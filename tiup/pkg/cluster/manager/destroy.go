@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/tui"
 )
@@ -62,14 +63,38 @@ func (m *Manager) DestroyCluster(name string, gOpt operator.Options, destroyOpt
 		m.logger.Infof("Destroying cluster...")
 	}
 
+	// the backup agent is removed from the hosts it's recorded on, the ones of
+	// the topology are used for the clusters deployed before it's recorded
+	var backupTasks []*task.StepDisplay
+	if cm, ok := metadata.(*spec.ClusterMeta); ok {
+		agent := cm.BackupAgent
+		if agent == nil {
+			agent = cm.Topology.BackupAgent()
+		}
+		if agent != nil {
+			sshProxyProps := &tui.SSHConnectionProps{}
+			if gOpt.SSHType.UseSSH() && len(gOpt.SSHProxyHost) != 0 {
+				if sshProxyProps, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
+					return err
+				}
+			}
+			destroyGOpt := gOpt
+			destroyGOpt.Force = destroyOpt.Force
+			backupTasks = buildBackupAgentTeardownTasks(m, name, cm.Topology, agent, agent.Hosts, destroyGOpt, sshProxyProps)
+		}
+	}
+
 	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
 	if err != nil {
 		return err
 	}
+	b.Func("StopCluster", func(ctx context.Context) error {
+		return operator.Stop(ctx, topo, operator.Options{Force: destroyOpt.Force}, tlsCfg)
+	})
+	if len(backupTasks) > 0 {
+		b.ParallelStep("+ Destroy backup agent", destroyOpt.Force, backupTasks...)
+	}
 	t := b.
-		Func("StopCluster", func(ctx context.Context) error {
-			return operator.Stop(ctx, topo, operator.Options{Force: destroyOpt.Force}, tlsCfg)
-		}).
 		Func("DestroyCluster", func(ctx context.Context) error {
 			return operator.Destroy(ctx, topo, destroyOpt)
 		}).
@@ -88,6 +113,9 @@ func (m *Manager) DestroyCluster(name string, gOpt operator.Options, destroyOpt
 		return perrs.Trace(err)
 	}
 
+	if err := m.deleteClusterTLSSecrets(name); err != nil {
+		m.logger.Warnf("Failed to delete the TLS private keys of %s from the secret vault: %s", name, err)
+	}
 	if err := m.specManager.Remove(name); err != nil {
 		return perrs.Trace(err)
 	}
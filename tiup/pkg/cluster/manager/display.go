@@ -726,10 +726,10 @@ func (m *Manager) DisplayDashboardInfo(clusterName string, tlsCfg *tls.Config) e
 
 	u.Path = "/dashboard/"
 
-	if tlsCfg != nil {
+	if pfx := m.specManager.Path(clusterName, spec.TLSCertKeyDir, spec.PFXClientCert); tlsCfg != nil && utils.IsExist(pfx) {
 		fmt.Println(
 			"Client certificate:",
-			color.CyanString(m.specManager.Path(clusterName, spec.TLSCertKeyDir, spec.PFXClientCert)),
+			color.CyanString(pfx),
 		)
 		fmt.Println(
 			"Certificate password:",
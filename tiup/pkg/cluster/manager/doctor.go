@@ -0,0 +1,753 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+)
+
+// Names of the doctor checks
+const (
+	DoctorCheckStore      = "store-state"
+	DoctorCheckBalance    = "balance"
+	DoctorCheckConfig     = "config-drift"
+	DoctorCheckClock      = "clock-skew"
+	DoctorCheckDisk       = "disk-usage"
+	DoctorCheckChangefeed = "changefeed"
+	DoctorCheckBinlog     = "binlog"
+	DoctorCheckVersion    = "version"
+	DoctorCheckGeneral    = "general"
+)
+
+// thresholds of the doctor checks
+const (
+	doctorMaxClockSkew       = 500 * time.Millisecond
+	doctorMaxBalanceRatio    = 0.2 // max deviation to the average leader/region count
+	doctorMinBalanceCount    = 100 // ignore balance of small clusters
+	doctorDiskWarnPercent    = 80
+	doctorDiskFailPercent    = 90
+	doctorStatusWarn         = "Warn"
+	doctorStatusFail         = "Fail"
+	doctorStoreEngineTiFlash = "tiflash"
+)
+
+// DoctorResult is a problem found in a running cluster
+type DoctorResult struct {
+	Check      string `json:"check"`
+	Target     string `json:"target"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Doctor diagnoses the live state of a running cluster and suggests remediation
+func (m *Manager) Doctor(name string, gOpt operator.Options) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo, ok := metadata.GetTopology().(*spec.Specification)
+	if !ok {
+		return perrs.Errorf("doctor is not supported for %s clusters", m.sysName)
+	}
+	version := metadata.GetBaseMeta().Version
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	ctx := ctxt.New(
+		context.Background(),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	timeout := time.Second * time.Duration(gOpt.APITimeout)
+	pdClient := api.NewPDClient(
+		context.WithValue(ctx, logprinter.ContextKeyLogger, m.logger),
+		topo.GetPDList(),
+		timeout,
+		tlsCfg,
+	)
+
+	var results []DoctorResult
+	m.logger.Infof("Checking stores of the cluster %s...", name)
+	stores, err := pdClient.GetStores()
+	if err != nil {
+		results = append(results, DoctorResult{
+			Check:      DoctorCheckGeneral,
+			Target:     strings.Join(topo.GetPDList(), ","),
+			Status:     doctorStatusFail,
+			Message:    fmt.Sprintf("failed to query stores from PD: %s", err),
+			Suggestion: fmt.Sprintf("tiup cluster display %s -R pd", name),
+		})
+	} else {
+		results = append(results, diagnoseStores(name, stores)...)
+		results = append(results, diagnoseBalance(stores, topo.GetPDList())...)
+	}
+
+	m.logger.Infof("Checking versions of the cluster %s...", name)
+	results = append(results, m.diagnoseVersions(ctx, name, version, topo, pdClient, stores, tlsCfg, timeout)...)
+
+	m.logger.Infof("Checking config drift of the cluster %s...", name)
+	results = append(results, m.diagnoseConfigDrift(ctx, name, topo, pdClient, tlsCfg, timeout)...)
+
+	m.logger.Infof("Checking hosts of the cluster %s...", name)
+	results = append(results, m.diagnoseHosts(ctx, name, metadata, gOpt)...)
+
+	if len(topo.CDCServers) > 0 {
+		m.logger.Infof("Checking changefeeds of the cluster %s...", name)
+		results = append(results, m.diagnoseChangefeeds(ctx, version, topo, tlsCfg, timeout)...)
+	}
+	if len(topo.PumpServers) > 0 || len(topo.Drainers) > 0 {
+		m.logger.Infof("Checking binlog nodes of the cluster %s...", name)
+		results = append(results, m.diagnoseBinlog(ctx, name, topo, tlsCfg)...)
+	}
+
+	if m.logger.GetDisplayMode() == logprinter.DisplayModeJSON {
+		if results == nil {
+			results = []DoctorResult{}
+		}
+		d, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(d))
+		return nil
+	}
+
+	if len(results) == 0 {
+		m.logger.Infof("No problem found in cluster %s.", name)
+		return nil
+	}
+
+	resultTable := [][]string{
+		// Header
+		{"Check", "Target", "Status", "Message", "Suggestion"},
+	}
+	for _, r := range results {
+		status := color.YellowString(r.Status)
+		if r.Status == doctorStatusFail {
+			status = color.HiRedString(r.Status)
+		}
+		resultTable = append(resultTable, []string{r.Check, r.Target, status, r.Message, r.Suggestion})
+	}
+	tui.PrintTable(resultTable, true)
+	m.logger.Warnf("Found %d problem(s) in cluster %s.", len(results), name)
+	return nil
+}
+
+// diagnoseStores reports stores that are not serving normally
+func diagnoseStores(name string, stores *api.StoresInfo) []DoctorResult {
+	var results []DoctorResult
+	for _, s := range stores.Stores {
+		if s.Store == nil || s.Store.Store == nil {
+			continue
+		}
+		addr := s.Store.Address
+		switch s.Store.StateName {
+		case "Down", "Disconnected":
+			results = append(results, DoctorResult{
+				Check:      DoctorCheckStore,
+				Target:     addr,
+				Status:     doctorStatusFail,
+				Message:    fmt.Sprintf("store %d is %s", s.Store.Id, s.Store.StateName),
+				Suggestion: fmt.Sprintf("tiup cluster start %s -N %s", name, addr),
+			})
+		case "Tombstone":
+			results = append(results, DoctorResult{
+				Check:      DoctorCheckStore,
+				Target:     addr,
+				Status:     doctorStatusWarn,
+				Message:    fmt.Sprintf("store %d is Tombstone", s.Store.Id),
+				Suggestion: fmt.Sprintf("tiup cluster prune %s", name),
+			})
+		case "Offline":
+			results = append(results, DoctorResult{
+				Check:      DoctorCheckStore,
+				Target:     addr,
+				Status:     doctorStatusWarn,
+				Message:    fmt.Sprintf("store %d is Offline, regions are being migrated", s.Store.Id),
+				Suggestion: fmt.Sprintf("tiup cluster display %s", name),
+			})
+		}
+	}
+	return results
+}
+
+// isTiFlashStore checks if the store is a TiFlash store by its engine label
+func isTiFlashStore(s *api.StoreInfo) bool {
+	for _, label := range s.Store.Labels {
+		if label.Key == "engine" && label.Value == doctorStoreEngineTiFlash {
+			return true
+		}
+	}
+	return false
+}
+
+// diagnoseBalance reports TiKV stores whose leader or region count is far away
+// from the average
+func diagnoseBalance(stores *api.StoresInfo, pdList []string) []DoctorResult {
+	var up []*api.StoreInfo
+	for _, s := range stores.Stores {
+		if s.Store == nil || s.Store.Store == nil || s.Status == nil {
+			continue
+		}
+		if s.Store.StateName != "Up" || isTiFlashStore(s) {
+			continue
+		}
+		up = append(up, s)
+	}
+	if len(up) < 2 {
+		return nil
+	}
+
+	pdAddr := ""
+	if len(pdList) > 0 {
+		pdAddr = pdList[0]
+	}
+
+	var results []DoctorResult
+	for _, item := range []struct {
+		name  string
+		count func(*api.StoreInfo) int
+	}{
+		{"leader", func(s *api.StoreInfo) int { return s.Status.LeaderCount }},
+		{"region", func(s *api.StoreInfo) int { return s.Status.RegionCount }},
+	} {
+		total := 0
+		for _, s := range up {
+			total += item.count(s)
+		}
+		avg := float64(total) / float64(len(up))
+		if avg < doctorMinBalanceCount {
+			continue
+		}
+		for _, s := range up {
+			cnt := item.count(s)
+			if math.Abs(float64(cnt)-avg)/avg <= doctorMaxBalanceRatio {
+				continue
+			}
+			results = append(results, DoctorResult{
+				Check:      DoctorCheckBalance,
+				Target:     s.Store.Address,
+				Status:     doctorStatusWarn,
+				Message:    fmt.Sprintf("store %d has %d %ss, the average is %.0f", s.Store.Id, cnt, item.name, avg),
+				Suggestion: fmt.Sprintf("tiup ctl pd -u %s scheduler show", pdAddr),
+			})
+		}
+	}
+	return results
+}
+
+// diagnoseVersions reports instances whose running version is different from the one in meta
+func (m *Manager) diagnoseVersions(
+	ctx context.Context,
+	name, version string,
+	topo *spec.Specification,
+	pdClient *api.PDClient,
+	stores *api.StoresInfo,
+	tlsCfg *tls.Config,
+	timeout time.Duration,
+) []DoctorResult {
+	if utils.Version(version).IsNightly() {
+		return nil
+	}
+
+	var results []DoctorResult
+	mismatch := func(target, comp, running string) {
+		if running == "" || sameVersion(version, running) {
+			return
+		}
+		results = append(results, DoctorResult{
+			Check:      DoctorCheckVersion,
+			Target:     target,
+			Status:     doctorStatusWarn,
+			Message:    fmt.Sprintf("%s is running %s but the cluster version is %s", comp, running, version),
+			Suggestion: fmt.Sprintf("tiup cluster restart %s -N %s", name, target),
+		})
+	}
+
+	patched := set.NewStringSet()
+	topo.IterInstance(func(inst spec.Instance) {
+		if inst.IsPatched() {
+			patched.Insert(inst.ID())
+		}
+	})
+
+	if members, err := pdClient.GetMembers(); err != nil {
+		m.logger.Debugf("failed to get members from PD: %s", err)
+	} else {
+		for _, pd := range topo.PDServers {
+			target := fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort)
+			if patched.Exist(target) {
+				continue
+			}
+			for _, member := range members.Members {
+				if member.Name == pd.Name {
+					mismatch(target, spec.ComponentPD, member.BinaryVersion)
+				}
+			}
+		}
+	}
+
+	if stores != nil {
+		for _, s := range stores.Stores {
+			if s.Store == nil || s.Store.Store == nil || s.Store.StateName == "Tombstone" {
+				continue
+			}
+			if patched.Exist(s.Store.Address) {
+				continue
+			}
+			comp := spec.ComponentTiKV
+			if isTiFlashStore(s) {
+				comp = spec.ComponentTiFlash
+			}
+			mismatch(s.Store.Address, comp, s.Store.Version)
+		}
+	}
+
+	client := utils.NewHTTPClient(timeout, tlsCfg)
+	for _, db := range topo.TiDBServers {
+		target := fmt.Sprintf("%s:%d", db.Host, db.Port)
+		if patched.Exist(target) {
+			continue
+		}
+		body, err := client.Get(ctx, fmt.Sprintf("%s://%s:%d/status", scheme(tlsCfg), db.Host, db.StatusPort))
+		if err != nil {
+			m.logger.Debugf("failed to get status of %s: %s", target, err)
+			continue
+		}
+		status := struct {
+			Version string `json:"version"`
+		}{}
+		if err := json.Unmarshal(body, &status); err != nil {
+			continue
+		}
+		// the version is like 5.7.25-TiDB-v5.2.1
+		if idx := strings.Index(status.Version, "TiDB-"); idx >= 0 {
+			mismatch(target, spec.ComponentTiDB, status.Version[idx+len("TiDB-"):])
+		}
+	}
+	return results
+}
+
+// sameVersion checks if the running version matches the expected one, the
+// leading "v" and build suffixes are ignored
+func sameVersion(expected, running string) bool {
+	expected = strings.TrimPrefix(expected, "v")
+	running = strings.TrimPrefix(running, "v")
+	return running == expected || strings.HasPrefix(running, expected+"-")
+}
+
+func scheme(tlsCfg *tls.Config) string {
+	if tlsCfg != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// diagnoseConfigDrift compares the config set in topology with the one used by running
+// PD and TiKV instances
+func (m *Manager) diagnoseConfigDrift(
+	ctx context.Context,
+	name string,
+	topo *spec.Specification,
+	pdClient *api.PDClient,
+	tlsCfg *tls.Config,
+	timeout time.Duration,
+) []DoctorResult {
+	var results []DoctorResult
+	drift := func(target, comp string, diffs []string) {
+		for _, diff := range diffs {
+			results = append(results, DoctorResult{
+				Check:      DoctorCheckConfig,
+				Target:     target,
+				Status:     doctorStatusWarn,
+				Message:    diff,
+				Suggestion: fmt.Sprintf("tiup cluster reload %s -R %s", name, comp),
+			})
+		}
+	}
+
+	if len(topo.ServerConfigs.PD) > 0 {
+		live, err := pdClient.GetConfig()
+		if err != nil {
+			m.logger.Debugf("failed to get config from PD: %s", err)
+		} else {
+			drift(strings.Join(topo.GetPDList(), ","), spec.ComponentPD, diffConfig(spec.FlattenMap(topo.ServerConfigs.PD), live))
+		}
+	}
+
+	client := utils.NewHTTPClient(timeout, tlsCfg)
+	for _, kv := range topo.TiKVServers {
+		expected := spec.FlattenMap(spec.MergeConfig(topo.ServerConfigs.TiKV, kv.Config))
+		if len(expected) == 0 {
+			continue
+		}
+		target := fmt.Sprintf("%s:%d", kv.Host, kv.Port)
+		body, err := client.Get(ctx, fmt.Sprintf("%s://%s:%d/config", scheme(tlsCfg), kv.Host, kv.StatusPort))
+		if err != nil {
+			m.logger.Debugf("failed to get config of %s: %s", target, err)
+			continue
+		}
+		live := map[string]interface{}{}
+		if err := json.Unmarshal(body, &live); err != nil {
+			m.logger.Debugf("failed to parse config of %s: %s", target, err)
+			continue
+		}
+		drift(target, spec.ComponentTiKV, diffConfig(expected, spec.FlattenMap(live)))
+	}
+	return results
+}
+
+// diffConfig compares the flattened expected config with the live one, only
+// keys set in the expected config are compared
+func diffConfig(expected, live map[string]interface{}) []string {
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var diffs []string
+	for _, k := range keys {
+		lv, ok := liveConfigValue(live, k)
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s is set to %v but not found in running config", k, expected[k]))
+			continue
+		}
+		if !configValueEqual(expected[k], lv) {
+			diffs = append(diffs, fmt.Sprintf("%s is set to %v but running with %v", k, expected[k], lv))
+		}
+	}
+	return diffs
+}
+
+// liveConfigValue gets a value from the flattened live config, arrays may be
+// flattened as `key.0`, `key.1`... and are reassembled
+func liveConfigValue(live map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := live[key]; ok {
+		return v, true
+	}
+	var items []interface{}
+	for i := 0; ; i++ {
+		v, ok := live[fmt.Sprintf("%s.%d", key, i)]
+		if !ok {
+			break
+		}
+		items = append(items, v)
+	}
+	if len(items) == 0 {
+		return nil, false
+	}
+	return items, true
+}
+
+// configValueEqual compares two config values, readable sizes (1GB/1GiB) and
+// durations (1h/60m) are compared by their parsed values
+func configValueEqual(expected, live interface{}) bool {
+	if fmt.Sprint(expected) == fmt.Sprint(live) {
+		return true
+	}
+
+	ev, lv := reflect.ValueOf(expected), reflect.ValueOf(live)
+	if ev.Kind() == reflect.Slice && lv.Kind() == reflect.Slice {
+		if ev.Len() != lv.Len() {
+			return false
+		}
+		for i := 0; i < ev.Len(); i++ {
+			if !configValueEqual(ev.Index(i).Interface(), lv.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+
+	es, ls := fmt.Sprint(expected), fmt.Sprint(live)
+	if ef, err := strconv.ParseFloat(es, 64); err == nil {
+		if lf, err := strconv.ParseFloat(ls, 64); err == nil {
+			return ef == lf
+		}
+	}
+	if ed, err := time.ParseDuration(es); err == nil {
+		if ld, err := time.ParseDuration(ls); err == nil {
+			return ed == ld
+		}
+	}
+	if eb, err := units.RAMInBytes(es); err == nil {
+		if lb, err := units.RAMInBytes(ls); err == nil {
+			return eb == lb
+		}
+	}
+	return false
+}
+
+// diagnoseHosts checks the clock skew and disk usage of all the hosts
+func (m *Manager) diagnoseHosts(ctx context.Context, name string, metadata spec.Metadata, gOpt operator.Options) []DoctorResult {
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	if err := SetSSHKeySet(ctx, m.specManager.Path(name, "ssh", "id_rsa"), m.specManager.Path(name, "ssh", "id_rsa.pub")); err != nil {
+		m.logger.Debugf("failed to set ssh keys: %s", err)
+		return nil
+	}
+	if err := SetClusterSSH(ctx, topo, base.User, gOpt.SSHTimeout, gOpt.SSHType, topo.BaseTopo().GlobalOptions.SSHType); err != nil {
+		m.logger.Debugf("failed to connect hosts: %s", err)
+		return nil
+	}
+
+	// collect the dirs to check on each host
+	hostDirs := make(map[string][]string)
+	topo.IterInstance(func(inst spec.Instance) {
+		dirs := spec.MultiDirAbs(base.User, inst.DataDir())
+		if len(dirs) == 0 {
+			dirs = []string{spec.Abs(base.User, inst.DeployDir())}
+		}
+		hostDirs[inst.GetHost()] = append(hostDirs[inst.GetHost()], dirs...)
+	})
+	hosts := make([]string, 0, len(hostDirs))
+	for host := range hostDirs {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var results []DoctorResult
+	offsets := make(map[string]time.Duration)
+	for _, host := range hosts {
+		e, ok := ctxt.GetInner(ctx).GetExecutor(host)
+		if !ok {
+			continue
+		}
+
+		before := time.Now()
+		stdout, _, err := e.Execute(ctx, "date +%s%N", false)
+		after := time.Now()
+		if err == nil {
+			if ns, err := strconv.ParseInt(strings.TrimSpace(string(stdout)), 10, 64); err == nil {
+				offsets[host] = time.Unix(0, ns).Sub(before.Add(after.Sub(before) / 2))
+			}
+		}
+
+		mounts := set.NewStringSet()
+		for _, dir := range hostDirs[host] {
+			stdout, _, err := e.Execute(ctx, fmt.Sprintf("df -P %s | tail -n 1", dir), false)
+			if err != nil {
+				m.logger.Debugf("failed to get disk usage of %s:%s: %s", host, dir, err)
+				continue
+			}
+			mount, used, err := parseDiskUsage(string(stdout))
+			if err != nil || mounts.Exist(mount) {
+				continue
+			}
+			mounts.Insert(mount)
+			if r := diagnoseDiskUsage(host, mount, used); r != nil {
+				results = append(results, *r)
+			}
+		}
+	}
+	return append(results, diagnoseClockSkew(offsets, doctorMaxClockSkew)...)
+}
+
+// parseDiskUsage parses the output of `df -P` and returns the mount point and
+// its usage in percent
+func parseDiskUsage(out string) (string, int, error) {
+	fields := strings.Fields(strings.TrimSpace(out))
+	if len(fields) < 6 {
+		return "", 0, perrs.Errorf("unexpected output of df: %s", out)
+	}
+	used, err := strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+	if err != nil {
+		return "", 0, perrs.Annotatef(err, "unexpected output of df: %s", out)
+	}
+	return fields[5], used, nil
+}
+
+func diagnoseDiskUsage(host, mount string, used int) *DoctorResult {
+	status := ""
+	switch {
+	case used >= doctorDiskFailPercent:
+		status = doctorStatusFail
+	case used >= doctorDiskWarnPercent:
+		status = doctorStatusWarn
+	default:
+		return nil
+	}
+	return &DoctorResult{
+		Check:      DoctorCheckDisk,
+		Target:     host,
+		Status:     status,
+		Message:    fmt.Sprintf("%d%% of %s is used", used, mount),
+		Suggestion: "clean up unused files or expand the disk",
+	}
+}
+
+// diagnoseClockSkew reports hosts whose clock offset is too far from the median of all hosts
+func diagnoseClockSkew(offsets map[string]time.Duration, maxSkew time.Duration) []DoctorResult {
+	if len(offsets) < 2 {
+		return nil
+	}
+	hosts := make([]string, 0, len(offsets))
+	values := make([]time.Duration, 0, len(offsets))
+	for host, offset := range offsets {
+		hosts = append(hosts, host)
+		values = append(values, offset)
+	}
+	sort.Strings(hosts)
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	median := values[len(values)/2]
+
+	var results []DoctorResult
+	for _, host := range hosts {
+		skew := offsets[host] - median
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew <= maxSkew {
+			continue
+		}
+		results = append(results, DoctorResult{
+			Check:      DoctorCheckClock,
+			Target:     host,
+			Status:     doctorStatusFail,
+			Message:    fmt.Sprintf("clock is %s away from other hosts", skew.Round(time.Millisecond)),
+			Suggestion: "sync the clock with NTP, e.g. `chronyc makestep`",
+		})
+	}
+	return results
+}
+
+// diagnoseChangefeeds reports TiCDC changefeeds in abnormal state
+func (m *Manager) diagnoseChangefeeds(
+	ctx context.Context,
+	version string,
+	topo *spec.Specification,
+	tlsCfg *tls.Config,
+	timeout time.Duration,
+) []DoctorResult {
+	var addrs []string
+	for _, cdc := range topo.CDCServers {
+		addrs = append(addrs, fmt.Sprintf("%s:%d", cdc.Host, cdc.Port))
+	}
+	client := api.NewCDCOpenAPIClient(ctx, addrs, timeout, tlsCfg)
+	changefeeds, err := client.GetChangefeeds()
+	if err != nil {
+		return []DoctorResult{{
+			Check:   DoctorCheckChangefeed,
+			Target:  strings.Join(addrs, ","),
+			Status:  doctorStatusWarn,
+			Message: fmt.Sprintf("failed to list changefeeds: %s", err),
+		}}
+	}
+
+	pdAddr := ""
+	if pds := topo.GetPDList(); len(pds) > 0 {
+		pdAddr = fmt.Sprintf("%s://%s", scheme(tlsCfg), pds[0])
+	}
+	var results []DoctorResult
+	for _, cf := range changefeeds {
+		status := ""
+		switch cf.State {
+		case "error", "failed":
+			status = doctorStatusFail
+		case "stopped":
+			status = doctorStatusWarn
+		default:
+			if cf.Error == nil {
+				continue
+			}
+			status = doctorStatusWarn
+		}
+		msg := fmt.Sprintf("changefeed is %s, checkpoint %s", cf.State, cf.CheckpointTime)
+		if cf.Error != nil {
+			msg = fmt.Sprintf("%s, error: %s", msg, cf.Error.Message)
+		}
+		results = append(results, DoctorResult{
+			Check:      DoctorCheckChangefeed,
+			Target:     cf.ID,
+			Status:     status,
+			Message:    msg,
+			Suggestion: fmt.Sprintf("tiup ctl:%s cdc changefeed query --pd=%s -c %s", version, pdAddr, cf.ID),
+		})
+	}
+	return results
+}
+
+// diagnoseBinlog reports pump and drainer nodes of the topology that are not online
+func (m *Manager) diagnoseBinlog(ctx context.Context, name string, topo *spec.Specification, tlsCfg *tls.Config) []DoctorResult {
+	client, err := api.NewBinlogClient(topo.GetPDList(), tlsCfg)
+	if err != nil {
+		m.logger.Debugf("failed to create binlog client: %s", err)
+		return nil
+	}
+
+	expected := set.NewStringSet()
+	for _, pump := range topo.PumpServers {
+		expected.Insert(fmt.Sprintf("%s:%d", pump.Host, pump.Port))
+	}
+	for _, drainer := range topo.Drainers {
+		expected.Insert(fmt.Sprintf("%s:%d", drainer.Host, drainer.Port))
+	}
+
+	var results []DoctorResult
+	for _, fn := range []func(context.Context) ([]*api.NodeStatus, error){
+		client.PumpNodeStatus,
+		client.DrainerNodeStatus,
+	} {
+		nodes, err := fn(ctx)
+		if err != nil {
+			m.logger.Debugf("failed to get binlog node status: %s", err)
+			continue
+		}
+		for _, node := range nodes {
+			if !expected.Exist(node.Addr) || node.State == "online" {
+				continue
+			}
+			results = append(results, DoctorResult{
+				Check:      DoctorCheckBinlog,
+				Target:     node.Addr,
+				Status:     doctorStatusWarn,
+				Message:    fmt.Sprintf("node %s is %s", node.NodeID, node.State),
+				Suggestion: fmt.Sprintf("tiup cluster start %s -N %s", name, node.Addr),
+			})
+		}
+	}
+	return results
+}
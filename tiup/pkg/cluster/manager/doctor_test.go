@@ -0,0 +1,120 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(id uint64, addr, state string, leaders, regions int, labels ...*metapb.StoreLabel) *api.StoreInfo {
+	return &api.StoreInfo{
+		Store: &api.MetaStore{
+			Store:     &metapb.Store{Id: id, Address: addr, Labels: labels},
+			StateName: state,
+		},
+		Status: &api.StoreStatus{LeaderCount: leaders, RegionCount: regions},
+	}
+}
+
+func TestDiagnoseStores(t *testing.T) {
+	stores := &api.StoresInfo{Stores: []*api.StoreInfo{
+		newTestStore(1, "10.0.0.1:20160", "Up", 0, 0),
+		newTestStore(2, "10.0.0.2:20160", "Down", 0, 0),
+		newTestStore(3, "10.0.0.3:20160", "Tombstone", 0, 0),
+	}}
+	results := diagnoseStores("test", stores)
+	require.Len(t, results, 2)
+	assert.Equal(t, doctorStatusFail, results[0].Status)
+	assert.Equal(t, "tiup cluster start test -N 10.0.0.2:20160", results[0].Suggestion)
+	assert.Equal(t, "tiup cluster prune test", results[1].Suggestion)
+}
+
+func TestDiagnoseBalance(t *testing.T) {
+	tiflash := &metapb.StoreLabel{Key: "engine", Value: "tiflash"}
+	stores := &api.StoresInfo{Stores: []*api.StoreInfo{
+		newTestStore(1, "10.0.0.1:20160", "Up", 1000, 3000),
+		newTestStore(2, "10.0.0.2:20160", "Up", 1000, 3000),
+		newTestStore(3, "10.0.0.3:20160", "Up", 1000, 3000),
+		newTestStore(4, "10.0.0.4:20160", "Up", 400, 3000),
+		newTestStore(5, "10.0.0.5:3930", "Up", 0, 10, tiflash),
+	}}
+	results := diagnoseBalance(stores, []string{"10.0.0.1:2379"})
+	require.Len(t, results, 1)
+	assert.Equal(t, "10.0.0.4:20160", results[0].Target)
+
+	// small clusters are ignored
+	stores = &api.StoresInfo{Stores: []*api.StoreInfo{
+		newTestStore(1, "10.0.0.1:20160", "Up", 10, 30),
+		newTestStore(2, "10.0.0.2:20160", "Up", 1, 30),
+	}}
+	assert.Empty(t, diagnoseBalance(stores, nil))
+}
+
+func TestDiffConfig(t *testing.T) {
+	expected := map[string]interface{}{
+		"storage.block-cache.capacity": "4GB",
+		"raftstore.apply-pool-szie":    2,
+		"server.grpc-concurrency":      4,
+		"raftstore.split-region-check": "10m",
+		"replication.location-labels":  []interface{}{"zone", "host"},
+	}
+	live := map[string]interface{}{
+		"storage.block-cache.capacity":  "4GiB",
+		"server.grpc-concurrency":       float64(8),
+		"raftstore.split-region-check":  "600s",
+		"replication.location-labels.0": "zone",
+		"replication.location-labels.1": "host",
+	}
+	diffs := diffConfig(expected, live)
+	assert.Equal(t, []string{
+		"raftstore.apply-pool-szie is set to 2 but not found in running config",
+		"server.grpc-concurrency is set to 4 but running with 8",
+	}, diffs)
+}
+
+func TestDiagnoseClockSkew(t *testing.T) {
+	offsets := map[string]time.Duration{
+		"10.0.0.1": 100 * time.Millisecond,
+		"10.0.0.2": 120 * time.Millisecond,
+		"10.0.0.3": 2 * time.Second,
+	}
+	results := diagnoseClockSkew(offsets, 500*time.Millisecond)
+	require.Len(t, results, 1)
+	assert.Equal(t, "10.0.0.3", results[0].Target)
+}
+
+func TestParseDiskUsage(t *testing.T) {
+	mount, used, err := parseDiskUsage("/dev/nvme0n1p1 1921802432 1757419540 164382892 92% /data1\n")
+	require.Nil(t, err)
+	assert.Equal(t, "/data1", mount)
+	assert.Equal(t, 92, used)
+	assert.Equal(t, doctorStatusFail, diagnoseDiskUsage("10.0.0.1", mount, used).Status)
+	assert.Nil(t, diagnoseDiskUsage("10.0.0.1", mount, 50))
+
+	_, _, err = parseDiskUsage("df: /data1: No such file or directory")
+	assert.NotNil(t, err)
+}
+
+func TestSameVersion(t *testing.T) {
+	assert.True(t, sameVersion("v5.2.1", "5.2.1"))
+	assert.True(t, sameVersion("v5.2.1", "v5.2.1-20210915"))
+	assert.False(t, sameVersion("v5.2.1", "5.2.0"))
+	assert.False(t, sameVersion("v5.2.1", "5.2.10"))
+}
@@ -32,7 +32,8 @@ import (
 
 // EditConfigOptions contains the options for config edition.
 type EditConfigOptions struct {
-	NewTopoFile string // path to new topology file to substitute the original one
+	NewTopoFile       string // path to new topology file to substitute the original one
+	IgnoreConfigCheck bool   // ignore the config schema check result
 }
 
 // EditConfig lets the user edit the cluster's config.
@@ -53,7 +54,7 @@ func (m *Manager) EditConfig(name string, opt EditConfigOptions, skipConfirm boo
 		return perrs.AddStack(err)
 	}
 
-	newTopo, err := m.editTopo(topo, data, opt, skipConfirm)
+	newTopo, err := m.editTopo(topo, metadata.GetBaseMeta().Version, data, opt, skipConfirm)
 	if err != nil {
 		return err
 	}
@@ -78,7 +79,7 @@ func (m *Manager) EditConfig(name string, opt EditConfigOptions, skipConfirm boo
 // 2. Open file in editor.
 // 3. Check and update Topology.
 // 4. Save meta file.
-func (m *Manager) editTopo(origTopo spec.Topology, data []byte, opt EditConfigOptions, skipConfirm bool) (spec.Topology, error) {
+func (m *Manager) editTopo(origTopo spec.Topology, clusterVersion string, data []byte, opt EditConfigOptions, skipConfirm bool) (spec.Topology, error) {
 	var name string
 	if opt.NewTopoFile == "" {
 		file, err := os.CreateTemp(os.TempDir(), "*")
@@ -119,7 +120,7 @@ func (m *Manager) editTopo(origTopo spec.Topology, data []byte, opt EditConfigOp
 		m.logger.Infof("Failed to parse topology file: %v", err)
 		if opt.NewTopoFile == "" {
 			if pass, _ := tui.PromptForConfirmNo("Do you want to continue editing? [Y/n]: "); !pass {
-				return m.editTopo(origTopo, newData, opt, skipConfirm)
+				return m.editTopo(origTopo, clusterVersion, newData, opt, skipConfirm)
 			}
 		}
 		m.logger.Infof("Nothing changed.")
@@ -132,7 +133,20 @@ func (m *Manager) editTopo(origTopo spec.Topology, data []byte, opt EditConfigOp
 		m.logger.Errorf("%s", err)
 		if opt.NewTopoFile == "" {
 			if pass, _ := tui.PromptForConfirmNo("Do you want to continue editing? [Y/n]: "); !pass {
-				return m.editTopo(origTopo, newData, opt, skipConfirm)
+				return m.editTopo(origTopo, clusterVersion, newData, opt, skipConfirm)
+			}
+		}
+		m.logger.Infof("Nothing changed.")
+		return nil, nil
+	}
+
+	// report error if the config options are invalid for the cluster version
+	if err := m.checkConfigSchema(newTopo, clusterVersion, opt.IgnoreConfigCheck); err != nil {
+		fmt.Print(color.RedString("New topology could not be saved: "))
+		m.logger.Errorf("%s", err)
+		if opt.NewTopoFile == "" {
+			if pass, _ := tui.PromptForConfirmNo("Do you want to continue editing? [Y/n]: "); !pass {
+				return m.editTopo(origTopo, clusterVersion, newData, opt, skipConfirm)
 			}
 		}
 		m.logger.Infof("Nothing changed.")
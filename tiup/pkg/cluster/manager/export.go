@@ -0,0 +1,71 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"os"
+
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/k8s"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+)
+
+// ExportK8sOptions contains the options for exporting a cluster to Kubernetes
+type ExportK8sOptions struct {
+	k8s.Options
+	Output string // the file to write the resources, stdout if empty
+}
+
+// ExportK8s exports the topology of a cluster to the resources of tidb-operator
+func (m *Manager) ExportK8s(name string, opt ExportK8sOptions) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil {
+		return err
+	}
+	topo, ok := metadata.GetTopology().(*spec.Specification)
+	if !ok {
+		return perrs.Errorf("cluster %s can't be exported to Kubernetes", name)
+	}
+
+	export, err := k8s.Convert(name, metadata.GetBaseMeta().Version, topo, opt.Options)
+	if err != nil {
+		return err
+	}
+	if err := export.Validate(); err != nil {
+		return err
+	}
+	data, err := export.YAML()
+	if err != nil {
+		return err
+	}
+
+	for _, w := range export.Warnings {
+		m.logger.Warnf("%s", w)
+	}
+	if opt.Output == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+	if err := os.WriteFile(opt.Output, data, 0644); err != nil {
+		return perrs.AddStack(err)
+	}
+	m.logger.Infof("Kubernetes resources of cluster %s are written to %s", name, opt.Output)
+	return nil
+}
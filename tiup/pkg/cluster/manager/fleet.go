@@ -0,0 +1,248 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/meta"
+	"github.com/pingcap/tiup/pkg/tui"
+)
+
+// ClusterSelector selects a group of clusters managed by the same home
+type ClusterSelector struct {
+	All      bool              // select all the clusters
+	Patterns []string          // names or glob patterns of the clusters
+	Labels   map[string]string // labels the selected clusters must have
+}
+
+// IsFleet checks if the selector may select more than one cluster
+func (s ClusterSelector) IsFleet() bool {
+	if s.All || len(s.Labels) > 0 || len(s.Patterns) > 1 {
+		return true
+	}
+	for _, p := range s.Patterns {
+		if strings.ContainsAny(p, "*?[") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClusterLabels parses labels in the form of `key=value`
+func ParseClusterLabels(labels []string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, label := range labels {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, perrs.Errorf("invalid label '%s', should be in the form of key=value", label)
+		}
+		result[kv[0]] = kv[1]
+	}
+	return result, nil
+}
+
+// SelectClusters returns the sorted names of the clusters matching the selector
+func (m *Manager) SelectClusters(sel ClusterSelector) ([]string, error) {
+	for _, p := range sel.Patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, perrs.Annotatef(err, "invalid cluster name pattern '%s'", p)
+		}
+	}
+
+	clusters, err := m.GetClusterList()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, c := range clusters {
+		if !sel.All && len(sel.Patterns) > 0 && !matchAny(sel.Patterns, c.Name) {
+			continue
+		}
+		if !matchLabels(sel.Labels, c.Labels) {
+			continue
+		}
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return nil, perrs.New("no cluster matches the selector")
+	}
+	return names, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchLabels(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// FleetResult is the result of an operation on one cluster
+type FleetResult struct {
+	Cluster  string        `json:"cluster"`
+	Success  bool          `json:"success"`
+	Message  string        `json:"message,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// ForEachCluster runs fn on the clusters, at most concurrency clusters are
+// processed at the same time. The per-cluster results are printed as a summary
+// and an error is returned if any of the clusters failed.
+func (m *Manager) ForEachCluster(names []string, concurrency int, fn func(name string) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]FleetResult, len(names))
+	limit := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for i, name := range names {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, name string) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+
+			if concurrency == 1 {
+				m.logger.Infof("Running on cluster %s...", color.CyanString(name))
+			}
+			start := time.Now()
+			err := fn(name)
+			results[i] = FleetResult{
+				Cluster:  name,
+				Success:  err == nil,
+				Duration: time.Since(start).Round(time.Second),
+			}
+			if err != nil {
+				results[i].Message = err.Error()
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+
+	if m.logger.GetDisplayMode() == logprinter.DisplayModeJSON {
+		d, err := json.MarshalIndent(struct {
+			Results []FleetResult `json:"results"`
+		}{results}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(d))
+	} else {
+		resultTable := [][]string{
+			// Header
+			{"Cluster", "Result", "Duration", "Message"},
+		}
+		for _, r := range results {
+			result := color.GreenString("Success")
+			if !r.Success {
+				result = color.HiRedString("Fail")
+			}
+			resultTable = append(resultTable, []string{r.Cluster, result, r.Duration.String(), r.Message})
+		}
+		fmt.Println()
+		tui.PrintTable(resultTable, true)
+		fmt.Printf("Total clusters: %d, succeeded: %d, failed: %d\n", len(results), len(results)-failed, failed)
+	}
+
+	if failed > 0 {
+		return perrs.Errorf("%d of %d clusters failed", failed, len(results))
+	}
+	return nil
+}
+
+// SetClusterLabels sets and removes labels of a cluster, the labels are used
+// to select groups of clusters
+func (m *Manager) SetClusterLabels(name string, labels map[string]string, remove []string) error {
+	if err := clusterutil.ValidateClusterNameOrError(name); err != nil {
+		return err
+	}
+
+	metadata, err := m.meta(name)
+	if err != nil && !errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		return err
+	}
+
+	lm, ok := metadata.(spec.LabeledMetadata)
+	if !ok {
+		return perrs.Errorf("labels are not supported for %s clusters", m.sysName)
+	}
+
+	newLabels := make(map[string]string)
+	for k, v := range metadata.GetBaseMeta().Labels {
+		newLabels[k] = v
+	}
+	for k, v := range labels {
+		newLabels[k] = v
+	}
+	for _, k := range remove {
+		delete(newLabels, k)
+	}
+	if len(newLabels) == 0 {
+		newLabels = nil
+	}
+	lm.SetLabels(newLabels)
+
+	if err := m.specManager.SaveMeta(name, metadata); err != nil {
+		return err
+	}
+	m.logger.Infof("Labels of cluster %s: %s", name, color.CyanString(FormatClusterLabels(newLabels)))
+	return nil
+}
+
+// FormatClusterLabels formats labels in the form of `k1=v1,k2=v2`
+func FormatClusterLabels(labels map[string]string) string {
+	items := make([]string, 0, len(labels))
+	for k, v := range labels {
+		items = append(items, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterSelector(t *testing.T) {
+	assert.False(t, ClusterSelector{}.IsFleet())
+	assert.False(t, ClusterSelector{Patterns: []string{"prod"}}.IsFleet())
+	assert.True(t, ClusterSelector{Patterns: []string{"prod-*"}}.IsFleet())
+	assert.True(t, ClusterSelector{Patterns: []string{"a", "b"}}.IsFleet())
+	assert.True(t, ClusterSelector{All: true}.IsFleet())
+	assert.True(t, ClusterSelector{Labels: map[string]string{"env": "prod"}}.IsFleet())
+
+	assert.True(t, matchAny([]string{"dev", "prod-*"}, "prod-1"))
+	assert.False(t, matchAny([]string{"dev", "prod-*"}, "test"))
+}
+
+func TestClusterLabels(t *testing.T) {
+	labels, err := ParseClusterLabels([]string{"env=prod", "region=us-west=2"})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "region": "us-west=2"}, labels)
+	assert.Equal(t, "env=prod,region=us-west=2", FormatClusterLabels(labels))
+
+	_, err = ParseClusterLabels([]string{"env"})
+	assert.NotNil(t, err)
+	_, err = ParseClusterLabels([]string{"=prod"})
+	assert.NotNil(t, err)
+
+	assert.True(t, matchLabels(map[string]string{"env": "prod"}, labels))
+	assert.True(t, matchLabels(nil, labels))
+	assert.False(t, matchLabels(map[string]string{"env": "dev"}, labels))
+	assert.False(t, matchLabels(map[string]string{"team": "db"}, nil))
+}
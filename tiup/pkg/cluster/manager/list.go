@@ -27,11 +27,12 @@ import (
 
 // Cluster represents a clsuter
 type Cluster struct {
-	Name       string `json:"name"`
-	User       string `json:"user"`
-	Version    string `json:"version"`
-	Path       string `json:"path"`
-	PrivateKey string `json:"private_key"`
+	Name       string            `json:"name"`
+	User       string            `json:"user"`
+	Version    string            `json:"version"`
+	Path       string            `json:"path"`
+	PrivateKey string            `json:"private_key"`
+	Labels     map[string]string `json:"labels,omitempty"`
 }
 
 // ListCluster list the clusters.
@@ -56,7 +57,7 @@ func (m *Manager) ListCluster() error {
 	default:
 		clusterTable := [][]string{
 			// Header
-			{"Name", "User", "Version", "Path", "PrivateKey"},
+			{"Name", "User", "Version", "Path", "PrivateKey", "Labels"},
 		}
 		for _, v := range clusters {
 			clusterTable = append(clusterTable, []string{
@@ -65,6 +66,7 @@ func (m *Manager) ListCluster() error {
 				v.Version,
 				v.Path,
 				v.PrivateKey,
+				FormatClusterLabels(v.Labels),
 			})
 		}
 		tui.PrintTable(clusterTable, true)
@@ -96,6 +98,7 @@ func (m *Manager) GetClusterList() ([]Cluster, error) {
 			Version:    base.Version,
 			Path:       m.specManager.Path(name),
 			PrivateKey: m.specManager.Path(name, "ssh", "id_rsa"),
+			Labels:     base.Labels,
 		})
 	}
 
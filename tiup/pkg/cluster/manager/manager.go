@@ -22,7 +22,6 @@ import (
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
@@ -39,6 +38,9 @@ var (
 	errNSRename              = errorx.NewNamespace("rename")
 	errorRenameNameNotExist  = errNSRename.NewType("name_not_exist", utils.ErrTraitPreCheck)
 	errorRenameNameDuplicate = errNSRename.NewType("name_dup", utils.ErrTraitPreCheck)
+
+	errNSConfig         = errorx.NewNamespace("config")
+	errConfigSchemaFail = errNSConfig.NewType("schema_check_failed", utils.ErrTraitPreCheck)
 )
 
 // Manager to deploy a cluster.
@@ -137,7 +139,7 @@ You may read the OpenJDK doc for a reference: https://openjdk.java.net/install/
 
 func (m *Manager) sshTaskBuilder(name string, topo spec.Topology, user string, gOpt operator.Options) (*task.Builder, error) {
 	var p *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
-	if gOpt.SSHType != executor.SSHTypeNone && len(gOpt.SSHProxyHost) != 0 {
+	if gOpt.SSHType.UseSSH() && len(gOpt.SSHProxyHost) != 0 {
 		var err error
 		if p, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
 			return nil, err
@@ -166,6 +168,66 @@ func (m *Manager) sshTaskBuilder(name string, topo spec.Topology, user string, g
 		), nil
 }
 
+// sshUser returns the user to login the host, it's the one set in the hosts
+// inventory of the topology, or the user of the command if not set
+func sshUser(topo spec.Topology, host, user string) string {
+	if s, ok := topo.(*spec.Specification); ok {
+		return s.Hosts.SSHUser(host, user)
+	}
+	return user
+}
+
+// checkConfigSchema validates the configs in the topology against the config
+// schemas of the cluster version, the invalid options fail the check unless
+// the result is ignored
+func (m *Manager) checkConfigSchema(topo spec.Topology, clusterVersion string, ignore bool) error {
+	clusterSpec, ok := topo.(*spec.Specification)
+	if !ok {
+		return nil
+	}
+	issues, err := spec.CheckConfigSchema(clusterSpec, clusterVersion)
+	if err != nil {
+		return err
+	}
+
+	invalid := 0
+	for _, issue := range issues {
+		if issue.Fatal {
+			invalid++
+			m.logger.Errorf("%s", issue)
+		} else {
+			m.logger.Warnf("%s", issue)
+		}
+	}
+	if invalid == 0 || ignore {
+		return nil
+	}
+	return errConfigSchemaFail.
+		New("%d invalid config options found for %s", invalid, clusterVersion).
+		WithProperty(tui.SuggestionFromString("Please fix the config options above, or use '--ignore-config-check' to skip the check"))
+}
+
+// checkTopologySecrets resolves the secrets referenced in the topology, and
+// warns about the secrets saved in plaintext in the meta of the cluster
+func (m *Manager) checkTopologySecrets(topo spec.Topology) error {
+	if err := spec.CheckTopologySecrets(topo); err != nil {
+		return err
+	}
+	// only the cluster has the command to manage secrets
+	if _, ok := topo.(*spec.Specification); !ok {
+		return nil
+	}
+	paths, err := spec.PlaintextSecrets(topo)
+	if err != nil {
+		return err
+	}
+	if len(paths) > 0 {
+		m.logger.Warnf("The following items are saved in plaintext in the topology: %s", strings.Join(paths, ", "))
+		m.logger.Warnf("Save them by 'tiup cluster secret set <name>' and replace the values with 'secret://<name>' by 'tiup cluster edit-config'")
+	}
+	return nil
+}
+
 func (m *Manager) fillHostArch(s, p *tui.SSHConnectionProps, topo spec.Topology, gOpt *operator.Options, user string) error {
 	globalSSHType := topo.BaseTopo().GlobalOptions.SSHType
 	hostArch := map[string]string{}
@@ -183,7 +245,7 @@ func (m *Manager) fillHostArch(s, p *tui.SSHConnectionProps, topo spec.Topology,
 			RootSSH(
 				inst.GetHost(),
 				inst.GetSSHPort(),
-				user,
+				sshUser(topo, inst.GetHost(), user),
 				s.Password,
 				s.IdentityFile,
 				s.IdentityFilePassphrase,
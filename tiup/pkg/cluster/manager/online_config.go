@@ -0,0 +1,287 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// the settings of TiDB changed online via the status port, by the config options
+var tidbOnlineSettings = map[string]string{
+	"log.level": "log_level",
+}
+
+// reloadPlan is how the instances are reloaded, the instances with online
+// changes only are not restarted, nor the ones without any change
+type reloadPlan struct {
+	restart   []string                          // the instances to restart
+	online    map[string]map[string]interface{} // instance -> options changed online
+	unchanged []string                          // the instances without any change
+}
+
+// applyOnlineConfig changes the options of the running instance online, it
+// returns false if some of the options can't be changed without restart
+func (m *Manager) applyOnlineConfig(
+	inst spec.Instance,
+	clusterVersion string,
+	changes map[string]interface{},
+	tlsCfg *tls.Config,
+	gOpt operator.Options,
+) (bool, error) {
+	schema, err := spec.LoadConfigSchema(inst.ComponentName(), clusterVersion)
+	if err != nil {
+		return false, err
+	}
+	for k := range changes {
+		if !schema.IsOnline(k) {
+			return false, nil
+		}
+	}
+
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, m.logger)
+	timeout := time.Second * time.Duration(gOpt.APITimeout)
+	switch i := inst.(type) {
+	case *spec.PDInstance:
+		err = api.NewPDClient(ctx, []string{inst.ID()}, timeout, tlsCfg).UpdateConfig(changes)
+	case *spec.TiKVInstance:
+		addr := fmt.Sprintf("%s:%d", inst.GetHost(), i.InstanceSpec.(*spec.TiKVSpec).StatusPort)
+		err = api.NewStatusClient(ctx, []string{addr}, timeout, tlsCfg).UpdateConfig(changes)
+	case *spec.TiDBInstance:
+		settings := make(map[string]string)
+		for k, v := range changes {
+			setting, ok := tidbOnlineSettings[k]
+			if !ok {
+				return false, nil
+			}
+			settings[setting] = spec.FormatConfigValue(v)
+		}
+		addr := fmt.Sprintf("%s:%d", inst.GetHost(), i.InstanceSpec.(*spec.TiDBSpec).StatusPort)
+		err = api.NewStatusClient(ctx, []string{addr}, timeout, tlsCfg).UpdateSettings(settings)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	m.logger.Infof("Changed %s of %s online", strings.Join(sortedKeys(changes), ", "), inst.ID())
+	return true, nil
+}
+
+// planReload compares the files rendered from the topology with the ones
+// deployed on the hosts, and the config with the one the instances are running
+// with, to find the instances need to restart. Only PD, TiKV and TiDB are
+// compared as all their files are known, the other instances are always
+// restarted
+func (m *Manager) planReload(
+	name string,
+	topo spec.Topology,
+	base *spec.BaseMeta,
+	tlsCfg *tls.Config,
+	gOpt operator.Options,
+) (*reloadPlan, error) {
+	plan := &reloadPlan{online: make(map[string]map[string]interface{})}
+
+	roleFilter := set.NewStringSet(gOpt.Roles...)
+	nodeFilter := set.NewStringSet(gOpt.Nodes...)
+	var compared []spec.Instance
+	rendered := make(map[string]map[string][]byte)
+	for _, comp := range operator.FilterComponent(topo.ComponentsByUpdateOrder(), roleFilter) {
+		for _, inst := range operator.FilterInstance(comp.Instances(), nodeFilter) {
+			switch inst.ComponentName() {
+			case spec.ComponentPD, spec.ComponentTiKV, spec.ComponentTiDB:
+			default:
+				plan.restart = append(plan.restart, inst.ID())
+				continue
+			}
+			files, err := m.renderInstanceFiles(name, base, inst)
+			if err != nil {
+				return nil, err
+			}
+			rendered[inst.ID()] = files
+			compared = append(compared, inst)
+		}
+	}
+	if len(compared) == 0 {
+		return plan, nil
+	}
+
+	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
+	if err != nil {
+		return nil, err
+	}
+	var fetchTasks []*task.StepDisplay
+	for _, inst := range compared {
+		paths := sortedFilePaths(rendered[inst.ID()])
+		t := task.NewBuilder(m.logger).
+			Shell(
+				inst.GetHost(),
+				fmt.Sprintf("cat %s 2>/dev/null || true", instanceConfigPath(base.User, inst)),
+				"config-"+inst.ID(),
+				false,
+			).
+			Shell(
+				inst.GetHost(),
+				fmt.Sprintf("sha256sum %s 2>/dev/null || true", strings.Join(paths, " ")),
+				"checksum-"+inst.ID(),
+				false,
+			).
+			BuildAsStep(fmt.Sprintf("  - Comparing config of %s -> %s", inst.ComponentName(), inst.ID()))
+		fetchTasks = append(fetchTasks, t)
+	}
+	ctx := ctxt.New(
+		context.Background(),
+		gOpt.Concurrency,
+		m.logger,
+	)
+	t := b.ParallelStep("+ Compare instance configs", false, fetchTasks...).Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return nil, err
+		}
+		return nil, perrs.Trace(err)
+	}
+
+	for _, inst := range compared {
+		files := rendered[inst.ID()]
+		stdout, _, _ := ctxt.GetInner(ctx).GetOutputs("checksum-" + inst.ID())
+		deployedSums := parseChecksums(string(stdout))
+
+		configPath := instanceConfigPath(base.User, inst)
+		restart := false
+		for path, data := range files {
+			if path == configPath {
+				continue
+			}
+			// the other files such as the run script and service file need a restart
+			if sum := sha256.Sum256(data); deployedSums[path] != hex.EncodeToString(sum[:]) {
+				restart = true
+				break
+			}
+		}
+		if restart {
+			plan.restart = append(plan.restart, inst.ID())
+			continue
+		}
+
+		stdout, _, _ = ctxt.GetInner(ctx).GetOutputs("config-" + inst.ID())
+		deployed, err := spec.ParseTomlConfig(stdout)
+		if err != nil {
+			plan.restart = append(plan.restart, inst.ID())
+			continue
+		}
+		intended, err := spec.ParseTomlConfig(files[configPath])
+		if err != nil {
+			return nil, err
+		}
+		// the file may be pushed without the instance restarted, such as by
+		// reload --skip-restart, so it's compared with the running one
+		live, err := m.liveInstanceConfig(inst, tlsCfg, gOpt)
+		if err != nil {
+			m.logger.Warnf("Failed to get the live config of %s, restart it: %s", inst.ID(), err)
+		}
+
+		changes, restart := reloadChanges(intended, deployed, live)
+		switch {
+		case restart:
+			plan.restart = append(plan.restart, inst.ID())
+		case len(changes) == 0:
+			plan.unchanged = append(plan.unchanged, inst.ID())
+		default:
+			plan.online[inst.ID()] = changes
+		}
+	}
+	return plan, nil
+}
+
+// reloadChanges returns the options to change for the instance to run with
+// the intended config, the value of an option the instance is running with is
+// the live one, or the one in the deployed file if it's not reported. It
+// returns true if the instance needs to restart, such as the live config is
+// unknown or an option is unset
+func reloadChanges(intended, deployed, live map[string]interface{}) (map[string]interface{}, bool) {
+	if live == nil {
+		return nil, true
+	}
+	changes := make(map[string]interface{})
+	for _, drift := range spec.DiffConfig(intended, deployed, live) {
+		if _, reported := live[drift.Key]; reported && !drift.LiveDrifted() || !reported && !drift.FileDrifted() {
+			continue
+		}
+		if drift.Intended == nil {
+			// an option can't be unset online
+			return nil, true
+		}
+		changes[drift.Key] = drift.Intended
+	}
+	return changes, false
+}
+
+// onlineInstances returns the instances to change config online in the plan
+func onlineInstances(topo spec.Topology, plan *reloadPlan) []spec.Instance {
+	var insts []spec.Instance
+	topo.IterInstance(func(inst spec.Instance) {
+		if _, ok := plan.online[inst.ID()]; ok {
+			insts = append(insts, inst)
+		}
+	})
+	return insts
+}
+
+// parseChecksums parses the output of sha256sum to path -> checksum
+func parseChecksums(out string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			sums[fields[1]] = fields[0]
+		}
+	}
+	return sums
+}
+
+func sortedFilePaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
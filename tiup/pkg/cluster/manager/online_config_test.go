@@ -0,0 +1,160 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestApplyOnlineConfig(t *testing.T) {
+	assert := require.New(t)
+
+	var received []map[string]interface{}
+	var settings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config":
+			body := map[string]interface{}{}
+			assert.Nil(json.NewDecoder(r.Body).Decode(&body))
+			received = append(received, body)
+		case "/settings":
+			settings = append(settings, r.URL.RawQuery)
+		}
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.Nil(err)
+	statusPort, err := strconv.Atoi(port)
+	assert.Nil(err)
+
+	topo := &spec.Specification{}
+	assert.Nil(yaml.Unmarshal([]byte(fmt.Sprintf(`
+pd_servers:
+  - host: 127.0.0.1
+tikv_servers:
+  - host: 127.0.0.1
+    status_port: %[1]d
+tidb_servers:
+  - host: localhost
+    status_port: %[1]d
+`, statusPort)), topo))
+
+	m := &Manager{logger: logprinter.NewLogger("")}
+	gOpt := operator.Options{APITimeout: 5}
+	var tikv, tidb spec.Instance
+	for _, comp := range topo.ComponentsByStartOrder() {
+		switch comp.Name() {
+		case spec.ComponentTiKV:
+			tikv = comp.Instances()[0]
+		case spec.ComponentTiDB:
+			tidb = comp.Instances()[0]
+		}
+	}
+
+	applied, err := m.applyOnlineConfig(tikv, "v5.0.0", map[string]interface{}{
+		"raftstore.apply-pool-size":    int64(4),
+		"storage.block-cache.capacity": "8GB",
+	}, nil, gOpt)
+	assert.Nil(err)
+	assert.True(applied)
+	assert.Len(received, 1)
+	assert.Equal(float64(4), received[0]["raftstore.apply-pool-size"])
+	assert.Equal("8GB", received[0]["storage.block-cache.capacity"])
+
+	// an option requiring restart makes the whole change not applied
+	applied, err = m.applyOnlineConfig(tikv, "v5.0.0", map[string]interface{}{
+		"raftstore.apply-pool-size": int64(4),
+		"server.grpc-concurrency":   int64(8),
+	}, nil, gOpt)
+	assert.Nil(err)
+	assert.False(applied)
+	assert.Len(received, 1)
+
+	// the apply pool size can't be changed online in v4.0
+	applied, err = m.applyOnlineConfig(tikv, "v4.0.0", map[string]interface{}{
+		"raftstore.apply-pool-size": int64(4),
+	}, nil, gOpt)
+	assert.Nil(err)
+	assert.False(applied)
+
+	applied, err = m.applyOnlineConfig(tidb, "v5.0.0", map[string]interface{}{
+		"log.level": "warn",
+	}, nil, gOpt)
+	assert.Nil(err)
+	assert.True(applied)
+	assert.Equal([]string{"log_level=warn"}, settings)
+}
+
+func TestParseChecksums(t *testing.T) {
+	sums := parseChecksums(`3b5d  /tidb-deploy/tikv-20160/scripts/run_tikv.sh
+9f86  /etc/systemd/system/tikv-20160.service
+`)
+	require.Equal(t, map[string]string{
+		"/tidb-deploy/tikv-20160/scripts/run_tikv.sh": "3b5d",
+		"/etc/systemd/system/tikv-20160.service":      "9f86",
+	}, sums)
+}
+
+func TestReloadChanges(t *testing.T) {
+	assert := require.New(t)
+
+	intended := map[string]interface{}{
+		"raftstore.apply-pool-size":    int64(4),
+		"storage.block-cache.capacity": "8GB",
+	}
+
+	// the file is pushed already while the instance is not restarted, such as
+	// by reload --skip-restart, the change is still applied
+	changes, restart := reloadChanges(intended, intended, map[string]interface{}{
+		"raftstore.apply-pool-size":    int64(2),
+		"storage.block-cache.capacity": "8GiB",
+	})
+	assert.False(restart)
+	assert.Equal(map[string]interface{}{"raftstore.apply-pool-size": int64(4)}, changes)
+
+	// the options not reported by the instance are compared with the file
+	changes, restart = reloadChanges(intended, map[string]interface{}{
+		"raftstore.apply-pool-size": int64(4),
+	}, map[string]interface{}{
+		"raftstore.apply-pool-size": int64(4),
+	})
+	assert.False(restart)
+	assert.Equal(map[string]interface{}{"storage.block-cache.capacity": "8GB"}, changes)
+
+	changes, restart = reloadChanges(intended, intended, map[string]interface{}{
+		"raftstore.apply-pool-size":    int64(4),
+		"storage.block-cache.capacity": "8GB",
+	})
+	assert.False(restart)
+	assert.Empty(changes)
+
+	// the instance is restarted if it's unknown how it's running, or an
+	// option is unset
+	_, restart = reloadChanges(intended, intended, nil)
+	assert.True(restart)
+	_, restart = reloadChanges(intended, map[string]interface{}{"server.grpc-concurrency": int64(8)}, intended)
+	assert.True(restart)
+}
@@ -81,8 +81,10 @@ func (m *Manager) getSecretAccessKey() string {
 	return os.Getenv("SECRET_ACCESS_KEY")
 }
 
-func (m *Manager) getS3Address(s, t string) string {
-	return fmt.Sprintf(s3Address, s, t, m.getAccessKey(), m.getSecretAccessKey())
+// getS3Address returns the address of backups, the access keys in env
+// can be secret references such as secret://s3-access-key
+func (m *Manager) getS3Address(s, t string) (string, error) {
+	return spec.ResolveURLSecrets(fmt.Sprintf(s3Address, s, t, m.getAccessKey(), m.getSecretAccessKey()))
 }
 
 func (m *Manager) DoBackup(info ClusterInfo, us string) error {
@@ -98,7 +100,10 @@ func (m *Manager) DoBackup(info ClusterInfo, us string) error {
 	}
 
 	builder := backup.NewBackup(info.PDAddr[0])
-	backupURL := m.getS3Address(us, "full")
+	backupURL, err := m.getS3Address(us, "full")
+	if err != nil {
+		return err
+	}
 	builder.Storage(backupURL)
 	b := backup.BR{Path: br, Version: ver}
 	cmd := b.CreateCmd(context.TODO(), *builder...)
@@ -129,8 +134,16 @@ func (m *Manager) DoRestore(pdAddr string, metadata spec.Metadata, us string, to
 		return err
 	}
 	// Do full restore
+	fullURL, err := m.getS3Address(us, "full")
+	if err != nil {
+		return err
+	}
+	incURL, err := m.getS3Address(us, "inc")
+	if err != nil {
+		return err
+	}
 	builder := backup.NewRestore(pdAddr)
-	builder.Storage(m.getS3Address(us, "full"))
+	builder.Storage(fullURL)
 	b := backup.BR{Path: br, Version: ver}
 	fmt.Println(color.GreenString("start downloading..."))
 	cmd := b.Execute(context.TODO(), *builder...)
@@ -139,7 +152,7 @@ func (m *Manager) DoRestore(pdAddr string, metadata spec.Metadata, us string, to
 	}
 
 	builder = backup.NewLogRestore(pdAddr)
-	builder.Storage(m.getS3Address(us, "inc"))
+	builder.Storage(incURL)
 	builder.TimeRange(0, toTS)
 	b = backup.BR{Path: br, Version: ver}
 	fmt.Println(color.GreenString("start incremental downloading..."))
@@ -177,8 +190,12 @@ func (m *Manager) StartsIncrementalBackup(pdAddr string, metadata spec.Metadata,
 		return errors.New("backup to cloud is enabled already")
 	}
 	c.PipeYes = true
+	incURL, err := m.getS3Address(us, "inc")
+	if err != nil {
+		return err
+	}
 	builder = backup.NewIncrementalBackup(us, pdAddr)
-	builder.Storage(m.getS3Address(us, "inc"))
+	builder.Storage(incURL)
 	out, err = c.Execute(context.TODO(), *builder...)
 	if err != nil {
 		return err
@@ -197,7 +214,7 @@ func (m *Manager) GetPCloudClusterID(name string) (string, error) {
 	clusterFile := path.Join(cloudDir, authKeyForCluster(name), "cloudFile")
 	clusterID, err := os.ReadFile(clusterFile)
 	if os.IsNotExist(err) {
-		return "", errors.Errorf("the cluster %s hasn't been registered to pCloud")
+		return "", errors.Errorf("the cluster %s hasn't been registered to pCloud", name)
 	}
 	if err != nil {
 		return "", err
@@ -0,0 +1,209 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/joomcode/errorx"
+	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	operator "github.com/pingcap/tiup/pkg/cluster/operation"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/pingcap/tiup/pkg/tui"
+	"gopkg.in/yaml.v2"
+)
+
+// PlanOptions contains the options for planning a topology
+type PlanOptions struct {
+	User         string // username to login to the SSH server
+	IdentityFile string // path to the private key file
+	UsePassword  bool   // use password instead of identity file for ssh connection
+	HostsFile    string // the hosts inventory merged by host into the one in topology
+	VarsFile     string // the file of variables substituted in the topology files
+	Output       string // the file to write the planned topology to, stdout if empty
+}
+
+// Plan completes the topology with the placement of instances planned from the
+// resources probed on the hosts, and writes out the planned topology
+func (m *Manager) Plan(topoFiles []string, opt PlanOptions, gOpt operator.Options) error {
+	if opt.HostsFile != "" {
+		// the hosts file is composed after the topology, its hosts are added to the
+		// inventory of topology and replace the ones with the same address
+		topoFiles = append(append([]string{}, topoFiles...), opt.HostsFile)
+	}
+	topo, err := spec.ParseTopologyDraft(topoFiles, opt.VarsFile)
+	if err != nil {
+		return err
+	}
+
+	var (
+		sshConnProps  *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
+		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
+	)
+	if gOpt.SSHType.UseSSH() {
+		if sshConnProps, err = tui.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword); err != nil {
+			return err
+		}
+		if len(gOpt.SSHProxyHost) != 0 {
+			if sshProxyProps, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opt.Output == "" {
+		// keep stdout for the planned topology, the progress of probing is
+		// printed to stderr without the progress bars
+		m.logger.SetStdout(os.Stderr)
+		m.logger.SetDisplayMode(logprinter.DisplayModePlain)
+	}
+	resources, err := m.probeHostResources(topo, opt, sshConnProps, sshProxyProps, gOpt)
+	if err != nil {
+		return err
+	}
+	if err := spec.PlanPlacement(topo, resources); err != nil {
+		return err
+	}
+	printPlacement(topo)
+
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return perrs.AddStack(err)
+	}
+	// the planned topology must be valid to deploy
+	if err := yaml.UnmarshalStrict(data, &spec.Specification{}); err != nil {
+		return perrs.Annotate(err, "the planned topology is invalid")
+	}
+
+	if opt.Output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(opt.Output, data, 0644); err != nil {
+		return perrs.AddStack(err)
+	}
+	m.logger.Infof("The planned topology is written to %s", opt.Output)
+	return nil
+}
+
+// probeHostResources runs the probe script on the hosts of the topology and
+// returns the resources of each host
+func (m *Manager) probeHostResources(
+	topo *spec.Specification,
+	opt PlanOptions,
+	s, p *tui.SSHConnectionProps,
+	gOpt operator.Options,
+) (map[string]*spec.HostResources, error) {
+	ctx := ctxt.New(
+		context.Background(),
+		gOpt.Concurrency,
+		m.logger,
+	)
+
+	uniqueHosts := make(map[string]int) // host -> ssh-port
+	var probeTasks []*task.StepDisplay
+	topo.IterInstance(func(inst spec.Instance) {
+		host := inst.GetHost()
+		if _, found := uniqueHosts[host]; found {
+			return
+		}
+		uniqueHosts[host] = inst.GetSSHPort()
+		t := task.NewBuilder(m.logger).
+			RootSSH(
+				host,
+				inst.GetSSHPort(),
+				sshUser(topo, host, opt.User),
+				s.Password,
+				s.IdentityFile,
+				s.IdentityFilePassphrase,
+				gOpt.SSHTimeout,
+				gOpt.OptTimeout,
+				gOpt.SSHProxyHost,
+				gOpt.SSHProxyPort,
+				gOpt.SSHProxyUser,
+				p.Password,
+				p.IdentityFile,
+				p.IdentityFilePassphrase,
+				gOpt.SSHProxyTimeout,
+				gOpt.SSHType,
+				topo.GlobalOptions.SSHType,
+			).
+			Shell(host, spec.HostProbeScript, "", false).
+			BuildAsStep(fmt.Sprintf("  - Probing resources of %s:%d", host, inst.GetSSHPort()))
+		probeTasks = append(probeTasks, t)
+	})
+
+	t := task.NewBuilder(m.logger).
+		ParallelStep("+ Probe host resources", false, probeTasks...).
+		Build()
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return nil, err
+		}
+		return nil, perrs.Trace(err)
+	}
+
+	resources := make(map[string]*spec.HostResources)
+	for host := range uniqueHosts {
+		stdout, _, ok := ctxt.GetInner(ctx).GetOutputs(host)
+		if !ok {
+			return nil, perrs.Errorf("no resources probed on host %s", host)
+		}
+		res, err := spec.ParseHostResources(string(stdout))
+		if err != nil {
+			return nil, perrs.Annotatef(err, "failed to parse resources of host %s", host)
+		}
+		resources[host] = res
+	}
+	return resources, nil
+}
+
+// printPlacement prints the placement of the instances in the planned topology,
+// it's printed to stderr so that the planned topology in stdout is pure YAML
+func printPlacement(topo *spec.Specification) {
+	placement := [][]string{{"Instance", "Role", "Host", "Ports", "NUMA Node", "Data Dir", "Memory Limit"}}
+	for _, comp := range topo.ComponentsByStartOrder() {
+		for _, inst := range comp.Instances() {
+			ports := ""
+			for i, port := range inst.UsedPorts() {
+				if i > 0 {
+					ports += "/"
+				}
+				ports += strconv.Itoa(port)
+			}
+			numaNode, memoryLimit := spec.InstancePlacement(inst)
+			if numaNode == "" {
+				numaNode = "-"
+			}
+			if memoryLimit == "" {
+				memoryLimit = "-"
+			}
+			dataDir := inst.DataDir()
+			if dataDir == "" {
+				dataDir = "-"
+			}
+			placement = append(placement, []string{
+				inst.ID(), inst.Role(), inst.GetHost(), ports, numaNode, dataDir, memoryLimit,
+			})
+		}
+	}
+	tui.PrintTableTo(os.Stderr, placement, true)
+}
@@ -23,9 +23,9 @@ import (
 	perrs "github.com/pingcap/errors"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/task"
 	"github.com/pingcap/tiup/pkg/set"
 	"github.com/pingcap/tiup/pkg/tui"
 )
@@ -50,7 +50,7 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 	}
 
 	var sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
-	if gOpt.SSHType != executor.SSHTypeNone && len(gOpt.SSHProxyHost) != 0 {
+	if gOpt.SSHType.UseSSH() && len(gOpt.SSHProxyHost) != 0 {
 		var err error
 		if sshProxyProps, err = tui.ReadIdentityFileOrPassword(gOpt.SSHProxyIdentity, gOpt.SSHProxyUsePassword); err != nil {
 			return err
@@ -73,6 +73,10 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 	topo := metadata.GetTopology()
 	base := metadata.GetBaseMeta()
 
+	if err := m.checkTopologySecrets(topo); err != nil {
+		return err
+	}
+
 	uniqueHosts := make(map[string]hostInfo) // host -> ssh-port, os, arch
 	noAgentHosts := set.NewStringSet()
 	topo.IterInstance(func(inst spec.Instance) {
@@ -90,6 +94,22 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		}
 	})
 
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	// compare the configs before they are refreshed to find the instances to restart
+	var plan *reloadPlan
+	if !skipRestart {
+		if plan, err = m.planReload(name, topo, base, tlsCfg, gOpt); err != nil {
+			return err
+		}
+		if len(plan.unchanged) > 0 {
+			m.logger.Infof("Instances without config change are not restarted: %s", strings.Join(plan.unchanged, ","))
+		}
+	}
+
 	refreshConfigTasks, hasImported := buildRegenConfigTasks(m, name, topo, base, gOpt, nil, gOpt.IgnoreConfigCheck)
 	monitorConfigTasks := buildRefreshMonitoredConfigTasks(
 		m.specManager,
@@ -105,6 +125,21 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		sshProxyProps,
 	)
 
+	// the backup agent is only refreshed when reloading the whole cluster, and
+	// it's removed from the hosts it's no longer on after the backup is edited
+	var backupTeardownTasks, backupDownloadTasks, backupConfigTasks []*task.StepDisplay
+	cm, isCluster := metadata.(*spec.ClusterMeta)
+	refreshBackup := isCluster && len(gOpt.Roles) == 0 && len(gOpt.Nodes) == 0
+	if refreshBackup {
+		backupDownloadTasks, backupConfigTasks, err = buildBackupAgentTasks(m, name, topo, base.Version, gOpt, sshProxyProps)
+		if err != nil {
+			return err
+		}
+		if stale := cm.BackupAgent.StaleHosts(cm.Topology.BackupAgent()); len(stale) > 0 {
+			backupTeardownTasks = buildBackupAgentTeardownTasks(m, name, cm.Topology, cm.BackupAgent, stale, gOpt, sshProxyProps)
+		}
+	}
+
 	// handle dir scheme changes
 	if hasImported {
 		if err := spec.HandleImportPathMigration(name); err != nil {
@@ -130,13 +165,37 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		b.ParallelStep("+ Refresh monitor configs", gOpt.Force, monitorConfigTasks...)
 	}
 
+	if len(backupTeardownTasks) > 0 {
+		b.ParallelStep("+ Remove stale backup agent", gOpt.Force, backupTeardownTasks...)
+	}
+	if len(backupConfigTasks) > 0 {
+		b.ParallelStep("+ Download backup agent", gOpt.Force, backupDownloadTasks...).
+			ParallelStep("+ Refresh backup agent", gOpt.Force, backupConfigTasks...)
+	}
+
 	if !skipRestart {
-		tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
-		if err != nil {
-			return err
-		}
+		restartNodes := plan.restart
+		b.Func("ApplyOnlineConfig", func(ctx context.Context) error {
+			for _, inst := range onlineInstances(topo, plan) {
+				applied, err := m.applyOnlineConfig(inst, base.Version, plan.online[inst.ID()], tlsCfg, gOpt)
+				if err != nil {
+					m.logger.Warnf("Failed to change config of %s online, restart it instead: %s", inst.ID(), err)
+				}
+				if !applied {
+					restartNodes = append(restartNodes, inst.ID())
+				}
+			}
+			return nil
+		})
 		b.Func("UpgradeCluster", func(ctx context.Context) error {
-			return operator.Upgrade(ctx, topo, gOpt, tlsCfg)
+			if len(restartNodes) == 0 {
+				m.logger.Infof("No instance needs to restart")
+				return nil
+			}
+			restartOpt := gOpt
+			restartOpt.Roles = nil
+			restartOpt.Nodes = restartNodes
+			return operator.Upgrade(ctx, topo, restartOpt, tlsCfg)
 		})
 	}
 
@@ -155,6 +214,13 @@ func (m *Manager) Reload(name string, gOpt operator.Options, skipRestart, skipCo
 		return perrs.Trace(err)
 	}
 
+	if refreshBackup {
+		recordBackupAgent(metadata)
+		if err := m.specManager.SaveMeta(name, metadata); err != nil {
+			return err
+		}
+	}
+
 	m.logger.Infof("Reloaded cluster `%s` successfully", name)
 
 	return nil
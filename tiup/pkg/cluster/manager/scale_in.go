@@ -17,16 +17,22 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/docker/go-units"
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	perrs "github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
 	"github.com/pingcap/tiup/pkg/meta"
 	"github.com/pingcap/tiup/pkg/tui"
 )
@@ -51,6 +57,32 @@ func (m *Manager) ScaleIn(
 		force bool     = gOpt.Force
 		nodes []string = gOpt.Nodes
 	)
+
+	metadata, err := m.meta(name)
+	if err != nil &&
+		!errors.Is(perrs.Cause(err), meta.ErrValidate) &&
+		!errors.Is(perrs.Cause(err), spec.ErrMultipleTiSparkMaster) &&
+		!errors.Is(perrs.Cause(err), spec.ErrMultipleTisparkWorker) &&
+		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
+		// ignore conflict check error, node may be deployed by former version
+		// that lack of some certain conflict checks
+		return err
+	}
+
+	topo := metadata.GetTopology()
+	base := metadata.GetBaseMeta()
+
+	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
+	if err != nil {
+		return err
+	}
+
+	if cluster, ok := topo.(*spec.Specification); ok {
+		if err := m.planScaleIn(cluster, nodes, force, tlsCfg); err != nil {
+			return err
+		}
+	}
+
 	if !skipConfirm {
 		if force {
 			m.logger.Warnf(color.HiRedString(tui.ASCIIArtWarning))
@@ -77,20 +109,6 @@ func (m *Manager) ScaleIn(
 		m.logger.Infof("Scale-in nodes...")
 	}
 
-	metadata, err := m.meta(name)
-	if err != nil &&
-		!errors.Is(perrs.Cause(err), meta.ErrValidate) &&
-		!errors.Is(perrs.Cause(err), spec.ErrMultipleTiSparkMaster) &&
-		!errors.Is(perrs.Cause(err), spec.ErrMultipleTisparkWorker) &&
-		!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
-		// ignore conflict check error, node may be deployed by former version
-		// that lack of some certain conflict checks
-		return err
-	}
-
-	topo := metadata.GetTopology()
-	base := metadata.GetBaseMeta()
-
 	// Regenerate configuration
 	regenConfigTasks, hasImported := buildRegenConfigTasks(m, name, topo, base, gOpt, nodes, true)
 
@@ -101,11 +119,6 @@ func (m *Manager) ScaleIn(
 		}
 	}
 
-	tlsCfg, err := topo.TLSConfig(m.specManager.Path(name, spec.TLSCertKeyDir))
-	if err != nil {
-		return err
-	}
-
 	b, err := m.sshTaskBuilder(name, topo, base.User, gOpt)
 	if err != nil {
 		return err
@@ -135,3 +148,67 @@ func (m *Manager) ScaleIn(
 
 	return nil
 }
+
+// scaleInPlanTimeout is the timeout of the PD queries to plan the scale-in
+const scaleInPlanTimeout = 10 * time.Second
+
+// planScaleIn checks if the stores remaining after the scale-in can hold the
+// data of the stores to be removed and still satisfy the replica constraints,
+// the scale-in is refused if they can't, the plan is skipped if the scale-in
+// is forced as the stores and PD may be unreachable
+func (m *Manager) planScaleIn(
+	cluster *spec.Specification,
+	nodes []string,
+	force bool,
+	tlsCfg *tls.Config,
+) error {
+	addrs := operator.ScaleInStoreAddrs(cluster, nodes)
+	if len(addrs) == 0 {
+		return nil
+	}
+	if os.Getenv(operator.EnvNameSkipScaleInTopoCheck) != "" {
+		return nil
+	}
+	if force {
+		m.logger.Warnf("The scale-in is forced, the capacity of the remaining stores is not checked")
+		return nil
+	}
+
+	ctx := context.WithValue(context.Background(), logprinter.ContextKeyLogger, m.logger)
+	pdClient := api.NewPDClient(ctx, cluster.GetPDList(), scaleInPlanTimeout, tlsCfg)
+	stores, err := pdClient.GetStores()
+	if err == nil {
+		var replication *api.PDReplicationConfig
+		if replication, err = pdClient.GetReplicationConfig(); err == nil {
+			plan := operator.PlanScaleIn(stores, replication, addrs)
+			return m.reportScaleInPlan(plan)
+		}
+	}
+	return perrs.Annotate(err, "failed to plan the scale-in, use `--force` to skip the capacity check")
+}
+
+func (m *Manager) reportScaleInPlan(plan *operator.ScaleInPlan) error {
+	m.logger.Infof("Capacity plan of the scale-in (max-replicas: %d, isolation-level: %q):", plan.MaxReplicas, plan.IsolationLevel)
+	planTable := [][]string{
+		// Header
+		{"Engine", "Removing", "Remaining", "Data to Migrate", "Available Space"},
+	}
+	for _, g := range plan.Groups {
+		planTable = append(planTable, []string{
+			g.Engine,
+			strings.Join(g.Removed, ","),
+			strconv.Itoa(g.Remain),
+			units.BytesSize(float64(g.MigrateSize)),
+			units.BytesSize(float64(g.RemainSpace)),
+		})
+	}
+	tui.PrintTable(planTable, true)
+
+	if plan.Safe() {
+		return nil
+	}
+	for _, problem := range plan.Problems {
+		m.logger.Warnf("  - %s", color.YellowString(problem))
+	}
+	return perrs.Errorf("the stores remaining after scale-in can't hold the data safely, add more stores or use `--force` to ignore")
+}
@@ -25,7 +25,6 @@ import (
 	"github.com/pingcap/tiup/pkg/cluster/api"
 	"github.com/pingcap/tiup/pkg/cluster/clusterutil"
 	"github.com/pingcap/tiup/pkg/cluster/ctxt"
-	"github.com/pingcap/tiup/pkg/cluster/executor"
 	operator "github.com/pingcap/tiup/pkg/cluster/operation"
 	"github.com/pingcap/tiup/pkg/cluster/spec"
 	"github.com/pingcap/tiup/pkg/cluster/task"
@@ -40,7 +39,7 @@ import (
 // ScaleOut scale out the cluster.
 func (m *Manager) ScaleOut(
 	name string,
-	topoFile string,
+	topoFiles []string,
 	afterDeploy func(b *task.Builder, newPart spec.Topology, gOpt operator.Options),
 	final func(b *task.Builder, name string, meta spec.Metadata, gOpt operator.Options),
 	opt DeployOptions,
@@ -81,14 +80,14 @@ func (m *Manager) ScaleOut(
 	} else { // if stage2 is true, not need check topology or other
 		// check for the input topology to let user confirm if there're any
 		// global configs set
-		if err := checkForGlobalConfigs(m.logger, topoFile, skipConfirm); err != nil {
+		if err := checkForGlobalConfigs(m.logger, topoFiles, opt.VarsFile, skipConfirm); err != nil {
 			return err
 		}
 
 		// The no tispark master error is ignored, as if the tispark master is removed from the topology
 		// file for some reason (manual edit, for example), it is still possible to scale-out it to make
 		// the whole topology back to normal state.
-		if err := spec.ParseTopologyYaml(topoFile, newPart); err != nil &&
+		if err := spec.ParseTopologyFiles(topoFiles, opt.VarsFile, newPart); err != nil &&
 			!errors.Is(perrs.Cause(err), spec.ErrNoTiSparkMaster) {
 			return err
 		}
@@ -114,7 +113,7 @@ func (m *Manager) ScaleOut(
 		sshConnProps  *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 		sshProxyProps *tui.SSHConnectionProps = &tui.SSHConnectionProps{}
 	)
-	if gOpt.SSHType != executor.SSHTypeNone {
+	if gOpt.SSHType.UseSSH() {
 		var err error
 		if sshConnProps, err = tui.ReadIdentityFileOrPassword(opt.IdentityFile, opt.UsePassword); err != nil {
 			return err
@@ -142,6 +141,13 @@ func (m *Manager) ScaleOut(
 		}
 		spec.ExpandRelativeDir(mergedTopo)
 
+		if err := m.checkConfigSchema(newPart, base.Version, opt.IgnoreConfigCheck); err != nil {
+			return err
+		}
+		if err := m.checkTopologySecrets(mergedTopo); err != nil {
+			return err
+		}
+
 		if topo, ok := mergedTopo.(*spec.Specification); ok {
 			// Check if TiKV's label set correctly
 			if !opt.NoLabels {
@@ -243,8 +249,8 @@ func validateNewTopo(topo spec.Topology) (err error) {
 
 // checkForGlobalConfigs checks the input scale out topology to make sure users are aware
 // of the global config fields in it will be ignored.
-func checkForGlobalConfigs(logger *logprinter.Logger, topoFile string, skipConfirm bool) error {
-	yamlFile, err := spec.ReadYamlFile(topoFile)
+func checkForGlobalConfigs(logger *logprinter.Logger, topoFiles []string, varsFile string, skipConfirm bool) error {
+	yamlFile, err := spec.ComposeTopology(topoFiles, varsFile)
 	if err != nil {
 		return err
 	}
@@ -64,6 +64,14 @@ func (m *Manager) Upgrade(name string, clusterVersion string, opt operator.Optio
 		return err
 	}
 
+	// the options removed in the new version are reported
+	if err := m.checkConfigSchema(topo, clusterVersion, opt.IgnoreConfigCheck); err != nil {
+		return err
+	}
+	if err := m.checkTopologySecrets(topo); err != nil {
+		return err
+	}
+
 	if !skipConfirm {
 		if err := tui.PromptForConfirmOrAbortError(
 			"This operation will upgrade %s %s cluster %s to %s.\nDo you want to continue? [y/N]:",
@@ -37,6 +37,9 @@ type CheckOptions struct {
 	EnableMem  bool
 	EnableDisk bool
 
+	// custom checks loaded from the check.d directory
+	Plugins []*CheckPlugin
+
 	// pre-defined goups of checks
 	// GroupMinimal bool // a minimal set of checks
 }
@@ -66,10 +69,12 @@ var (
 
 // CheckResult is the result of a check
 type CheckResult struct {
-	Name string // Name of the check
-	Err  error  // An embedded error
-	Warn bool   // The check didn't pass, but not a big problem
-	Msg  string // A message or description
+	Name    string // Name of the check
+	Err     error  // An embedded error
+	Warn    bool   // The check didn't pass, but not a big problem
+	Msg     string // A message or description
+	Fix     string // A command to fix the failed check, only set by custom checks
+	FixSudo bool   // Run the fix command with root privilege
 }
 
 // Error implements the error interface
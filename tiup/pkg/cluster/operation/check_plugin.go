@@ -0,0 +1,216 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"gopkg.in/yaml.v2"
+)
+
+// CheckPluginDirName is the name of the directory (under the TiUP home) where
+// custom check rules are loaded from
+const CheckPluginDirName = "check.d"
+
+// CheckPlugin is a user defined check rule, it runs a shell command or an external
+// binary on every host being checked, and compares its output with the expected one
+type CheckPlugin struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	Command     string   `yaml:"command,omitempty"` // shell command to run on the remote host
+	Binary      string   `yaml:"binary,omitempty"`  // local binary that is copied to and run on the remote host, {os} and {arch} in it are replaced by the platform of the host
+	Args        []string `yaml:"args,omitempty"`    // arguments passed to the binary
+	Expect      string   `yaml:"expect,omitempty"`  // regexp the trimmed stdout must match, exit code 0 is required if empty
+	Sudo        bool     `yaml:"sudo,omitempty"`    // run the command and the fix with root privilege
+	Warn        bool     `yaml:"warn,omitempty"`    // report a warning instead of a failure
+	Fix         string   `yaml:"fix,omitempty"`     // command that is run with `--apply` when the check fails
+
+	expect *regexp.Regexp
+}
+
+// checkPluginFile is the content of a rule file in the check.d directory
+type checkPluginFile struct {
+	Checks []*CheckPlugin `yaml:"checks"`
+}
+
+// Validate checks if the rule is well formed and compiles the expected pattern
+func (p *CheckPlugin) Validate() error {
+	if p.Name == "" {
+		return errors.New("name of custom check is not set")
+	}
+	if (p.Command == "") == (p.Binary == "") {
+		return errors.Errorf("custom check %s must set exactly one of 'command' and 'binary'", p.Name)
+	}
+	if p.Expect != "" {
+		re, err := regexp.Compile(p.Expect)
+		if err != nil {
+			return errors.Annotatef(err, "invalid expect pattern of custom check %s", p.Name)
+		}
+		p.expect = re
+	}
+	return nil
+}
+
+// RemoteBinary returns the path of the plugin binary on remote hosts, the
+// binary is placed under binDir and named after its local path, so binaries
+// with the same name in different directories don't overwrite each other, an
+// empty string is returned for command rules
+func (p *CheckPlugin) RemoteBinary(binDir string) string {
+	if p.Binary == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(p.Binary))
+	name := strings.NewReplacer("{os}", "", "{arch}", "").Replace(filepath.Base(p.Binary))
+	return filepath.Join(binDir, fmt.Sprintf("check-%s-%x", strings.Trim(name, "-_."), sum[:4]))
+}
+
+// elfMachines is the machine of ELF executables built for each arch
+var elfMachines = map[string]elf.Machine{
+	"amd64": elf.EM_X86_64,
+	"arm64": elf.EM_AARCH64,
+}
+
+// LocalBinary returns the local path of the plugin binary for the platform of
+// host, the binary must be a script or an ELF executable of the arch
+func (p *CheckPlugin) LocalBinary(goos, arch string) (string, error) {
+	path := strings.NewReplacer("{os}", goos, "{arch}", arch).Replace(p.Binary)
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to open the binary of custom check %s", p.Name)
+	}
+	defer f.Close()
+
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(f, magic); err == nil && string(magic) == "#!" {
+		return path, nil
+	}
+	ef, err := elf.NewFile(f)
+	if err != nil || goos != "linux" {
+		return "", errors.Errorf("binary %s of custom check %s is not a script or a %s executable", path, p.Name, goos)
+	}
+	if machine, ok := elfMachines[arch]; !ok || ef.Machine != machine {
+		return "", errors.Errorf("binary %s of custom check %s is built for %s, but the host is %s/%s, use {arch} in the path to set binaries for each arch",
+			path, p.Name, ef.Machine, goos, arch)
+	}
+	return path, nil
+}
+
+// LoadCheckPlugins reads all the `*.yaml` and `*.yml` rule files in dir, a
+// missing directory is not considered as an error. Relative binary paths are
+// resolved against dir.
+func LoadCheckPlugins(dir string) ([]*CheckPlugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Trace(err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+
+	var plugins []*CheckPlugin
+	names := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var content checkPluginFile
+		if err := yaml.UnmarshalStrict(data, &content); err != nil {
+			return nil, errors.Annotatef(err, "failed to parse custom check file %s", file)
+		}
+		for _, p := range content.Checks {
+			if err := p.Validate(); err != nil {
+				return nil, errors.Annotatef(err, "invalid custom check in %s", file)
+			}
+			if prev, ok := names[p.Name]; ok {
+				return nil, errors.Errorf("custom check %s is defined in both %s and %s", p.Name, prev, file)
+			}
+			names[p.Name] = file
+			if p.Binary != "" && !filepath.IsAbs(p.Binary) {
+				p.Binary = filepath.Join(dir, p.Binary)
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins, nil
+}
+
+// CheckPlugins runs the custom check rules on the host, binaries of the rules
+// are expected to be already copied to binDir
+func CheckPlugins(ctx context.Context, e ctxt.Executor, binDir string, plugins []*CheckPlugin) []*CheckResult {
+	results := make([]*CheckResult, 0, len(plugins))
+	for _, p := range plugins {
+		results = append(results, runCheckPlugin(ctx, e, binDir, p))
+	}
+	return results
+}
+
+func runCheckPlugin(ctx context.Context, e ctxt.Executor, binDir string, p *CheckPlugin) *CheckResult {
+	result := &CheckResult{
+		Name:    p.Name,
+		Warn:    p.Warn,
+		Fix:     p.Fix,
+		FixSudo: p.Sudo,
+	}
+
+	cmd := p.Command
+	if p.Binary != "" {
+		cmd = strings.Join(append([]string{p.RemoteBinary(binDir)}, p.Args...), " ")
+	}
+
+	stdout, stderr, err := e.Execute(ctx, cmd, p.Sudo)
+	out := strings.TrimSpace(string(stdout))
+	if err != nil && p.expect == nil {
+		result.Err = fmt.Errorf("%s failed, %s", checkPluginDesc(p), strings.TrimSpace(string(stderr)))
+		return result
+	}
+	if p.expect != nil && !p.expect.MatchString(out) {
+		result.Err = fmt.Errorf("%s, expect output matching '%s' but got '%s'", checkPluginDesc(p), p.Expect, out)
+		return result
+	}
+
+	result.Msg = checkPluginDesc(p)
+	if out != "" {
+		result.Msg = fmt.Sprintf("%s: %s", result.Msg, strings.Split(out, "\n")[0])
+	}
+	return result
+}
+
+func checkPluginDesc(p *CheckPlugin) string {
+	if p.Description != "" {
+		return p.Description
+	}
+	return fmt.Sprintf("custom check %s", p.Name)
+}
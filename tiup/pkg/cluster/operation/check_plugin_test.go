@@ -0,0 +1,158 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExecutor returns the predefined output of each command
+type fakeExecutor map[string]string
+
+func (e fakeExecutor) Execute(ctx context.Context, cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	out, ok := e[cmd]
+	if !ok {
+		return nil, []byte("command not found"), errors.New("exit status 127")
+	}
+	return []byte(out), nil, nil
+}
+
+func (e fakeExecutor) Transfer(ctx context.Context, src, dst string, download bool, limit int, compress bool) error {
+	return nil
+}
+
+func TestLoadCheckPlugins(t *testing.T) {
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	plugins, err := LoadCheckPlugins(filepath.Join(dir, "not-exist"))
+	assert.Nil(err)
+	assert.Empty(plugins)
+
+	assert.Nil(os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(`
+checks:
+  - name: kernel
+    command: uname -r
+    expect: "^5\\."
+  - name: audit
+    binary: audit-checker
+    args: ["--strict"]
+    warn: true
+`), 0644))
+	assert.Nil(os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0644))
+
+	plugins, err = LoadCheckPlugins(dir)
+	assert.Nil(err)
+	assert.Len(plugins, 2)
+	assert.Equal("kernel", plugins[0].Name)
+	assert.Equal(filepath.Join(dir, "audit-checker"), plugins[1].Binary)
+	remote := plugins[1].RemoteBinary("/tmp/tiup/bin")
+	assert.Regexp("^/tmp/tiup/bin/check-audit-checker-[0-9a-f]{8}$", remote)
+	// binaries of the same name in different directories are kept apart
+	other := &CheckPlugin{Name: "other", Binary: "/opt/audit-checker"}
+	assert.NotEqual(remote, other.RemoteBinary("/tmp/tiup/bin"))
+
+	// duplicated names are rejected
+	assert.Nil(os.WriteFile(filepath.Join(dir, "b.yml"), []byte(`
+checks:
+  - name: kernel
+    command: uname -a
+`), 0644))
+	_, err = LoadCheckPlugins(dir)
+	assert.NotNil(err)
+
+	// both command and binary set
+	assert.Nil(os.WriteFile(filepath.Join(dir, "b.yml"), []byte(`
+checks:
+  - name: both
+    command: uname -a
+    binary: uname
+`), 0644))
+	_, err = LoadCheckPlugins(dir)
+	assert.NotNil(err)
+}
+
+func TestCheckPluginLocalBinary(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+
+	// scripts run on any platform
+	assert.Nil(os.WriteFile(filepath.Join(dir, "check.sh"), []byte("#!/bin/sh\necho ok\n"), 0755))
+	p := &CheckPlugin{Name: "script", Binary: filepath.Join(dir, "check.sh")}
+	path, err := p.LocalBinary("linux", "arm64")
+	assert.Nil(err)
+	assert.Equal(p.Binary, path)
+
+	// the test binary itself is an ELF executable of the current arch
+	if runtime.GOOS != "linux" {
+		t.Skip("ELF executables are only built on linux")
+	}
+	self, err := os.Executable()
+	assert.Nil(err)
+	assert.Nil(os.Symlink(self, filepath.Join(dir, "checker-"+runtime.GOARCH)))
+	p = &CheckPlugin{Name: "checker", Binary: filepath.Join(dir, "checker-{arch}")}
+	path, err = p.LocalBinary("linux", runtime.GOARCH)
+	assert.Nil(err)
+	assert.Equal(filepath.Join(dir, "checker-"+runtime.GOARCH), path)
+	_, err = p.LocalBinary("linux", "riscv64")
+	assert.NotNil(err)
+
+	other := map[string]string{"amd64": "arm64", "arm64": "amd64"}[runtime.GOARCH]
+	p = &CheckPlugin{Name: "checker", Binary: self}
+	_, err = p.LocalBinary("linux", other)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "is built for")
+}
+
+func TestCheckPlugins(t *testing.T) {
+	assert := require.New(t)
+
+	plugins := []*CheckPlugin{
+		{Name: "kernel", Command: "uname -r", Expect: "^5\\."},
+		{Name: "kernel-old", Command: "uname -r", Expect: "^3\\.", Fix: "reboot"},
+		{Name: "missing", Command: "not-exist", Warn: true},
+		{Name: "audit", Binary: "/local/audit", Args: []string{"-v"}, Sudo: true, Fix: "audit --fix"},
+	}
+	for _, p := range plugins {
+		assert.Nil(p.Validate())
+	}
+
+	e := fakeExecutor{
+		"uname -r": "5.10.0\n",
+		plugins[3].RemoteBinary("/tmp/bin") + " -v": "",
+	}
+	results := CheckPlugins(context.Background(), e, "/tmp/bin", plugins)
+	assert.Len(results, 4)
+
+	assert.True(results[0].Passed())
+	assert.Equal("custom check kernel: 5.10.0", results[0].Msg)
+
+	assert.False(results[1].Passed())
+	assert.Equal("reboot", results[1].Fix)
+	assert.False(results[1].FixSudo)
+
+	assert.False(results[2].Passed())
+	assert.True(results[2].IsWarning())
+
+	assert.True(results[3].Passed())
+	assert.True(results[3].FixSudo)
+}
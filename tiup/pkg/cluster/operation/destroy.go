@@ -144,6 +144,46 @@ func StopAndDestroyInstance(ctx context.Context, cluster spec.Topology, instance
 	return nil
 }
 
+// DestroyBackupAgent stops and disables the systemd timer of the backup agent,
+// and removes its units and directories on the hosts. The backups in the
+// storage are kept.
+func DestroyBackupAgent(ctx context.Context, name, user string, agent *spec.BackupAgentMeta, hosts []string, options Options) error {
+	if agent == nil {
+		return nil
+	}
+	logger := ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger)
+	service := (&spec.BackupOptions{}).ServiceName(name)
+
+	for _, host := range hosts {
+		e := ctxt.GetInner(ctx).Get(host)
+		logger.Infof("Destroying backup agent %s", host)
+
+		delPaths := []string{
+			spec.Abs(user, agent.LogDir),
+			spec.Abs(user, agent.DeployDir),
+			fmt.Sprintf("/etc/systemd/system/%s.timer", service),
+			fmt.Sprintf("/etc/systemd/system/%s.service", service),
+		}
+		c := module.ShellModuleConfig{
+			Command: fmt.Sprintf("systemctl disable --now %[1]s.timer; systemctl stop %[1]s.service; rm -rf %[2]s && systemctl daemon-reload",
+				service, strings.Join(delPaths, " ")),
+			Sudo:     true, // the units are in a directory owned by root
+			UseShell: true,
+		}
+		shell := module.NewShellModule(c)
+		_, stderr, err := shell.Execute(ctx, e)
+		if err != nil {
+			if len(stderr) > 0 {
+				logger.Errorf(string(stderr))
+			}
+			if !options.Force {
+				return errors.Annotatef(err, "failed to destroy backup agent: %s", host)
+			}
+		}
+	}
+	return nil
+}
+
 // DeleteGlobalDirs deletes all global directories if they are empty
 func DeleteGlobalDirs(ctx context.Context, host string, options *spec.GlobalOptions) error {
 	if options == nil {
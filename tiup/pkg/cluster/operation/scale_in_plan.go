@@ -0,0 +1,182 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+// scaleInLowSpaceRatio is the default low-space-ratio of PD, a store is
+// considered out of space and no more regions are scheduled to it once
+// its used space exceeds this ratio of its capacity
+const scaleInLowSpaceRatio = 0.8
+
+// StoreGroupPlan is the capacity plan of the stores of an engine
+type StoreGroupPlan struct {
+	Engine        string   // tikv or tiflash
+	Removed       []string // addresses of the stores to be removed
+	Remain        int      // number of stores remaining
+	MigrateSize   uint64   // data size on the stores to be removed
+	RemainSpace   uint64   // space available to schedule regions on the remaining stores
+	RemainDomains int      // number of isolation domains of the remaining stores
+}
+
+// ScaleInPlan is the result of the pre-flight planning of a scale-in
+type ScaleInPlan struct {
+	MaxReplicas    int
+	IsolationLevel string
+	Groups         []*StoreGroupPlan
+	Problems       []string // the reasons why the scale-in is not safe
+}
+
+// Safe returns true if the remaining stores can hold the data of the cluster
+func (p *ScaleInPlan) Safe() bool {
+	return len(p.Problems) == 0
+}
+
+// ScaleInStoreAddrs returns the store addresses of the TiKV and TiFlash
+// nodes to be removed, it returns nil if no store is going to be removed
+func ScaleInStoreAddrs(cluster *spec.Specification, nodes []string) []string {
+	deleted := set.NewStringSet(nodes...)
+	var addrs []string
+	for _, inst := range (&spec.TiKVComponent{Topology: cluster}).Instances() {
+		if deleted.Exist(inst.ID()) {
+			addrs = append(addrs, inst.ID())
+		}
+	}
+	for _, inst := range (&spec.TiFlashComponent{Topology: cluster}).Instances() {
+		if deleted.Exist(inst.ID()) {
+			addrs = append(addrs, inst.GetHost()+":"+strconv.Itoa(inst.(*spec.TiFlashInstance).GetServicePort()))
+		}
+	}
+	return addrs
+}
+
+// PlanScaleIn checks if the stores remaining after removing the stores of
+// removedAddrs can still hold all the data, and if the replica count and
+// isolation level set in PD can still be satisfied
+func PlanScaleIn(stores *api.StoresInfo, replication *api.PDReplicationConfig, removedAddrs []string) *ScaleInPlan {
+	removed := set.NewStringSet(removedAddrs...)
+	plan := &ScaleInPlan{
+		MaxReplicas:    int(replication.MaxReplicas),
+		IsolationLevel: replication.IsolationLevel,
+	}
+
+	groups := map[string]*StoreGroupPlan{}
+	domains := map[string]set.StringSet{}
+	for _, engine := range []string{spec.ComponentTiKV, spec.ComponentTiFlash} {
+		groups[engine] = &StoreGroupPlan{Engine: engine}
+		domains[engine] = set.NewStringSet()
+	}
+
+	for _, store := range stores.Stores {
+		if store.Store == nil || store.Store.State == metapb.StoreState_Tombstone {
+			continue
+		}
+		group := groups[storeEngine(store)]
+		if removed.Exist(store.Store.Address) {
+			group.Removed = append(group.Removed, store.Store.Address)
+			if store.Status != nil {
+				group.MigrateSize += uint64(store.Status.UsedSize)
+			}
+			continue
+		}
+		// stores already being removed can not receive data
+		if store.Store.State == metapb.StoreState_Offline {
+			continue
+		}
+		group.Remain++
+		if store.Status != nil {
+			group.RemainSpace += storeSchedulableSpace(store.Status)
+		}
+		if plan.IsolationLevel != "" {
+			domains[group.Engine].Insert(storeLabel(store, plan.IsolationLevel))
+		}
+	}
+
+	for _, engine := range []string{spec.ComponentTiKV, spec.ComponentTiFlash} {
+		group := groups[engine]
+		if len(group.Removed) == 0 {
+			continue
+		}
+		group.RemainDomains = len(domains[engine])
+		plan.Groups = append(plan.Groups, group)
+
+		if group.Remain == 0 {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("no %s store would remain in the cluster", engine))
+			continue
+		}
+		if group.MigrateSize > group.RemainSpace {
+			plan.Problems = append(plan.Problems, fmt.Sprintf(
+				"%s of data on the %s stores to be removed can not fit in the %s available on the %d remaining stores (low-space-ratio %.1f)",
+				units.BytesSize(float64(group.MigrateSize)), engine,
+				units.BytesSize(float64(group.RemainSpace)), group.Remain, scaleInLowSpaceRatio))
+		}
+
+		// the replica constraints only apply to the raft stores of TiKV, the
+		// replicas of TiFlash are set per table
+		if engine != spec.ComponentTiKV {
+			continue
+		}
+		if group.Remain < plan.MaxReplicas {
+			plan.Problems = append(plan.Problems, fmt.Sprintf(
+				"only %d TiKV stores would remain, less than max-replicas %d",
+				group.Remain, plan.MaxReplicas))
+		}
+		if plan.IsolationLevel != "" && group.RemainDomains < plan.MaxReplicas {
+			plan.Problems = append(plan.Problems, fmt.Sprintf(
+				"the remaining TiKV stores are in %d different '%s', less than max-replicas %d required by isolation-level",
+				group.RemainDomains, plan.IsolationLevel, plan.MaxReplicas))
+		}
+	}
+
+	return plan
+}
+
+func storeEngine(store *api.StoreInfo) string {
+	for _, label := range store.Store.Labels {
+		if label.Key == "engine" && label.Value == spec.ComponentTiFlash {
+			return spec.ComponentTiFlash
+		}
+	}
+	return spec.ComponentTiKV
+}
+
+func storeLabel(store *api.StoreInfo, key string) string {
+	for _, label := range store.Store.Labels {
+		if label.Key == key {
+			return label.Value
+		}
+	}
+	// stores without the label are in the same domain
+	return ""
+}
+
+// storeSchedulableSpace returns the space of a store that is available to
+// receive regions before it reaches the low space ratio
+func storeSchedulableSpace(status *api.StoreStatus) uint64 {
+	limit := uint64(float64(status.Capacity) * scaleInLowSpaceRatio)
+	used := uint64(status.Capacity) - uint64(status.Available)
+	if status.Available > status.Capacity || used >= limit {
+		return 0
+	}
+	return limit - used
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tiup/pkg/cluster/api"
+	"github.com/pingcap/tiup/pkg/cluster/api/typeutil"
+	"github.com/stretchr/testify/require"
+)
+
+const gb = 1 << 30
+
+func newPlanStore(addr, zone string, capacity, available, used uint64) *api.StoreInfo {
+	return &api.StoreInfo{
+		Store: &api.MetaStore{
+			Store: &metapb.Store{
+				Address: addr,
+				State:   metapb.StoreState_Up,
+				Labels:  []*metapb.StoreLabel{{Key: "zone", Value: zone}},
+			},
+		},
+		Status: &api.StoreStatus{
+			Capacity:  typeutil.ByteSize(capacity),
+			Available: typeutil.ByteSize(available),
+			UsedSize:  typeutil.ByteSize(used),
+		},
+	}
+}
+
+func TestPlanScaleIn(t *testing.T) {
+	stores := &api.StoresInfo{Stores: []*api.StoreInfo{
+		newPlanStore("10.0.0.1:20160", "z1", 1000*gb, 800*gb, 200*gb),
+		newPlanStore("10.0.0.2:20160", "z2", 1000*gb, 800*gb, 200*gb),
+		newPlanStore("10.0.0.3:20160", "z3", 1000*gb, 800*gb, 200*gb),
+		newPlanStore("10.0.0.4:20160", "z3", 1000*gb, 800*gb, 200*gb),
+	}}
+	replication := &api.PDReplicationConfig{MaxReplicas: 3}
+
+	plan := PlanScaleIn(stores, replication, []string{"10.0.0.4:20160"})
+	require.True(t, plan.Safe(), plan.Problems)
+	require.Len(t, plan.Groups, 1)
+	require.Equal(t, 3, plan.Groups[0].Remain)
+	require.Equal(t, uint64(200*gb), plan.Groups[0].MigrateSize)
+	require.Equal(t, uint64(3*600*gb), plan.Groups[0].RemainSpace)
+
+	// not enough stores for the replicas
+	plan = PlanScaleIn(stores, replication, []string{"10.0.0.3:20160", "10.0.0.4:20160"})
+	require.False(t, plan.Safe())
+	require.Len(t, plan.Problems, 1)
+	require.Contains(t, plan.Problems[0], "max-replicas 3")
+
+	// the remaining stores are not in enough zones
+	replication.IsolationLevel = "zone"
+	plan = PlanScaleIn(stores, replication, []string{"10.0.0.1:20160"})
+	require.False(t, plan.Safe())
+	require.Contains(t, plan.Problems[0], "isolation-level")
+
+	// the remaining stores are almost full
+	replication = &api.PDReplicationConfig{MaxReplicas: 1}
+	stores.Stores[0].Status.Available = typeutil.ByteSize(250 * gb)
+	stores.Stores[1].Status.Available = typeutil.ByteSize(250 * gb)
+	stores.Stores[2].Status.Available = typeutil.ByteSize(250 * gb)
+	plan = PlanScaleIn(stores, replication, []string{"10.0.0.4:20160"})
+	require.False(t, plan.Safe())
+	require.Contains(t, plan.Problems[0], "can not fit")
+
+	// nothing to plan if no store is removed
+	plan = PlanScaleIn(stores, replication, nil)
+	require.True(t, plan.Safe())
+	require.Empty(t, plan.Groups)
+}
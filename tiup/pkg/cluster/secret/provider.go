@@ -0,0 +1,58 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// notFoundExitCode is the exit code of the plugin command if it doesn't
+// have the secret, the other non-zero exit codes are treated as failures
+const notFoundExitCode = 3
+
+// CommandProvider looks up the secrets by running a plugin command, the
+// name of the secret is appended to the arguments of the command and the
+// value is read from its stdout, it's used to integrate external secret
+// stores such as HashiCorp Vault or the OS keychain
+type CommandProvider struct {
+	Command string // the command line of the plugin, e.g. tiup-secret-vault --mount kv
+}
+
+// Lookup implements the Provider interface
+func (p *CommandProvider) Lookup(name string) (string, bool, error) {
+	args := strings.Fields(p.Command)
+	if len(args) == 0 {
+		return "", false, nil
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.Command(args[0], append(args[1:], name)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), "TIUP_SECRET_NAME="+name)
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == notFoundExitCode {
+			return "", false, nil
+		}
+		return "", false, errors.Annotatef(err, "secret provider '%s' failed, stderr: %s",
+			args[0], strings.TrimSpace(stderr.String()))
+	}
+	// a trailing newline is not part of the secret
+	return strings.TrimSuffix(strings.TrimSuffix(stdout.String(), "\n"), "\r"), true, nil
+}
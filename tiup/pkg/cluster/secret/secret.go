@@ -0,0 +1,218 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+)
+
+// RefPrefix is the prefix of the secret references, a reference is replaced
+// by the value of the secret when the config is rendered, e.g. secret://grafana
+const RefPrefix = "secret://"
+
+var refRegexp = regexp.MustCompile(`secret://([A-Za-z0-9_][A-Za-z0-9_.\-/]*)`)
+
+// ErrNotFound is returned if the secret is not found in any of the providers
+var ErrNotFound = errors.New("secret not found")
+
+// Provider is the source of the secrets
+type Provider interface {
+	// Lookup returns the value of the secret, found is false if the provider
+	// doesn't have the secret
+	Lookup(name string) (value string, found bool, err error)
+}
+
+// ValidName returns true if the name can be used in the secret references
+func ValidName(name string) bool {
+	m := refRegexp.FindStringSubmatch(RefPrefix + name)
+	return m != nil && m[1] == name
+}
+
+// HasRef returns true if the value contains secret references
+func HasRef(value string) bool {
+	return strings.Contains(value, RefPrefix) && refRegexp.MatchString(value)
+}
+
+// Refs returns the names of secrets referenced in the value
+func Refs(value string) []string {
+	var names []string
+	for _, m := range refRegexp.FindAllStringSubmatch(value, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// Resolver replaces the secret references with the values looked up from
+// the providers, the former providers take precedence
+type Resolver struct {
+	providers []Provider
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// NewResolver returns a Resolver looking up secrets from the providers
+func NewResolver(providers ...Provider) *Resolver {
+	return &Resolver{
+		providers: providers,
+		cache:     make(map[string]string),
+	}
+}
+
+// Resolve replaces all secret references in the value
+func (r *Resolver) Resolve(value string) (string, error) {
+	return r.ResolveEscaped(value, nil)
+}
+
+// ResolveEscaped replaces all secret references in the value with the
+// escaped values of secrets, e.g. the references in the query of an URL
+// need to be escaped by url.QueryEscape
+func (r *Resolver) ResolveEscaped(value string, escape func(string) string) (string, error) {
+	if !HasRef(value) {
+		return value, nil
+	}
+	var err error
+	resolved := refRegexp.ReplaceAllStringFunc(value, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		name := strings.TrimPrefix(ref, RefPrefix)
+		var v string
+		if v, err = r.lookup(name); err == nil && escape != nil {
+			v = escape(v)
+			addRedaction(name, v)
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// ResolveConfig returns a copy of the config with the secret references in
+// string values resolved, the config itself is not modified
+func (r *Resolver) ResolveConfig(config map[string]interface{}) (map[string]interface{}, error) {
+	v, err := r.resolveValue(config)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+func (r *Resolver) resolveValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return r.Resolve(val)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			resolved, err := r.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = resolved
+		}
+		return result, nil
+	case map[interface{}]interface{}:
+		result := make(map[interface{}]interface{}, len(val))
+		for k, item := range val {
+			resolved, err := r.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = resolved
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			resolved, err := r.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, resolved)
+		}
+		return result, nil
+	}
+	return v, nil
+}
+
+func (r *Resolver) lookup(name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if v, ok := r.cache[name]; ok {
+		return v, nil
+	}
+	for _, p := range r.providers {
+		v, found, err := p.Lookup(name)
+		if err != nil {
+			return "", errors.Annotatef(err, "failed to look up secret %s", name)
+		}
+		if found {
+			r.cache[name] = v
+			addRedaction(name, v)
+			return v, nil
+		}
+	}
+	return "", errors.Annotatef(ErrNotFound, "secret %s", name)
+}
+
+// minRedactLength is the minimum length of values to be redacted, the shorter
+// values are too likely to be part of unrelated words in the outputs
+const minRedactLength = 6
+
+var (
+	redactionMu sync.RWMutex
+	redactions  = make(map[string]string) // the value -> the reference
+)
+
+func addRedaction(name, value string) {
+	if len(value) < minRedactLength {
+		return
+	}
+	redactionMu.Lock()
+	defer redactionMu.Unlock()
+	redactions[value] = RefPrefix + name
+}
+
+// Redact replaces the values of secrets resolved in this process with
+// their references, it's used to keep the secrets out of outputs and logs,
+// the values shorter than minRedactLength are kept as is
+func Redact(s string) string {
+	redactionMu.RLock()
+	defer redactionMu.RUnlock()
+	if len(redactions) == 0 {
+		return s
+	}
+
+	// replace the longer values first in case one contains another
+	values := make([]string, 0, len(redactions))
+	for v := range redactions {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, redactions[v])
+	}
+	return s
+}
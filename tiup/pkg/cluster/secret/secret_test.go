@@ -0,0 +1,182 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVault(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.vault")
+
+	// the vault doesn't exist yet
+	v, err := OpenVault(path, Key{Passphrase: "pass"})
+	assert.Nil(err)
+	assert.Empty(v.Names())
+
+	assert.Nil(v.Set("grafana", "admin-password"))
+	assert.Nil(v.Set("s3/access-key", "AKIA0000"))
+	assert.NotNil(v.Set("bad name", "x"))
+	assert.Nil(v.Save())
+
+	// the secrets are not saved in plaintext
+	data, err := os.ReadFile(path)
+	assert.Nil(err)
+	assert.NotContains(string(data), "admin-password")
+	assert.NotContains(string(data), "grafana")
+	fi, err := os.Stat(path)
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0600), fi.Mode().Perm())
+
+	v, err = OpenVault(path, Key{Passphrase: "pass"})
+	assert.Nil(err)
+	assert.Equal([]string{"grafana", "s3/access-key"}, v.Names())
+	value, found, err := v.Lookup("grafana")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal("admin-password", value)
+	_, found, _ = v.Lookup("missing")
+	assert.False(found)
+
+	_, err = OpenVault(path, Key{Passphrase: "wrong"})
+	assert.Equal(ErrWrongKey, err)
+	_, err = OpenVault(path, Key{KeyFile: filepath.Join(dir, "key")})
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "encrypted by a passphrase")
+
+	assert.True(v.Delete("grafana"))
+	assert.False(v.Delete("grafana"))
+}
+
+func TestVaultKeyFile(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "secret.key")
+	path := filepath.Join(dir, "secrets.vault")
+
+	assert.Nil(GenerateKeyFile(keyFile))
+	// the existing key file is never overwritten
+	assert.True(os.IsExist(errors.Cause(GenerateKeyFile(keyFile))))
+
+	v, err := OpenVault(path, Key{KeyFile: keyFile})
+	assert.Nil(err)
+	assert.Nil(v.Set("pd-password", "p@ss"))
+	assert.Nil(v.Save())
+
+	v, err = OpenVault(path, Key{KeyFile: keyFile})
+	assert.Nil(err)
+	value, found, err := v.Lookup("pd-password")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal("p@ss", value)
+
+	assert.Nil(GenerateKeyFile(filepath.Join(dir, "another.key")))
+	_, err = OpenVault(path, Key{KeyFile: filepath.Join(dir, "another.key")})
+	assert.Equal(ErrWrongKey, err)
+}
+
+type mapProvider map[string]string
+
+func (p mapProvider) Lookup(name string) (string, bool, error) {
+	v, ok := p[name]
+	return v, ok, nil
+}
+
+func TestResolver(t *testing.T) {
+	assert := require.New(t)
+
+	assert.True(ValidName("tls/ca-1.key"))
+	assert.False(ValidName("a b"))
+	assert.False(ValidName(""))
+	assert.False(HasRef("admin"))
+	assert.Equal([]string{"ak", "sk"}, Refs("s3://b/p?access-key=secret://ak&secret-access-key=secret://sk"))
+
+	r := NewResolver(
+		mapProvider{"grafana": "Gr@fana-pass", "sk": "a/b+c", "short": "tidb"},
+		mapProvider{"grafana": "shadowed", "ak": "AKIA1234"},
+	)
+
+	v, err := r.Resolve("admin")
+	assert.Nil(err)
+	assert.Equal("admin", v)
+	v, err = r.Resolve("secret://grafana")
+	assert.Nil(err)
+	assert.Equal("Gr@fana-pass", v)
+	v, err = r.ResolveEscaped("s3://b/p?access-key=secret://ak&secret-access-key=secret://sk", url.QueryEscape)
+	assert.Nil(err)
+	assert.Equal("s3://b/p?access-key=AKIA1234&secret-access-key=a%2Fb%2Bc", v)
+
+	_, err = r.Resolve("secret://missing")
+	assert.Equal(ErrNotFound, errors.Cause(err))
+
+	config := map[string]interface{}{
+		"security": map[string]interface{}{
+			"password": "secret://grafana",
+		},
+		"list": []interface{}{"secret://ak", 1},
+	}
+	resolved, err := r.ResolveConfig(config)
+	assert.Nil(err)
+	assert.Equal("Gr@fana-pass", resolved["security"].(map[string]interface{})["password"])
+	assert.Equal([]interface{}{"AKIA1234", 1}, resolved["list"])
+	// the original config is not modified
+	assert.Equal("secret://grafana", config["security"].(map[string]interface{})["password"])
+
+	// the resolved values are redacted
+	assert.Equal("password=secret://grafana key=secret://sk",
+		Redact("password=Gr@fana-pass key=a%2Fb%2Bc"))
+
+	// the short values are not redacted in case they are common words
+	v, err = r.Resolve("secret://short")
+	assert.Nil(err)
+	assert.Equal("tidb", v)
+	assert.Equal("tidb-0 password=secret://grafana", Redact("tidb-0 password=Gr@fana-pass"))
+}
+
+func TestCommandProvider(t *testing.T) {
+	assert := require.New(t)
+	dir := t.TempDir()
+	script := filepath.Join(dir, "provider.sh")
+	assert.Nil(os.WriteFile(script, []byte(strings.Join([]string{
+		"#!/bin/sh",
+		`case "$2" in`,
+		`  db-password) echo "$1-secret";;`,
+		`  broken) echo "boom" >&2; exit 1;;`,
+		`  *) exit 3;;`,
+		`esac`,
+	}, "\n")), 0755))
+
+	p := &CommandProvider{Command: script + " prefix"}
+	v, found, err := p.Lookup("db-password")
+	assert.Nil(err)
+	assert.True(found)
+	assert.Equal("prefix-secret", v)
+
+	_, found, err = p.Lookup("missing")
+	assert.Nil(err)
+	assert.False(found)
+
+	_, _, err = p.Lookup("broken")
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "boom")
+}
@@ -0,0 +1,262 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// the ways the encryption key of a vault is derived
+const (
+	KDFScrypt  = "scrypt"  // from a passphrase
+	KDFKeyFile = "keyfile" // from the content of a key file
+)
+
+const (
+	keySize     = 32 // AES-256
+	saltSize    = 16
+	vaultFormat = 1
+)
+
+// ErrWrongKey is returned if the vault can't be decrypted with the key
+var ErrWrongKey = errors.New("wrong passphrase or key file of the secret vault")
+
+// Key is the key to encrypt and decrypt a vault, it's either a passphrase
+// or a key file, the key file takes precedence if both are set
+type Key struct {
+	Passphrase string
+	KeyFile    string
+}
+
+// Empty returns true if neither the passphrase nor the key file is set
+func (k Key) Empty() bool {
+	return k.Passphrase == "" && k.KeyFile == ""
+}
+
+func (k Key) kdf() string {
+	if k.KeyFile != "" {
+		return KDFKeyFile
+	}
+	return KDFScrypt
+}
+
+// derive returns the encryption key with the salt
+func (k Key) derive(salt []byte) ([]byte, error) {
+	if k.KeyFile == "" {
+		if k.Passphrase == "" {
+			return nil, errors.New("no passphrase or key file is set for the secret vault")
+		}
+		return scrypt.Key([]byte(k.Passphrase), salt, 1<<15, 8, 1, keySize)
+	}
+	data, err := os.ReadFile(k.KeyFile)
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to read the key file of the secret vault")
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, errors.Errorf("invalid key file %s, it should contain %d bytes in hex", k.KeyFile, keySize)
+	}
+	return key, nil
+}
+
+// GenerateKeyFile writes a random key to the file, the file is only readable
+// by the current user, an existing file is never overwritten
+func GenerateKeyFile(path string) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return errors.AddStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.AddStack(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(hex.EncodeToString(key) + "\n")
+	return errors.AddStack(err)
+}
+
+// vaultFile is the content of a vault file, the secrets are encrypted with
+// AES-GCM by the key derived from the passphrase or read from the key file
+type vaultFile struct {
+	Format     int    `json:"format"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Vault is a local file of encrypted secrets
+type Vault struct {
+	path    string
+	key     Key
+	secrets map[string]string
+}
+
+// OpenVault decrypts the vault file with the key, an empty vault is
+// returned if the file doesn't exist
+func OpenVault(path string, key Key) (*Vault, error) {
+	v := &Vault{
+		path:    path,
+		key:     key,
+		secrets: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	file := vaultFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Annotatef(err, "invalid secret vault %s", path)
+	}
+	if file.Format != vaultFormat {
+		return nil, errors.Errorf("unsupported format %d of secret vault %s", file.Format, path)
+	}
+	if file.KDF != key.kdf() {
+		return nil, errors.Errorf("the secret vault %s is encrypted by a %s, but a %s is given",
+			path, kdfName(file.KDF), kdfName(key.kdf()))
+	}
+
+	encKey, err := key.derive(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, []byte(file.KDF))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	if err := json.Unmarshal(plaintext, &v.secrets); err != nil {
+		return nil, errors.Annotatef(err, "invalid secret vault %s", path)
+	}
+	return v, nil
+}
+
+// Lookup implements the Provider interface
+func (v *Vault) Lookup(name string) (string, bool, error) {
+	value, ok := v.secrets[name]
+	return value, ok, nil
+}
+
+// Set adds or updates the secret, the vault must be saved to persist it
+func (v *Vault) Set(name, value string) error {
+	if !ValidName(name) {
+		return errors.Errorf("invalid secret name '%s', only letters, digits, '_', '.', '-' and '/' are allowed", name)
+	}
+	v.secrets[name] = value
+	return nil
+}
+
+// Delete removes the secret, it returns false if the secret doesn't exist
+func (v *Vault) Delete(name string) bool {
+	if _, ok := v.secrets[name]; !ok {
+		return false
+	}
+	delete(v.secrets, name)
+	return true
+}
+
+// Names returns the sorted names of the secrets in the vault
+func (v *Vault) Names() []string {
+	names := make([]string, 0, len(v.secrets))
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts the secrets and writes them to the vault file
+func (v *Vault) Save() error {
+	plaintext, err := json.Marshal(v.secrets)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	file := vaultFile{
+		Format: vaultFormat,
+		KDF:    v.key.kdf(),
+		Salt:   make([]byte, saltSize),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return errors.AddStack(err)
+	}
+	encKey, err := v.key.derive(file.Salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(encKey)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return errors.AddStack(err)
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, []byte(file.KDF))
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0755); err != nil {
+		return errors.AddStack(err)
+	}
+	// write to a temporary file and rename it to not corrupt the vault on failure
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return errors.AddStack(err)
+	}
+	return errors.AddStack(os.Rename(tmp, v.path))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return gcm, nil
+}
+
+func kdfName(kdf string) string {
+	if kdf == KDFKeyFile {
+		return "key file"
+	}
+	return "passphrase"
+}
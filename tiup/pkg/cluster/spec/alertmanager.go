@@ -29,6 +29,7 @@ import (
 // AlertmanagerSpec represents the AlertManager topology specification in topology.yaml
 type AlertmanagerSpec struct {
 	Host            string               `yaml:"host"`
+	HostGroup       string               `yaml:"host_group,omitempty"`
 	SSHPort         int                  `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported        bool                 `yaml:"imported,omitempty"`
 	Patched         bool                 `yaml:"patched,omitempty"`
@@ -146,7 +147,7 @@ func (i *AlertManagerInstance) InitConfig(
 	// Transfer start script
 	spec := i.InstanceSpec.(*AlertmanagerSpec)
 	cfg := scripts.NewAlertManagerScript(spec.Host, spec.ListenHost, paths.Deploy, paths.Data[0], paths.Log, enableTLS).
-		WithWebPort(spec.WebPort).WithClusterPort(spec.ClusterPort).WithNumaNode(spec.NumaNode).
+		WithWebPort(spec.WebPort).WithClusterPort(spec.ClusterPort).WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).
 		AppendEndpoints(AlertManagerEndpoints(alertmanagers, deployUser, enableTLS))
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_alertmanager_%s_%d.sh", i.GetHost(), i.GetPort()))
@@ -0,0 +1,229 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/set"
+)
+
+const (
+	// ComponentBR is the name of the BR component used by the backup agent
+	ComponentBR = "br"
+	// RoleBackup is the role of the backup agent
+	RoleBackup = "backup"
+
+	defaultBackupSchedule = "daily"
+)
+
+// BackupOptions represents the scheduled backup of the cluster, the backups are
+// taken by BR started by a systemd timer on the host of the backup agent
+type BackupOptions struct {
+	Host      string   `yaml:"host,omitempty" validate:"host:editable"`
+	DeployDir string   `yaml:"deploy_dir,omitempty" validate:"deploy_dir:editable"`
+	LogDir    string   `yaml:"log_dir,omitempty" validate:"log_dir:editable"`
+	Storage   string   `yaml:"storage,omitempty" validate:"storage:editable"`     // the storage URL of BR, e.g. s3://bucket/prefix
+	Schedule  string   `yaml:"schedule,omitempty" validate:"schedule:editable"`   // systemd calendar expression, e.g. daily or *-*-* 02:00:00
+	Retention int      `yaml:"retention,omitempty" validate:"retention:editable"` // the number of backups kept in local storage, 0 to keep all
+	Filters   []string `yaml:"filters,omitempty" validate:"filters:editable"`     // table filters of BR, e.g. db1.*
+}
+
+// Enabled returns if the scheduled backup is set in topology
+func (b *BackupOptions) Enabled() bool {
+	return b.Host != ""
+}
+
+// ServiceName returns the systemd unit name of the backup agent of the cluster
+func (b *BackupOptions) ServiceName(clusterName string) string {
+	return fmt.Sprintf("tiup-backup-%s", clusterName)
+}
+
+// fillDefaults sets the default values of the backup agent
+func (b *BackupOptions) fillDefaults(globalOptions *GlobalOptions) {
+	if !b.Enabled() {
+		return
+	}
+	if b.DeployDir == "" {
+		b.DeployDir = filepath.Join(globalOptions.DeployDir, fmt.Sprintf("%s-agent", RoleBackup))
+	}
+	if b.LogDir == "" {
+		b.LogDir = "log"
+	}
+	if !strings.HasPrefix(b.LogDir, "/") && !strings.HasPrefix(b.LogDir, b.DeployDir) {
+		b.LogDir = filepath.Join(b.DeployDir, b.LogDir)
+	}
+	if b.Schedule == "" {
+		b.Schedule = defaultBackupSchedule
+	}
+}
+
+// storageURL parses the storage, a path without scheme is in local storage
+func (b *BackupOptions) storageURL() (*url.URL, error) {
+	u, err := url.Parse(b.Storage)
+	if err != nil {
+		return nil, errors.Annotatef(err, "invalid backup storage '%s'", b.Storage)
+	}
+	if u.Scheme == "" {
+		u.Scheme = "local"
+	}
+	return u, nil
+}
+
+// StorageURL returns the storage URL of a backup with the name, the
+// name is appended to the path of the storage
+func (b *BackupOptions) StorageURL(name string) string {
+	storage, query := b.Storage, ""
+	if i := strings.Index(storage, "?"); i >= 0 {
+		storage, query = storage[:i], storage[i:]
+	}
+	if strings.HasPrefix(storage, "/") {
+		// BR expects local:///path for local storage
+		storage = "local://" + storage
+	}
+	return strings.TrimSuffix(storage, "/") + "/" + name + query
+}
+
+// LocalPath returns the path of the local storage, it's empty if the
+// backups are not stored in local storage
+func (b *BackupOptions) LocalPath() string {
+	u, err := b.storageURL()
+	if err != nil || u.Scheme != "local" {
+		return ""
+	}
+	return u.Path
+}
+
+// BackupHosts returns the hosts the backup agent is installed on, the agent
+// host is the first one. A backup in local storage is written on the hosts of
+// every TiKV, so the agent is also installed on them to prune the backups.
+func (s *Specification) BackupHosts() []string {
+	if !s.Backup.Enabled() {
+		return nil
+	}
+	hosts := []string{s.Backup.Host}
+	if s.Backup.LocalPath() == "" {
+		return hosts
+	}
+	seen := set.NewStringSet(s.Backup.Host)
+	for _, kv := range s.TiKVServers {
+		if !seen.Exist(kv.Host) {
+			seen.Insert(kv.Host)
+			hosts = append(hosts, kv.Host)
+		}
+	}
+	return hosts
+}
+
+// BackupAgentMeta records where the backup agent is installed, it's saved in
+// the meta of the cluster to remove the agent from the hosts it's no longer on
+// after the backup settings are edited.
+type BackupAgentMeta struct {
+	Hosts     []string `yaml:"hosts"`
+	DeployDir string   `yaml:"deploy_dir"`
+	LogDir    string   `yaml:"log_dir"`
+}
+
+// BackupAgent returns where the backup agent of the topology is installed, it's
+// nil if the scheduled backup is not set
+func (s *Specification) BackupAgent() *BackupAgentMeta {
+	if !s.Backup.Enabled() {
+		return nil
+	}
+	return &BackupAgentMeta{
+		Hosts:     s.BackupHosts(),
+		DeployDir: s.Backup.DeployDir,
+		LogDir:    s.Backup.LogDir,
+	}
+}
+
+// StaleHosts returns the hosts of the agent that are not in the other one, all
+// the hosts are stale if the other agent is nil or in other directories
+func (a *BackupAgentMeta) StaleHosts(other *BackupAgentMeta) []string {
+	if a == nil {
+		return nil
+	}
+	if other == nil || other.DeployDir != a.DeployDir || other.LogDir != a.LogDir {
+		return a.Hosts
+	}
+	current := set.NewStringSet(other.Hosts...)
+	stale := []string{}
+	for _, host := range a.Hosts {
+		if !current.Exist(host) {
+			stale = append(stale, host)
+		}
+	}
+	return stale
+}
+
+// validateBackup checks the backup settings
+func (s *Specification) validateBackup() error {
+	b := s.Backup
+	if !b.Enabled() {
+		if b.Storage != "" || len(b.Filters) > 0 {
+			return errors.New("`backup.host` is required to schedule backups")
+		}
+		return nil
+	}
+
+	found := false
+	s.IterInstance(func(inst Instance) {
+		if inst.GetHost() == b.Host {
+			found = true
+		}
+	})
+	if !found {
+		return errors.Errorf("`backup.host` %s is not a host of the cluster, the backup agent must run on one of the hosts", b.Host)
+	}
+
+	if b.Storage == "" {
+		return errors.New("`backup.storage` is required to schedule backups")
+	}
+	if strings.ContainsAny(b.Storage, "\"`$\\\n") {
+		return errors.Errorf("invalid `backup.storage` %q", b.Storage)
+	}
+	u, err := b.storageURL()
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "local":
+		if !strings.HasPrefix(u.Path, "/") {
+			return errors.Errorf("`backup.storage` %s must be an absolute path", b.Storage)
+		}
+	case "s3", "gcs", "gs", "azure", "azblob", "hdfs":
+	default:
+		return errors.Errorf("unsupported `backup.storage` scheme '%s'", u.Scheme)
+	}
+
+	if b.Retention < 0 {
+		return errors.Errorf("`backup.retention` %d should not be negative", b.Retention)
+	}
+	if b.Retention > 0 && u.Scheme != "local" {
+		return errors.New("`backup.retention` is only supported for local storage, use the lifecycle rules of the storage instead")
+	}
+	if strings.ContainsAny(b.Schedule, "\n'\"") {
+		return errors.Errorf("invalid `backup.schedule` %q", b.Schedule)
+	}
+	for _, f := range b.Filters {
+		if f == "" || strings.ContainsAny(f, "\n'") {
+			return errors.Errorf("invalid `backup.filters` item %q", f)
+		}
+	}
+	return nil
+}
@@ -30,6 +30,7 @@ import (
 // CDCSpec represents the Drainer topology specification in topology.yaml
 type CDCSpec struct {
 	Host            string                 `yaml:"host"`
+	HostGroup       string                 `yaml:"host_group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Patched         bool                   `yaml:"patched,omitempty"`
@@ -176,7 +177,7 @@ func (i *CDCInstance) InitConfig(
 		enableTLS,
 		spec.GCTTL,
 		spec.TZ,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(topo.Endpoints(deployUser)...)
+	).WithPort(spec.Port).WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).AppendEndpoints(topo.Endpoints(deployUser)...)
 
 	if len(paths.Data) != 0 {
 		cfg = cfg.PatchByVersion(clusterVersion, paths.Data[0])
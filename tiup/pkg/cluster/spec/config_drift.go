@@ -0,0 +1,142 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/errors"
+)
+
+var configSizeUnitRegexp = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*([KMGTP]?)(?:I?B)?$`)
+
+// ConfigDrift is a config option whose values differ among the intended
+// config rendered from the topology, the config file deployed on the host
+// and the config of the running instance
+type ConfigDrift struct {
+	Key      string
+	Intended interface{} // nil if it's not set
+	Deployed interface{} // nil if it's not set
+	Live     interface{} // nil if it's not set or the live config is unknown
+}
+
+// FileDrifted returns true if the deployed file differs from the intended one
+func (d ConfigDrift) FileDrifted() bool {
+	return normalizeConfigValue(d.Intended) != normalizeConfigValue(d.Deployed)
+}
+
+// LiveDrifted returns true if the running instance differs from the intended config
+func (d ConfigDrift) LiveDrifted() bool {
+	return d.Live != nil && normalizeConfigValue(d.Intended) != normalizeConfigValue(d.Live)
+}
+
+// ParseTomlConfig parses the config file into the flattened options
+func ParseTomlConfig(data []byte) (map[string]interface{}, error) {
+	config := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &config); err != nil {
+		return nil, errors.Annotate(err, "invalid config file")
+	}
+	return FlattenMap(config), nil
+}
+
+// DiffConfig returns the options set in the intended or deployed config whose
+// values differ among the configs, the live config is not compared if it's nil,
+// and the options missing in the live config are not compared as the running
+// instance may not report all of them
+func DiffConfig(intended, deployed, live map[string]interface{}) []ConfigDrift {
+	keys := make(map[string]struct{})
+	for k := range intended {
+		keys[k] = struct{}{}
+	}
+	for k := range deployed {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var drifts []ConfigDrift
+	for _, k := range sorted {
+		d := ConfigDrift{Key: k, Intended: intended[k], Deployed: deployed[k]}
+		if lv, ok := live[k]; ok {
+			d.Live = lv
+		}
+		if d.FileDrifted() || d.LiveDrifted() {
+			drifts = append(drifts, d)
+		}
+	}
+	return drifts
+}
+
+// FormatConfigValue returns the value for display
+func FormatConfigValue(v interface{}) string {
+	if v == nil {
+		return "-"
+	}
+	switch val := v.(type) {
+	case string:
+		return val
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(val)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// normalizeConfigValue converts the value to a comparable form, as the same
+// value can be written in different forms in the config file and the config
+// reported by the running instance, such as 1GB and 1GiB, 60s and 1m0s
+func normalizeConfigValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case int:
+		return strconv.FormatInt(int64(val), 10)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case string:
+		// the durations are in lower case, so 1m is a minute and 1M is a size
+		if d, err := time.ParseDuration(val); err == nil {
+			return d.String()
+		}
+		if m := configSizeUnitRegexp.FindStringSubmatch(val); m != nil && m[2] != "" {
+			f, _ := strconv.ParseFloat(m[1], 64)
+			shift := strings.Index("KMGTP", strings.ToUpper(m[2])) + 1
+			return strconv.FormatFloat(f*float64(uint64(1)<<(10*shift)), 'f', -1, 64)
+		}
+		return val
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			items = append(items, normalizeConfigValue(item))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+	return fmt.Sprint(v)
+}
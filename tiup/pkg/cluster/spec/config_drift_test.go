@@ -0,0 +1,94 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfig(t *testing.T) {
+	assert := require.New(t)
+
+	intended, err := ParseTomlConfig([]byte(`
+log-level = "info"
+[raftstore]
+apply-pool-size = 2
+[storage.block-cache]
+capacity = "16GB"
+[server.labels]
+zone = "z1"
+`))
+	assert.Nil(err)
+	assert.Equal("16GB", intended["storage.block-cache.capacity"])
+	assert.Equal("z1", intended["server.labels.zone"])
+
+	deployed, err := ParseTomlConfig([]byte(`
+log-level = "debug"
+[raftstore]
+apply-pool-size = 2
+[storage.block-cache]
+capacity = "16GB"
+[server.labels]
+zone = "z1"
+[gc]
+batch-keys = 256
+`))
+	assert.Nil(err)
+
+	live := map[string]interface{}{
+		"log-level":                    "debug",
+		"raftstore.apply-pool-size":    float64(4),
+		"storage.block-cache.capacity": "16GiB",
+		"server.labels.zone":           "z1",
+	}
+
+	drifts := DiffConfig(intended, deployed, live)
+	assert.Len(drifts, 3)
+
+	assert.Equal("gc.batch-keys", drifts[0].Key)
+	assert.Nil(drifts[0].Intended)
+	assert.True(drifts[0].FileDrifted())
+	assert.False(drifts[0].LiveDrifted())
+	assert.Equal("-", FormatConfigValue(drifts[0].Intended))
+
+	assert.Equal("log-level", drifts[1].Key)
+	assert.True(drifts[1].FileDrifted())
+	assert.True(drifts[1].LiveDrifted())
+
+	// only changed online, the config file is intact
+	assert.Equal("raftstore.apply-pool-size", drifts[2].Key)
+	assert.False(drifts[2].FileDrifted())
+	assert.True(drifts[2].LiveDrifted())
+
+	// the live config is not compared if it's unknown
+	drifts = DiffConfig(intended, intended, nil)
+	assert.Empty(drifts)
+
+	_, err = ParseTomlConfig([]byte("log-level = "))
+	assert.NotNil(err)
+}
+
+func TestNormalizeConfigValue(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal(normalizeConfigValue("1GB"), normalizeConfigValue("1GiB"))
+	assert.Equal(normalizeConfigValue("1024MB"), normalizeConfigValue("1G"))
+	assert.Equal(normalizeConfigValue("60s"), normalizeConfigValue("1m"))
+	assert.Equal(normalizeConfigValue(int64(4)), normalizeConfigValue(float64(4)))
+	assert.Equal(normalizeConfigValue([]interface{}{"zone", "host"}), normalizeConfigValue([]interface{}{"zone", "host"}))
+	assert.NotEqual(normalizeConfigValue("1GB"), normalizeConfigValue("1MB"))
+	assert.Equal("", normalizeConfigValue(nil))
+}
@@ -0,0 +1,426 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/embed"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/utils"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v2"
+)
+
+// the types of config options in the schemas
+const (
+	ConfigTypeInt      = "int"
+	ConfigTypeFloat    = "float"
+	ConfigTypeBool     = "bool"
+	ConfigTypeString   = "string"
+	ConfigTypeSize     = "size"
+	ConfigTypeDuration = "duration"
+	ConfigTypeMap      = "map"
+	ConfigTypeList     = "list"
+)
+
+var (
+	configSizeRegexp     = regexp.MustCompile(`(?i)^\d+(\.\d+)?\s*(B|K|M|G|T|P|KB|MB|GB|TB|PB|KiB|MiB|GiB|TiB|PiB)?$`)
+	configDurationRegexp = regexp.MustCompile(`^(\d+(\.\d+)?(ns|us|µs|ms|s|m|h|d))+$`)
+)
+
+// the max edit distance of an unknown key to the known one to be suggested
+const configSuggestDistance = 3
+
+// ConfigOption is the definition of a config option in the schema
+type ConfigOption struct {
+	Type   string   `yaml:"type"`
+	Min    *float64 `yaml:"min,omitempty"`
+	Max    *float64 `yaml:"max,omitempty"`
+	Enum   []string `yaml:"enum,omitempty"`
+	Online bool     `yaml:"online,omitempty"` // can be changed without restart
+}
+
+// configSchemaFile is the content of a schema file, a schema inherits the
+// options of the one of former version and adds or removes some of them
+type configSchemaFile struct {
+	Inherit string                   `yaml:"inherit,omitempty"`
+	Options map[string]*ConfigOption `yaml:"options"`
+	Removed []string                 `yaml:"removed,omitempty"`
+}
+
+// ConfigSchema is the config options of a component version
+type ConfigSchema struct {
+	Component string
+	Version   string
+	Options   map[string]*ConfigOption
+	Removed   map[string]string // the removed option -> the version it's removed in
+	Fallback  bool              // the schema is of a former minor version than the cluster
+}
+
+// LoadConfigSchema returns the schema of the latest version not newer than
+// the cluster version. A schema covers the patch versions of its minor
+// version, the schema of a former minor version is returned as a fallback
+// if there is no schema of the minor version of the cluster, the options
+// added or removed since then are unknown to it. It returns nil if there
+// is no such schema, e.g. for the nightly version.
+func LoadConfigSchema(component, clusterVersion string) (*ConfigSchema, error) {
+	if utils.Version(clusterVersion).IsNightly() || !semver.IsValid(clusterVersion) {
+		return nil, nil
+	}
+	versions, err := configSchemaVersions(component)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := ""
+	for _, v := range versions {
+		if semver.Compare(v, clusterVersion) <= 0 {
+			picked = v
+		}
+	}
+	if picked == "" {
+		return nil, nil
+	}
+	schema, err := loadConfigSchema(component, picked)
+	if err != nil {
+		return nil, err
+	}
+	schema.Fallback = semver.MajorMinor(picked) != semver.MajorMinor(clusterVersion)
+	return schema, nil
+}
+
+// configSchemaVersions returns the sorted versions of the schemas of the
+// component, it's empty if the configs of the component are not checked
+func configSchemaVersions(component string) ([]string, error) {
+	names, err := embed.ListSchemas(component)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	versions := make([]string, 0, len(names))
+	for _, name := range names {
+		versions = append(versions, strings.TrimSuffix(name, path.Ext(name)))
+	}
+	semver.Sort(versions)
+	return versions, nil
+}
+
+func loadConfigSchema(component, version string) (*ConfigSchema, error) {
+	data, err := embed.ReadSchema(path.Join("schemas", component, version+".yaml"))
+	if err != nil {
+		return nil, errors.Annotatef(err, "config schema %s of %s not found", version, component)
+	}
+	file := configSchemaFile{}
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, errors.Annotatef(err, "invalid config schema %s of %s", version, component)
+	}
+
+	schema := &ConfigSchema{
+		Component: component,
+		Version:   version,
+		Options:   make(map[string]*ConfigOption),
+		Removed:   make(map[string]string),
+	}
+	if file.Inherit != "" {
+		base, err := loadConfigSchema(component, file.Inherit)
+		if err != nil {
+			return nil, err
+		}
+		schema.Options, schema.Removed = base.Options, base.Removed
+	}
+	for k, opt := range file.Options {
+		schema.Options[k] = opt
+		delete(schema.Removed, k)
+	}
+	for _, k := range file.Removed {
+		delete(schema.Options, k)
+		schema.Removed[k] = version
+	}
+	return schema, nil
+}
+
+// IsOnline returns true if the option can be changed without restarting the
+// instance, the options unknown to the schema require a restart, and so do the
+// ones of a fallback schema as they may have changed since its version
+func (s *ConfigSchema) IsOnline(key string) bool {
+	if s == nil || s.Fallback {
+		return false
+	}
+	opt, ok := s.Options[key]
+	return ok && opt.Online
+}
+
+// ConfigIssue is a problem of a config option found by the schema
+type ConfigIssue struct {
+	Source  string // where the option is set, e.g. server_configs.tikv
+	Key     string
+	Message string
+	Fatal   bool // the option is invalid, or a warning only
+}
+
+func (i ConfigIssue) String() string {
+	if i.Key == "" {
+		return fmt.Sprintf("%s: %s", i.Source, i.Message)
+	}
+	return fmt.Sprintf("%s: %s %s", i.Source, i.Key, i.Message)
+}
+
+// Check validates the keys, types and ranges of the config, the unknown keys
+// are reported as warnings as the schema doesn't cover all the options, along
+// with the similar known ones as they may be typos
+func (s *ConfigSchema) Check(source string, config map[string]interface{}) []ConfigIssue {
+	flat := make(map[string]interface{})
+	s.flatten("", config, flat)
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var issues []ConfigIssue
+	for _, k := range keys {
+		issue := ConfigIssue{Source: source, Key: k, Fatal: true}
+		if opt, ok := s.Options[k]; ok {
+			if err := opt.check(flat[k]); err != nil {
+				issue.Message = err.Error()
+				issues = append(issues, issue)
+			}
+			continue
+		}
+		if v, ok := s.Removed[k]; ok {
+			issue.Message = fmt.Sprintf("is removed since %s %s", s.Component, v)
+			issues = append(issues, issue)
+			continue
+		}
+		issue.Message = fmt.Sprintf("is unknown to %s %s", s.Component, s.Version)
+		if s.Fallback {
+			issue.Message += " (the schema of a former version)"
+		}
+		if suggestion := s.suggest(k); suggestion != "" {
+			issue.Message += fmt.Sprintf(", did you mean %s?", suggestion)
+		}
+		issue.Fatal = false
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// flatten flattens the config into the keys of the options, the values of
+// options in map type are kept as they are
+func (s *ConfigSchema) flatten(prefix string, config map[string]interface{}, result map[string]interface{}) {
+	for k, v := range config {
+		key := prefix + k
+		if opt, ok := s.Options[key]; ok && opt.Type == ConfigTypeMap {
+			result[key] = v
+			continue
+		}
+		if sub, ok := strKeyMap(v).(map[string]interface{}); ok {
+			s.flatten(key+".", sub, result)
+			continue
+		}
+		result[key] = v
+	}
+}
+
+// suggest returns the known key closest to the unknown one
+func (s *ConfigSchema) suggest(key string) string {
+	best, bestDistance := "", configSuggestDistance+1
+	for k := range s.Options {
+		if d := editDistance(key, k); d < bestDistance || (d == bestDistance && k < best) {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+func (opt *ConfigOption) check(v interface{}) error {
+	var num float64
+	isNum := false
+	switch val := v.(type) {
+	case int:
+		num, isNum = float64(val), true
+	case int64:
+		num, isNum = float64(val), true
+	case uint64:
+		num, isNum = float64(val), true
+	case float64:
+		num, isNum = val, true
+	}
+
+	switch opt.Type {
+	case ConfigTypeInt:
+		if !isNum || num != float64(int64(num)) {
+			return errors.Errorf("should be an integer, got %v", v)
+		}
+	case ConfigTypeFloat:
+		if !isNum {
+			return errors.Errorf("should be a number, got %v", v)
+		}
+	case ConfigTypeBool:
+		if _, ok := v.(bool); !ok {
+			return errors.Errorf("should be true or false, got %v", v)
+		}
+	case ConfigTypeString:
+		s, ok := v.(string)
+		if !ok {
+			return errors.Errorf("should be a string, got %v", v)
+		}
+		if len(opt.Enum) > 0 && !set.NewStringSet(opt.Enum...).Exist(s) {
+			return errors.Errorf("should be one of [%s], got %s", strings.Join(opt.Enum, ", "), s)
+		}
+	case ConfigTypeSize:
+		if s, ok := v.(string); (!ok || !configSizeRegexp.MatchString(s)) && !isNum {
+			return errors.Errorf("should be a size such as 512MB, got %v", v)
+		}
+	case ConfigTypeDuration:
+		if s, ok := v.(string); (!ok || !configDurationRegexp.MatchString(s)) && !isNum {
+			return errors.Errorf("should be a duration such as 10s, got %v", v)
+		}
+	case ConfigTypeMap:
+		if _, ok := strKeyMap(v).(map[string]interface{}); !ok {
+			return errors.Errorf("should be a map, got %v", v)
+		}
+	case ConfigTypeList:
+		if _, ok := v.([]interface{}); !ok {
+			return errors.Errorf("should be a list, got %v", v)
+		}
+	}
+
+	if isNum && opt.Min != nil && num < *opt.Min {
+		return errors.Errorf("should not be less than %v, got %v", *opt.Min, v)
+	}
+	if isNum && opt.Max != nil && num > *opt.Max {
+		return errors.Errorf("should not be greater than %v, got %v", *opt.Max, v)
+	}
+	return nil
+}
+
+// CheckConfigSchema validates the server configs and the configs of instances
+// against the schemas of the cluster version, the components without schema
+// are not checked. A warning is reported for a component if its configs are
+// checked against the schema of a former version, or not checked as there is
+// no schema of the cluster version.
+func CheckConfigSchema(topo *Specification, clusterVersion string) ([]ConfigIssue, error) {
+	var issues []ConfigIssue
+	schemas := make(map[string]*ConfigSchema)
+	getSchema := func(comp string) (*ConfigSchema, error) {
+		if s, ok := schemas[comp]; ok {
+			return s, nil
+		}
+		s, err := LoadConfigSchema(comp, clusterVersion)
+		if err != nil {
+			return nil, err
+		}
+		schemas[comp] = s
+
+		switch {
+		case s == nil:
+			versions, err := configSchemaVersions(comp)
+			if err != nil {
+				return nil, err
+			}
+			if len(versions) > 0 {
+				issues = append(issues, ConfigIssue{
+					Source:  comp,
+					Message: fmt.Sprintf("config schema of %s is not found, the configs are not validated", clusterVersion),
+				})
+			}
+		case s.Fallback:
+			issues = append(issues, ConfigIssue{
+				Source:  comp,
+				Message: fmt.Sprintf("config schema of %s is not found, the configs are validated against %s", clusterVersion, s.Version),
+			})
+		}
+		return s, nil
+	}
+
+	sc := reflect.ValueOf(topo.ServerConfigs)
+	for i := 0; i < sc.NumField(); i++ {
+		comp := strings.Split(sc.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		config, ok := sc.Field(i).Interface().(map[string]interface{})
+		if !ok || len(config) == 0 {
+			continue
+		}
+		schema, err := getSchema(comp)
+		if err != nil {
+			return nil, err
+		}
+		if schema != nil {
+			issues = append(issues, schema.Check("server_configs."+comp, config)...)
+		}
+	}
+
+	var err error
+	topo.IterInstance(func(inst Instance) {
+		if err != nil {
+			return
+		}
+		spec := reflect.Indirect(reflect.ValueOf(inst)).FieldByName("InstanceSpec")
+		if !spec.IsValid() {
+			return
+		}
+		f := reflect.Indirect(spec.Elem()).FieldByName("Config")
+		if !f.IsValid() {
+			return
+		}
+		config, ok := f.Interface().(map[string]interface{})
+		if !ok || len(config) == 0 {
+			return
+		}
+		var schema *ConfigSchema
+		if schema, err = getSchema(inst.ComponentName()); err != nil || schema == nil {
+			return
+		}
+		issues = append(issues, schema.Check(fmt.Sprintf("%s %s config", inst.ComponentName(), inst.ID()), config)...)
+	})
+	return issues, err
+}
+
+// editDistance returns the Levenshtein distance of the strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiup/embed"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestLoadConfigSchema(t *testing.T) {
+	assert := require.New(t)
+
+	schema, err := LoadConfigSchema(ComponentTiKV, "v4.0.9")
+	assert.Nil(err)
+	assert.Equal("v4.0.0", schema.Version)
+	assert.Contains(schema.Options, "raftstore.sync-log")
+
+	schema, err = LoadConfigSchema(ComponentTiKV, "v5.0.3")
+	assert.Nil(err)
+	assert.Equal("v5.0.0", schema.Version)
+	assert.NotContains(schema.Options, "raftstore.sync-log")
+	assert.Equal("v5.0.0", schema.Removed["raftstore.sync-log"])
+	// the options are inherited from the former version
+	assert.Contains(schema.Options, "raftstore.apply-pool-size")
+	assert.Contains(schema.Options, "gc.enable-compaction-filter")
+
+	// the options changed online in the version
+	assert.True(schema.IsOnline("raftstore.apply-pool-size"))
+	assert.True(schema.IsOnline("storage.block-cache.capacity"))
+	assert.False(schema.IsOnline("server.grpc-concurrency"))
+	assert.False(schema.IsOnline("unknown-option"))
+	v4, err := LoadConfigSchema(ComponentTiKV, "v4.0.0")
+	assert.Nil(err)
+	assert.False(v4.IsOnline("raftstore.apply-pool-size"))
+	var noSchema *ConfigSchema
+	assert.False(noSchema.IsOnline("log.level"))
+
+	// the schema of a former minor version is a fallback
+	for _, version := range []string{"v5.1.0", "v6.5.0"} {
+		schema, err = LoadConfigSchema(ComponentTiKV, version)
+		assert.Nil(err)
+		assert.Equal("v5.0.0", schema.Version, version)
+		assert.True(schema.Fallback, version)
+		assert.False(schema.IsOnline("raftstore.apply-pool-size"), version)
+	}
+	assert.False(v4.Fallback)
+
+	// no schema for the version or the component
+	for _, version := range []string{"v3.0.0", "nightly"} {
+		schema, err = LoadConfigSchema(ComponentTiKV, version)
+		assert.Nil(err)
+		assert.Nil(schema, version)
+	}
+	schema, err = LoadConfigSchema(ComponentDrainer, "v5.0.0")
+	assert.Nil(err)
+	assert.Nil(schema)
+}
+
+func TestCheckConfigSchema(t *testing.T) {
+	assert := require.New(t)
+
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+server_configs:
+  tikv:
+    raftstore.apply-pool-szie: 2
+    raftstore.sync-log: true
+    server:
+      grpc-concurrency: 0
+      labels: {zone: z1}
+    storage.block-cache.capacity: 16GB
+    unknown-option: 1
+  tidb:
+    log.level: verbose
+    performance.feedback-probability: 0.05
+    lease: 45s
+  pd:
+    replication.location-labels: [zone, host]
+pd_servers:
+  - host: 172.16.5.1
+tidb_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+    config:
+      readpool.storage.use-unified-pool: "yes"
+`), &topo)
+	assert.Nil(err)
+
+	issues, err := CheckConfigSchema(&topo, "v5.0.0")
+	assert.Nil(err)
+
+	messages := make(map[string]ConfigIssue)
+	for _, issue := range issues {
+		messages[issue.Key] = issue
+	}
+	assert.Len(messages, 6)
+	assert.Equal("is unknown to tikv v5.0.0, did you mean raftstore.apply-pool-size?", messages["raftstore.apply-pool-szie"].Message)
+	assert.False(messages["raftstore.apply-pool-szie"].Fatal)
+	assert.Equal("is removed since tikv v5.0.0", messages["raftstore.sync-log"].Message)
+	assert.True(messages["raftstore.sync-log"].Fatal)
+	assert.Equal("should not be less than 1, got 0", messages["server.grpc-concurrency"].Message)
+	assert.Equal("server_configs.tikv", messages["server.grpc-concurrency"].Source)
+	assert.False(messages["unknown-option"].Fatal)
+	assert.Equal("should be one of [debug, info, warn, error, fatal], got verbose", messages["log.level"].Message)
+	assert.Equal("should be true or false, got yes", messages["readpool.storage.use-unified-pool"].Message)
+	assert.Equal("tikv 172.16.5.1:20160 config", messages["readpool.storage.use-unified-pool"].Source)
+
+	// the options removed are valid in the former version
+	issues, err = CheckConfigSchema(&topo, "v4.0.0")
+	assert.Nil(err)
+	for _, issue := range issues {
+		assert.NotEqual("raftstore.sync-log", issue.Key)
+	}
+}
+
+func TestCheckConfigSchemaUnknownKey(t *testing.T) {
+	assert := require.New(t)
+
+	// the valid options not in the schema are not invalid, even if they're
+	// similar to the known ones
+	topo := Specification{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+server_configs:
+  tikv:
+    log.level: warn
+    log-level: info
+tikv_servers:
+  - host: 172.16.5.1
+`), &topo))
+	issues, err := CheckConfigSchema(&topo, "v5.0.0")
+	assert.Nil(err)
+	assert.Len(issues, 1)
+	assert.Equal("log.level", issues[0].Key)
+	assert.False(issues[0].Fatal)
+	assert.Contains(issues[0].Message, "did you mean log-level?")
+
+	// the versions newer than the schemas are checked against the former one
+	issues, err = CheckConfigSchema(&topo, "v6.5.0")
+	assert.Nil(err)
+	assert.Len(issues, 2)
+	assert.Equal("tikv: config schema of v6.5.0 is not found, the configs are validated against v5.0.0", issues[0].String())
+	assert.False(issues[0].Fatal)
+	assert.Equal("log.level", issues[1].Key)
+	assert.Contains(issues[1].Message, "(the schema of a former version)")
+	assert.False(issues[1].Fatal)
+
+	// the configs are not checked without a schema, with a warning
+	issues, err = CheckConfigSchema(&topo, "nightly")
+	assert.Nil(err)
+	assert.Len(issues, 1)
+	assert.Equal("tikv: config schema of nightly is not found, the configs are not validated", issues[0].String())
+	assert.False(issues[0].Fatal)
+}
+
+func TestCheckConfigSchemaExamples(t *testing.T) {
+	assert := require.New(t)
+
+	for _, name := range []string{"topology.example.yaml", "multi-dc.yaml", "minimal.yaml"} {
+		data, err := embed.ReadExample("examples/cluster/" + name)
+		assert.Nil(err)
+		topo := Specification{}
+		assert.Nil(yaml.Unmarshal(data, &topo))
+
+		issues, err := CheckConfigSchema(&topo, "v5.0.0")
+		assert.Nil(err)
+		assert.Empty(issues, name)
+	}
+}
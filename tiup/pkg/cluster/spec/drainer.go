@@ -30,6 +30,7 @@ import (
 // DrainerSpec represents the Drainer topology specification in topology.yaml
 type DrainerSpec struct {
 	Host            string                 `yaml:"host"`
+	HostGroup       string                 `yaml:"host_group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Patched         bool                   `yaml:"patched,omitempty"`
@@ -167,7 +168,7 @@ func (i *DrainerInstance) InitConfig(
 		paths.Deploy,
 		paths.Data[0],
 		paths.Log,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(topo.Endpoints(deployUser)...)
+	).WithPort(spec.Port).WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).AppendEndpoints(topo.Endpoints(deployUser)...)
 
 	cfg.WithCommitTs(spec.CommitTS)
 
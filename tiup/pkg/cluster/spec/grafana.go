@@ -32,6 +32,7 @@ import (
 // GrafanaSpec represents the Grafana topology specification in topology.yaml
 type GrafanaSpec struct {
 	Host            string               `yaml:"host"`
+	HostGroup       string               `yaml:"host_group,omitempty"`
 	SSHPort         int                  `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported        bool                 `yaml:"imported,omitempty"`
 	Patched         bool                 `yaml:"patched,omitempty"`
@@ -161,11 +162,15 @@ func (i *GrafanaInstance) InitConfig(
 
 	// transfer config
 	spec := i.InstanceSpec.(*GrafanaSpec)
+	password, err := ResolveSecrets(spec.Password)
+	if err != nil {
+		return err
+	}
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("grafana_%s.ini", i.GetHost()))
 	if err := config.NewGrafanaConfig(i.GetHost(), paths.Deploy).
 		WithPort(uint64(i.GetPort())).
 		WithUsername(spec.Username).
-		WithPassword(spec.Password).
+		WithPassword(password).
 		WithAnonymousenable(spec.AnonymousEnable).
 		WithRootURL(spec.RootURL).
 		WithDomain(spec.Domain).
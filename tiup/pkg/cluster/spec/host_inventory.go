@@ -0,0 +1,322 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// the well-known location labels, ordered from the outermost to the innermost,
+// they are placed before the other labels in the derived location-labels of PD
+var wellKnownLocationLabels = []string{"dc", "zone", "rack", "host"}
+
+// HostSpec represents a host in the inventory, the attributes are applied
+// to all the instances on the host
+type HostSpec struct {
+	Host      string            `yaml:"host"`
+	SSHPort   int               `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
+	SSHUser   string            `yaml:"ssh_user,omitempty" validate:"ssh_user:editable"`
+	Arch      string            `yaml:"arch,omitempty"`
+	OS        string            `yaml:"os,omitempty"`
+	NumaNodes []string          `yaml:"numa_nodes,omitempty" validate:"numa_nodes:editable"`
+	Disks     []string          `yaml:"disks,omitempty" validate:"disks:editable"`
+	Labels    map[string]string `yaml:"labels,omitempty" validate:"labels:editable"`
+	Groups    []string          `yaml:"groups,omitempty" validate:"groups:editable"`
+}
+
+// HostInventory is the hosts of the cluster, the instances can refer to a
+// group of hosts by `host_group` instead of listing every host
+type HostInventory []*HostSpec
+
+// Get returns the host in the inventory, nil if the host is not found
+func (inv HostInventory) Get(host string) *HostSpec {
+	for _, h := range inv {
+		if h.Host == host {
+			return h
+		}
+	}
+	return nil
+}
+
+// Group returns the hosts in the group, in the order of the inventory
+func (inv HostInventory) Group(name string) []*HostSpec {
+	var hosts []*HostSpec
+	for _, h := range inv {
+		for _, g := range h.Groups {
+			if g == name {
+				hosts = append(hosts, h)
+				break
+			}
+		}
+	}
+	return hosts
+}
+
+// merge returns the inventory overlaid by the other one, hosts in both of
+// them are replaced by the ones in the other inventory
+func (inv HostInventory) merge(other HostInventory) HostInventory {
+	merged := make(HostInventory, 0, len(inv)+len(other))
+	for _, h := range inv {
+		if other.Get(h.Host) == nil {
+			merged = append(merged, h)
+		}
+	}
+	return append(merged, other...)
+}
+
+// LocationLabels returns the keys of the labels of hosts in the order of
+// location-labels of PD, the well-known ones are placed first
+func (inv HostInventory) LocationLabels() []string {
+	keys := make(map[string]struct{})
+	for _, h := range inv {
+		for k := range h.Labels {
+			keys[k] = struct{}{}
+		}
+	}
+
+	labels := []string{}
+	for _, k := range wellKnownLocationLabels {
+		if _, ok := keys[k]; ok {
+			labels = append(labels, k)
+			delete(keys, k)
+		}
+	}
+	others := []string{}
+	for k := range keys {
+		others = append(others, k)
+	}
+	sort.Strings(others)
+	return append(labels, others...)
+}
+
+// SSHUser returns the user to login the host via SSH, or the default one if
+// it is not set in the inventory
+func (inv HostInventory) SSHUser(host, defaultUser string) string {
+	if h := inv.Get(host); h != nil && h.SSHUser != "" {
+		return h.SSHUser
+	}
+	return defaultUser
+}
+
+// validate checks the hosts in the inventory are unique
+func (inv HostInventory) validate() error {
+	hosts := make(map[string]struct{})
+	for _, h := range inv {
+		if h.Host == "" {
+			return errors.New("`host` of the hosts inventory should not be empty")
+		}
+		if _, ok := hosts[h.Host]; ok {
+			return errors.Errorf("host %s is duplicated in the hosts inventory", h.Host)
+		}
+		hosts[h.Host] = struct{}{}
+	}
+	return nil
+}
+
+// applyHostInventory expands the instances referring to host groups to the
+// instances on the hosts in the groups, and applies the attributes of hosts
+// in the inventory to the instances
+func (s *Specification) applyHostInventory() error {
+	if err := s.Hosts.validate(); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(s).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if isSkipField(field) || field.Kind() != reflect.Slice {
+			continue
+		}
+		cfg := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		expanded := reflect.MakeSlice(field.Type(), 0, field.Len())
+		for j := 0; j < field.Len(); j++ {
+			insts, err := s.expandHostGroup(field.Index(j), cfg)
+			if err != nil {
+				return err
+			}
+			expanded = reflect.Append(expanded, insts...)
+		}
+		field.Set(expanded)
+	}
+
+	return nil
+}
+
+// hostLabels returns the labels of the host of the TiKV instance in the
+// inventory, they're not used if `server.labels` is set in the topology
+func (s *Specification) hostLabels(kv *TiKVSpec) map[string]string {
+	h := s.Hosts.Get(kv.Host)
+	if h == nil ||
+		GetValueFromPath(kv.Config, "server.labels") != nil ||
+		GetValueFromPath(s.ServerConfigs.TiKV, "server.labels") != nil {
+		return nil
+	}
+	return h.Labels
+}
+
+// tikvLabels returns the labels of the TiKV instance, they're the labels of its
+// host in the inventory if `server.labels` is not set. The labels of hosts are
+// derived each time the config is rendered, so an edit of them is applied to
+// the instances by reload.
+func (s *Specification) tikvLabels(kv *TiKVSpec) (map[string]string, error) {
+	lbs, err := kv.Labels()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.hostLabels(kv) {
+		lbs[k] = v
+	}
+	return lbs, nil
+}
+
+// withHostLabels returns the config of the TiKV instance with `server.labels`
+// derived from the labels of its host in the inventory, the config of the
+// instance is not changed
+func (s *Specification) withHostLabels(kv *TiKVSpec) map[string]interface{} {
+	hostLabels := s.hostLabels(kv)
+	if len(hostLabels) == 0 {
+		return kv.Config
+	}
+	labels := make(map[string]interface{}, len(hostLabels))
+	for k, v := range hostLabels {
+		labels[k] = v
+	}
+	config := make(map[string]interface{}, len(kv.Config)+1)
+	for k, v := range kv.Config {
+		config[k] = v
+	}
+	config["server.labels"] = labels
+	return config
+}
+
+// withLocationLabels returns the global config of PD with
+// `replication.location-labels` derived from the labels of hosts in the
+// inventory, the config is not changed
+func (s *Specification) withLocationLabels(global map[string]interface{}) map[string]interface{} {
+	locationLabels := s.Hosts.LocationLabels()
+	if len(locationLabels) == 0 || GetValueFromPath(global, "replication.location-labels") != nil {
+		return global
+	}
+	lbs := make([]interface{}, 0, len(locationLabels))
+	for _, l := range locationLabels {
+		lbs = append(lbs, l)
+	}
+	config := make(map[string]interface{}, len(global)+1)
+	for k, v := range global {
+		config[k] = v
+	}
+	config["replication.location-labels"] = lbs
+	return config
+}
+
+// placeInventoryDisks places the data dir of the instances without one on the
+// disks of their hosts in the inventory. The data dir can't be changed, so it's
+// placed once when the instance is added.
+func (s *Specification) placeInventoryDisks() {
+	if len(s.Hosts) == 0 {
+		return
+	}
+	hosts, _ := placedInstances(s)
+	for host, insts := range hosts {
+		if h := s.Hosts.Get(host); h != nil && len(h.Disks) > 0 {
+			placeDisks(h.Disks, insts)
+		}
+	}
+}
+
+// numaNode returns the NUMA node the instance is bound to, an instance without
+// `numa_node` is bound to the `numa_nodes` of its host in the inventory
+func numaNode(topo Topology, host, numa string) string {
+	if numa != "" {
+		return numa
+	}
+	if s, ok := topo.(*Specification); ok {
+		if h := s.Hosts.Get(host); h != nil {
+			return strings.Join(h.NumaNodes, ",")
+		}
+	}
+	return numa
+}
+
+// expandHostGroup returns the instances of the spec, it's one instance per host
+// in the group if `host_group` is set, the attributes of the host in inventory
+// are filled if they are not set in the spec
+func (s *Specification) expandHostGroup(inst reflect.Value, cfg string) ([]reflect.Value, error) {
+	spec := reflect.Indirect(inst)
+	if spec.Kind() != reflect.Struct {
+		return []reflect.Value{inst}, nil
+	}
+	group := spec.FieldByName("HostGroup")
+	if !group.IsValid() || group.String() == "" {
+		if h := s.Hosts.Get(spec.FieldByName("Host").String()); h != nil {
+			fillHostAttributes(spec, h)
+		}
+		return []reflect.Value{inst}, nil
+	}
+
+	if spec.FieldByName("Host").String() != "" {
+		return nil, errors.Errorf("`%s` can't set both host and host_group (%s)", cfg, group.String())
+	}
+	hosts := s.Hosts.Group(group.String())
+	if len(hosts) == 0 {
+		return nil, errors.Errorf("host_group %s of `%s` is not found in the hosts inventory", group.String(), cfg)
+	}
+
+	insts := make([]reflect.Value, 0, len(hosts))
+	for _, h := range hosts {
+		ref := reflect.New(spec.Type())
+		ref.Elem().Set(spec)
+		elem := ref.Elem()
+		// the maps such as config should not be shared by the instances
+		for i := 0; i < elem.NumField(); i++ {
+			f := elem.Field(i)
+			if f.Kind() != reflect.Map || f.IsNil() || !f.CanSet() {
+				continue
+			}
+			m := reflect.MakeMapWithSize(f.Type(), f.Len())
+			for _, k := range f.MapKeys() {
+				m.SetMapIndex(k, f.MapIndex(k))
+			}
+			f.Set(m)
+		}
+		elem.FieldByName("Host").SetString(h.Host)
+		elem.FieldByName("HostGroup").SetString("")
+		fillHostAttributes(elem, h)
+
+		if inst.Kind() == reflect.Ptr {
+			insts = append(insts, ref)
+		} else {
+			insts = append(insts, elem)
+		}
+	}
+	return insts, nil
+}
+
+// fillHostAttributes sets the SSH port, arch and OS of the instance from the host
+func fillHostAttributes(spec reflect.Value, h *HostSpec) {
+	if f := spec.FieldByName("SSHPort"); f.IsValid() && f.CanSet() && f.Int() == 0 && h.SSHPort != 0 {
+		f.SetInt(int64(h.SSHPort))
+	}
+	if f := spec.FieldByName("Arch"); f.IsValid() && f.CanSet() && f.String() == "" && h.Arch != "" {
+		f.SetString(h.Arch)
+	}
+	if f := spec.FieldByName("OS"); f.IsValid() && f.CanSet() && f.String() == "" && h.OS != "" {
+		f.SetString(h.OS)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestHostInventory(t *testing.T) {
+	assert := require.New(t)
+
+	topo := Specification{}
+	err := yaml.UnmarshalStrict([]byte(`
+global:
+  ssh_port: 22
+hosts:
+  - host: 172.16.5.1
+    ssh_port: 2222
+    ssh_user: admin
+    arch: arm64
+    labels: {zone: z1, host: h1}
+    groups: [pd, kv]
+  - host: 172.16.5.2
+    labels: {zone: z2, host: h2, disk: ssd}
+    groups: [kv]
+pd_servers:
+  - host_group: pd
+tidb_servers:
+  - host: 172.16.5.2
+tikv_servers:
+  - host_group: kv
+    config:
+      log.level: warn
+`), &topo)
+	assert.Nil(err)
+
+	assert.Len(topo.PDServers, 1)
+	assert.Equal("172.16.5.1", topo.PDServers[0].Host)
+	assert.Equal("", topo.PDServers[0].HostGroup)
+	assert.Equal(2222, topo.PDServers[0].SSHPort)
+	assert.Equal("arm64", topo.PDServers[0].Arch)
+	assert.Equal(22, topo.TiDBServers[0].SSHPort)
+
+	assert.Len(topo.TiKVServers, 2)
+	assert.Equal("172.16.5.2", topo.TiKVServers[1].Host)
+	assert.Equal(22, topo.TiKVServers[1].SSHPort)
+	lbs, _, err := topo.GetTiKVLabels()
+	assert.Nil(err)
+	assert.Equal(map[string]string{"zone": "z1", "host": "h1"}, lbs["172.16.5.1:20160"])
+	assert.Equal(map[string]string{"zone": "z2", "host": "h2", "disk": "ssd"}, lbs["172.16.5.2:20160"])
+	assert.Equal("warn", topo.TiKVServers[1].Config["log.level"])
+	// the labels are derived when the config is rendered, not saved in the topology
+	assert.Nil(topo.TiKVServers[1].Config["server.labels"])
+	assert.Equal(map[string]interface{}{"zone": "z2", "host": "h2", "disk": "ssd"},
+		topo.withHostLabels(topo.TiKVServers[1])["server.labels"])
+	assert.Nil(topo.ServerConfigs.PD)
+	assert.Equal([]interface{}{"zone", "host", "disk"},
+		topo.withLocationLabels(topo.ServerConfigs.PD)["replication.location-labels"])
+
+	locationLabels, err := topo.LocationLabels()
+	assert.Nil(err)
+	assert.Equal([]string{"zone", "host", "disk"}, locationLabels)
+
+	assert.Equal("admin", topo.Hosts.SSHUser("172.16.5.1", "root"))
+	assert.Equal("root", topo.Hosts.SSHUser("172.16.5.2", "root"))
+
+	// the expanded topology can be loaded again
+	data, err := yaml.Marshal(&topo)
+	assert.Nil(err)
+	reloaded := Specification{}
+	assert.Nil(yaml.UnmarshalStrict(data, &reloaded))
+	assert.Len(reloaded.TiKVServers, 2)
+
+	// the edited labels of hosts are applied to the instances
+	reloaded.Hosts.Get("172.16.5.1").Labels["zone"] = "z3"
+	lbs, _, err = reloaded.GetTiKVLabels()
+	assert.Nil(err)
+	assert.Equal(map[string]string{"zone": "z3", "host": "h1"}, lbs["172.16.5.1:20160"])
+}
+
+func TestHostInventoryNumaNodesAndDisks(t *testing.T) {
+	assert := require.New(t)
+
+	topo := Specification{}
+	err := yaml.UnmarshalStrict([]byte(`
+global:
+  deploy_dir: /tidb-deploy
+hosts:
+  - host: 172.16.5.1
+    numa_nodes: ["0", "1"]
+    disks: [/data1, /data2]
+pd_servers:
+  - host: 172.16.5.1
+    numa_node: "1"
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.1
+    port: 20161
+    status_port: 20181
+    data_dir: /data3/tikv
+tidb_servers:
+  - host: 172.16.5.2
+`), &topo)
+	assert.Nil(err)
+
+	// the instances without numa_node are bound to the NUMA nodes of the host
+	assert.Equal("0,1", numaNode(&topo, "172.16.5.1", topo.TiKVServers[0].NumaNode))
+	assert.Equal("1", numaNode(&topo, "172.16.5.1", topo.PDServers[0].NumaNode))
+	assert.Equal("", numaNode(&topo, "172.16.5.2", topo.TiDBServers[0].NumaNode))
+
+	// the data dirs without value are placed on the disks
+	assert.Equal("/data1/tidb-data/tikv-20160", topo.TiKVServers[0].DataDir)
+	assert.Equal("/data3/tikv", topo.TiKVServers[1].DataDir)
+	assert.Equal("/data2/tidb-data/pd-2379", topo.PDServers[0].DataDir)
+}
+
+func TestHostInventoryErrors(t *testing.T) {
+	assert := require.New(t)
+
+	cases := map[string]string{
+		`
+pd_servers:
+  - host_group: pd
+`: "host_group pd of `pd_servers` is not found",
+		`
+hosts:
+  - host: 172.16.5.1
+    groups: [pd]
+pd_servers:
+  - host: 172.16.5.1
+    host_group: pd
+`: "can't set both host and host_group",
+		`
+hosts:
+  - host: 172.16.5.1
+  - host: 172.16.5.1
+pd_servers:
+  - host: 172.16.5.1
+`: "host 172.16.5.1 is duplicated",
+	}
+	for content, msg := range cases {
+		topo := Specification{}
+		err := yaml.UnmarshalStrict([]byte(content), &topo)
+		assert.NotNil(err)
+		assert.Contains(err.Error(), msg)
+	}
+}
@@ -36,6 +36,7 @@ import (
 // PrometheusSpec represents the Prometheus Server topology specification in topology.yaml
 type PrometheusSpec struct {
 	Host                  string                 `yaml:"host"`
+	HostGroup             string                 `yaml:"host_group,omitempty"`
 	SSHPort               int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported              bool                   `yaml:"imported,omitempty"`
 	Patched               bool                   `yaml:"patched,omitempty"`
@@ -170,7 +171,7 @@ func (i *MonitorInstance) InitConfig(
 		paths.Data[0],
 		paths.Log,
 	).WithPort(spec.Port).
-		WithNumaNode(spec.NumaNode).
+		WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).
 		WithRetention(spec.Retention).
 		WithNG(spec.NgPort)
 
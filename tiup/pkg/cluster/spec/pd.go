@@ -32,6 +32,7 @@ import (
 // PDSpec represents the PD topology specification in topology.yaml
 type PDSpec struct {
 	Host                string `yaml:"host"`
+	HostGroup           string `yaml:"host_group,omitempty"`
 	ListenHost          string `yaml:"listen_host,omitempty"`
 	AdvertiseClientAddr string `yaml:"advertise_client_addr,omitempty"`
 	AdvertisePeerAddr   string `yaml:"advertise_peer_addr,omitempty"`
@@ -173,7 +174,7 @@ func (i *PDInstance) InitConfig(
 	spec := i.InstanceSpec.(*PDSpec)
 	cfg := scripts.
 		NewPDScript(spec.Name, i.GetHost(), paths.Deploy, paths.Data[0], paths.Log).
-		WithNumaNode(spec.NumaNode).
+		WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).
 		WithClientPort(spec.ClientPort).
 		WithPeerPort(spec.PeerPort).
 		AppendEndpoints(topo.Endpoints(deployUser)...).
@@ -240,7 +241,7 @@ func (i *PDInstance) InitConfig(
 			i.Role())
 	}
 
-	if err := i.MergeServerConfig(ctx, e, globalConfig, spec.Config, paths); err != nil {
+	if err := i.MergeServerConfig(ctx, e, topo.withLocationLabels(globalConfig), spec.Config, paths); err != nil {
 		return err
 	}
 
@@ -273,7 +274,7 @@ func (i *PDInstance) ScaleConfig(
 		paths.Data[0],
 		paths.Log,
 	).WithPeerPort(spec.PeerPort).
-		WithNumaNode(spec.NumaNode).
+		WithNumaNode(numaNode(topo, spec.Host, spec.NumaNode)).
 		WithClientPort(spec.ClientPort).
 		AppendEndpoints(cluster.Endpoints(deployUser)...).
 		WithListenHost(i.GetListenHost())
@@ -0,0 +1,395 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+// HostProbeScript prints the memory, NUMA nodes and disks of the host, the
+// output is parsed by ParseHostResources
+const HostProbeScript = `awk '/^MemTotal:/{print "mem", $2}' /proc/meminfo;
+for n in /sys/devices/system/node/node[0-9]*; do
+  [ -d "$n" ] || continue;
+  echo "numa ${n##*/node} $(cat $n/cpulist) $(awk '/MemTotal:/{print $4}' $n/meminfo)";
+done;
+df -P -k -l -x tmpfs -x devtmpfs -x overlay -x squashfs 2>/dev/null | awk 'NR>1{print "disk", $6, $2, $4}'`
+
+const (
+	// the ratio of memory of a host that can be used by the instances
+	placementMemoryRatio = 0.9
+	// the max offset of ports tried to resolve the conflicts on a host
+	placementMaxPortOffset = 100
+)
+
+// the weights of roles used to share the NUMA nodes, disks and memory of a
+// host, the roles not listed have a weight of 1
+var placementWeights = map[string]int{
+	ComponentTiKV:    4,
+	ComponentTiFlash: 4,
+	ComponentTiDB:    2,
+}
+
+// the roles storing data, they are placed on the disks of the host
+var placementDataRoles = map[string]bool{
+	ComponentTiKV:    true,
+	ComponentTiFlash: true,
+	ComponentPD:      true,
+	ComponentPump:    true,
+	ComponentDrainer: true,
+}
+
+// HostResources is the hardware of a host used to plan the placement of instances
+type HostResources struct {
+	MemoryMB  int
+	NumaNodes []NumaNode
+	Disks     []DiskResource
+}
+
+// NumaNode is a NUMA node of a host
+type NumaNode struct {
+	ID       string
+	CPUs     string
+	MemoryMB int
+}
+
+// DiskResource is a filesystem mounted on a host
+type DiskResource struct {
+	MountPoint string
+	SizeMB     int
+	AvailMB    int
+}
+
+// ParseHostResources parses the output of HostProbeScript
+func ParseHostResources(out string) (*HostResources, error) {
+	res := &HostResources{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0] == "mem" && len(fields) == 2:
+			kb, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, errors.Annotatef(err, "invalid memory size %s", fields[1])
+			}
+			res.MemoryMB = kb / 1024
+		case fields[0] == "numa" && len(fields) == 4:
+			kb, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return nil, errors.Annotatef(err, "invalid memory size %s of NUMA node %s", fields[3], fields[1])
+			}
+			res.NumaNodes = append(res.NumaNodes, NumaNode{ID: fields[1], CPUs: fields[2], MemoryMB: kb / 1024})
+		case fields[0] == "disk" && len(fields) == 4:
+			size, err1 := strconv.Atoi(fields[2])
+			avail, err2 := strconv.Atoi(fields[3])
+			if err1 != nil || err2 != nil {
+				return nil, errors.Errorf("invalid size of disk %s", fields[1])
+			}
+			res.Disks = append(res.Disks, DiskResource{MountPoint: fields[1], SizeMB: size / 1024, AvailMB: avail / 1024})
+		}
+	}
+	return res, scanner.Err()
+}
+
+// dataDisks returns the mount points to place data on, the system ones are excluded
+func (r *HostResources) dataDisks() []string {
+	var disks []string
+	for _, d := range r.Disks {
+		if d.MountPoint == "/" || strings.HasPrefix(d.MountPoint, "/boot") {
+			continue
+		}
+		disks = append(disks, d.MountPoint)
+	}
+	return disks
+}
+
+// placedInstance is an instance whose placement is planned
+type placedInstance struct {
+	spec   reflect.Value // the struct of the instance spec
+	role   string
+	weight int
+}
+
+func (p *placedInstance) stringField(name string) reflect.Value {
+	return p.spec.FieldByName(name)
+}
+
+// ports returns the non-zero port fields of the instance
+func (p *placedInstance) ports() []reflect.Value {
+	var ports []reflect.Value
+	for i := 0; i < p.spec.NumField(); i++ {
+		name := p.spec.Type().Field(i).Name
+		if !strings.HasSuffix(name, "Port") || name == "SSHPort" || p.spec.Field(i).Kind() != reflect.Int {
+			continue
+		}
+		if p.spec.Field(i).Int() != 0 {
+			ports = append(ports, p.spec.Field(i))
+		}
+	}
+	return ports
+}
+
+// PlanPlacement completes the topology for the instances sharing hosts, with the
+// resources probed on the hosts: it assigns non-conflicting ports, binds the
+// instances to the NUMA nodes and places their data on the disks, and shares
+// the memory of the hosts by the memory limits, the values set in the topology
+// are kept
+func PlanPlacement(topo *Specification, resources map[string]*HostResources) error {
+	hosts, hostOrder := placedInstances(topo)
+	for _, host := range hostOrder {
+		insts := hosts[host]
+		if err := placePorts(topo, host, insts); err != nil {
+			return err
+		}
+		res, ok := resources[host]
+		if !ok || len(insts) < 2 {
+			// a host with only one instance is left as it is
+			continue
+		}
+		numaNodes := res.numaNodeIDs()
+		disks := res.dataDisks()
+		if h := topo.Hosts.Get(host); h != nil {
+			if len(h.NumaNodes) > 0 {
+				var err error
+				if numaNodes, err = inventoryNumaNodes(res, h); err != nil {
+					return err
+				}
+			}
+			if len(h.Disks) > 0 {
+				disks = h.Disks
+			}
+		}
+		placeNumaNodes(numaNodes, insts)
+		placeDisks(disks, insts)
+		placeMemory(res, insts)
+	}
+	return nil
+}
+
+// placedInstances groups the instances of the topology by their hosts, the
+// hosts are in the order they first appear
+func placedInstances(topo *Specification) (map[string][]*placedInstance, []string) {
+	hosts := make(map[string][]*placedInstance)
+	var hostOrder []string
+
+	v := reflect.ValueOf(topo).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if isSkipField(field) || field.Kind() != reflect.Slice {
+			continue
+		}
+		for j := 0; j < field.Len(); j++ {
+			inst := field.Index(j)
+			is, ok := inst.Interface().(InstanceSpec)
+			if !ok {
+				continue
+			}
+			spec := reflect.Indirect(inst)
+			host := spec.FieldByName("Host").String()
+			if _, ok := hosts[host]; !ok {
+				hostOrder = append(hostOrder, host)
+			}
+			weight := placementWeights[is.Role()]
+			if weight == 0 {
+				weight = 1
+			}
+			hosts[host] = append(hosts[host], &placedInstance{spec: spec, role: is.Role(), weight: weight})
+		}
+	}
+
+	return hosts, hostOrder
+}
+
+// placePorts shifts the ports of instances conflicting with the former ones on
+// the same host, all the ports of an instance are shifted by the same offset
+func placePorts(topo *Specification, host string, insts []*placedInstance) error {
+	used := map[int]bool{
+		topo.MonitoredOptions.NodeExporterPort:     true,
+		topo.MonitoredOptions.BlackboxExporterPort: true,
+	}
+	for _, inst := range insts {
+		ports := inst.ports()
+		offset := 0
+		for ; offset <= placementMaxPortOffset; offset++ {
+			conflict := false
+			for _, p := range ports {
+				if used[int(p.Int())+offset] {
+					conflict = true
+					break
+				}
+			}
+			if !conflict {
+				break
+			}
+		}
+		if offset > placementMaxPortOffset {
+			return errors.Errorf("failed to find free ports for %s on %s", inst.role, host)
+		}
+		for _, p := range ports {
+			p.SetInt(p.Int() + int64(offset))
+			used[int(p.Int())] = true
+		}
+	}
+	return nil
+}
+
+// numaNodeIDs returns the NUMA nodes the instances are spread on, they're not
+// bound if the host has only one node
+func (res *HostResources) numaNodeIDs() []string {
+	if len(res.NumaNodes) < 2 {
+		return nil
+	}
+	ids := make([]string, 0, len(res.NumaNodes))
+	for _, n := range res.NumaNodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+// inventoryNumaNodes returns the NUMA nodes of the host in the inventory, the
+// instances are bound to them even if there's only one
+func inventoryNumaNodes(res *HostResources, h *HostSpec) ([]string, error) {
+	for _, id := range h.NumaNodes {
+		found := false
+		for _, n := range res.NumaNodes {
+			if n.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("NUMA node %s of host %s in the hosts inventory is not found on the host", id, h.Host)
+		}
+	}
+	return h.NumaNodes, nil
+}
+
+// placeNumaNodes binds the instances to the NUMA node with the least weight
+func placeNumaNodes(nodes []string, insts []*placedInstance) {
+	if len(nodes) == 0 {
+		return
+	}
+	load := make(map[string]int)
+	var unbound []*placedInstance
+	for _, inst := range insts {
+		f := inst.stringField("NumaNode")
+		if !f.IsValid() {
+			continue
+		}
+		if node := f.String(); node != "" {
+			load[node] += inst.weight
+			continue
+		}
+		unbound = append(unbound, inst)
+	}
+	// the heavy instances are placed first to balance the nodes
+	sort.SliceStable(unbound, func(i, j int) bool { return unbound[i].weight > unbound[j].weight })
+	for _, inst := range unbound {
+		best := nodes[0]
+		for _, n := range nodes[1:] {
+			if load[n] < load[best] {
+				best = n
+			}
+		}
+		load[best] += inst.weight
+		inst.stringField("NumaNode").SetString(best)
+	}
+}
+
+// placeDisks places the data dir of data roles on the disk with the least weight
+func placeDisks(disks []string, insts []*placedInstance) {
+	if len(disks) == 0 {
+		return
+	}
+	load := make(map[string]int)
+	var unplaced []*placedInstance
+	for _, inst := range insts {
+		f := inst.stringField("DataDir")
+		if !f.IsValid() || !placementDataRoles[inst.role] {
+			continue
+		}
+		if dir := f.String(); dir != "" {
+			for _, d := range disks {
+				if strings.HasPrefix(dir, d+"/") {
+					load[d] += inst.weight
+				}
+			}
+			continue
+		}
+		unplaced = append(unplaced, inst)
+	}
+	sort.SliceStable(unplaced, func(i, j int) bool { return unplaced[i].weight > unplaced[j].weight })
+	for _, inst := range unplaced {
+		best := disks[0]
+		for _, d := range disks[1:] {
+			if load[d] < load[best] {
+				best = d
+			}
+		}
+		load[best] += inst.weight
+		port := inst.spec.Addr().Interface().(InstanceSpec).GetMainPort()
+		inst.stringField("DataDir").SetString(filepath.Join(best, "tidb-data", fmt.Sprintf("%s-%d", inst.role, port)))
+	}
+}
+
+// placeMemory shares the memory of the host to the instances by their weights
+func placeMemory(res *HostResources, insts []*placedInstance) {
+	if res.MemoryMB == 0 {
+		return
+	}
+	total := 0
+	for _, inst := range insts {
+		total += inst.weight
+	}
+	available := int(float64(res.MemoryMB) * placementMemoryRatio)
+	for _, inst := range insts {
+		f := inst.stringField("ResourceControl")
+		if !f.IsValid() {
+			continue
+		}
+		rc := f.Addr().Interface().(*meta.ResourceControl)
+		if rc.MemoryLimit != "" {
+			continue
+		}
+		rc.MemoryLimit = fmt.Sprintf("%dM", available*inst.weight/total)
+	}
+}
+
+// InstancePlacement returns the NUMA node and memory limit of the instance
+func InstancePlacement(inst Instance) (numaNode, memoryLimit string) {
+	v := reflect.Indirect(reflect.ValueOf(inst)).FieldByName("InstanceSpec")
+	if !v.IsValid() {
+		return "", ""
+	}
+	spec := reflect.Indirect(v.Elem())
+	if f := spec.FieldByName("NumaNode"); f.IsValid() {
+		numaNode = f.String()
+	}
+	if f := spec.FieldByName("ResourceControl"); f.IsValid() {
+		memoryLimit = f.Interface().(meta.ResourceControl).MemoryLimit
+	}
+	return numaNode, memoryLimit
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseHostResources(t *testing.T) {
+	assert := require.New(t)
+
+	res, err := ParseHostResources(`mem 65536000
+numa 0 0-15 32768000
+numa 1 16-31 32768000
+disk / 102400 51200
+disk /data1 1048576 1048576
+disk /boot 1024 512
+`)
+	assert.Nil(err)
+	assert.Equal(64000, res.MemoryMB)
+	assert.Equal([]NumaNode{{ID: "0", CPUs: "0-15", MemoryMB: 32000}, {ID: "1", CPUs: "16-31", MemoryMB: 32000}}, res.NumaNodes)
+	assert.Len(res.Disks, 3)
+	assert.Equal([]string{"/data1"}, res.dataDisks())
+
+	_, err = ParseHostResources("mem abc\n")
+	assert.NotNil(err)
+}
+
+func TestPlanPlacement(t *testing.T) {
+	assert := require.New(t)
+
+	dir := writeTopologyFiles(t, map[string]string{
+		"topo.yaml": `
+pd_servers:
+  - host: 172.16.5.1
+tidb_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.1
+    numa_node: "1"
+  - host: 172.16.5.2
+`,
+		"hosts.yaml": `
+hosts:
+  - host: 172.16.5.1
+    disks: [/data1, /data2]
+`,
+	})
+
+	// the draft topology has conflicting ports, it's not valid before planning
+	topo, err := ParseTopologyDraft([]string{filepath.Join(dir, "topo.yaml"), filepath.Join(dir, "hosts.yaml")}, "")
+	assert.Nil(err)
+
+	err = PlanPlacement(topo, map[string]*HostResources{
+		"172.16.5.1": {
+			MemoryMB:  10000,
+			NumaNodes: []NumaNode{{ID: "0"}, {ID: "1"}},
+		},
+		"172.16.5.2": {MemoryMB: 10000},
+	})
+	assert.Nil(err)
+
+	kv := topo.TiKVServers
+	assert.Equal(20160, kv[0].Port)
+	assert.Equal(20180, kv[0].StatusPort)
+	assert.Equal(20161, kv[1].Port)
+	assert.Equal(20181, kv[1].StatusPort)
+	assert.Equal("0", kv[0].NumaNode)
+	assert.Equal("1", kv[1].NumaNode)
+	assert.Equal("/data1/tidb-data/tikv-20160", kv[0].DataDir)
+	assert.Equal("/data2/tidb-data/tikv-20161", kv[1].DataDir)
+	// total weight of the host is pd 1 + tidb 2 + tikv 4 * 2
+	assert.Equal("3272M", kv[0].ResourceControl.MemoryLimit)
+	assert.Equal("1636M", topo.TiDBServers[0].ResourceControl.MemoryLimit)
+	assert.Equal("818M", topo.PDServers[0].ResourceControl.MemoryLimit)
+
+	// the host with a single instance is left as it is
+	assert.Equal(20160, kv[2].Port)
+	assert.Equal("", kv[2].NumaNode)
+	assert.Equal("", kv[2].ResourceControl.MemoryLimit)
+
+	// the planned topology is valid
+	data, err := yaml.Marshal(topo)
+	assert.Nil(err)
+	assert.Nil(yaml.UnmarshalStrict(data, &Specification{}))
+}
+
+func TestPlanPlacementInventoryNumaNodes(t *testing.T) {
+	assert := require.New(t)
+
+	dir := writeTopologyFiles(t, map[string]string{
+		"topo.yaml": `
+hosts:
+  - host: 172.16.5.1
+    numa_nodes: ["1", "2"]
+tidb_servers:
+  - host: 172.16.5.1
+tikv_servers:
+  - host: 172.16.5.1
+  - host: 172.16.5.1
+`,
+	})
+	resources := map[string]*HostResources{
+		"172.16.5.1": {
+			MemoryMB:  10000,
+			NumaNodes: []NumaNode{{ID: "0"}, {ID: "1"}, {ID: "2"}},
+		},
+	}
+
+	// only the NUMA nodes in the inventory are used
+	topo, err := ParseTopologyDraft([]string{filepath.Join(dir, "topo.yaml")}, "")
+	assert.Nil(err)
+	assert.Nil(PlanPlacement(topo, resources))
+	assert.Equal("1", topo.TiKVServers[0].NumaNode)
+	assert.Equal("2", topo.TiKVServers[1].NumaNode)
+	assert.Equal("1", topo.TiDBServers[0].NumaNode)
+
+	// the NUMA nodes in the inventory must exist on the host
+	topo, err = ParseTopologyDraft([]string{filepath.Join(dir, "topo.yaml")}, "")
+	assert.Nil(err)
+	topo.Hosts[0].NumaNodes = []string{"3"}
+	err = PlanPlacement(topo, resources)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "NUMA node 3 of host 172.16.5.1")
+}
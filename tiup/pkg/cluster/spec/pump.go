@@ -29,6 +29,7 @@ import (
 // PumpSpec represents the Pump topology specification in topology.yaml
 type PumpSpec struct {
 	Host            string                 `yaml:"host"`
+	HostGroup       string                 `yaml:"host_group,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported        bool                   `yaml:"imported,omitempty"`
 	Patched         bool                   `yaml:"patched,omitempty"`
@@ -165,7 +166,7 @@ func (i *PumpInstance) InitConfig(
 		paths.Deploy,
 		paths.Data[0],
 		paths.Log,
-	).WithPort(spec.Port).WithNumaNode(spec.NumaNode).AppendEndpoints(topo.Endpoints(deployUser)...)
+	).WithPort(spec.Port).WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).AppendEndpoints(topo.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_pump_%s_%d.sh", i.GetHost(), i.GetPort()))
 	if err := cfg.ConfigToFile(fp); err != nil {
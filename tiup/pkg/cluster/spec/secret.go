@@ -0,0 +1,286 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/secret"
+	"github.com/pingcap/tiup/pkg/set"
+	"github.com/pingcap/tiup/pkg/tui"
+	"github.com/pingcap/tiup/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// SecretVaultFile is the encrypted vault of secrets in the profile dir
+	SecretVaultFile = "secrets.vault"
+	// SecretKeyFile is the default key file of the vault in the profile dir
+	SecretKeyFile = "secret.key"
+
+	// EnvSecretPassphrase is the passphrase of the vault
+	EnvSecretPassphrase = "TIUP_SECRET_PASSPHRASE"
+	// EnvSecretKeyFile is the key file of the vault, it takes precedence over the passphrase
+	EnvSecretKeyFile = "TIUP_SECRET_KEY_FILE"
+	// EnvSecretProvider is the plugin command to look up the secrets not in the vault
+	EnvSecretProvider = "TIUP_SECRET_PROVIDER"
+)
+
+// SecretKey returns the key of the vault set by the environment variables
+// or the default key file, it's empty if none of them is set
+func SecretKey() secret.Key {
+	if kf := os.Getenv(EnvSecretKeyFile); kf != "" {
+		return secret.Key{KeyFile: kf}
+	}
+	if pass := os.Getenv(EnvSecretPassphrase); pass != "" {
+		return secret.Key{Passphrase: pass}
+	}
+	if kf := ProfilePath(SecretKeyFile); utils.IsExist(kf) {
+		return secret.Key{KeyFile: kf}
+	}
+	return secret.Key{}
+}
+
+// OpenSecretVault opens the vault in the profile dir, the passphrase is
+// asked if no key is set
+func OpenSecretVault() (*secret.Vault, error) {
+	key := SecretKey()
+	if key.Empty() {
+		key.Passphrase = tui.PromptForPassword("Input the passphrase of secret vault: ")
+		if key.Passphrase == "" {
+			return nil, errors.Errorf("no passphrase of the secret vault, set %s or %s, or create the key file with `secret init`",
+				EnvSecretPassphrase, EnvSecretKeyFile)
+		}
+	}
+	return secret.OpenVault(ProfilePath(SecretVaultFile), key)
+}
+
+// lazyVault opens the vault on the first use, so that the passphrase is
+// only asked if the topology does reference secrets
+type lazyVault struct {
+	mu    sync.Mutex
+	vault *secret.Vault
+}
+
+// localVault is shared by the resolver and the changes of secrets, so that
+// the secrets saved are visible to the resolver
+var localVault = &lazyVault{}
+
+// open returns the opened vault, the caller must hold the lock
+func (v *lazyVault) open() (*secret.Vault, error) {
+	if v.vault == nil {
+		vault, err := OpenSecretVault()
+		if err != nil {
+			return nil, err
+		}
+		v.vault = vault
+	}
+	return v.vault, nil
+}
+
+// Lookup implements the secret.Provider interface
+func (v *lazyVault) Lookup(name string) (string, bool, error) {
+	if !utils.IsExist(ProfilePath(SecretVaultFile)) {
+		return "", false, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	vault, err := v.open()
+	if err != nil {
+		return "", false, err
+	}
+	return vault.Lookup(name)
+}
+
+var (
+	secretResolverOnce sync.Once
+	secretResolver     *secret.Resolver
+)
+
+// getSecretResolver returns the resolver looking up the secrets from the
+// vault, and then from the plugin command if it's set
+func getSecretResolver() *secret.Resolver {
+	secretResolverOnce.Do(func() {
+		providers := []secret.Provider{localVault}
+		if cmd := os.Getenv(EnvSecretProvider); cmd != "" {
+			providers = append(providers, &secret.CommandProvider{Command: cmd})
+		}
+		secretResolver = secret.NewResolver(providers...)
+	})
+	return secretResolver
+}
+
+// ResolveSecrets replaces the secret references in the value with the
+// values of secrets, the value is returned as is if it has no reference
+func ResolveSecrets(value string) (string, error) {
+	return getSecretResolver().Resolve(value)
+}
+
+// ResolveURLSecrets replaces the secret references in the URL with the
+// query escaped values of secrets
+func ResolveURLSecrets(value string) (string, error) {
+	return getSecretResolver().ResolveEscaped(value, url.QueryEscape)
+}
+
+// ResolveConfigSecrets returns a copy of the config with secret references resolved
+func ResolveConfigSecrets(config map[string]interface{}) (map[string]interface{}, error) {
+	return getSecretResolver().ResolveConfig(config)
+}
+
+// ReadSecretFile reads the file, if the file only contains a secret reference,
+// e.g. the private keys kept in the vault, the value of the secret is returned
+func ReadSecretFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := fileSecretRef(data)
+	if name == "" {
+		return data, nil
+	}
+	value, err := ResolveSecrets(secret.RefPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+// SecretFileRef returns the name of secret referenced by the file, it's
+// empty if the file doesn't exist or isn't a reference
+func SecretFileRef(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return fileSecretRef(data)
+}
+
+func fileSecretRef(data []byte) string {
+	ref := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(ref, secret.RefPrefix) || !secret.ValidName(strings.TrimPrefix(ref, secret.RefPrefix)) {
+		return ""
+	}
+	return strings.TrimPrefix(ref, secret.RefPrefix)
+}
+
+// TopologySecrets returns the sorted names of secrets referenced in the topology
+func TopologySecrets(topo Topology) ([]string, error) {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	names := set.NewStringSet(secret.Refs(string(data))...).Slice()
+	sort.Strings(names)
+	return names, nil
+}
+
+// secretKeyRegexp matches the names of config items holding secrets
+var secretKeyRegexp = regexp.MustCompile(`(?i)(password|passwd|secret|access[-_]key)$`)
+
+// PlaintextSecrets returns the sorted paths of the items in topology which
+// look like secrets but are not references, e.g. grafana_servers[0].password,
+// the well known default password of Grafana is not counted
+func PlaintextSecrets(topo Topology) ([]string, error) {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var root interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	var paths []string
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch val := v.(type) {
+		case map[interface{}]interface{}:
+			for k, item := range val {
+				key := fmt.Sprint(k)
+				itemPath := key
+				if path != "" {
+					itemPath = path + "." + key
+				}
+				if s, ok := item.(string); ok && secretKeyRegexp.MatchString(key) {
+					if s != "" && s != "admin" && !secret.HasRef(s) {
+						paths = append(paths, itemPath)
+					}
+					continue
+				}
+				walk(itemPath, item)
+			}
+		case []interface{}:
+			for i, item := range val {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		}
+	}
+	walk("", root)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// CheckTopologySecrets resolves all the secrets referenced in the topology,
+// so that the missing secrets are reported before any change is made
+func CheckTopologySecrets(topo Topology) error {
+	names, err := TopologySecrets(topo)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := ResolveSecrets(secret.RefPrefix + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveSecret adds or updates the secret in the vault
+func SaveSecret(name, value string) error {
+	localVault.mu.Lock()
+	defer localVault.mu.Unlock()
+	vault, err := localVault.open()
+	if err != nil {
+		return err
+	}
+	if err := vault.Set(name, value); err != nil {
+		return err
+	}
+	return vault.Save()
+}
+
+// DeleteSecret removes the secret from the vault, it returns false if the
+// secret doesn't exist
+func DeleteSecret(name string) (bool, error) {
+	if !utils.IsExist(ProfilePath(SecretVaultFile)) {
+		return false, nil
+	}
+	localVault.mu.Lock()
+	defer localVault.mu.Unlock()
+	vault, err := localVault.open()
+	if err != nil {
+		return false, err
+	}
+	if !vault.Delete(name) {
+		return false, nil
+	}
+	return true, vault.Save()
+}
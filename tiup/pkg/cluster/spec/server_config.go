@@ -213,7 +213,10 @@ func Merge2Toml(comp string, global, overwrite map[string]interface{}) ([]byte,
 }
 
 func merge2Toml(comp string, global, overwrite map[string]interface{}) ([]byte, error) {
-	lhs := MergeConfig(global, overwrite)
+	lhs, err := ResolveConfigSecrets(MergeConfig(global, overwrite))
+	if err != nil {
+		return nil, err
+	}
 	buf := bytes.NewBufferString(fmt.Sprintf(`# WARNING: This file is auto-generated. Do not edit! All your modification will be overwritten!
 # You can use 'tiup cluster edit-config' and 'tiup cluster reload' to update the configuration
 # All configuration items you want to change can be added to:
@@ -225,7 +228,7 @@ func merge2Toml(comp string, global, overwrite map[string]interface{}) ([]byte,
 
 	enc := toml.NewEncoder(buf)
 	enc.Indent = ""
-	err := enc.Encode(lhs)
+	err = enc.Encode(lhs)
 	if err != nil {
 		return nil, perrs.Trace(err)
 	}
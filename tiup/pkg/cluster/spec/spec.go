@@ -106,6 +106,7 @@ type (
 		GlobalOptions    GlobalOptions        `yaml:"global,omitempty" validate:"global:editable"`
 		MonitoredOptions MonitoredOptions     `yaml:"monitored,omitempty" validate:"monitored:editable"`
 		ServerConfigs    ServerConfigs        `yaml:"server_configs,omitempty" validate:"server_configs:ignore"`
+		Hosts            HostInventory        `yaml:"hosts,omitempty" validate:"hosts:editable"`
 		TiDBServers      []*TiDBSpec          `yaml:"tidb_servers"`
 		TiKVServers      []*TiKVSpec          `yaml:"tikv_servers"`
 		TiFlashServers   []*TiFlashSpec       `yaml:"tiflash_servers"`
@@ -118,6 +119,7 @@ type (
 		Monitors         []*PrometheusSpec    `yaml:"monitoring_servers"`
 		Grafanas         []*GrafanaSpec       `yaml:"grafana_servers,omitempty"`
 		Alertmanagers    []*AlertmanagerSpec  `yaml:"alertmanager_servers,omitempty"`
+		Backup           BackupOptions        `yaml:"backup,omitempty" validate:"backup:editable"`
 	}
 )
 
@@ -160,7 +162,8 @@ type BaseMeta struct {
 	User    string
 	Group   string
 	Version string
-	OpsVer  *string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	OpsVer  *string           `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	Labels  map[string]string `yaml:"labels,omitempty"`
 }
 
 // Metadata of a cluster.
@@ -187,12 +190,18 @@ type UpgradableMetadata interface {
 	SetUser(u string)
 }
 
+// LabeledMetadata represents a Metadata with labels used to group clusters.
+type LabeledMetadata interface {
+	SetLabels(labels map[string]string)
+}
+
 // NewPart implements ScaleOutTopology interface.
 func (s *Specification) NewPart() Topology {
 	return &Specification{
 		GlobalOptions:    s.GlobalOptions,
 		MonitoredOptions: s.MonitoredOptions,
 		ServerConfigs:    s.ServerConfigs,
+		Hosts:            s.Hosts,
 	}
 }
 
@@ -259,9 +268,11 @@ func (s *Specification) LocationLabels() ([]string, error) {
 			}
 			lbs = append(lbs, lb)
 		}
+		return lbs, nil
 	}
 
-	return lbs, nil
+	// derived from the labels of hosts in the inventory if not set
+	return append(lbs, s.Hosts.LocationLabels()...), nil
 }
 
 // GetTiKVLabels implements TiKVLabelProvider
@@ -271,7 +282,7 @@ func (s *Specification) GetTiKVLabels() (map[string]map[string]string, []map[str
 	for _, kv := range kvs {
 		address := fmt.Sprintf("%s:%d", kv.Host, kv.GetMainPort())
 		var err error
-		if locationLabels[address], err = kv.Labels(); err != nil {
+		if locationLabels[address], err = s.tikvLabels(kv); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -302,11 +313,19 @@ func (s *Specification) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	// expand the host groups before filling the default values of instances
+	if err := s.applyHostInventory(); err != nil {
+		return err
+	}
+
 	// set default values from tag
 	if err := defaults.Set(s); err != nil {
 		return errors.Trace(err)
 	}
 
+	// the data dir of instances on the disks of hosts in the inventory
+	s.placeInventoryDisks()
+
 	// Set monitored options
 	if s.MonitoredOptions.DeployDir == "" {
 		s.MonitoredOptions.DeployDir = filepath.Join(s.GlobalOptions.DeployDir,
@@ -324,6 +343,9 @@ func (s *Specification) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		s.MonitoredOptions.LogDir = filepath.Join(s.MonitoredOptions.DeployDir, s.MonitoredOptions.LogDir)
 	}
 
+	// Set backup options
+	s.Backup.fillDefaults(&s.GlobalOptions)
+
 	// populate custom default values as needed
 	if err := fillCustomDefaults(&s.GlobalOptions, s); err != nil {
 		return err
@@ -434,6 +456,7 @@ func (s *Specification) Merge(that Topology) Topology {
 		GlobalOptions:    s.GlobalOptions,
 		MonitoredOptions: s.MonitoredOptions,
 		ServerConfigs:    s.ServerConfigs,
+		Hosts:            s.Hosts.merge(spec.Hosts),
 		TiDBServers:      append(s.TiDBServers, spec.TiDBServers...),
 		TiKVServers:      append(s.TiKVServers, spec.TiKVServers...),
 		PDServers:        append(s.PDServers, spec.PDServers...),
@@ -446,6 +469,7 @@ func (s *Specification) Merge(that Topology) Topology {
 		Monitors:         append(s.Monitors, spec.Monitors...),
 		Grafanas:         append(s.Grafanas, spec.Grafanas...),
 		Alertmanagers:    append(s.Alertmanagers, spec.Alertmanagers...),
+		Backup:           s.Backup,
 	}
 }
 
@@ -468,12 +492,15 @@ var (
 	globalOptionTypeName  = reflect.TypeOf(GlobalOptions{}).Name()
 	monitorOptionTypeName = reflect.TypeOf(MonitoredOptions{}).Name()
 	serverConfigsTypeName = reflect.TypeOf(ServerConfigs{}).Name()
+	backupOptionTypeName  = reflect.TypeOf(BackupOptions{}).Name()
+	hostInventoryTypeName = reflect.TypeOf(HostInventory{}).Name()
 )
 
-// Skip global/monitored options
+// Skip global/monitored/backup options and the hosts inventory
 func isSkipField(field reflect.Value) bool {
 	tp := field.Type().Name()
-	return tp == globalOptionTypeName || tp == monitorOptionTypeName || tp == serverConfigsTypeName
+	return tp == globalOptionTypeName || tp == monitorOptionTypeName || tp == serverConfigsTypeName ||
+		tp == backupOptionTypeName || tp == hostInventoryTypeName
 }
 
 func setDefaultDir(parent, role, port string, field reflect.Value) {
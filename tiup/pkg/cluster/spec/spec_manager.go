@@ -14,7 +14,9 @@
 package spec
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -152,6 +154,15 @@ func (s *SpecManager) Exist(clusterName string) (exist bool, err error) {
 	return true, nil
 }
 
+// MetaHash returns the SHA256 hash of the meta of the cluster.
+func (s *SpecManager) MetaHash(clusterName string) (string, error) {
+	data, err := os.ReadFile(s.Path(clusterName, metaFileName))
+	if err != nil {
+		return "", perrs.AddStack(err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
 // Remove remove the data with specified cluster name.
 func (s *SpecManager) Remove(clusterName string) error {
 	return os.RemoveAll(s.Path(clusterName))
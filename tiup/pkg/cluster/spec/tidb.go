@@ -29,6 +29,7 @@ import (
 // TiDBSpec represents the TiDB topology specification in topology.yaml
 type TiDBSpec struct {
 	Host            string                 `yaml:"host"`
+	HostGroup       string                 `yaml:"host_group,omitempty"`
 	ListenHost      string                 `yaml:"listen_host,omitempty"`
 	AdvertiseAddr   string                 `yaml:"advertise_address,omitempty"`
 	SSHPort         int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
@@ -140,7 +141,7 @@ func (i *TiDBInstance) InitConfig(
 	cfg := scripts.
 		NewTiDBScript(i.GetHost(), paths.Deploy, paths.Log).
 		WithPort(spec.Port).
-		WithNumaNode(spec.NumaNode).
+		WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).
 		WithStatusPort(spec.StatusPort).
 		AppendEndpoints(topo.Endpoints(deployUser)...).
 		WithListenHost(i.GetListenHost()).
@@ -42,6 +42,7 @@ import (
 // TiFlashSpec represents the TiFlash topology specification in topology.yaml
 type TiFlashSpec struct {
 	Host                 string                 `yaml:"host"`
+	HostGroup            string                 `yaml:"host_group,omitempty"`
 	SSHPort              int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported             bool                   `yaml:"imported,omitempty"`
 	Patched              bool                   `yaml:"patched,omitempty"`
@@ -602,7 +603,7 @@ func (i *TiFlashInstance) InitConfig(
 		WithFlashProxyStatusPort(spec.FlashProxyStatusPort).
 		WithStatusPort(spec.StatusPort).
 		WithTmpDir(spec.TmpDir).
-		WithNumaNode(spec.NumaNode).
+		WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).
 		AppendEndpoints(topo.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tiflash_%s_%d.sh", i.GetHost(), i.GetPort()))
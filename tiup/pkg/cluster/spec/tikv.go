@@ -46,6 +46,7 @@ const (
 // TiKVSpec represents the TiKV topology specification in topology.yaml
 type TiKVSpec struct {
 	Host                string                 `yaml:"host"`
+	HostGroup           string                 `yaml:"host_group,omitempty"`
 	ListenHost          string                 `yaml:"listen_host,omitempty"`
 	AdvertiseAddr       string                 `yaml:"advertise_addr,omitempty"`
 	SSHPort             int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
@@ -215,7 +216,7 @@ func (i *TiKVInstance) InitConfig(
 	spec := i.InstanceSpec.(*TiKVSpec)
 	cfg := scripts.
 		NewTiKVScript(clusterVersion, i.GetHost(), spec.Port, spec.StatusPort, paths.Deploy, paths.Data[0], paths.Log).
-		WithNumaNode(spec.NumaNode).
+		WithNumaNode(numaNode(i.topo, spec.Host, spec.NumaNode)).
 		AppendEndpoints(topo.Endpoints(deployUser)...).
 		WithListenHost(i.GetListenHost()).
 		WithAdvertiseAddr(spec.AdvertiseAddr).
@@ -278,7 +279,7 @@ func (i *TiKVInstance) InitConfig(
 			i.Role())
 	}
 
-	if err := i.MergeServerConfig(ctx, e, globalConfig, spec.Config, paths); err != nil {
+	if err := i.MergeServerConfig(ctx, e, globalConfig, topo.withHostLabels(spec), paths); err != nil {
 		return err
 	}
 
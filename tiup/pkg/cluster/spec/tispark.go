@@ -37,6 +37,7 @@ import (
 // TiSparkMasterSpec is the topology specification for TiSpark master node
 type TiSparkMasterSpec struct {
 	Host           string                 `yaml:"host"`
+	HostGroup      string                 `yaml:"host_group,omitempty"`
 	ListenHost     string                 `yaml:"listen_host,omitempty"`
 	SSHPort        int                    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported       bool                   `yaml:"imported,omitempty"`
@@ -80,6 +81,7 @@ func (s *TiSparkMasterSpec) IgnoreMonitorAgent() bool {
 // TiSparkWorkerSpec is the topology specification for TiSpark slave nodes
 type TiSparkWorkerSpec struct {
 	Host           string `yaml:"host"`
+	HostGroup      string `yaml:"host_group,omitempty"`
 	ListenHost     string `yaml:"listen_host,omitempty"`
 	SSHPort        int    `yaml:"ssh_port,omitempty" validate:"ssh_port:editable"`
 	Imported       bool   `yaml:"imported,omitempty"`
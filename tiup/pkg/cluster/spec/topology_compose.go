@@ -0,0 +1,496 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/tui"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// the key of the fragments included by a topology file
+const includeKey = "include"
+
+// the key of the hosts inventory, which is merged by host in composition
+const hostsKey = "hosts"
+
+var (
+	// ${VAR} or ${VAR:-default}, $${ is escaped to a literal ${
+	topoVarRegexp = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// topologyComposer loads the topology files, it resolves the included fragments
+// and the variables, and keeps the source file of every node to report errors.
+// The variables are only substituted when the topology is composed, that is a
+// variables file is given, or more than one file is given or included, so that
+// a single topology file with literal ${...} is loaded as it was.
+type topologyComposer struct {
+	vars       map[string]string
+	substitute bool
+	sources    map[*yaml3.Node]string
+	loading    []string // the files being loaded, used to detect include cycles
+}
+
+func newTopologyComposer(varsFile string) (*topologyComposer, error) {
+	c := &topologyComposer{
+		vars:       make(map[string]string),
+		substitute: varsFile != "",
+		sources:    make(map[*yaml3.Node]string),
+	}
+	if varsFile == "" {
+		return c, nil
+	}
+
+	data, err := ReadYamlFile(varsFile)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &vars); err != nil {
+		return nil, ErrTopologyParseFailed.Wrap(err, "Failed to parse variables file %s", varsFile)
+	}
+	for k, v := range vars {
+		switch v.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			return nil, ErrTopologyParseFailed.New("Variable %s in %s must be a scalar value", k, varsFile)
+		case nil:
+			c.vars[k] = ""
+		default:
+			c.vars[k] = fmt.Sprint(v)
+		}
+	}
+	return c, nil
+}
+
+// ComposeTopology reads the topology files and returns the composed topology, the
+// includes and variables in the files are resolved, and the files are merged in
+// order so that the latter files overlay the former ones
+func ComposeTopology(files []string, varsFile string) ([]byte, error) {
+	c, err := newTopologyComposer(varsFile)
+	if err != nil {
+		return nil, err
+	}
+	root, err := c.compose(files)
+	if err != nil {
+		return nil, err
+	}
+	return yaml3.Marshal(root)
+}
+
+// ParseTopologyFiles reads the topology files and unmarshal the composed topology
+// to `out`, see ComposeTopology for how the files are composed
+func ParseTopologyFiles(files []string, varsFile string, out Topology) error {
+	zap.L().Debug("Parse topology files", zap.Strings("files", files), zap.String("vars", varsFile))
+
+	c, err := newTopologyComposer(varsFile)
+	if err != nil {
+		return err
+	}
+	root, err := c.compose(files)
+	if err != nil {
+		return err
+	}
+
+	// unknown fields are checked before merging into the topology, so that the
+	// error points at the file and line where the field is written
+	if err := c.checkFields(root, reflect.TypeOf(out).Elem()); err != nil {
+		return err
+	}
+
+	data, err := yaml3.Marshal(root)
+	if err != nil {
+		return ErrTopologyParseFailed.Wrap(err, "Failed to compose topology files %s", strings.Join(files, ", "))
+	}
+	if err = yaml.UnmarshalStrict(data, out); err != nil {
+		return ErrTopologyParseFailed.
+			Wrap(err, "Failed to parse topology files %s", strings.Join(files, ", ")).
+			WithProperty(tui.SuggestionFromFormat("Please check the topology files and try again."))
+	}
+
+	zap.L().Debug("Parse topology files succeeded", zap.Any("topology", out))
+
+	return nil
+}
+
+// ParseTopologyDraft reads the topology files like ParseTopologyFiles, but the
+// topology is not validated and only the default values of the fields, such as
+// ports, are set, it's used to plan a topology before it can be deployed
+func ParseTopologyDraft(files []string, varsFile string) (*Specification, error) {
+	c, err := newTopologyComposer(varsFile)
+	if err != nil {
+		return nil, err
+	}
+	root, err := c.compose(files)
+	if err != nil {
+		return nil, err
+	}
+	topo := &Specification{}
+	if err := c.checkFields(root, reflect.TypeOf(topo).Elem()); err != nil {
+		return nil, err
+	}
+	data, err := yaml3.Marshal(root)
+	if err != nil {
+		return nil, ErrTopologyParseFailed.Wrap(err, "Failed to compose topology files %s", strings.Join(files, ", "))
+	}
+
+	// decode without the validation in Specification.UnmarshalYAML
+	type draft Specification
+	if err := yaml.UnmarshalStrict(data, (*draft)(topo)); err != nil {
+		return nil, ErrTopologyParseFailed.Wrap(err, "Failed to parse topology files %s", strings.Join(files, ", "))
+	}
+	if err := topo.applyHostInventory(); err != nil {
+		return nil, ErrTopologyParseFailed.Wrap(err, "Failed to parse topology files %s", strings.Join(files, ", "))
+	}
+	if err := defaults.Set(topo); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return topo, nil
+}
+
+// compose loads the files and overlays them in order
+func (c *topologyComposer) compose(files []string) (*yaml3.Node, error) {
+	if len(files) == 0 {
+		return nil, ErrTopologyReadFailed.New("No topology file is specified")
+	}
+	if len(files) > 1 {
+		c.substitute = true
+	}
+	var root *yaml3.Node
+	for _, file := range files {
+		node, err := c.load(file)
+		if err != nil {
+			return nil, err
+		}
+		root = mergeTopologyRoot(root, node)
+	}
+	return root, nil
+}
+
+// load reads a topology file and the fragments it includes, the fragments are
+// merged in order and then overlaid by the content of the file
+func (c *topologyComposer) load(file string) (*yaml3.Node, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, ErrTopologyReadFailed.Wrap(err, "Failed to read topology file %s", file)
+	}
+	for _, f := range c.loading {
+		if f == abs {
+			return nil, ErrTopologyParseFailed.New("Topology file %s is included recursively: %s -> %s",
+				file, strings.Join(c.loading, " -> "), abs)
+		}
+	}
+	c.loading = append(c.loading, abs)
+	defer func() { c.loading = c.loading[:len(c.loading)-1] }()
+
+	data, err := ReadYamlFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return nil, ErrTopologyParseFailed.
+			Wrap(err, "Failed to parse topology file %s", file).
+			WithProperty(tui.SuggestionFromFormat("Please check the syntax of the topology file %s and try again.", file))
+	}
+	if len(doc.Content) == 0 {
+		// an empty file
+		return &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map"}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml3.MappingNode {
+		return nil, ErrTopologyParseFailed.New("%s:%d: the topology must be a mapping", file, root.Line)
+	}
+	includes := c.popIncludes(root)
+	if len(includes) > 0 {
+		c.substitute = true
+	}
+	if err := c.resolve(root, file); err != nil {
+		return nil, err
+	}
+
+	var merged *yaml3.Node
+	for _, inc := range includes {
+		if err := c.resolve(inc, file); err != nil {
+			return nil, err
+		}
+		path := inc.Value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		node, err := c.load(path)
+		if err != nil {
+			return nil, ErrTopologyParseFailed.Wrap(err, "%s:%d: failed to include %s", file, inc.Line, inc.Value)
+		}
+		merged = mergeTopologyRoot(merged, node)
+	}
+	return mergeTopologyRoot(merged, root), nil
+}
+
+// resolve records the source file of the nodes and substitutes the variables
+// in scalar values if the topology is composed
+func (c *topologyComposer) resolve(node *yaml3.Node, file string) error {
+	c.sources[node] = file
+	if c.substitute && node.Kind == yaml3.ScalarNode && strings.Contains(node.Value, "${") {
+		value, err := c.expand(node.Value)
+		if err != nil {
+			return ErrTopologyParseFailed.New("%s:%d: %s", file, node.Line, err)
+		}
+		node.Value = value
+		if node.Style == 0 {
+			// let the type of an unquoted value be resolved from the substituted one
+			node.Tag = ""
+		}
+	}
+	for _, n := range node.Content {
+		if err := c.resolve(n, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expand substitutes ${VAR} and ${VAR:-default} in the value, the variables in
+// the environment take precedence over the ones in the variables file
+func (c *topologyComposer) expand(value string) (string, error) {
+	var err error
+	result := topoVarRegexp.ReplaceAllStringFunc(value, func(m string) string {
+		if m == "$${" {
+			return "${"
+		}
+		sub := topoVarRegexp.FindStringSubmatch(m)
+		name := sub[1]
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+		if v, ok := c.vars[name]; ok {
+			return v
+		}
+		if sub[2] != "" {
+			return sub[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("variable %s is not set", name)
+		}
+		return m
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(result, "\n") {
+		return "", fmt.Errorf("the value of variables must not contain newlines")
+	}
+	return result, nil
+}
+
+// popIncludes removes the include key from the mapping and returns the files
+func (c *topologyComposer) popIncludes(root *yaml3.Node) []*yaml3.Node {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != includeKey {
+			continue
+		}
+		value := root.Content[i+1]
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		if value.Kind == yaml3.SequenceNode {
+			return value.Content
+		}
+		return []*yaml3.Node{value}
+	}
+	return nil
+}
+
+// mergeTopologyRoot overlays the base topology with the overlay one like
+// mergeTopologyNode, except that the hosts inventories are merged by host,
+// the hosts in both of them are replaced by the ones in the overlay
+func mergeTopologyRoot(base, overlay *yaml3.Node) *yaml3.Node {
+	merged := mergeTopologyNode(base, overlay)
+	baseHosts, overlayHosts := mappingValue(base, hostsKey), mappingValue(overlay, hostsKey)
+	if baseHosts == nil || overlayHosts == nil ||
+		baseHosts.Kind != yaml3.SequenceNode || overlayHosts.Kind != yaml3.SequenceNode {
+		return merged
+	}
+
+	hosts := *overlayHosts
+	hosts.Content = nil
+	for _, h := range baseHosts.Content {
+		if findHostNode(overlayHosts, mappingValue(h, "host")) == nil {
+			hosts.Content = append(hosts.Content, h)
+		}
+	}
+	hosts.Content = append(hosts.Content, overlayHosts.Content...)
+	for i := 0; i+1 < len(merged.Content); i += 2 {
+		if merged.Content[i].Value == hostsKey {
+			merged.Content[i+1] = &hosts
+		}
+	}
+	return merged
+}
+
+// mappingValue returns the value of the key in the mapping node, nil if the
+// node is not a mapping or the key is not found
+func mappingValue(node *yaml3.Node, key string) *yaml3.Node {
+	if node == nil || node.Kind != yaml3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// findHostNode returns the item of the host in the hosts inventory node
+func findHostNode(hosts, host *yaml3.Node) *yaml3.Node {
+	if host == nil {
+		return nil
+	}
+	for _, h := range hosts.Content {
+		if v := mappingValue(h, "host"); v != nil && v.Value == host.Value {
+			return h
+		}
+	}
+	return nil
+}
+
+// mergeTopologyNode overlays the base node with the overlay one, mappings are
+// merged key by key recursively, and other values are replaced, a null value
+// removes the key from the base
+func mergeTopologyNode(base, overlay *yaml3.Node) *yaml3.Node {
+	if base == nil || base.Kind != yaml3.MappingNode || overlay.Kind != yaml3.MappingNode {
+		return overlay
+	}
+
+	merged := *base
+	merged.Content = append([]*yaml3.Node{}, base.Content...)
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		idx := -1
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value == key.Value {
+				idx = j
+				break
+			}
+		}
+		switch {
+		case idx < 0:
+			merged.Content = append(merged.Content, key, value)
+		case value.Kind == yaml3.ScalarNode && value.Tag == "!!null":
+			merged.Content = append(merged.Content[:idx], merged.Content[idx+2:]...)
+		default:
+			merged.Content[idx+1] = mergeTopologyNode(merged.Content[idx+1], value)
+		}
+	}
+	return &merged
+}
+
+// checkFields checks the fields in the node are known by the type
+func (c *topologyComposer) checkFields(node *yaml3.Node, t reflect.Type) error {
+	if node.Kind == yaml3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml3.MappingNode:
+		fields, anyKey := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				if anyKey || key.Value == "<<" {
+					continue
+				}
+				return ErrTopologyParseFailed.New("%s:%d: field %s not found in type %s",
+					c.sources[key], key.Line, key.Value, t.String())
+			}
+			if reflect.PtrTo(ft).Implements(yamlUnmarshalerType) {
+				continue
+			}
+			if err := c.checkFields(value, ft); err != nil {
+				return err
+			}
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && node.Kind == yaml3.SequenceNode:
+		for _, n := range node.Content {
+			if err := c.checkFields(n, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml3.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := c.checkFields(node.Content[i], t.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// yamlFields returns the types of the fields of the struct by their yaml keys,
+// and whether any key is accepted by an inline map
+func yamlFields(t reflect.Type) (map[string]reflect.Type, bool) {
+	fields := make(map[string]reflect.Type)
+	anyKey := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		inline := false
+		for _, opt := range opts[1:] {
+			if opt == "inline" {
+				inline = true
+			}
+		}
+		if inline {
+			switch f.Type.Kind() {
+			case reflect.Map:
+				anyKey = true
+			case reflect.Struct:
+				sub, subAnyKey := yamlFields(f.Type)
+				for k, v := range sub {
+					fields[k] = v
+				}
+				anyKey = anyKey || subAnyKey
+			}
+			continue
+		}
+		name := opts[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields, anyKey
+}
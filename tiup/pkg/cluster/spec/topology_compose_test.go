@@ -0,0 +1,226 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pingcap/tiup/embed"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTopologyFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestComposeTopologyFiles(t *testing.T) {
+	assert := require.New(t)
+	dir := writeTopologyFiles(t, map[string]string{
+		"monitoring.yaml": `
+monitoring_servers:
+  - host: ${MONITOR_HOST}
+`,
+		"base.yaml": `
+include: monitoring.yaml
+global:
+  user: tidb
+  deploy_dir: ${DEPLOY_DIR:-/tidb-deploy}
+server_configs:
+  tidb:
+    log.slow-threshold: 300
+    token-limit: 1000
+pd_servers:
+  - host: 172.16.5.1
+    client_port: ${PD_PORT}
+tidb_servers:
+  - host: 172.16.5.1
+    config:
+      log.level: "$${literal}"
+`,
+		"prod.yaml": `
+global:
+  user: prod
+server_configs:
+  tidb:
+    token-limit: ~
+    log.slow-threshold: 500
+tidb_servers:
+  - host: 172.16.5.2
+  - host: 172.16.5.3
+`,
+		"vars.yaml": `
+MONITOR_HOST: 172.16.5.9
+PD_PORT: 2479
+`,
+	})
+
+	os.Setenv("PD_PORT", "12379")
+	defer os.Unsetenv("PD_PORT")
+
+	topo := Specification{}
+	err := ParseTopologyFiles(
+		[]string{filepath.Join(dir, "base.yaml"), filepath.Join(dir, "prod.yaml")},
+		filepath.Join(dir, "vars.yaml"),
+		&topo,
+	)
+	assert.Nil(err)
+	assert.Equal("prod", topo.GlobalOptions.User)
+	assert.Equal("/tidb-deploy", topo.GlobalOptions.DeployDir)
+	assert.Equal(500, topo.ServerConfigs.TiDB["log.slow-threshold"])
+	assert.NotContains(topo.ServerConfigs.TiDB, "token-limit")
+	// the environment takes precedence over the variables file
+	assert.Equal(12379, topo.PDServers[0].ClientPort)
+	assert.Len(topo.TiDBServers, 2)
+	assert.Equal("172.16.5.2", topo.TiDBServers[0].Host)
+	assert.Len(topo.Monitors, 1)
+	assert.Equal("172.16.5.9", topo.Monitors[0].Host)
+}
+
+func TestComposeTopologyErrors(t *testing.T) {
+	assert := require.New(t)
+	dir := writeTopologyFiles(t, map[string]string{
+		"base.yaml": `
+pd_servers:
+  - host: 172.16.5.1
+`,
+		"unknown.yaml": `
+tidb_servers:
+  - host: 172.16.5.1
+    unknown_port: 4000
+`,
+		"unset.yaml": `
+tidb_servers:
+  - host: ${UNSET_TOPOLOGY_HOST}
+`,
+		"a.yaml": "include: b.yaml\n",
+		"b.yaml": "include: [a.yaml]\n",
+	})
+	base := filepath.Join(dir, "base.yaml")
+
+	topo := Specification{}
+	err := ParseTopologyFiles([]string{base, filepath.Join(dir, "unknown.yaml")}, "", &topo)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), filepath.Join(dir, "unknown.yaml")+":4: field unknown_port not found")
+
+	err = ParseTopologyFiles([]string{base, filepath.Join(dir, "unset.yaml")}, "", &topo)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), filepath.Join(dir, "unset.yaml")+":3: variable UNSET_TOPOLOGY_HOST is not set")
+
+	err = ParseTopologyFiles([]string{filepath.Join(dir, "a.yaml")}, "", &topo)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "included recursively")
+}
+
+func TestComposeTopologySingleFile(t *testing.T) {
+	assert := require.New(t)
+	dir := writeTopologyFiles(t, map[string]string{
+		"topology.yaml": `
+tidb_servers:
+  - host: 172.16.5.1
+    config:
+      log.file.filename: "${LOG_DIR}/tidb.log"
+`,
+		"vars.yaml": "LOG_DIR: /var/log\n",
+	})
+	file := filepath.Join(dir, "topology.yaml")
+
+	// a single file is loaded as it is
+	topo := Specification{}
+	assert.Nil(ParseTopologyFiles([]string{file}, "", &topo))
+	assert.Equal("${LOG_DIR}/tidb.log", topo.TiDBServers[0].Config["log.file.filename"])
+
+	// the variables are substituted with a variables file
+	topo = Specification{}
+	assert.Nil(ParseTopologyFiles([]string{file}, filepath.Join(dir, "vars.yaml"), &topo))
+	assert.Equal("/var/log/tidb.log", topo.TiDBServers[0].Config["log.file.filename"])
+}
+
+func TestComposeTopologyTemplates(t *testing.T) {
+	assert := require.New(t)
+	base, err := embed.ReadExample("examples/cluster/base.yaml")
+	assert.Nil(err)
+	overlay, err := embed.ReadExample("examples/cluster/overlay.yaml")
+	assert.Nil(err)
+	dir := writeTopologyFiles(t, map[string]string{
+		"base.yaml":    string(base),
+		"overlay.yaml": string(overlay),
+		"vars.yaml": `
+PD_HOST_1: 172.16.5.1
+PD_HOST_2: 172.16.5.2
+PD_HOST_3: 172.16.5.3
+TIDB_HOST_1: 172.16.5.4
+TIDB_HOST_2: 172.16.5.5
+TIKV_HOST_1: 172.16.5.6
+TIKV_HOST_2: 172.16.5.7
+TIKV_HOST_3: 172.16.5.8
+MONITOR_HOST: 172.16.5.9
+`,
+	})
+
+	topo := Specification{}
+	err = ParseTopologyFiles(
+		[]string{filepath.Join(dir, "base.yaml"), filepath.Join(dir, "overlay.yaml")},
+		filepath.Join(dir, "vars.yaml"),
+		&topo,
+	)
+	assert.Nil(err)
+	assert.Equal("/data/tidb-data", topo.GlobalOptions.DataDir)
+	assert.Len(topo.TiDBServers, 2)
+	assert.Equal("16GB", topo.ServerConfigs.TiKV["storage.block-cache.capacity"])
+	assert.Equal(true, topo.ServerConfigs.TiKV["readpool.storage.use-unified-pool"])
+}
+
+func TestComposeHostInventory(t *testing.T) {
+	assert := require.New(t)
+	dir := writeTopologyFiles(t, map[string]string{
+		"topology.yaml": `
+hosts:
+  - host: 172.16.5.1
+    groups: [pd]
+  - host: 172.16.5.2
+    groups: [kv]
+    labels: {zone: z1}
+pd_servers:
+  - host_group: pd
+tikv_servers:
+  - host_group: kv
+`,
+		"hosts.yaml": `
+hosts:
+  - host: 172.16.5.2
+    groups: [kv]
+    labels: {zone: z2}
+  - host: 172.16.5.3
+    groups: [kv]
+    labels: {zone: z3}
+`,
+	})
+
+	// the inventory of the hosts file is merged by host into the one in topology
+	topo, err := ParseTopologyDraft([]string{filepath.Join(dir, "topology.yaml"), filepath.Join(dir, "hosts.yaml")}, "")
+	assert.Nil(err)
+	assert.Len(topo.Hosts, 3)
+	assert.Equal("172.16.5.1", topo.Hosts[0].Host)
+	assert.Equal("z2", topo.Hosts.Get("172.16.5.2").Labels["zone"])
+	assert.Len(topo.PDServers, 1)
+	assert.Len(topo.TiKVServers, 2)
+	assert.Equal("172.16.5.2", topo.TiKVServers[0].Host)
+	assert.Equal("172.16.5.3", topo.TiKVServers[1].Host)
+}
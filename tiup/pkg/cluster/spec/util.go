@@ -17,7 +17,9 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -44,12 +46,18 @@ type ClusterMeta struct {
 	User    string `yaml:"user"`         // the user to run and manage cluster on remote
 	Version string `yaml:"tidb_version"` // the version of TiDB cluster
 	// EnableFirewall bool   `yaml:"firewall"`
-	OpsVer string `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	OpsVer string            `yaml:"last_ops_ver,omitempty"` // the version of ourself that updated the meta last time
+	Labels map[string]string `yaml:"labels,omitempty"`       // labels to group clusters
 
 	Topology *Specification `yaml:"topology"`
+	// the backup agent installed by the last deploy, reload or scale-out
+	BackupAgent *BackupAgentMeta `yaml:"backup_agent,omitempty"`
 }
 
-var _ UpgradableMetadata = &ClusterMeta{}
+var (
+	_ UpgradableMetadata = &ClusterMeta{}
+	_ LabeledMetadata    = &ClusterMeta{}
+)
 
 // SetVersion implement UpgradableMetadata interface.
 func (m *ClusterMeta) SetVersion(s string) {
@@ -61,6 +69,11 @@ func (m *ClusterMeta) SetUser(s string) {
 	m.User = s
 }
 
+// SetLabels implement LabeledMetadata interface.
+func (m *ClusterMeta) SetLabels(labels map[string]string) {
+	m.Labels = labels
+}
+
 // GetTopology implement Metadata interface.
 func (m *ClusterMeta) GetTopology() Topology {
 	return m.Topology
@@ -82,6 +95,7 @@ func (m *ClusterMeta) GetBaseMeta() *BaseMeta {
 		Version: m.Version,
 		User:    m.User,
 		OpsVer:  &m.OpsVer,
+		Labels:  m.Labels,
 	}
 }
 
@@ -114,13 +128,43 @@ func ClusterMetadata(clusterName string) (*ClusterMeta, error) {
 	return &cm, nil
 }
 
-// LoadClientCert read and load the client cert key pair and CA cert
+// LoadClientCert read and load the client cert key pair and CA cert, the
+// private key is looked up from the secret vault if it's kept there
 func LoadClientCert(dir string) (*tls.Config, error) {
-	return transport.TLSInfo{
-		TrustedCAFile: filepath.Join(dir, TLSCACert),
-		CertFile:      filepath.Join(dir, TLSClientCert),
-		KeyFile:       filepath.Join(dir, TLSClientKey),
-	}.ClientConfig()
+	keyPath := filepath.Join(dir, TLSClientKey)
+	if SecretFileRef(keyPath) == "" {
+		return transport.TLSInfo{
+			TrustedCAFile: filepath.Join(dir, TLSCACert),
+			CertFile:      filepath.Join(dir, TLSClientCert),
+			KeyFile:       keyPath,
+		}.ClientConfig()
+	}
+
+	key, err := ReadSecretFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := os.ReadFile(filepath.Join(dir, TLSClientCert))
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(filepath.Join(dir, TLSCACert))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no valid CA certificate in %s", filepath.Join(dir, TLSCACert))
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{pair},
+		RootCAs:      pool,
+	}, nil
 }
 
 // statusByHost queries current status of the instance by http status api.
@@ -1055,6 +1055,7 @@ func (s *Specification) Validate() error {
 		s.validateTiSparkSpec,
 		s.validateTiFlashConfigs,
 		s.validateMonitorAgent,
+		s.validateBackup,
 	}
 
 	for _, v := range validators {
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/joomcode/errorx"
 	. "github.com/pingcap/check"
@@ -1224,3 +1225,73 @@ tikv_servers:
 		c.Assert(err.Error(), Equals, "spec.deploy.dir_overlap: Deploy directory overlaps to another instance")
 	}
 }
+
+func (s *metaSuiteTopo) TestBackupValidate(c *C) {
+	topo := Specification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  deploy_dir: "/tidb-deploy"
+tidb_servers:
+  - host: 172.16.5.138
+pd_servers:
+  - host: 172.16.5.138
+tikv_servers:
+  - host: 172.16.5.138
+  - host: 172.16.5.139
+  - host: 172.16.5.139
+    port: 20161
+    status_port: 20181
+  - host: 172.16.5.140
+backup:
+  host: 172.16.5.138
+  storage: /data/backup
+  retention: 7
+  filters:
+    - "db1.*"
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.Backup.DeployDir, Equals, "/tidb-deploy/backup-agent")
+	c.Assert(topo.Backup.LogDir, Equals, "/tidb-deploy/backup-agent/log")
+	c.Assert(topo.Backup.Schedule, Equals, "daily")
+	c.Assert(topo.Backup.LocalPath(), Equals, "/data/backup")
+	c.Assert(topo.Backup.StorageURL("20210101"), Equals, "local:///data/backup/20210101")
+	// the backups in local storage are pruned on the hosts of TiKV
+	c.Assert(topo.BackupHosts(), DeepEquals, []string{"172.16.5.138", "172.16.5.139", "172.16.5.140"})
+	agent := topo.BackupAgent()
+	topo.Backup.Storage = "s3://bucket/prefix"
+	c.Assert(topo.BackupHosts(), DeepEquals, []string{"172.16.5.138"})
+
+	// the agent is removed from the hosts it's no longer installed on
+	c.Assert(agent.StaleHosts(topo.BackupAgent()), DeepEquals, []string{"172.16.5.139", "172.16.5.140"})
+	c.Assert(agent.StaleHosts(agent), HasLen, 0)
+	c.Assert(agent.StaleHosts(nil), DeepEquals, agent.Hosts)
+	topo.Backup.DeployDir = "/tidb-deploy/backup"
+	c.Assert(agent.StaleHosts(topo.BackupAgent()), DeepEquals, agent.Hosts)
+	c.Assert((*BackupAgentMeta)(nil).StaleHosts(topo.BackupAgent()), HasLen, 0)
+
+	cases := []struct {
+		backup string
+		errMsg string
+	}{
+		{"host: 172.16.5.139\n  storage: /data/backup", "`backup.host` 172.16.5.139 is not a host of the cluster"},
+		{"host: 172.16.5.138", "`backup.storage` is required"},
+		{"storage: s3://bucket/prefix", "`backup.host` is required"},
+		{"host: 172.16.5.138\n  storage: data/backup", "must be an absolute path"},
+		{"host: 172.16.5.138\n  storage: ftp://bucket/prefix", "unsupported `backup.storage` scheme"},
+		{"host: 172.16.5.138\n  storage: s3://bucket/prefix\n  retention: 3", "`backup.retention` is only supported for local storage"},
+	}
+	for _, cas := range cases {
+		topo := Specification{}
+		err := yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.138
+backup:
+  `+cas.backup), &topo)
+		c.Assert(err, NotNil)
+		c.Assert(err.Error(), Matches, ".*"+regexp.QuoteMeta(cas.errMsg)+".*")
+	}
+
+	b := BackupOptions{Storage: "s3://bucket/prefix/?endpoint=http://minio:9000"}
+	c.Assert(b.LocalPath(), Equals, "")
+	c.Assert(b.StorageURL("x"), Equals, "s3://bucket/prefix/x?endpoint=http://minio:9000")
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiup/pkg/cluster/ctxt"
+	"github.com/pingcap/tiup/pkg/cluster/spec"
+	"github.com/pingcap/tiup/pkg/cluster/template/scripts"
+	system "github.com/pingcap/tiup/pkg/cluster/template/systemd"
+	"github.com/pingcap/tiup/pkg/meta"
+)
+
+// BackupAgentConfig is used to generate the script and the systemd timer of
+// the backup agent, and enable the timer to take backups on schedule. On the
+// hosts other than the one in the backup section, the agent only prunes the
+// backups in local storage.
+type BackupAgentConfig struct {
+	name       string
+	host       string
+	options    *spec.BackupOptions
+	pdList     []string
+	deployUser string
+	tlsEnabled bool
+	paths      meta.DirPaths
+}
+
+// Execute implements the Task interface
+func (b *BackupAgentConfig) Execute(ctx context.Context) error {
+	host := b.host
+	exec, found := ctxt.GetInner(ctx).GetExecutor(host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	if err := os.MkdirAll(b.paths.Cache, 0755); err != nil {
+		return err
+	}
+
+	// transfer the backup script, the access keys in storage may be secrets
+	storage, err := spec.ResolveURLSecrets(b.options.StorageURL("${NAME}"))
+	if err != nil {
+		return err
+	}
+	script := scripts.NewBackupScript(
+		b.paths.Deploy,
+		b.paths.Log,
+		b.pdList,
+		storage,
+		b.tlsEnabled,
+	).
+		WithRetention(b.options.LocalPath(), b.options.Retention).
+		WithFilters(b.options.Filters).
+		WithPruneOnly(host != b.options.Host)
+	fp := filepath.Join(b.paths.Cache, fmt.Sprintf("run_backup_%s.sh", host))
+	if err := script.ConfigToFile(fp); err != nil {
+		return err
+	}
+	dst := filepath.Join(b.paths.Deploy, "scripts", "run_backup.sh")
+	if err := exec.Transfer(ctx, fp, dst, false, 0, false); err != nil {
+		return err
+	}
+	if _, _, err := exec.Execute(ctx, "chmod +x "+dst, false); err != nil {
+		return err
+	}
+
+	// transfer the systemd service and timer
+	service := b.options.ServiceName(b.name)
+	sysCfg := system.NewBackupConfig(service, b.deployUser, b.paths.Deploy, b.options.Schedule)
+	units := map[string]func(string) error{
+		service + ".service": sysCfg.ServiceToFile,
+		service + ".timer":   sysCfg.TimerToFile,
+	}
+	for unit, toFile := range units {
+		fp := filepath.Join(b.paths.Cache, fmt.Sprintf("%s-%s", host, unit))
+		if err := toFile(fp); err != nil {
+			return err
+		}
+		tgt := filepath.Join("/tmp", uuid.New().String()+"_"+unit)
+		if err := exec.Transfer(ctx, fp, tgt, false, 0, false); err != nil {
+			return err
+		}
+		if _, stderr, err := exec.Execute(ctx, fmt.Sprintf("mv %s /etc/systemd/system/%s", tgt, unit), true); err != nil {
+			return errors.Annotatef(err, "stderr: %s", string(stderr))
+		}
+	}
+
+	cmd := fmt.Sprintf("systemctl daemon-reload && systemctl enable %[1]s.timer && systemctl restart %[1]s.timer", service)
+	if _, stderr, err := exec.Execute(ctx, cmd, true); err != nil {
+		return errors.Annotatef(err, "failed to enable the backup timer, stderr: %s", string(stderr))
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (b *BackupAgentConfig) Rollback(ctx context.Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (b *BackupAgentConfig) String() string {
+	// the storage is not shown since it may contain access keys
+	return fmt.Sprintf("BackupAgentConfig: cluster=%s, host=%s, schedule=%s, %v",
+		b.name, b.host, b.options.Schedule, b.paths)
+}
@@ -261,6 +261,20 @@ func (b *Builder) MonitoredConfig(name, comp, host string, globResCtl meta.Resou
 	return b
 }
 
+// BackupAgentConfig appends a BackupAgentConfig task to the current task collection
+func (b *Builder) BackupAgentConfig(name, host string, options *spec.BackupOptions, pdList []string, deployUser string, tlsEnabled bool, paths meta.DirPaths) *Builder {
+	b.tasks = append(b.tasks, &BackupAgentConfig{
+		name:       name,
+		host:       host,
+		options:    options,
+		pdList:     pdList,
+		deployUser: deployUser,
+		tlsEnabled: tlsEnabled,
+		paths:      paths,
+	})
+	return b
+}
+
 // SSHKeyGen appends a SSHKeyGen task to the current task collection
 func (b *Builder) SSHKeyGen(keypath string) *Builder {
 	b.tasks = append(b.tasks, &SSHKeyGen{
@@ -36,6 +36,7 @@ var (
 	CheckTypePartitions   = "partitions"
 	CheckTypeFIO          = "fio"
 	CheckTypePermission   = "permission"
+	CheckTypePlugin       = "plugin"
 )
 
 // place the check utilities are stored
@@ -147,6 +148,15 @@ func (c *CheckSys) Execute(ctx context.Context) error {
 			return ErrNoExecutor
 		}
 		storeResults(ctx, c.host, operator.CheckDirPermission(ctx, e, c.topo.GlobalOptions.User, c.checkDir))
+	case CheckTypePlugin:
+		if len(c.opt.Plugins) == 0 {
+			break
+		}
+		e, ok := ctxt.GetInner(ctx).GetExecutor(c.host)
+		if !ok {
+			return ErrNoExecutor
+		}
+		storeResults(ctx, c.host, operator.CheckPlugins(ctx, e, filepath.Join(CheckToolsPathDir, "bin"), c.opt.Plugins))
 	}
 
 	return nil
@@ -73,6 +73,39 @@ func isDisplayTask(t Task) bool {
 	return false
 }
 
+// isRecordable checks if the result of the task is recorded in the audit log
+// so it can be skipped on replay. Tasks setting up the context used by the
+// following tasks are not recorded and always executed.
+func isRecordable(t Task) bool {
+	if isDisplayTask(t) {
+		return false
+	}
+	switch t.(type) {
+	case *RootSSH, *UserSSH, *SSHKeySet, *SSHKeyGen,
+		*Shell, *CheckSys, *Limit, *Sysctl, *SystemCtl, *Func:
+		return false
+	}
+	return true
+}
+
+// executeTask executes the task, tasks finished in the replayed audit log are skipped
+func executeTask(ctx context.Context, t Task) error {
+	if !isRecordable(t) {
+		return t.Execute(ctx)
+	}
+
+	id := t.String()
+	if checkpoint.AcquireTask(id) {
+		ctx.Value(logprinter.ContextKeyLogger).(*logprinter.Logger).
+			Debugf("Skip task finished in the replayed audit: %s", id)
+		checkpoint.ReleaseTask(id, true, nil)
+		return nil
+	}
+	err := t.Execute(ctx)
+	checkpoint.ReleaseTask(id, false, err)
+	return err
+}
+
 // Execute implements the Task interface
 func (s *Serial) Execute(ctx context.Context) error {
 	for _, t := range s.inner {
@@ -83,7 +116,7 @@ func (s *Serial) Execute(ctx context.Context) error {
 			}
 		}
 		ctxt.GetInner(ctx).Ev.PublishTaskBegin(t)
-		err := t.Execute(ctx)
+		err := executeTask(ctx, t)
 		ctxt.GetInner(ctx).Ev.PublishTaskFinish(t, err)
 		if err != nil && !s.ignoreError {
 			return err
@@ -141,7 +174,7 @@ func (pt *Parallel) Execute(ctx context.Context) error {
 				}
 			}
 			ctxt.GetInner(ctx).Ev.PublishTaskBegin(t)
-			err := t.Execute(ctx)
+			err := executeTask(ctx, t)
 			ctxt.GetInner(ctx).Ev.PublishTaskFinish(t, err)
 			if err != nil {
 				mu.Lock()
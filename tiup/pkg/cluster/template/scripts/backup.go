@@ -0,0 +1,102 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scripts
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/pingcap/tiup/embed"
+)
+
+// BackupScript represent the data to generate the script of the backup agent
+type BackupScript struct {
+	DeployDir  string
+	LogDir     string
+	PD         string
+	Storage    string
+	LocalPath  string
+	Retention  int
+	Filters    []string
+	TLSEnabled bool
+	PruneOnly  bool
+}
+
+// NewBackupScript returns a BackupScript with given arguments
+func NewBackupScript(deployDir, logDir string, pdList []string, storage string, tlsEnabled bool) *BackupScript {
+	return &BackupScript{
+		DeployDir:  deployDir,
+		LogDir:     logDir,
+		PD:         strings.Join(pdList, ","),
+		Storage:    storage,
+		TLSEnabled: tlsEnabled,
+	}
+}
+
+// WithRetention set the number of backups kept in the local path
+func (c *BackupScript) WithRetention(localPath string, retention int) *BackupScript {
+	c.LocalPath = localPath
+	c.Retention = retention
+	return c
+}
+
+// WithPruneOnly set PruneOnly field of BackupScript, the script only prunes
+// the backups in local storage on the hosts of TiKV without taking backups
+func (c *BackupScript) WithPruneOnly(pruneOnly bool) *BackupScript {
+	c.PruneOnly = pruneOnly
+	return c
+}
+
+// WithFilters set Filters field of BackupScript
+func (c *BackupScript) WithFilters(filters []string) *BackupScript {
+	c.Filters = filters
+	return c
+}
+
+// Config generate the config file data.
+func (c *BackupScript) Config() ([]byte, error) {
+	fp := path.Join("templates", "scripts", "run_backup.sh.tpl")
+	tpl, err := embed.ReadTemplate(fp)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConfigWithTemplate(string(tpl))
+}
+
+// ConfigToFile write config content to specific path
+func (c *BackupScript) ConfigToFile(file string) error {
+	config, err := c.Config()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, config, 0755)
+}
+
+// ConfigWithTemplate generate the backup script content by tpl
+func (c *BackupScript) ConfigWithTemplate(tpl string) ([]byte, error) {
+	tmpl, err := template.New("Backup").Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	content := bytes.NewBufferString("")
+	if err := tmpl.Execute(content, c); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
+}
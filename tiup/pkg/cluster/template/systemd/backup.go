@@ -0,0 +1,79 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/pingcap/tiup/embed"
+)
+
+// BackupConfig represent the data to generate the systemd service and timer
+// of the backup agent
+type BackupConfig struct {
+	ServiceName string
+	User        string
+	DeployDir   string
+	Schedule    string
+}
+
+// NewBackupConfig returns a BackupConfig with given arguments
+func NewBackupConfig(service, user, deployDir, schedule string) *BackupConfig {
+	return &BackupConfig{
+		ServiceName: service,
+		User:        user,
+		DeployDir:   deployDir,
+		Schedule:    schedule,
+	}
+}
+
+// ServiceToFile write the service content to specific path
+func (c *BackupConfig) ServiceToFile(file string) error {
+	return c.toFile(path.Join("templates", "systemd", "backup.service.tpl"), file)
+}
+
+// TimerToFile write the timer content to specific path
+func (c *BackupConfig) TimerToFile(file string) error {
+	return c.toFile(path.Join("templates", "systemd", "backup.timer.tpl"), file)
+}
+
+func (c *BackupConfig) toFile(fp, file string) error {
+	tpl, err := embed.ReadTemplate(fp)
+	if err != nil {
+		return err
+	}
+	config, err := c.ConfigWithTemplate(string(tpl))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, config, 0755)
+}
+
+// ConfigWithTemplate generate the system config content by tpl
+func (c *BackupConfig) ConfigWithTemplate(tpl string) ([]byte, error) {
+	tmpl, err := template.New("system").Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	content := bytes.NewBufferString("")
+	if err := tmpl.Execute(content, c); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
+}
@@ -141,6 +141,17 @@ func ReadCA(clsName, certPath, keyPath string) (*CertificateAuthority, error) {
 	if err != nil {
 		return nil, errors.Annotatef(err, "error reading CA private key for %s", clsName)
 	}
+
+	// read certificate
+	rawCert, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, errors.Annotatef(err, "error reading CA certificate for %s", clsName)
+	}
+	return LoadCA(clsName, rawCert, rawKey)
+}
+
+// LoadCA loads an existing CA certificate from the PEM encoded certificate and private key
+func LoadCA(clsName string, rawCert, rawKey []byte) (*CertificateAuthority, error) {
 	keyPem, _ := pem.Decode(rawKey)
 	if keyPem == nil {
 		return nil, errors.Errorf("error decoding CA private key for %s", clsName)
@@ -157,11 +168,6 @@ func ReadCA(clsName, certPath, keyPath string) (*CertificateAuthority, error) {
 		return nil, errors.Errorf("the CA private key type \"%s\" is not supported", keyPem.Type)
 	}
 
-	// read certificate
-	rawCert, err := os.ReadFile(certPath)
-	if err != nil {
-		return nil, errors.Annotatef(err, "error reading CA certificate for %s", clsName)
-	}
 	certPem, _ := pem.Decode(rawCert)
 	if certPem == nil {
 		return nil, errors.Errorf("error decoding CA certificate for %s", clsName)
@@ -66,6 +66,9 @@ const (
 	// EnvNameHome represents the environment name of tiup home directory
 	EnvNameHome = "TIUP_HOME"
 
+	// EnvNameContainerRuntime represents the CLI of container runtime used by the container executor, docker or podman
+	EnvNameContainerRuntime = "TIUP_CONTAINER_RUNTIME"
+
 	// EnvNameTelemetryStatus represents the environment name of tiup telemetry status
 	EnvNameTelemetryStatus = "TIUP_TELEMETRY_STATUS"
 
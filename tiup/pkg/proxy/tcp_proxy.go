@@ -15,7 +15,6 @@ package proxy
 
 import (
 	"fmt"
-	"io"
 	"net"
 	"strconv"
 	"sync"
@@ -36,10 +35,16 @@ type TCPProxy struct {
 	l        sync.RWMutex
 	listener net.Listener
 	cli      *ssh.Client
-	config   *easyssh.MakeConfig
+	config   *easyssh.MakeConfig // nil if the upstream is dialed directly
 	closed   int32
 	endpoint string
 	logger   *logprinter.Logger
+
+	// the network faults injected into the connections
+	fl      sync.Mutex
+	latency time.Duration
+	drop    bool
+	conns   map[net.Conn]struct{}
 }
 
 // NewTCPProxy starts a 1to1 TCP proxy
@@ -83,15 +88,89 @@ func NewTCPProxy(
 	return p
 }
 
+// NewLocalTCPProxy starts a 1to1 TCP proxy listening on the addr, the upstream
+// is dialed directly instead of via SSH
+func NewLocalTCPProxy(addr string, logger *logprinter.Logger) (*TCPProxy, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, perrs.Annotatef(err, "failed to listen on %s", addr)
+	}
+	return &TCPProxy{
+		listener: listener,
+		endpoint: listener.Addr().String(),
+		logger:   logger,
+	}, nil
+}
+
 // GetEndpoints returns the endpoint list
 func (p *TCPProxy) GetEndpoints() []string {
 	return []string{p.endpoint}
 }
 
-// Stop stops the tcp proxy
+// Stop stops the tcp proxy, the connections through it are closed
 func (p *TCPProxy) Stop() error {
 	atomic.StoreInt32(&p.closed, 1)
-	return p.listener.Close()
+	err := p.listener.Close()
+	p.fl.Lock()
+	defer p.fl.Unlock()
+	p.closeConns()
+	return err
+}
+
+// SetLatency delays the data in both directions by the latency
+func (p *TCPProxy) SetLatency(latency time.Duration) {
+	p.fl.Lock()
+	defer p.fl.Unlock()
+	p.latency = latency
+}
+
+func (p *TCPProxy) getLatency() time.Duration {
+	p.fl.Lock()
+	defer p.fl.Unlock()
+	return p.latency
+}
+
+// SetDrop closes the connections and refuses the new ones if drop is true
+func (p *TCPProxy) SetDrop(drop bool) {
+	p.fl.Lock()
+	defer p.fl.Unlock()
+	p.drop = drop
+	if drop {
+		p.closeConns()
+	}
+}
+
+// closeConns closes all the connections, the fl should be held
+func (p *TCPProxy) closeConns() {
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+	p.conns = nil
+}
+
+// track adds the connections to be closed when dropped, it returns false if
+// they should be dropped right away
+func (p *TCPProxy) track(conns ...net.Conn) bool {
+	p.fl.Lock()
+	defer p.fl.Unlock()
+	if p.drop || atomic.LoadInt32(&p.closed) == 1 {
+		return false
+	}
+	if p.conns == nil {
+		p.conns = make(map[net.Conn]struct{})
+	}
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *TCPProxy) untrack(conns ...net.Conn) {
+	p.fl.Lock()
+	defer p.fl.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
 }
 
 // Run runs proxy all traffic to upstream
@@ -147,55 +226,116 @@ func (p *TCPProxy) getConn() (*ssh.Client, error) {
 	return cli, nil
 }
 
-func (p *TCPProxy) forward(localConn io.ReadWriter, endpoints []string) {
+// dial connects to the endpoint via SSH, or directly if SSH is not set
+func (p *TCPProxy) dial(endpoint string) (net.Conn, error) {
+	if p.config == nil {
+		return net.DialTimeout("tcp", endpoint, 5*time.Second)
+	}
 	cli, err := p.getConn()
 	if err != nil {
-		zap.L().Error("Failed to get ssh client", zap.String("error", err.Error()))
+		return nil, err
+	}
+	return cli.Dial("tcp", endpoint)
+}
+
+func (p *TCPProxy) forward(localConn net.Conn, endpoints []string) {
+	defer localConn.Close()
+	if !p.track() {
 		return
 	}
+	if p.config != nil {
+		if _, err := p.getConn(); err != nil {
+			zap.L().Error("Failed to get ssh client", zap.String("error", err.Error()))
+			return
+		}
+	}
 
 	var remoteConn net.Conn
 OUTER_LOOP:
 	for _, endpoint := range endpoints {
 		endpoint := endpoint
-		errC := make(chan error, 1)
+		connC := make(chan net.Conn, 1)
 		go func() {
-			var err error
-			remoteConn, err = cli.Dial("tcp", endpoint)
+			conn, err := p.dial(endpoint)
 			if err != nil {
 				zap.L().Error("Failed to connect endpoint", zap.String("error", err.Error()))
 			}
-
-			errC <- err
+			connC <- conn
 		}()
 
 		select {
-		case err := <-errC:
-			if err == nil {
+		case conn := <-connC:
+			if conn != nil {
+				remoteConn = conn
 				break OUTER_LOOP
 			}
 		case <-time.After(5 * time.Second):
 			zap.L().Debug("Connect to endpoint timeout, retry the next endpoint", zap.String("endpoint", endpoint))
 		}
 	}
+	if remoteConn == nil {
+		return
+	}
+	defer remoteConn.Close()
+	if !p.track(localConn, remoteConn) {
+		return
+	}
+	defer p.untrack(localConn, remoteConn)
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		_, err = io.Copy(remoteConn, localConn)
-		if err != nil {
+		if err := p.pipe(remoteConn, localConn); err != nil {
 			zap.L().Error("Failed to copy from local to remote", zap.String("error", err.Error()))
 		}
 	}()
 
 	go func() {
 		defer wg.Done()
-		_, err = io.Copy(localConn, remoteConn)
-		if err != nil {
+		if err := p.pipe(localConn, remoteConn); err != nil {
 			zap.L().Error("Failed to copy from remote to local", zap.String("error", err.Error()))
 		}
 	}()
 	wg.Wait()
 }
+
+// pipe copies the data from src to dst, each chunk of data is delayed by the
+// latency when it's read, so the throughput is kept
+func (p *TCPProxy) pipe(dst, src net.Conn) error {
+	type chunk struct {
+		data []byte
+		at   time.Time
+	}
+	chunks := make(chan chunk, 64)
+
+	go func() {
+		defer close(chunks)
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := src.Read(buf)
+			if n > 0 {
+				chunks <- chunk{buf[:n], time.Now().Add(p.getLatency())}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var err error
+	for c := range chunks {
+		if err != nil {
+			continue
+		}
+		time.Sleep(time.Until(c.at))
+		if _, err = dst.Write(c.data); err != nil {
+			_ = src.Close()
+		}
+	}
+	if cw, ok := dst.(interface{ CloseWrite() error }); ok && err == nil {
+		_ = cw.CloseWrite()
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	logprinter "github.com/pingcap/tiup/pkg/logger/printer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalTCPProxyFaults(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	proxy, err := NewLocalTCPProxy("127.0.0.1:0", logprinter.NewLogger(""))
+	assert.Nil(t, err)
+	proxy.Run([]string{l.Addr().String()})
+	addr := proxy.GetEndpoints()[0]
+	echo := func(conn net.Conn) (time.Duration, error) {
+		start := time.Now()
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			return 0, err
+		}
+		buf := make([]byte, 4)
+		_, err := io.ReadFull(conn, buf)
+		return time.Since(start), err
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	_, err = echo(conn)
+	assert.Nil(t, err)
+
+	// the latency is added in both directions
+	proxy.SetLatency(100 * time.Millisecond)
+	rtt, err := echo(conn)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, int64(rtt), int64(200*time.Millisecond))
+	proxy.SetLatency(0)
+
+	// the connections are closed and the new ones are refused
+	proxy.SetDrop(true)
+	_, err = echo(conn)
+	assert.NotNil(t, err)
+	conn, err = net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	_, err = echo(conn)
+	assert.NotNil(t, err)
+
+	proxy.SetDrop(false)
+	conn, err = net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	_, err = echo(conn)
+	assert.Nil(t, err)
+
+	assert.Nil(t, proxy.Stop())
+	_, err = echo(conn)
+	assert.NotNil(t, err)
+	_, err = net.Dial("tcp", addr)
+	assert.NotNil(t, err)
+}
@@ -16,12 +16,14 @@ package tui
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"syscall"
 
 	"github.com/AstroProfundis/tabby"
 	"github.com/fatih/color"
+	"github.com/juju/ansiterm"
 	"github.com/pingcap/tiup/pkg/utils/mock"
 	"golang.org/x/term"
 )
@@ -33,8 +35,12 @@ func PrintTable(rows [][]string, header bool) {
 		return
 	}
 
-	// Print the table
-	t := tabby.New()
+	PrintTableTo(os.Stdout, rows, header)
+}
+
+// PrintTableTo prints the matrix of strings as ASCII table to the writer
+func PrintTableTo(w io.Writer, rows [][]string, header bool) {
+	t := tabby.NewCustom(ansiterm.NewTabWriter(w, 0, 0, 2, ' ', 0))
 	if header {
 		addRow(t, rows[0], header)
 		rows = rows[1:]
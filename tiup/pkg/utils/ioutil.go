@@ -92,7 +92,8 @@ func IsSubDir(parent, sub string) bool {
 	return false
 }
 
-// Untar decompresses the tarball
+// Untar decompresses the tarball, the entries out of the target dir are
+// rejected
 func Untar(reader io.Reader, to string) error {
 	gr, err := gzip.NewReader(reader)
 	if err != nil {
@@ -128,6 +129,9 @@ func Untar(reader io.Reader, to string) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
+		if !isLocalPath(hdr.Name) {
+			return errors.Errorf("invalid entry %s in the tarball, it's out of the target dir", hdr.Name)
+		}
 		if hdr.FileInfo().IsDir() {
 			if err := os.MkdirAll(path.Join(to, hdr.Name), hdr.FileInfo().Mode()); err != nil {
 				return errors.Trace(err)
@@ -141,6 +145,19 @@ func Untar(reader io.Reader, to string) error {
 	return nil
 }
 
+// isLocalPath returns whether the path is relative and in its base dir
+func isLocalPath(name string) bool {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return false
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(name), "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
 // Copy copies a file or directory from src to dst
 func Copy(src, dst string) error {
 	// check if src is a directory
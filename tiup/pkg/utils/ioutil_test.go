@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"math/rand"
 	"os"
 	"path"
@@ -74,6 +76,23 @@ func (s *TestIOUtilSuite) TestUntar(c *C) {
 	err = Untar(f, path.Join(currentDir(), "testdata"))
 	c.Assert(err, IsNil)
 	c.Assert(IsExist(path.Join(currentDir(), "testdata", "parent", "child", "content")), IsTrue)
+
+	// the entries out of the target dir are rejected
+	for _, name := range []string{"../escaped", "/tmp/escaped", "a/../../escaped"} {
+		buf := new(bytes.Buffer)
+		gw := gzip.NewWriter(buf)
+		tw := tar.NewWriter(gw)
+		c.Assert(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 1}), IsNil)
+		_, err = tw.Write([]byte("x"))
+		c.Assert(err, IsNil)
+		c.Assert(tw.Close(), IsNil)
+		c.Assert(gw.Close(), IsNil)
+
+		dir := c.MkDir()
+		err = Untar(buf, filepath.Join(dir, "target"))
+		c.Assert(err, ErrorMatches, ".*out of the target dir.*", Commentf(name))
+		c.Assert(IsNotExist(filepath.Join(dir, "escaped")), IsTrue)
+	}
 }
 
 func (s *TestIOUtilSuite) TestCopy(c *C) {